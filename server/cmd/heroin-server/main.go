@@ -8,17 +8,24 @@ import (
 	"syscall"
 	"time"
 
+	"dev.c0rex64.heroin/internal/account"
 	grpcapi "dev.c0rex64.heroin/internal/api/grpc"
 	httpapi "dev.c0rex64.heroin/internal/api/http"
 	"dev.c0rex64.heroin/internal/config"
 	"dev.c0rex64.heroin/internal/discovery"
 	"dev.c0rex64.heroin/internal/groups"
+	"dev.c0rex64.heroin/internal/ipfs"
 	"dev.c0rex64.heroin/internal/metrics"
+	"dev.c0rex64.heroin/internal/notify"
 	"dev.c0rex64.heroin/internal/p2p"
+	"dev.c0rex64.heroin/internal/presence"
 	"dev.c0rex64.heroin/internal/relay"
 	"dev.c0rex64.heroin/internal/routing"
+	"dev.c0rex64.heroin/internal/sharing"
+	"dev.c0rex64.heroin/internal/storage"
 	"dev.c0rex64.heroin/internal/store"
 	"dev.c0rex64.heroin/internal/transport"
+	"dev.c0rex64.heroin/internal/vfs"
 
 	"github.com/libp2p/go-libp2p/core/peer"
 )
@@ -122,6 +129,13 @@ func main() {
 	// создаем сервис групп
 	groupSvc := groups.NewService(db.SQL)
 
+	// присутствие: стрим событий плюс живость прямых p2p соединений
+	presenceStore := presence.NewStore(db.SQL)
+	presenceHub := presence.NewHub(presenceStore)
+	p2pStream.SetLivenessHandler(func(id peer.ID, alive bool) {
+		presenceHub.PeerLive(id.String(), alive)
+	})
+
 	// собираем сервисы
 	services, err := grpcapi.BuildServices(ctx, cfg, db)
 	if err != nil {
@@ -140,17 +154,120 @@ func main() {
 	gs.StreamMgr = p2pStream
 	gs.RelayMgr = relayMgr
 	gs.Router = router
+	gs.PresenceHub = presenceHub
+	gs.PresenceStore = presenceStore
+	ic := cfg.IPFS.Client
+	ipfsOpts := ipfs.Options{
+		Timeout:         time.Duration(ic.TimeoutSec) * time.Second,
+		PinTimeout:      time.Duration(ic.PinTimeoutSec) * time.Second,
+		ImportTimeout:   time.Duration(ic.ImportTimeoutSec) * time.Second,
+		ExportTimeout:   time.Duration(ic.ExportTimeoutSec) * time.Second,
+		Retries:         ic.Retries,
+		RetryBase:       time.Duration(ic.RetryBaseMs) * time.Millisecond,
+		RetryMax:        time.Duration(ic.RetryMaxMs) * time.Millisecond,
+		BreakerFailures: ic.BreakerFailures,
+		BreakerCooldown: time.Duration(ic.BreakerCooldownSec) * time.Second,
+		Username:        cfg.IPFS.Auth.Username,
+		Password:        cfg.IPFS.Auth.Password,
+		Token:           cfg.IPFS.Auth.Token,
+	}
+	it := cfg.IPFS.TLS
+	if ipfsOpts.TLS, err = ipfs.LoadTLS(it.CAFile, it.CertFile, it.KeyFile, it.ServerName); err != nil {
+		log.Fatalf("ipfs tls: %v", err)
+	}
+	healthInterval := time.Duration(ic.HealthIntervalSec) * time.Second
 	gs.WireStorageAndMessaging(
 		cfg.IPFS.Endpoint,
+		ipfsOpts,
 		cfg.IPFS.PinningEnabled,
 		cfg.IPFS.ReplicationFactor,
+		cfg.IPFS.MaxUploadBytes,
 		db.SQL,
 		services,
 		collector,
 	)
+	s3 := cfg.IPFS.S3
+	err = gs.WireBlobStore(cfg.IPFS.Backend, cfg.IPFS.BlobDir, storage.S3Options{
+		Endpoint:  s3.Endpoint,
+		Region:    s3.Region,
+		Bucket:    s3.Bucket,
+		AccessKey: s3.AccessKey,
+		SecretKey: s3.SecretKey,
+		Prefix:    s3.Prefix,
+		PathStyle: s3.PathStyle,
+		SpoolDir:  s3.SpoolDir,
+	})
+	if err != nil {
+		log.Fatalf("blob store: %v", err)
+	}
+	if err := gs.WireCARPolicy(cfg.IPFS.MaxBlockBytes, cfg.IPFS.AllowedCodecs); err != nil {
+		log.Fatalf("car policy: %v", err)
+	}
+	if len(cfg.IPFS.Endpoints) > 0 {
+		if err := gs.WireReplication(ctx, db.SQL, cfg.IPFS.Endpoints, cfg.IPFS.ReplicationFactor, cfg.IPFS.WriteQuorum, healthInterval, time.Duration(cfg.IPFS.RepairIntervalMin)*time.Minute); err != nil {
+			log.Fatalf("replication: %v", err)
+		}
+	}
+	if cfg.IPFS.CacheDir != "" {
+		if err := gs.WireBlobCache(cfg.IPFS.CacheDir, cfg.IPFS.CacheMaxBytes, cfg.IPFS.CacheMaxEntryBytes); err != nil {
+			log.Fatalf("blob cache: %v", err)
+		}
+	}
+	gs.WireIPFSHealth(ctx, healthInterval)
+	gs.WireBlockIndex(ctx, db.SQL, time.Duration(cfg.IPFS.BlockGCIntervalMin)*time.Minute)
+	gs.WireVersionRetention(ctx, cfg.IPFS.VersionKeep, cfg.IPFS.VersionKeepDays)
+	if cfg.IPFS.UploadStagingDir != "" {
+		if err := gs.WireUploads(ctx, db.SQL, cfg.IPFS.UploadStagingDir, time.Duration(cfg.IPFS.UploadTTLHours)*time.Hour); err != nil {
+			log.Fatalf("uploads: %v", err)
+		}
+	}
+	if cfg.IPFS.OutboardDir != "" {
+		if err := gs.WireOutboards(ctx, db.SQL, cfg.IPFS.OutboardDir); err != nil {
+			log.Fatalf("outboards: %v", err)
+		}
+	}
+	if err := gs.WireQuotas(ctx, db.SQL, cfg.IPFS.QuotaPlans, cfg.IPFS.QuotaDefaultPlan, time.Minute); err != nil {
+		log.Fatalf("quotas: %v", err)
+	}
+	if cfg.IPFS.ReconcileIntervalMin > 0 {
+		gs.WireReconciler(ctx, db.SQL, time.Duration(cfg.IPFS.ReconcileIntervalMin)*time.Minute, time.Duration(cfg.IPFS.OrphanGraceHours)*time.Hour)
+	}
+	gs.AdminIDs = cfg.Security.AdminUserIDs
+	// каталоги с зашифрованными именами поверх загруженных файлов
+	gs.VFS = vfs.NewService(db.SQL)
+	// общий доступ и публичные ссылки, по ним же GetFile проверяет права на cid
+	gs.Sharing = sharing.NewService(db.SQL)
+
+	// экспорт и импорт аккаунта, архивы подписываются ключом сервера.
+	// car данные идут через storage: реплики, проверки car и квоты как у загрузок
+	blobs, ok := gs.StorageSvc.(*storage.Service)
+	if !ok {
+		log.Fatalf("account: storage service not configured")
+	}
+	gs.AccountSvc = account.NewService(db.SQL, blobs, cfg.SigningKey(), cfg.TrustedArchiveKeys())
+
+	// удаление аккаунта фоновым заданием, незавершенные задания продолжаются после рестарта
+	deleter := account.NewDeleter(db.SQL, groupSvc, blobs, cfg.AuditLegalHold(), cfg.Security.Deletion.MaxAttempts)
+	deleter.Start(ctx)
+	gs.Deleter = deleter
+
+	// офлайн уведомления через подписанный вебхук, токены принимаем всегда
+	pushTokens := notify.NewStore(db.SQL)
+	gs.PushTokens = pushTokens
+	if cfg.Notifications.WebhookURL != "" {
+		webhook := notify.NewWebhookNotifier(
+			cfg.Notifications.WebhookURL,
+			[]byte(cfg.Notifications.WebhookSecret),
+			time.Duration(cfg.Notifications.TimeoutSec)*time.Second,
+		)
+		dispatcher := notify.NewDispatcher(webhook, pushTokens, cfg.Notifications.MaxAttempts)
+		dispatcher.Start(ctx, cfg.Notifications.Workers)
+		gs.WireNotifications(dispatcher, pushTokens, groupSvc)
+	}
 
 	// http сервер
 	hs := httpapi.New(":8081")
+	hs.SetIPFSHealth(gs.IPFSHealth)
 
 	// запускаем серверы
 	go func() {
@@ -0,0 +1,226 @@
+package account
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"lukechampine.com/blake3"
+)
+
+// формат архива:
+//
+//	magic "HEROINAR" | version uint16 be | записи... | трейлер
+//	запись: kind uint8 | uvarint длина | payload
+//	трейлер: kindTrailer | uvarint 128 | blake3-256 всего предыдущего (32) | ed25519 pub (32) | подпись (64)
+//
+// подписывается signContext || digest, так что подпись покрывает весь архив.
+//
+// записи: заголовок, пользователь без учетных данных, устройства, членства, сообщения,
+// файлы, версии, превью, узлы vfs и в конце car данные их содержимого. общий доступ
+// и публичные ссылки архив не переносит: выдачи ссылаются на пользователей и группы
+// этого сервера, а ссылки - токены этого сервера. на новом сервере их выдают заново
+const (
+	archiveMagic   = "HEROINAR"
+	ArchiveVersion = 1
+
+	signContext = "heroin account archive v1"
+
+	maxRecordSize = 16 << 20
+	carChunkSize  = 256 << 10
+
+	defaultMaxSpool = 4 << 30
+)
+
+type recordKind uint8
+
+const (
+	kindHeader recordKind = iota + 1
+	kindUser
+	kindDevice
+	kindMembership
+	kindMessage
+	kindFile
+	kindCARChunk
+	kindCAREnd
+	kindVersion
+	kindPreview
+	kindVFSNode
+
+	kindTrailer recordKind = 0xFF
+)
+
+var (
+	ErrBadMagic           = errors.New("not an account archive")
+	ErrUnsupportedVersion = errors.New("unsupported archive version")
+	ErrTruncated          = errors.New("archive truncated")
+	ErrDigestMismatch     = errors.New("archive digest mismatch")
+	ErrBadSignature       = errors.New("archive signature invalid")
+	ErrUntrustedKey       = errors.New("archive signed by untrusted key")
+)
+
+// пишет архив и считает blake3 по всем байтам до трейлера
+type archiveWriter struct {
+	w      *bufio.Writer
+	h      *blake3.Hasher
+	signer ed25519.PrivateKey
+}
+
+func newArchiveWriter(w io.Writer, signer ed25519.PrivateKey) (*archiveWriter, error) {
+	aw := &archiveWriter{w: bufio.NewWriterSize(w, carChunkSize), h: blake3.New(32, nil), signer: signer}
+	var hdr [len(archiveMagic) + 2]byte
+	copy(hdr[:], archiveMagic)
+	binary.BigEndian.PutUint16(hdr[len(archiveMagic):], ArchiveVersion)
+	if err := aw.write(hdr[:]); err != nil {
+		return nil, err
+	}
+	return aw, nil
+}
+
+func (aw *archiveWriter) write(b []byte) error {
+	aw.h.Write(b)
+	_, err := aw.w.Write(b)
+	return err
+}
+
+func (aw *archiveWriter) record(kind recordKind, payload []byte) error {
+	if len(payload) > maxRecordSize {
+		return fmt.Errorf("archive record too large: %d", len(payload))
+	}
+	var pfx [1 + binary.MaxVarintLen64]byte
+	pfx[0] = byte(kind)
+	n := binary.PutUvarint(pfx[1:], uint64(len(payload)))
+	if err := aw.write(pfx[:1+n]); err != nil {
+		return err
+	}
+	return aw.write(payload)
+}
+
+// кусок car данных файла, cid повторяется в каждом куске
+func (aw *archiveWriter) carChunk(cid string, data []byte) error {
+	payload := make([]byte, 0, binary.MaxVarintLen64+len(cid)+len(data))
+	payload = binary.AppendUvarint(payload, uint64(len(cid)))
+	payload = append(payload, cid...)
+	payload = append(payload, data...)
+	return aw.record(kindCARChunk, payload)
+}
+
+// дописать трейлер с дайджестом и подписью
+func (aw *archiveWriter) close() error {
+	digest := aw.h.Sum(nil)
+	sig := ed25519.Sign(aw.signer, signedMessage(digest))
+	trailer := make([]byte, 0, 32+ed25519.PublicKeySize+ed25519.SignatureSize)
+	trailer = append(trailer, digest...)
+	trailer = append(trailer, aw.signer.Public().(ed25519.PublicKey)...)
+	trailer = append(trailer, sig...)
+	var pfx [1 + binary.MaxVarintLen64]byte
+	pfx[0] = byte(kindTrailer)
+	n := binary.PutUvarint(pfx[1:], uint64(len(trailer)))
+	if _, err := aw.w.Write(pfx[:1+n]); err != nil {
+		return err
+	}
+	if _, err := aw.w.Write(trailer); err != nil {
+		return err
+	}
+	return aw.w.Flush()
+}
+
+func signedMessage(digest []byte) []byte {
+	return append([]byte(signContext), digest...)
+}
+
+type record struct {
+	kind    recordKind
+	payload []byte
+}
+
+// читает записи, после трейлера проверяет дайджест и подпись и возвращает io.EOF.
+// до io.EOF содержимому архива доверять нельзя
+type archiveReader struct {
+	r       *bufio.Reader
+	h       *blake3.Hasher
+	trusted func(ed25519.PublicKey) bool
+	signer  ed25519.PublicKey
+	done    bool
+}
+
+func newArchiveReader(r io.Reader, trusted func(ed25519.PublicKey) bool) (*archiveReader, error) {
+	ar := &archiveReader{r: bufio.NewReaderSize(r, carChunkSize), h: blake3.New(32, nil), trusted: trusted}
+	var hdr [len(archiveMagic) + 2]byte
+	if _, err := io.ReadFull(ar.r, hdr[:]); err != nil {
+		return nil, ErrBadMagic
+	}
+	if string(hdr[:len(archiveMagic)]) != archiveMagic {
+		return nil, ErrBadMagic
+	}
+	if v := binary.BigEndian.Uint16(hdr[len(archiveMagic):]); v != ArchiveVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, v)
+	}
+	ar.h.Write(hdr[:])
+	return ar, nil
+}
+
+func (ar *archiveReader) next() (record, error) {
+	if ar.done {
+		return record{}, io.EOF
+	}
+	kb, err := ar.r.ReadByte()
+	if err != nil {
+		return record{}, ErrTruncated
+	}
+	size, err := binary.ReadUvarint(ar.r)
+	if err != nil {
+		return record{}, ErrTruncated
+	}
+	if size > maxRecordSize {
+		return record{}, fmt.Errorf("archive record too large: %d", size)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(ar.r, payload); err != nil {
+		return record{}, ErrTruncated
+	}
+	kind := recordKind(kb)
+	if kind == kindTrailer {
+		if err := ar.verify(payload); err != nil {
+			return record{}, err
+		}
+		ar.done = true
+		return record{}, io.EOF
+	}
+	ar.h.Write([]byte{kb})
+	ar.h.Write(binary.AppendUvarint(nil, size))
+	ar.h.Write(payload)
+	return record{kind: kind, payload: payload}, nil
+}
+
+func (ar *archiveReader) verify(trailer []byte) error {
+	if len(trailer) != 32+ed25519.PublicKeySize+ed25519.SignatureSize {
+		return ErrTruncated
+	}
+	digest := trailer[:32]
+	pub := ed25519.PublicKey(trailer[32 : 32+ed25519.PublicKeySize])
+	sig := trailer[32+ed25519.PublicKeySize:]
+	if !bytes.Equal(digest, ar.h.Sum(nil)) {
+		return ErrDigestMismatch
+	}
+	if !ed25519.Verify(pub, signedMessage(digest), sig) {
+		return ErrBadSignature
+	}
+	if ar.trusted != nil && !ar.trusted(pub) {
+		return ErrUntrustedKey
+	}
+	ar.signer = pub
+	return nil
+}
+
+func parseCARChunk(payload []byte) (string, []byte, error) {
+	n, k := binary.Uvarint(payload)
+	if k <= 0 || uint64(len(payload)-k) < n {
+		return "", nil, errors.New("malformed car chunk")
+	}
+	return string(payload[k : k+int(n)]), payload[k+int(n):], nil
+}
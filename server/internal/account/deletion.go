@@ -0,0 +1,319 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// исключение из групп с ротацией ключа, реализуется groups.Service
+type GroupRemover interface {
+	UserGroupIDs(ctx context.Context, userID string) ([]string, error)
+	RemoveUserAndRotate(ctx context.Context, groupID, userID string) error
+}
+
+// освобождение содержимого без ссылок, реализуется storage.Service: открепление,
+// outboard и gc блоков идут тем же путем, что и при удалении файла
+type ContentReleaser interface {
+	ReleaseUnreferenced(ctx context.Context, cids ...string) error
+}
+
+type DeletionState string
+
+const (
+	DeletionPending DeletionState = "pending"
+	DeletionRunning DeletionState = "running"
+	DeletionDone    DeletionState = "done"
+	DeletionFailed  DeletionState = "failed"
+)
+
+var ErrDeletionNotFound = errors.New("deletion job not found")
+
+type DeletionJob struct {
+	ID        string
+	UserID    string
+	State     DeletionState
+	Step      string
+	Attempts  int
+	LastError string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type deletionStep struct {
+	name string
+	run  func(ctx context.Context, userID string) error
+}
+
+// фоновое удаление аккаунта по шагам. каждый шаг идемпотентен, номер следующего шага
+// хранится в account_deletions, поэтому после рестарта задание продолжается с места остановки
+type Deleter struct {
+	db          *sql.DB
+	groups      GroupRemover
+	contents    ContentReleaser
+	legalHold   time.Duration
+	maxAttempts int
+	interval    time.Duration
+	steps       []deletionStep
+	wake        chan struct{}
+}
+
+// legalHold - сколько хранить записи аудита, более свежие остаются привязанными к надгробию пользователя
+func NewDeleter(db *sql.DB, groups GroupRemover, contents ContentReleaser, legalHold time.Duration, maxAttempts int) *Deleter {
+	if maxAttempts <= 0 {
+		maxAttempts = 10
+	}
+	d := &Deleter{
+		db:          db,
+		groups:      groups,
+		contents:    contents,
+		legalHold:   legalHold,
+		maxAttempts: maxAttempts,
+		interval:    30 * time.Second,
+		wake:        make(chan struct{}, 1),
+	}
+	d.steps = []deletionStep{
+		{"credentials", d.eraseCredentials},
+		{"sessions", d.deleteSessions},
+		{"devices", d.deleteDevices},
+		{"groups", d.leaveGroups},
+		{"messages", d.deleteMessages},
+		{"audit", d.deleteAudit},
+		{"files", d.unpinFiles},
+		{"erase", d.tombstone},
+	}
+	return d
+}
+
+// поставить аккаунт в очередь на удаление, повторный вызов возвращает то же задание,
+// упавшее задание перезапускается
+func (d *Deleter) Schedule(ctx context.Context, userID string) (*DeletionJob, error) {
+	now := time.Now().Unix()
+	_, err := d.db.ExecContext(ctx, `INSERT INTO account_deletions (id, user_id, state, step, attempts, created_at, updated_at) VALUES (?, ?, ?, 0, 0, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET state = CASE WHEN state = 'failed' THEN 'pending' ELSE state END,
+			attempts = CASE WHEN state = 'failed' THEN 0 ELSE attempts END, updated_at = excluded.updated_at`,
+		uuid.NewString(), userID, DeletionPending, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("schedule deletion: %w", err)
+	}
+	if _, err := d.db.ExecContext(ctx, `INSERT INTO audit_logs (id, user_id, device_id, event_type, created_at) VALUES (?, ?, NULL, ?, ?)`,
+		uuid.NewString(), userID, "account_deletion_requested", now); err != nil {
+		slog.Warn("account deletion: audit", "user_id", userID, "error", err)
+	}
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return d.load(ctx, `SELECT id, user_id, state, step, attempts, COALESCE(last_error, ''), created_at, updated_at FROM account_deletions WHERE user_id = ?`, userID)
+}
+
+func (d *Deleter) Status(ctx context.Context, jobID string) (*DeletionJob, error) {
+	return d.load(ctx, `SELECT id, user_id, state, step, attempts, COALESCE(last_error, ''), created_at, updated_at FROM account_deletions WHERE id = ?`, jobID)
+}
+
+func (d *Deleter) load(ctx context.Context, query string, arg string) (*DeletionJob, error) {
+	var j DeletionJob
+	var step int
+	var created, updated int64
+	err := d.db.QueryRowContext(ctx, query, arg).Scan(&j.ID, &j.UserID, &j.State, &step, &j.Attempts, &j.LastError, &created, &updated)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDeletionNotFound
+		}
+		return nil, fmt.Errorf("load deletion: %w", err)
+	}
+	j.Step = d.stepName(step)
+	j.CreatedAt = time.Unix(created, 0)
+	j.UpdatedAt = time.Unix(updated, 0)
+	return &j, nil
+}
+
+func (d *Deleter) stepName(step int) string {
+	if step >= 0 && step < len(d.steps) {
+		return d.steps[step].name
+	}
+	return "done"
+}
+
+// запустить обработку очереди, останавливается по отмене ctx
+func (d *Deleter) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+		for {
+			d.runPending(ctx)
+			select {
+			case <-ticker.C:
+			case <-d.wake:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (d *Deleter) runPending(ctx context.Context) {
+	rows, err := d.db.QueryContext(ctx, `SELECT id, user_id, step, attempts FROM account_deletions WHERE state IN ('pending', 'running') ORDER BY created_at`)
+	if err != nil {
+		slog.Error("account deletion: list jobs", "error", err)
+		return
+	}
+	type pending struct {
+		id, userID     string
+		step, attempts int
+	}
+	var jobs []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.userID, &p.step, &p.attempts); err != nil {
+			rows.Close()
+			slog.Error("account deletion: scan job", "error", err)
+			return
+		}
+		jobs = append(jobs, p)
+	}
+	rows.Close()
+	for _, j := range jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		d.run(ctx, j.id, j.userID, j.step, j.attempts)
+	}
+}
+
+// выполнить оставшиеся шаги, при ошибке задание ждет следующего тика
+func (d *Deleter) run(ctx context.Context, jobID, userID string, step, attempts int) {
+	for ; step < len(d.steps); step++ {
+		if err := d.steps[step].run(ctx, userID); err != nil {
+			attempts++
+			state := DeletionRunning
+			if attempts >= d.maxAttempts {
+				state = DeletionFailed
+			}
+			slog.Warn("account deletion: step failed", "job_id", jobID, "step", d.steps[step].name, "attempt", attempts, "error", err)
+			d.update(ctx, jobID, state, step, attempts, err.Error())
+			return
+		}
+		d.update(ctx, jobID, DeletionRunning, step+1, 0, "")
+	}
+	d.update(ctx, jobID, DeletionDone, step, 0, "")
+}
+
+func (d *Deleter) update(ctx context.Context, jobID string, state DeletionState, step, attempts int, lastErr string) {
+	_, err := d.db.ExecContext(ctx, `UPDATE account_deletions SET state = ?, step = ?, attempts = ?, last_error = NULLIF(?, ''), updated_at = ? WHERE id = ?`,
+		state, step, attempts, lastErr, time.Now().Unix(), jobID)
+	if err != nil {
+		slog.Error("account deletion: update job", "job_id", jobID, "error", err)
+	}
+}
+
+// шаги
+
+// сначала уничтожаем секреты входа, чтобы во время удаления нельзя было войти заново
+func (d *Deleter) eraseCredentials(ctx context.Context, userID string) error {
+	_, err := d.db.ExecContext(ctx, `UPDATE users SET server_salt = x'', password_hash = x'', verifier = NULL, second_factor_secret = x'' WHERE id = ?`, userID)
+	return err
+}
+
+func (d *Deleter) deleteSessions(ctx context.Context, userID string) error {
+	_, err := d.db.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = ?`, userID)
+	return err
+}
+
+func (d *Deleter) deleteDevices(ctx context.Context, userID string) error {
+	for _, q := range []string{
+		`DELETE FROM push_tokens WHERE user_id = ?`,
+		`DELETE FROM presence_settings WHERE user_id = ?`,
+		`DELETE FROM devices WHERE user_id = ?`,
+	} {
+		if _, err := d.db.ExecContext(ctx, q, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Deleter) leaveGroups(ctx context.Context, userID string) error {
+	if d.groups == nil {
+		_, err := d.db.ExecContext(ctx, `DELETE FROM group_members WHERE user_id = ?`, userID)
+		return err
+	}
+	ids, err := d.groups.UserGroupIDs(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := d.groups.RemoveUserAndRotate(ctx, id, userID); err != nil {
+			return fmt.Errorf("leave group %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func (d *Deleter) deleteMessages(ctx context.Context, userID string) error {
+	if _, err := d.db.ExecContext(ctx, `DELETE FROM messages WHERE json_extract(CAST(envelope AS TEXT), '$.sender_id') = ?`, userID); err != nil {
+		return err
+	}
+	_, err := d.db.ExecContext(ctx, `DELETE FROM message_refs WHERE sender_id = ?`, userID)
+	return err
+}
+
+func (d *Deleter) deleteAudit(ctx context.Context, userID string) error {
+	cutoff := time.Now().Add(-d.legalHold).Unix()
+	_, err := d.db.ExecContext(ctx, `DELETE FROM audit_logs WHERE user_id = ? AND created_at < ?`, userID, cutoff)
+	return err
+}
+
+// файлы удаляются сразу, счетчики contents уменьшают триггеры. освобождается только
+// содержимое без ссылок, включая оставшееся от прошлых попыток и чужих удалений, так что
+// повтор шага безопасен. cid, который сейчас загружает кто-то другой, освобождение
+// пропускает, его подберет сверка пинов
+func (d *Deleter) unpinFiles(ctx context.Context, userID string) error {
+	// выданные и полученные доступы и ссылки уходят раньше файлов, на которые ссылаются
+	if _, err := d.db.ExecContext(ctx, `DELETE FROM share_grants WHERE owner_id = ? OR (grantee_kind = 'user' AND grantee_id = ?)`, userID, userID); err != nil {
+		return err
+	}
+	if _, err := d.db.ExecContext(ctx, `DELETE FROM share_links WHERE owner_id = ?`, userID); err != nil {
+		return err
+	}
+	// дерево каталогов с зашифрованными именами уходит вместе с файлами
+	if _, err := d.db.ExecContext(ctx, `DELETE FROM vfs_nodes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	if _, err := d.db.ExecContext(ctx, `DELETE FROM files WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	if d.contents == nil {
+		return nil
+	}
+	rows, err := d.db.QueryContext(ctx, `SELECT cid FROM contents WHERE refcount <= 0`)
+	if err != nil {
+		return err
+	}
+	var cids []string
+	for rows.Next() {
+		var cid string
+		if err := rows.Scan(&cid); err != nil {
+			rows.Close()
+			return err
+		}
+		cids = append(cids, cid)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return d.contents.ReleaseUnreferenced(ctx, cids...)
+}
+
+// пользователь остается надгробием без ключей и имени: на него ссылаются записи аудита
+// под legal hold и созданные им группы, имя освобождается для новой регистрации
+func (d *Deleter) tombstone(ctx context.Context, userID string) error {
+	_, err := d.db.ExecContext(ctx, `UPDATE users SET username = 'deleted:' || id, public_key = NULL WHERE id = ?`, userID)
+	return err
+}
@@ -0,0 +1,144 @@
+package account
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+)
+
+// освобождение содержимого, которое отказывает fail раз подряд
+type flakyReleaser struct {
+	ts    *testServer
+	fail  int
+	calls int
+}
+
+func (f *flakyReleaser) ReleaseUnreferenced(ctx context.Context, cids ...string) error {
+	f.calls++
+	if f.fail > 0 {
+		f.fail--
+		return errors.New("storage unavailable")
+	}
+	return f.ts.svc.ReleaseUnreferenced(ctx, cids...)
+}
+
+func newTestDeleter(t *testing.T, fail, maxAttempts int) (*Deleter, *testServer, *flakyReleaser) {
+	t.Helper()
+	_, key, _ := ed25519.GenerateKey(nil)
+	ts := newTestServer(t, key)
+	seedSource(t, ts)
+	r := &flakyReleaser{ts: ts, fail: fail}
+	return NewDeleter(ts.db.SQL, nil, r, 0, maxAttempts), ts, r
+}
+
+func schedule(t *testing.T, d *Deleter) *DeletionJob {
+	t.Helper()
+	job, err := d.Schedule(context.Background(), "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func status(t *testing.T, d *Deleter, jobID string) *DeletionJob {
+	t.Helper()
+	job, err := d.Status(context.Background(), jobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestDeletionReleasesOnlyOwnContent(t *testing.T) {
+	d, ts, _ := newTestDeleter(t, 0, 0)
+	ctx := context.Background()
+	// у u2 файл с тем же содержимым, что у первой версии u1
+	shared := testCAR(t, []byte("first version"))
+	if _, _, err := ts.svc.PutCAR(ctx, "u2", "d2", "b.txt", "text/plain", int64(len(shared)), bytes.NewReader(shared), nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(ts.stored(t)); n != 3 {
+		t.Fatalf("%d cars stored before deletion", n)
+	}
+
+	job := schedule(t, d)
+	d.runPending(ctx)
+	if job = status(t, d, job.ID); job.State != DeletionDone {
+		t.Fatalf("job %+v", job)
+	}
+	if cids := ts.stored(t); len(cids) != 1 {
+		t.Fatalf("cars left after deletion: %v", cids)
+	}
+	if n := ts.count(t, `SELECT COUNT(*) FROM contents WHERE refcount <= 0`); n != 0 {
+		t.Fatalf("%d unreferenced content rows left", n)
+	}
+	if n := ts.count(t, `SELECT COUNT(*) FROM users WHERE id = 'u1' AND username = 'deleted:u1' AND public_key IS NULL`); n != 1 {
+		t.Fatal("user not tombstoned")
+	}
+}
+
+func TestDeletionResumesAfterFailedStep(t *testing.T) {
+	d, ts, r := newTestDeleter(t, 1, 0)
+	ctx := context.Background()
+	job := schedule(t, d)
+
+	d.runPending(ctx)
+	job = status(t, d, job.ID)
+	if job.State != DeletionRunning || job.Step != "files" || job.Attempts != 1 || job.LastError == "" {
+		t.Fatalf("after failed step: %+v", job)
+	}
+	// шаги до упавшего уже выполнены
+	if n := ts.count(t, `SELECT COUNT(*) FROM devices WHERE user_id = 'u1'`); n != 0 {
+		t.Fatal("devices kept before the failed step")
+	}
+	ts.exec(t, `INSERT INTO devices (id, user_id, device_id, created_at) VALUES ('d-late', 'u1', 'd9', 2)`)
+
+	d.runPending(ctx)
+	job = status(t, d, job.ID)
+	if job.State != DeletionDone || job.Attempts != 0 || job.LastError != "" {
+		t.Fatalf("after retry: %+v", job)
+	}
+	if r.calls != 2 {
+		t.Fatalf("files step ran %d times", r.calls)
+	}
+	// продолжение идет с упавшего шага, выполненные не повторяются
+	if n := ts.count(t, `SELECT COUNT(*) FROM devices WHERE user_id = 'u1'`); n != 1 {
+		t.Fatal("finished steps ran again on resume")
+	}
+	if len(ts.stored(t)) != 0 {
+		t.Fatal("cars left after resumed deletion")
+	}
+}
+
+func TestDeletionFailsAfterMaxAttemptsAndReschedules(t *testing.T) {
+	d, ts, r := newTestDeleter(t, 100, 2)
+	ctx := context.Background()
+	job := schedule(t, d)
+
+	d.runPending(ctx)
+	d.runPending(ctx)
+	job = status(t, d, job.ID)
+	if job.State != DeletionFailed || job.Attempts != 2 || job.Step != "files" {
+		t.Fatalf("after max attempts: %+v", job)
+	}
+	// упавшее задание больше не запускается само
+	d.runPending(ctx)
+	if r.calls != 2 {
+		t.Fatalf("failed job retried: %d calls", r.calls)
+	}
+
+	r.fail = 0
+	again := schedule(t, d)
+	if again.ID != job.ID || again.State != DeletionPending || again.Attempts != 0 || again.Step != "files" {
+		t.Fatalf("rescheduled job: %+v", again)
+	}
+	d.runPending(ctx)
+	if job = status(t, d, job.ID); job.State != DeletionDone {
+		t.Fatalf("rescheduled job: %+v", job)
+	}
+	if len(ts.stored(t)) != 0 {
+		t.Fatal("cars left after rescheduled deletion")
+	}
+}
@@ -0,0 +1,384 @@
+package account
+
+import (
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var ErrUserNotFound = errors.New("user not found")
+
+// хранилище car данных, его реализует storage.Service
+type BlobStore interface {
+	ExportCAR(ctx context.Context, cid string) (io.ReadCloser, error)
+	// car проходит те же проверки, что и загрузка. finish вызывают один раз: с keep после
+	// записи строк, которые ссылаются на car, без keep - чтобы отпустить car неудавшегося
+	// импорта, если на него никто не ссылается
+	ImportCAR(ctx context.Context, car io.Reader, size int64) (cid string, finish func(ctx context.Context, keep bool), err error)
+	// занять квоту пользователя под весь импорт, release снимает резерв
+	ReserveQuota(ctx context.Context, userID string, size int64) (release func(), err error)
+}
+
+type headerRecord struct {
+	UserID         string `json:"user_id"`
+	Username       string `json:"username"`
+	CreatedAt      int64  `json:"created_at"`
+	IncludeCARData bool   `json:"include_car_data"`
+}
+
+// учетные данные в архив не попадают: архив не должен позволять войти в аккаунт,
+// при импорте пользователь задает их заново
+type userRecord struct {
+	ID        string `json:"id"`
+	Username  string `json:"username"`
+	PublicKey []byte `json:"public_key,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+type deviceRecord struct {
+	DeviceID  string `json:"device_id"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// членство вместе с метаданными группы, ключ участника остается зашифрованным
+type membershipRecord struct {
+	GroupID         string `json:"group_id"`
+	GroupName       string `json:"group_name"`
+	CreatorID       string `json:"creator_id"`
+	GroupCreatedAt  int64  `json:"group_created_at"`
+	GroupKey        []byte `json:"group_key"`
+	GroupKeyVersion int    `json:"group_key_version"`
+	Role            string `json:"role"`
+	JoinedAt        int64  `json:"joined_at"`
+	EncryptedKey    []byte `json:"encrypted_key"`
+	KeyVersion      int    `json:"key_version"`
+}
+
+type messageRecord struct {
+	ConversationID  string `json:"conversation_id"`
+	MessageID       string `json:"message_id"`
+	Envelope        []byte `json:"envelope"`
+	SentAt          int64  `json:"sent_at"`
+	Kind            int    `json:"kind,omitempty"`
+	TargetMessageID string `json:"target_message_id,omitempty"`
+	SenderID        string `json:"sender_id,omitempty"`
+}
+
+type fileRecord struct {
+	ID        string `json:"id"`
+	CID       string `json:"cid"`
+	Name      string `json:"name"`
+	Mime      string `json:"mime"`
+	SizeBytes int64  `json:"size_bytes"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// версия файла из истории, включая текущую
+type versionRecord struct {
+	FileID    string `json:"file_id"`
+	Version   int64  `json:"version"`
+	CID       string `json:"cid"`
+	SizeBytes int64  `json:"size_bytes"`
+	Blake3    []byte `json:"blake3,omitempty"`
+	DeviceID  string `json:"device_id"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+type previewRecord struct {
+	FileID     string `json:"file_id"`
+	Kind       string `json:"kind"`
+	CID        string `json:"cid"`
+	Mime       string `json:"mime"`
+	SizeBytes  int64  `json:"size_bytes"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	WrappedKey []byte `json:"wrapped_key"`
+	CreatedAt  int64  `json:"created_at"`
+}
+
+// узел vfs, имя остается зашифрованным клиентом
+type vfsNodeRecord struct {
+	ID        string `json:"id"`
+	ParentID  string `json:"parent_id,omitempty"`
+	Kind      string `json:"kind"`
+	FileID    string `json:"file_id,omitempty"`
+	NameBlob  []byte `json:"name_blob"`
+	NameHash  []byte `json:"name_hash"`
+	Version   int64  `json:"version"`
+	TrashedAt int64  `json:"trashed_at,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+// экспорт и импорт аккаунта в подписанный архив
+type Service struct {
+	db      *sql.DB
+	blobs   BlobStore
+	signer  ed25519.PrivateKey
+	trusted []ed25519.PublicKey
+	// car импорта до проверки подписи лежат во временных файлах, maxSpool - их
+	// общий предел на один импорт
+	spoolDir string
+	maxSpool int64
+}
+
+// trusted - ключи серверов, чьи архивы принимаются, собственный ключ доверенный всегда
+func NewService(db *sql.DB, blobs BlobStore, signer ed25519.PrivateKey, trusted []ed25519.PublicKey) *Service {
+	return &Service{db: db, blobs: blobs, signer: signer, trusted: trusted, maxSpool: defaultMaxSpool}
+}
+
+func (s *Service) SigningPublicKey() ed25519.PublicKey {
+	return s.signer.Public().(ed25519.PublicKey)
+}
+
+// выгрузить аккаунт в w, car данные файлов только при includeData
+func (s *Service) Export(ctx context.Context, userID string, includeData bool, w io.Writer) error {
+	u, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	aw, err := newArchiveWriter(w, s.signer)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(aw, kindHeader, headerRecord{UserID: u.ID, Username: u.Username, CreatedAt: time.Now().Unix(), IncludeCARData: includeData}); err != nil {
+		return err
+	}
+	if err := writeJSON(aw, kindUser, u); err != nil {
+		return err
+	}
+	if err := s.exportDevices(ctx, aw, userID); err != nil {
+		return err
+	}
+	if err := s.exportMemberships(ctx, aw, userID); err != nil {
+		return err
+	}
+	if err := s.exportMessages(ctx, aw, userID); err != nil {
+		return err
+	}
+	// один cid может быть у нескольких файлов, версий и превью
+	var cids []string
+	seen := map[string]bool{}
+	content := func(cid string) {
+		if !seen[cid] {
+			seen[cid] = true
+			cids = append(cids, cid)
+		}
+	}
+	if err := s.exportFiles(ctx, aw, userID, content); err != nil {
+		return err
+	}
+	if err := s.exportVersions(ctx, aw, userID, content); err != nil {
+		return err
+	}
+	if err := s.exportPreviews(ctx, aw, userID, content); err != nil {
+		return err
+	}
+	if err := s.exportVFS(ctx, aw, userID); err != nil {
+		return err
+	}
+	if includeData {
+		for _, cid := range cids {
+			if err := s.exportCAR(ctx, aw, cid); err != nil {
+				return err
+			}
+		}
+	}
+	return aw.close()
+}
+
+func (s *Service) loadUser(ctx context.Context, userID string) (*userRecord, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT id, username, public_key, created_at FROM users WHERE id = ?`, userID)
+	var u userRecord
+	if err := row.Scan(&u.ID, &u.Username, &u.PublicKey, &u.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("load user: %w", err)
+	}
+	return &u, nil
+}
+
+func (s *Service) exportDevices(ctx context.Context, aw *archiveWriter, userID string) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT device_id, created_at FROM devices WHERE user_id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("export devices: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d deviceRecord
+		if err := rows.Scan(&d.DeviceID, &d.CreatedAt); err != nil {
+			return err
+		}
+		if err := writeJSON(aw, kindDevice, d); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (s *Service) exportMemberships(ctx context.Context, aw *archiveWriter, userID string) error {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT g.id, g.name, g.creator_id, g.created_at, g.group_key, g.key_version, m.role, m.joined_at, m.encrypted_key, m.key_version
+		FROM group_members m JOIN groups g ON g.id = m.group_id
+		WHERE m.user_id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("export memberships: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m membershipRecord
+		if err := rows.Scan(&m.GroupID, &m.GroupName, &m.CreatorID, &m.GroupCreatedAt, &m.GroupKey, &m.GroupKeyVersion, &m.Role, &m.JoinedAt, &m.EncryptedKey, &m.KeyVersion); err != nil {
+			return err
+		}
+		if err := writeJSON(aw, kindMembership, m); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// конверты бесед пользователя: его группы и беседы, где он что-то отправлял
+func (s *Service) exportMessages(ctx context.Context, aw *archiveWriter, userID string) error {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT m.conversation_id, m.message_id, m.envelope, m.sent_at, COALESCE(r.kind, 0), COALESCE(r.target_message_id, ''), COALESCE(r.sender_id, '')
+		FROM messages m
+		LEFT JOIN message_refs r ON r.conversation_id = m.conversation_id AND r.message_id = m.message_id
+		WHERE m.conversation_id IN (SELECT group_id FROM group_members WHERE user_id = ?)
+		   OR m.conversation_id IN (SELECT DISTINCT conversation_id FROM messages WHERE json_extract(CAST(envelope AS TEXT), '$.sender_id') = ?)
+		ORDER BY m.sent_at ASC`, userID, userID)
+	if err != nil {
+		return fmt.Errorf("export messages: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m messageRecord
+		if err := rows.Scan(&m.ConversationID, &m.MessageID, &m.Envelope, &m.SentAt, &m.Kind, &m.TargetMessageID, &m.SenderID); err != nil {
+			return err
+		}
+		if err := writeJSON(aw, kindMessage, m); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// content получает cid содержимого каждой записи
+func (s *Service) exportFiles(ctx context.Context, aw *archiveWriter, userID string, content func(string)) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, cid, COALESCE(name, ''), COALESCE(mime, ''), COALESCE(size_bytes, 0), created_at FROM files WHERE user_id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("export files: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var f fileRecord
+		if err := rows.Scan(&f.ID, &f.CID, &f.Name, &f.Mime, &f.SizeBytes, &f.CreatedAt); err != nil {
+			return err
+		}
+		if err := writeJSON(aw, kindFile, f); err != nil {
+			return err
+		}
+		content(f.CID)
+	}
+	return rows.Err()
+}
+
+func (s *Service) exportVersions(ctx context.Context, aw *archiveWriter, userID string, content func(string)) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT v.file_id, v.version, v.cid, v.size_bytes, v.blake3, v.device_id, v.created_at
+		FROM file_versions v JOIN files f ON f.id = v.file_id WHERE f.user_id = ? ORDER BY v.file_id, v.version`, userID)
+	if err != nil {
+		return fmt.Errorf("export versions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v versionRecord
+		if err := rows.Scan(&v.FileID, &v.Version, &v.CID, &v.SizeBytes, &v.Blake3, &v.DeviceID, &v.CreatedAt); err != nil {
+			return err
+		}
+		if err := writeJSON(aw, kindVersion, v); err != nil {
+			return err
+		}
+		content(v.CID)
+	}
+	return rows.Err()
+}
+
+func (s *Service) exportPreviews(ctx context.Context, aw *archiveWriter, userID string, content func(string)) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT p.file_id, p.kind, p.cid, p.mime, p.size_bytes, p.width, p.height, p.wrapped_key, p.created_at
+		FROM file_previews p JOIN files f ON f.id = p.file_id WHERE f.user_id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("export previews: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p previewRecord
+		if err := rows.Scan(&p.FileID, &p.Kind, &p.CID, &p.Mime, &p.SizeBytes, &p.Width, &p.Height, &p.WrappedKey, &p.CreatedAt); err != nil {
+			return err
+		}
+		if err := writeJSON(aw, kindPreview, p); err != nil {
+			return err
+		}
+		content(p.CID)
+	}
+	return rows.Err()
+}
+
+// дерево vfs вместе с корзиной
+func (s *Service) exportVFS(ctx context.Context, aw *archiveWriter, userID string) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, COALESCE(parent_id, ''), kind, COALESCE(file_id, ''), name_blob, name_hash, version, COALESCE(trashed_at, 0), created_at, updated_at
+		FROM vfs_nodes WHERE user_id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("export vfs: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var n vfsNodeRecord
+		if err := rows.Scan(&n.ID, &n.ParentID, &n.Kind, &n.FileID, &n.NameBlob, &n.NameHash, &n.Version, &n.TrashedAt, &n.CreatedAt, &n.UpdatedAt); err != nil {
+			return err
+		}
+		if err := writeJSON(aw, kindVFSNode, n); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (s *Service) exportCAR(ctx context.Context, aw *archiveWriter, cid string) error {
+	if s.blobs == nil {
+		return errors.New("car export: storage not configured")
+	}
+	r, err := s.blobs.ExportCAR(ctx, cid)
+	if err != nil {
+		return fmt.Errorf("car export %s: %w", cid, err)
+	}
+	defer r.Close()
+	buf := make([]byte, carChunkSize)
+	for {
+		n, readErr := io.ReadFull(r, buf)
+		if n > 0 {
+			if err := aw.carChunk(cid, buf[:n]); err != nil {
+				return err
+			}
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("car export %s: %w", cid, readErr)
+		}
+	}
+	return aw.record(kindCAREnd, []byte(cid))
+}
+
+func writeJSON(aw *archiveWriter, kind recordKind, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return aw.record(kind, b)
+}
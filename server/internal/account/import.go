@@ -0,0 +1,544 @@
+package account
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"dev.c0rex64.heroin/internal/messaging"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUserExists     = errors.New("user already exists")
+	ErrMalformed      = errors.New("malformed archive")
+	ErrCIDMismatch    = errors.New("car data does not match cid")
+	ErrUnexpectedData = errors.New("car data for unknown file")
+	ErrSpoolLimit     = errors.New("archive car data exceeds import limit")
+	ErrNoCredentials  = errors.New("import requires new credentials")
+)
+
+// учетные данные импортированного пользователя, в формате Register. архив их не
+// переносит, так что при импорте они задаются заново
+type Credentials struct {
+	ServerSalt   []byte
+	PasswordHash []byte
+}
+
+// итог импорта
+type ImportResult struct {
+	UserID   string
+	Username string
+	Devices  int
+	Groups   int
+	Messages int
+	Files    int
+	Versions int
+	Previews int
+	Nodes    int
+	Blobs    int
+	// группы из архива, которые не восстановлены: чужие или уже существующие здесь.
+	// в них вступают заново по приглашению админа
+	SkippedGroups []string
+}
+
+type importState struct {
+	header      *headerRecord
+	user        *userRecord
+	devices     []deviceRecord
+	memberships []membershipRecord
+	messages    []messageRecord
+	files       []fileRecord
+	versions    []versionRecord
+	previews    []previewRecord
+	nodes       []vfsNodeRecord
+
+	// cid содержимого записей и его объявленный размер
+	content map[string]int64
+	// car данные из архива в порядке появления
+	cars    map[string]*spooledCAR
+	order   []string
+	spooled int64
+}
+
+// car из архива во временном файле, done после записи конца car
+type spooledCAR struct {
+	file *os.File
+	size int64
+	done bool
+}
+
+// car, принятый хранилищем, finish вызывается после строк импорта или при ошибке
+type ingestedCAR struct {
+	cid    string
+	finish func(ctx context.Context, keep bool)
+}
+
+// восстановить аккаунт из архива. car данные до проверки дайджеста и подписи лежат
+// во временных файлах, в хранилище и бд ничего не попадает. затем car проходят
+// проверки загрузки и квоту пользователя, при ошибке записи в бд они отпускаются
+func (s *Service) Import(ctx context.Context, r io.Reader, cred Credentials) (*ImportResult, error) {
+	if len(cred.ServerSalt) == 0 || len(cred.PasswordHash) == 0 {
+		return nil, ErrNoCredentials
+	}
+	ar, err := newArchiveReader(r, s.isTrusted)
+	if err != nil {
+		return nil, err
+	}
+	st := &importState{content: map[string]int64{}, cars: map[string]*spooledCAR{}}
+	defer st.close()
+	for {
+		rec, err := ar.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := s.consume(st, rec); err != nil {
+			return nil, err
+		}
+	}
+	if err := st.validate(); err != nil {
+		return nil, err
+	}
+	if err := checkNewUser(ctx, s.db, st.user); err != nil {
+		return nil, err
+	}
+	if len(st.content) > 0 && s.blobs == nil {
+		return nil, errors.New("account import: storage not configured")
+	}
+	if s.blobs != nil {
+		release, err := s.blobs.ReserveQuota(ctx, st.user.ID, st.contentSize())
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+	cars, err := s.ingest(ctx, st)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.apply(ctx, st, cred)
+	if err != nil {
+		s.discard(ctx, cars)
+		return nil, err
+	}
+	for _, c := range cars {
+		c.finish(ctx, true)
+	}
+	res.Blobs = len(cars)
+	return res, nil
+}
+
+func (s *Service) isTrusted(pub ed25519.PublicKey) bool {
+	if pub.Equal(s.SigningPublicKey()) {
+		return true
+	}
+	for _, k := range s.trusted {
+		if pub.Equal(k) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) consume(st *importState, rec record) error {
+	if st.header == nil && rec.kind != kindHeader {
+		return fmt.Errorf("%w: header must come first", ErrMalformed)
+	}
+	switch rec.kind {
+	case kindHeader:
+		if st.header != nil {
+			return fmt.Errorf("%w: duplicate header", ErrMalformed)
+		}
+		st.header = &headerRecord{}
+		return decodeRecord(rec, st.header)
+	case kindUser:
+		if st.user != nil {
+			return fmt.Errorf("%w: duplicate user", ErrMalformed)
+		}
+		st.user = &userRecord{}
+		return decodeRecord(rec, st.user)
+	case kindDevice:
+		var d deviceRecord
+		if err := decodeRecord(rec, &d); err != nil {
+			return err
+		}
+		st.devices = append(st.devices, d)
+	case kindMembership:
+		var m membershipRecord
+		if err := decodeRecord(rec, &m); err != nil {
+			return err
+		}
+		st.memberships = append(st.memberships, m)
+	case kindMessage:
+		var m messageRecord
+		if err := decodeRecord(rec, &m); err != nil {
+			return err
+		}
+		st.messages = append(st.messages, m)
+	case kindFile:
+		var f fileRecord
+		if err := decodeRecord(rec, &f); err != nil {
+			return err
+		}
+		st.files = append(st.files, f)
+		st.addContent(f.CID, f.SizeBytes)
+	case kindVersion:
+		var v versionRecord
+		if err := decodeRecord(rec, &v); err != nil {
+			return err
+		}
+		st.versions = append(st.versions, v)
+		st.addContent(v.CID, v.SizeBytes)
+	case kindPreview:
+		var p previewRecord
+		if err := decodeRecord(rec, &p); err != nil {
+			return err
+		}
+		st.previews = append(st.previews, p)
+		st.addContent(p.CID, p.SizeBytes)
+	case kindVFSNode:
+		var n vfsNodeRecord
+		if err := decodeRecord(rec, &n); err != nil {
+			return err
+		}
+		st.nodes = append(st.nodes, n)
+	case kindCARChunk:
+		cid, data, err := parseCARChunk(rec.payload)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		if _, ok := st.content[cid]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnexpectedData, cid)
+		}
+		return s.spool(st, cid, data)
+	case kindCAREnd:
+		c := st.cars[string(rec.payload)]
+		if c == nil || c.done {
+			return fmt.Errorf("%w: car end without data", ErrMalformed)
+		}
+		c.done = true
+	default:
+		// записи из будущих минорных ревизий формата пропускаем, подпись их все равно покрывает
+		slog.Debug("account import: skip unknown record", "kind", rec.kind)
+	}
+	return nil
+}
+
+func (st *importState) addContent(cid string, size int64) {
+	if cur, ok := st.content[cid]; !ok || size > cur {
+		st.content[cid] = size
+	}
+}
+
+// дописать кусок car во временный файл, не выходя за общий предел импорта
+func (s *Service) spool(st *importState, cid string, data []byte) error {
+	if st.spooled+int64(len(data)) > s.maxSpool {
+		return ErrSpoolLimit
+	}
+	c := st.cars[cid]
+	if c == nil {
+		f, err := os.CreateTemp(s.spoolDir, "import-*.car")
+		if err != nil {
+			return fmt.Errorf("spool car: %w", err)
+		}
+		c = &spooledCAR{file: f}
+		st.cars[cid] = c
+		st.order = append(st.order, cid)
+	}
+	if c.done {
+		return fmt.Errorf("%w: car data after its end", ErrMalformed)
+	}
+	if _, err := c.file.Write(data); err != nil {
+		return fmt.Errorf("spool car: %w", err)
+	}
+	c.size += int64(len(data))
+	st.spooled += int64(len(data))
+	return nil
+}
+
+func (st *importState) close() {
+	for _, c := range st.cars {
+		c.file.Close()
+		os.Remove(c.file.Name())
+	}
+}
+
+// объем содержимого для квоты: принятые car по их размеру, остальное по записям
+func (st *importState) contentSize() int64 {
+	var n int64
+	for cid, size := range st.content {
+		if c := st.cars[cid]; c != nil {
+			size = c.size
+		}
+		n += size
+	}
+	return n
+}
+
+// ссылки между записями. узлы vfs заодно упорядочиваются: родитель раньше детей
+func (st *importState) validate() error {
+	if st.header == nil || st.user == nil || st.header.UserID != st.user.ID {
+		return fmt.Errorf("%w: missing header or user", ErrMalformed)
+	}
+	for _, c := range st.cars {
+		if !c.done {
+			return fmt.Errorf("%w: unterminated car data", ErrMalformed)
+		}
+	}
+	files := map[string]string{}
+	for _, f := range st.files {
+		files[f.ID] = f.CID
+	}
+	// текущая версия файла - его последняя версия
+	latest := map[string]versionRecord{}
+	for _, v := range st.versions {
+		if _, ok := files[v.FileID]; !ok {
+			return fmt.Errorf("%w: version of unknown file %s", ErrMalformed, v.FileID)
+		}
+		if v.Version > latest[v.FileID].Version {
+			latest[v.FileID] = v
+		}
+	}
+	for id, v := range latest {
+		if v.CID != files[id] {
+			return fmt.Errorf("%w: file %s differs from its latest version", ErrMalformed, id)
+		}
+	}
+	for _, p := range st.previews {
+		if _, ok := files[p.FileID]; !ok {
+			return fmt.Errorf("%w: preview of unknown file %s", ErrMalformed, p.FileID)
+		}
+	}
+	nodes := map[string]vfsNodeRecord{}
+	for _, n := range st.nodes {
+		if _, ok := files[n.FileID]; n.Kind == "file" && !ok || n.Kind == "dir" && n.FileID != "" || n.Kind != "file" && n.Kind != "dir" {
+			return fmt.Errorf("%w: vfs node %s", ErrMalformed, n.ID)
+		}
+		nodes[n.ID] = n
+	}
+	ordered := make([]vfsNodeRecord, 0, len(st.nodes))
+	placed := map[string]bool{}
+	for len(ordered) < len(st.nodes) {
+		progress := false
+		for _, n := range st.nodes {
+			if placed[n.ID] || n.ParentID != "" && !placed[n.ParentID] {
+				continue
+			}
+			if n.ParentID != "" && nodes[n.ParentID].Kind != "dir" {
+				return fmt.Errorf("%w: vfs node %s", ErrMalformed, n.ID)
+			}
+			ordered = append(ordered, n)
+			placed[n.ID] = true
+			progress = true
+		}
+		if !progress {
+			return fmt.Errorf("%w: vfs nodes without a root", ErrMalformed)
+		}
+	}
+	st.nodes = ordered
+	return nil
+}
+
+// car проходят проверки загрузки. неудавшийся импорт отпускает уже принятые
+func (s *Service) ingest(ctx context.Context, st *importState) ([]ingestedCAR, error) {
+	var done []ingestedCAR
+	for _, cid := range st.order {
+		c := st.cars[cid]
+		if _, err := c.file.Seek(0, io.SeekStart); err != nil {
+			s.discard(ctx, done)
+			return nil, fmt.Errorf("car import %s: %w", cid, err)
+		}
+		root, finish, err := s.blobs.ImportCAR(ctx, c.file, c.size)
+		if err == nil && root != cid {
+			finish(ctx, false)
+			err = fmt.Errorf("%w: %s != %s", ErrCIDMismatch, root, cid)
+		}
+		if err != nil {
+			s.discard(ctx, done)
+			return nil, fmt.Errorf("car import %s: %w", cid, err)
+		}
+		done = append(done, ingestedCAR{cid: cid, finish: finish})
+	}
+	return done, nil
+}
+
+func (s *Service) discard(ctx context.Context, cars []ingestedCAR) {
+	for _, c := range cars {
+		c.finish(context.WithoutCancel(ctx), false)
+	}
+}
+
+func decodeRecord(rec record, v any) error {
+	if err := json.Unmarshal(rec.payload, v); err != nil {
+		return fmt.Errorf("%w: record %d: %v", ErrMalformed, rec.kind, err)
+	}
+	return nil
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func checkNewUser(ctx context.Context, db queryRower, u *userRecord) error {
+	var exists int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE id = ? OR username = ?`, u.ID, u.Username).Scan(&exists); err != nil {
+		return fmt.Errorf("check user: %w", err)
+	}
+	if exists > 0 {
+		return ErrUserExists
+	}
+	return nil
+}
+
+func (s *Service) apply(ctx context.Context, st *importState, cred Credentials) (*ImportResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	u := st.user
+	if err := checkNewUser(ctx, tx, u); err != nil {
+		return nil, err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO users (id, username, server_salt, password_hash, public_key, second_factor_secret, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		u.ID, u.Username, cred.ServerSalt, cred.PasswordHash, u.PublicKey, secret, u.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("import user: %w", err)
+	}
+	res := &ImportResult{UserID: u.ID, Username: u.Username}
+
+	for _, d := range st.devices {
+		r, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO devices (id, user_id, device_id, created_at) VALUES (?, ?, ?, ?)`,
+			uuid.NewString(), u.ID, d.DeviceID, d.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("import device: %w", err)
+		}
+		res.Devices += affected(r)
+	}
+
+	// в существующую группу вступают только по приглашению ее админа, поэтому
+	// восстанавливаются лишь группы пользователя, которых на этом сервере нет
+	created := map[string]bool{}
+	for _, m := range st.memberships {
+		if m.CreatorID != u.ID {
+			res.SkippedGroups = append(res.SkippedGroups, m.GroupID)
+			continue
+		}
+		r, err := tx.ExecContext(ctx, `INSERT INTO groups (id, name, creator_id, created_at, group_key, key_version) VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT(id) DO NOTHING`,
+			m.GroupID, m.GroupName, u.ID, m.GroupCreatedAt, m.GroupKey, m.GroupKeyVersion)
+		if err != nil {
+			return nil, fmt.Errorf("import group: %w", err)
+		}
+		if affected(r) == 0 {
+			res.SkippedGroups = append(res.SkippedGroups, m.GroupID)
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO group_members (group_id, user_id, joined_at, role, encrypted_key, key_version) VALUES (?, ?, ?, ?, ?, ?)`,
+			m.GroupID, u.ID, m.JoinedAt, m.Role, m.EncryptedKey, m.KeyVersion); err != nil {
+			return nil, fmt.Errorf("import membership: %w", err)
+		}
+		created[m.GroupID] = true
+		res.Groups++
+	}
+
+	// сообщения восстановленных групп и личных бесед пользователя
+	for _, m := range st.messages {
+		if a, b, ok := messaging.DirectParticipants(m.ConversationID); !created[m.ConversationID] && !(ok && (a == u.ID || b == u.ID)) {
+			continue
+		}
+		r, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO messages (id, conversation_id, message_id, envelope, sent_at, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			m.MessageID, m.ConversationID, m.MessageID, m.Envelope, m.SentAt, m.SentAt)
+		if err != nil {
+			return nil, fmt.Errorf("import message: %w", err)
+		}
+		res.Messages += affected(r)
+		if m.Kind != 0 {
+			if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO message_refs (conversation_id, message_id, target_message_id, kind, sender_id, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+				m.ConversationID, m.MessageID, m.TargetMessageID, m.Kind, m.SenderID, m.SentAt); err != nil {
+				return nil, fmt.Errorf("import message ref: %w", err)
+			}
+		}
+	}
+
+	if err := applyFiles(ctx, tx, u.ID, st, res); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("import commit: %w", err)
+	}
+	return res, nil
+}
+
+// файлы, их история, превью и дерево vfs. id из архива сохраняются, совпадение с
+// чужой строкой обрывает импорт
+func applyFiles(ctx context.Context, tx *sql.Tx, userID string, st *importState, res *ImportResult) error {
+	for _, f := range st.files {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO files (id, user_id, cid, name, mime, size_bytes, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			f.ID, userID, f.CID, f.Name, f.Mime, f.SizeBytes, f.CreatedAt); err != nil {
+			return fmt.Errorf("import file: %w", err)
+		}
+		res.Files++
+	}
+
+	// первую версию создал триггер files, история из архива ее заменяет
+	replaced := map[string]bool{}
+	for _, v := range st.versions {
+		if !replaced[v.FileID] {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM file_versions WHERE file_id = ?`, v.FileID); err != nil {
+				return fmt.Errorf("import version: %w", err)
+			}
+			replaced[v.FileID] = true
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO file_versions (file_id, version, cid, size_bytes, blake3, device_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			v.FileID, v.Version, v.CID, v.SizeBytes, v.Blake3, v.DeviceID, v.CreatedAt); err != nil {
+			return fmt.Errorf("import version: %w", err)
+		}
+		res.Versions++
+	}
+
+	for _, p := range st.previews {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO file_previews (file_id, kind, cid, mime, size_bytes, width, height, wrapped_key, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			p.FileID, p.Kind, p.CID, p.Mime, p.SizeBytes, p.Width, p.Height, p.WrappedKey, p.CreatedAt); err != nil {
+			return fmt.Errorf("import preview: %w", err)
+		}
+		res.Previews++
+	}
+
+	for _, n := range st.nodes {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO vfs_nodes (id, user_id, parent_id, kind, file_id, name_blob, name_hash, version, trashed_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			n.ID, userID, nullString(n.ParentID), n.Kind, nullString(n.FileID), n.NameBlob, n.NameHash, n.Version, nullInt(n.TrashedAt), n.CreatedAt, n.UpdatedAt); err != nil {
+			return fmt.Errorf("import vfs node: %w", err)
+		}
+		res.Nodes++
+	}
+	return nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullInt(n int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: n, Valid: n != 0}
+}
+
+func affected(r sql.Result) int {
+	n, _ := r.RowsAffected()
+	return int(n)
+}
@@ -0,0 +1,239 @@
+package account
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"dev.c0rex64.heroin/internal/storage"
+	"dev.c0rex64.heroin/internal/store"
+	"github.com/ipfs/go-cid"
+)
+
+type testServer struct {
+	db    *store.DB
+	svc   *storage.Service
+	blobs *storage.LocalStore
+	acc   *Service
+}
+
+func newTestServer(t *testing.T, signer ed25519.PrivateKey, trusted ...ed25519.PublicKey) *testServer {
+	t.Helper()
+	db, err := store.Open(context.Background(), "file:"+t.TempDir()+"/account.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	blobs, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := storage.NewWithDB(nil, false, 0, db.SQL)
+	svc.SetBlobStore(blobs)
+	acc := NewService(db.SQL, svc, signer, trusted)
+	acc.spoolDir = t.TempDir()
+	return &testServer{db: db, svc: svc, blobs: blobs, acc: acc}
+}
+
+func (ts *testServer) exec(t *testing.T, query string, args ...any) {
+	t.Helper()
+	if _, err := ts.db.SQL.Exec(query, args...); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+}
+
+func (ts *testServer) count(t *testing.T, query string, args ...any) int {
+	t.Helper()
+	var n int
+	if err := ts.db.SQL.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return n
+}
+
+func (ts *testServer) stored(t *testing.T) []string {
+	t.Helper()
+	cids, err := ts.blobs.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cids
+}
+
+// car v1 из одного raw блока
+func testCAR(t *testing.T, data []byte) []byte {
+	t.Helper()
+	c, err := cid.Prefix{Version: 1, Codec: cid.Raw, MhType: 0x12, MhLength: -1}.Sum(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	link := append([]byte{0x00}, c.Bytes()...)
+	hdr := []byte{0xa2, 0x65, 'r', 'o', 'o', 't', 's', 0x81, 0xd8, 0x2a, 0x58, byte(len(link))}
+	hdr = append(hdr, link...)
+	hdr = append(hdr, 0x67, 'v', 'e', 'r', 's', 'i', 'o', 'n', 0x01)
+	car := binary.AppendUvarint(nil, uint64(len(hdr)))
+	car = append(car, hdr...)
+	car = binary.AppendUvarint(car, uint64(len(c.Bytes())+len(data)))
+	car = append(car, c.Bytes()...)
+	return append(car, data...)
+}
+
+var secretHash = []byte("source-password-hash")
+
+// u1 с файлом в двух версиях, превью, каталогом vfs, своей группой g1, чужой g2
+// и личной перепиской с u2
+func seedSource(t *testing.T, ts *testServer) {
+	t.Helper()
+	ctx := context.Background()
+	ts.exec(t, `INSERT INTO users (id, username, server_salt, password_hash, verifier, public_key, second_factor_secret, created_at) VALUES ('u1', 'alice', x'0102', ?, x'0304', x'aa', x'0506', 1)`, secretHash)
+	ts.exec(t, `INSERT INTO users (id, username, server_salt, password_hash, second_factor_secret, created_at) VALUES ('u2', 'bob', x'00', x'00', x'00', 1)`)
+	ts.exec(t, `INSERT INTO devices (id, user_id, device_id, created_at) VALUES ('d', 'u1', 'd1', 1)`)
+	ts.exec(t, `INSERT INTO groups (id, name, creator_id, created_at, group_key) VALUES ('g1', 'own', 'u1', 1, x'01'), ('g2', 'other', 'u2', 1, x'02')`)
+	ts.exec(t, `INSERT INTO group_members (group_id, user_id, joined_at, role, encrypted_key, key_version) VALUES ('g1', 'u1', 1, 'admin', x'01', 1), ('g2', 'u1', 1, 'member', x'02', 1)`)
+	ts.exec(t, `INSERT INTO messages (id, conversation_id, message_id, envelope, sent_at, created_at) VALUES
+		('m1', 'g1', 'm1', '{"sender_id":"u1"}', 1, 1), ('m2', 'g2', 'm2', '{"sender_id":"u1"}', 2, 2), ('m3', 'dm:u1:u2', 'm3', '{"sender_id":"u1"}', 3, 3)`)
+
+	v1 := testCAR(t, []byte("first version"))
+	fileID, _, err := ts.svc.PutCAR(ctx, "u1", "d1", "a.txt", "text/plain", int64(len(v1)), bytes.NewReader(v1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2 := testCAR(t, []byte("second version"))
+	if _, _, err := ts.svc.PutVersion(ctx, "u1", "d1", fileID, "", int64(len(v2)), bytes.NewReader(v2), nil); err != nil {
+		t.Fatal(err)
+	}
+	thumb := testCAR(t, []byte("thumbnail"))
+	if _, err := ts.svc.PutPreview(ctx, "u1", storage.Preview{FileID: fileID, Kind: storage.PreviewThumbnail, Mime: "image/webp", Size: int64(len(thumb)), WrappedKey: []byte{1}}, bytes.NewReader(thumb)); err != nil {
+		t.Fatal(err)
+	}
+	ts.exec(t, `INSERT INTO vfs_nodes (id, user_id, parent_id, kind, file_id, name_blob, name_hash, created_at, updated_at) VALUES
+		('n2', 'u1', NULL, 'dir', NULL, x'01', x'01', 1, 1)`)
+	ts.exec(t, `INSERT INTO vfs_nodes (id, user_id, parent_id, kind, file_id, name_blob, name_hash, trashed_at, created_at, updated_at) VALUES
+		('n1', 'u1', 'n2', 'file', ?, x'02', x'02', 5, 1, 1)`, fileID)
+}
+
+func export(t *testing.T, ts *testServer) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := ts.acc.Export(context.Background(), "u1", true, &buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+var newCred = Credentials{ServerSalt: []byte("new-salt"), PasswordHash: []byte("new-hash")}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(nil)
+	src := newTestServer(t, key)
+	seedSource(t, src)
+	archive := export(t, src)
+	if bytes.Contains(archive, secretHash) {
+		t.Fatal("archive carries the password hash")
+	}
+
+	dst := newTestServer(t, key)
+	dst.exec(t, `INSERT INTO users (id, username, server_salt, password_hash, second_factor_secret, created_at) VALUES ('u2', 'bob', x'00', x'00', x'00', 1)`)
+	res, err := dst.acc.Import(context.Background(), bytes.NewReader(archive), newCred)
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if res.Files != 1 || res.Versions != 2 || res.Previews != 1 || res.Nodes != 2 || res.Blobs != 3 || res.Groups != 1 {
+		t.Fatalf("result %+v", res)
+	}
+	if len(res.SkippedGroups) != 1 || res.SkippedGroups[0] != "g2" {
+		t.Fatalf("skipped groups %v", res.SkippedGroups)
+	}
+	var salt, hash []byte
+	var verifier []byte
+	if err := dst.db.SQL.QueryRow(`SELECT server_salt, password_hash, verifier FROM users WHERE id = 'u1'`).Scan(&salt, &hash, &verifier); err != nil {
+		t.Fatal(err)
+	}
+	if string(salt) != "new-salt" || string(hash) != "new-hash" || verifier != nil {
+		t.Fatalf("credentials not re-enrolled: %q %q %x", salt, hash, verifier)
+	}
+	if n := dst.count(t, `SELECT COUNT(*) FROM group_members WHERE user_id = 'u1'`); n != 1 {
+		t.Fatalf("%d memberships, want only the recreated group", n)
+	}
+	if n := dst.count(t, `SELECT COUNT(*) FROM messages WHERE conversation_id IN ('g1', 'dm:u1:u2')`); n != 2 {
+		t.Fatalf("%d messages imported", n)
+	}
+	if n := dst.count(t, `SELECT COUNT(*) FROM messages WHERE conversation_id = 'g2'`); n != 0 {
+		t.Fatal("messages of a skipped group imported")
+	}
+	if n := dst.count(t, `SELECT COUNT(*) FROM file_versions WHERE blake3 IS NOT NULL`); n != 2 {
+		t.Fatalf("%d versions with history", n)
+	}
+	if n := dst.count(t, `SELECT COUNT(*) FROM vfs_nodes WHERE id = 'n1' AND parent_id = 'n2' AND trashed_at = 5`); n != 1 {
+		t.Fatal("vfs tree not restored")
+	}
+	if n := len(dst.stored(t)); n != 3 {
+		t.Fatalf("%d blobs stored", n)
+	}
+}
+
+func TestImportOverQuotaLeavesNothing(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(nil)
+	src := newTestServer(t, key)
+	seedSource(t, src)
+	archive := export(t, src)
+
+	dst := newTestServer(t, key)
+	q, err := storage.NewQuotas(dst.db.SQL, map[string]int64{"tiny": 64}, "tiny")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst.svc.SetQuotas(q)
+	_, err = dst.acc.Import(context.Background(), bytes.NewReader(archive), newCred)
+	var qe *storage.QuotaError
+	if !errors.As(err, &qe) {
+		t.Fatalf("import over quota: %v", err)
+	}
+	if n := dst.count(t, `SELECT COUNT(*) FROM users`); n != 0 {
+		t.Fatal("user created by a rejected import")
+	}
+	if cids := dst.stored(t); len(cids) != 0 {
+		t.Fatalf("blobs left by a rejected import: %v", cids)
+	}
+}
+
+func TestImportRejectsTamperedCARBeforeStoring(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(nil)
+	src := newTestServer(t, key)
+	seedSource(t, src)
+	archive := export(t, src)
+	i := bytes.Index(archive, []byte("second version"))
+	archive[i] ^= 0xff
+
+	dst := newTestServer(t, key)
+	if _, err := dst.acc.Import(context.Background(), bytes.NewReader(archive), newCred); !errors.Is(err, ErrDigestMismatch) {
+		t.Fatalf("import of tampered archive: %v", err)
+	}
+	if cids := dst.stored(t); len(cids) != 0 {
+		t.Fatalf("unverified car data stored: %v", cids)
+	}
+}
+
+func TestImportSpoolLimit(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(nil)
+	src := newTestServer(t, key)
+	seedSource(t, src)
+	archive := export(t, src)
+
+	dst := newTestServer(t, key)
+	dst.acc.maxSpool = 100
+	if _, err := dst.acc.Import(context.Background(), bytes.NewReader(archive), newCred); !errors.Is(err, ErrSpoolLimit) {
+		t.Fatalf("import over spool limit: %v", err)
+	}
+}
+
+func TestImportRequiresCredentials(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(nil)
+	ts := newTestServer(t, key)
+	if _, err := ts.acc.Import(context.Background(), bytes.NewReader(nil), Credentials{}); !errors.Is(err, ErrNoCredentials) {
+		t.Fatalf("import without credentials: %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"dev.c0rex64.heroin/internal/account"
+	"dev.c0rex64.heroin/internal/auth"
+	authv1 "dev.c0rex64.heroin/internal/gen/shared/proto/auth/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const exportChunkSize = 256 * 1024
+
+// отдает архив кусками не больше exportChunkSize, последний кусок пустой с last_chunk
+type exportStreamWriter struct {
+	stream authv1.AuthService_ExportAccountServer
+}
+
+func (w exportStreamWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), exportChunkSize)
+		if err := w.stream.Send(&authv1.ExportAccountChunk{Data: append([]byte(nil), p[:n]...)}); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+func (s *Server) ExportAccount(req *authv1.ExportAccountRequest, stream authv1.AuthService_ExportAccountServer) error {
+	if s.AccountSvc == nil {
+		return status.Error(codes.Unimplemented, "account export not configured")
+	}
+	userID := getUserIDFromContext(stream.Context())
+	if userID == "" {
+		return status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	if s.Collector != nil { s.Collector.RecordMessage("auth", "export") }
+	if err := s.AccountSvc.Export(stream.Context(), userID, req.IncludeCarData, exportStreamWriter{stream: stream}); err != nil {
+		if errors.Is(err, account.ErrUserNotFound) {
+			return status.Error(codes.NotFound, err.Error())
+		}
+		return err
+	}
+	return stream.Send(&authv1.ExportAccountChunk{LastChunk: true})
+}
+
+// читает архив из клиентского стрима до last_chunk или конца стрима
+type importStreamReader struct {
+	stream authv1.AuthService_ImportAccountServer
+	buf    []byte
+	done   bool
+}
+
+func (r *importStreamReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		req, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		r.buf = req.Data
+		r.done = req.LastChunk
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (s *Server) ImportAccount(stream authv1.AuthService_ImportAccountServer) error {
+	if s.AccountSvc == nil {
+		return status.Error(codes.Unimplemented, "account import not configured")
+	}
+	if s.Collector != nil { s.Collector.RecordMessage("auth", "import") }
+	first, err := stream.Recv()
+	if err != nil { return err }
+	salt, hash, err := auth.ParsePasswordProof(first.PasswordProof)
+	if err != nil { return status.Error(codes.InvalidArgument, "password_proof: "+err.Error()) }
+	r := &importStreamReader{stream: stream, buf: first.Data, done: first.LastChunk}
+	res, err := s.AccountSvc.Import(stream.Context(), r, account.Credentials{ServerSalt: salt, PasswordHash: hash})
+	if err != nil {
+		if s.Collector != nil { s.Collector.RecordMessage("auth", "import_failed") }
+		switch {
+		case errors.Is(err, account.ErrUserExists):
+			return status.Error(codes.AlreadyExists, err.Error())
+		case errors.Is(err, account.ErrUntrustedKey), errors.Is(err, account.ErrBadSignature):
+			return status.Error(codes.PermissionDenied, err.Error())
+		case errors.Is(err, account.ErrBadMagic), errors.Is(err, account.ErrUnsupportedVersion), errors.Is(err, account.ErrTruncated),
+			errors.Is(err, account.ErrDigestMismatch), errors.Is(err, account.ErrMalformed), errors.Is(err, account.ErrCIDMismatch),
+			errors.Is(err, account.ErrUnexpectedData), errors.Is(err, account.ErrNoCredentials):
+			return status.Error(codes.InvalidArgument, err.Error())
+		case errors.Is(err, account.ErrSpoolLimit):
+			return status.Error(codes.ResourceExhausted, err.Error())
+		}
+		// car архива проходят проверки и квоту загрузки
+		s.recordQuotaRejection(err)
+		return uploadError(err)
+	}
+	return stream.SendAndClose(&authv1.ImportAccountResponse{
+		UserId:          res.UserID,
+		Username:        res.Username,
+		Devices:         int32(res.Devices),
+		Groups:          int32(res.Groups),
+		Messages:        int32(res.Messages),
+		Files:           int32(res.Files),
+		Blobs:           int32(res.Blobs),
+		SkippedGroupIds: res.SkippedGroups,
+		Versions:        int32(res.Versions),
+		Previews:        int32(res.Previews),
+		VfsNodes:        int32(res.Nodes),
+	})
+}
+
+func (s *Server) DeleteAccount(ctx context.Context, req *authv1.DeleteAccountRequest) (*authv1.DeleteAccountResponse, error) {
+	if s.Deleter == nil {
+		return nil, status.Error(codes.Unimplemented, "account deletion not configured")
+	}
+	if s.Collector != nil { s.Collector.RecordMessage("auth", "delete_account") }
+	// удаляется тот, кто заново прошел проверку пароля и второго фактора
+	userID, err := s.AuthSvc.Reauthenticate(ctx, req.Username, req.PasswordProof, req.SecondaryCode, time.Now())
+	if err != nil {
+		if s.Collector != nil { s.Collector.RecordMessage("auth", "delete_account_failed") }
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	job, err := s.Deleter.Schedule(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &authv1.DeleteAccountResponse{JobId: job.ID, State: deletionStateToProto(job.State)}, nil
+}
+
+func (s *Server) GetDeletionStatus(ctx context.Context, req *authv1.GetDeletionStatusRequest) (*authv1.GetDeletionStatusResponse, error) {
+	if s.Deleter == nil {
+		return nil, status.Error(codes.Unimplemented, "account deletion not configured")
+	}
+	job, err := s.Deleter.Status(ctx, req.JobId)
+	if err != nil {
+		if errors.Is(err, account.ErrDeletionNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, err
+	}
+	return &authv1.GetDeletionStatusResponse{
+		JobId:         job.ID,
+		State:         deletionStateToProto(job.State),
+		Step:          job.Step,
+		Attempts:      int32(job.Attempts),
+		LastError:     job.LastError,
+		CreatedAtUnix: job.CreatedAt.Unix(),
+		UpdatedAtUnix: job.UpdatedAt.Unix(),
+	}, nil
+}
+
+func deletionStateToProto(st account.DeletionState) authv1.DeletionState {
+	switch st {
+	case account.DeletionPending:
+		return authv1.DeletionState_DELETION_STATE_PENDING
+	case account.DeletionRunning:
+		return authv1.DeletionState_DELETION_STATE_RUNNING
+	case account.DeletionDone:
+		return authv1.DeletionState_DELETION_STATE_DONE
+	case account.DeletionFailed:
+		return authv1.DeletionState_DELETION_STATE_FAILED
+	}
+	return authv1.DeletionState_DELETION_STATE_UNSPECIFIED
+}
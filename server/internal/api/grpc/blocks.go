@@ -0,0 +1,78 @@
+package grpcapi
+
+import (
+	stgv1 "dev.c0rex64.heroin/internal/gen/shared/proto/storage/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"lukechampine.com/blake3"
+)
+
+// сколько cid отдавать в одном сообщении ListBlocks
+const listBlocksBatch = 1024
+
+// лимит на один запрос GetBlocks, дальше клиент просит следующую пачку
+const maxBlocksPerRequest = 4096
+
+func (s *Server) ListBlocks(req *stgv1.ListBlocksRequest, stream stgv1.StorageService_ListBlocksServer) error {
+	if req.Cid == "" {
+		return status.Error(codes.InvalidArgument, "cid required")
+	}
+	if _, err := s.authorizeRead(stream.Context(), req.Cid, "", ""); err != nil {
+		return err
+	}
+	cids, err := s.StorageSvc.BlockCIDs(stream.Context(), req.Cid)
+	if err != nil {
+		return err
+	}
+	for len(cids) > 0 {
+		n := min(len(cids), listBlocksBatch)
+		if err := stream.Send(&stgv1.ListBlocksResponse{Cids: cids[:n]}); err != nil {
+			return err
+		}
+		cids = cids[n:]
+	}
+	return nil
+}
+
+// блоки отдаются в порядке запроса, blake3 позволяет проверить блок до разбора cid
+func (s *Server) GetBlocks(req *stgv1.GetBlocksRequest, stream stgv1.StorageService_GetBlocksServer) error {
+	if len(req.Cids) > maxBlocksPerRequest {
+		return status.Errorf(codes.InvalidArgument, "at most %d blocks per request", maxBlocksPerRequest)
+	}
+	// доступ проверяется по корню, а запрошенные блоки должны входить в его dag
+	if s.Sharing != nil {
+		if req.RootCid == "" {
+			return status.Error(codes.InvalidArgument, "root cid required")
+		}
+		if _, err := s.authorizeRead(stream.Context(), req.RootCid, "", ""); err != nil {
+			return err
+		}
+		refs, err := s.StorageSvc.BlockCIDs(stream.Context(), req.RootCid)
+		if err != nil {
+			return err
+		}
+		inDAG := make(map[string]bool, len(refs))
+		for _, c := range refs {
+			inDAG[c] = true
+		}
+		for _, cid := range req.Cids {
+			if !inDAG[cid] {
+				return status.Errorf(codes.PermissionDenied, "block %s is not part of %s", cid, req.RootCid)
+			}
+		}
+	}
+	if s.Collector != nil { s.Collector.RecordFileOp("download", "blocks") }
+	for _, cid := range req.Cids {
+		data, err := s.StorageSvc.Block(stream.Context(), req.RootCid, cid)
+		if err != nil {
+			if s.Collector != nil { s.Collector.RecordFileOp("download", "failed") }
+			return err
+		}
+		sum := blake3.Sum256(data)
+		if err := stream.Send(&stgv1.GetBlocksResponse{Cid: cid, Data: data, Blake3: sum[:]}); err != nil {
+			return err
+		}
+		if s.Collector != nil { s.Collector.AddCARBytes(int64(len(data))) }
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	stgv1 "dev.c0rex64.heroin/internal/gen/shared/proto/storage/v1"
+	"dev.c0rex64.heroin/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) ListFiles(ctx context.Context, req *stgv1.ListFilesRequest) (*stgv1.ListFilesResponse, error) {
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	filter := storage.FileFilter{MimePrefix: req.MimePrefix, CreatedAfter: req.CreatedAfterUnix, CreatedBefore: req.CreatedBeforeUnix, WithPreviews: req.IncludePreviews}
+	files, next, err := s.StorageSvc.ListFiles(ctx, userID, filter, req.PageToken, int(req.PageSize))
+	if err != nil {
+		return nil, fileError(err)
+	}
+	resp := &stgv1.ListFilesResponse{Files: make([]*stgv1.FileInfo, 0, len(files)), NextPageToken: next}
+	for i := range files {
+		resp.Files = append(resp.Files, fileInfo(&files[i]))
+	}
+	return resp, nil
+}
+
+func (s *Server) StatFile(ctx context.Context, req *stgv1.StatFileRequest) (*stgv1.StatFileResponse, error) {
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	f, err := s.StorageSvc.StatFile(ctx, userID, req.FileId)
+	if err != nil {
+		return nil, fileError(err)
+	}
+	return &stgv1.StatFileResponse{File: fileInfo(f)}, nil
+}
+
+func (s *Server) RenameFile(ctx context.Context, req *stgv1.RenameFileRequest) (*stgv1.RenameFileResponse, error) {
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	f, err := s.StorageSvc.RenameFile(ctx, userID, req.FileId, req.Name)
+	if err != nil {
+		return nil, fileError(err)
+	}
+	return &stgv1.RenameFileResponse{File: fileInfo(f)}, nil
+}
+
+func (s *Server) DeleteFile(ctx context.Context, req *stgv1.DeleteFileRequest) (*stgv1.DeleteFileResponse, error) {
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	if err := s.StorageSvc.DeleteFile(ctx, userID, req.FileId); err != nil {
+		return nil, fileError(err)
+	}
+	if s.Collector != nil { s.Collector.RecordFileOp("delete", "success") }
+	return &stgv1.DeleteFileResponse{Deleted: true}, nil
+}
+
+func (s *Server) GetUsage(ctx context.Context, req *stgv1.GetUsageRequest) (*stgv1.GetUsageResponse, error) {
+	if s.Quotas == nil {
+		return nil, status.Error(codes.Unimplemented, "quotas not configured")
+	}
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	u, err := s.Quotas.Usage(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &stgv1.GetUsageResponse{
+		Plan:          u.Plan,
+		LimitBytes:    u.LimitBytes,
+		UsedBytes:     u.UsedBytes,
+		ReservedBytes: u.ReservedBytes,
+		Files:         u.Files,
+		Contents:      u.Contents,
+	}, nil
+}
+
+func fileInfo(f *storage.File) *stgv1.FileInfo {
+	info := &stgv1.FileInfo{
+		FileId:        f.ID,
+		Cid:           f.CID,
+		Name:          f.Name,
+		Mime:          f.Mime,
+		SizeBytes:     f.Size,
+		CreatedAtUnix: f.CreatedAt.Unix(),
+		BlockCount:    f.Blocks,
+		Version:       f.Version,
+		Previews:      make([]*stgv1.FilePreview, 0, len(f.Previews)),
+	}
+	for i := range f.Previews {
+		info.Previews = append(info.Previews, previewInfo(&f.Previews[i]))
+	}
+	return info
+}
+
+func fileError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrFileNotFound), errors.Is(err, storage.ErrPreviewNotFound), errors.Is(err, storage.ErrVersionNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, storage.ErrBadFileName), errors.Is(err, storage.ErrBadPageToken), errors.Is(err, storage.ErrBadPreview):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, storage.ErrContentReleasing):
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return err
+}
+
+func (s *Server) recordQuotaRejection(err error) {
+	var qe *storage.QuotaError
+	if s.Collector != nil && errors.As(err, &qe) {
+		s.Collector.RecordQuotaRejection()
+	}
+}
@@ -153,9 +153,3 @@ func (s *Server) GetGroupMembers(ctx context.Context, req *msgv1.GetGroupMembers
     return resp, nil
 }
 
-// хелпер для получения user id из контекста
-func getUserIDFromContext(ctx context.Context) string {
-    // тут должна быть реальная логика извлечения из jwt/контекста
-    // пока заглушка
-    return "user123"
-}
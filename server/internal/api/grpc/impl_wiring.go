@@ -4,16 +4,286 @@ import (
 	"dev.c0rex64.heroin/internal/ipfs"
 	"dev.c0rex64.heroin/internal/messaging"
 	"dev.c0rex64.heroin/internal/metrics"
+	"dev.c0rex64.heroin/internal/notify"
 	"dev.c0rex64.heroin/internal/storage"
+	"context"
 	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
 )
 
-func (s *Server) WireStorageAndMessaging(ipfsEndpoint string, pin bool, replicas int, db *sql.DB, kp KeyProvider, collector *metrics.Collector) {
+// ipfsOpts действуют и на узлы из WireReplication
+func (s *Server) WireStorageAndMessaging(ipfsEndpoint string, ipfsOpts ipfs.Options, pin bool, replicas int, maxUploadBytes int64, db *sql.DB, kp KeyProvider, collector *metrics.Collector) {
 	s.Collector = collector
-	ic := ipfs.New(ipfsEndpoint)
+	if collector != nil {
+		ipfsOpts.OnRetry = collector.RecordIPFSRetry
+	}
+	s.ipfsOpts = ipfsOpts
+	ic := ipfs.NewWithOptions(ipfsEndpoint, ipfsOpts)
 	st := storage.NewWithDB(ic, pin, replicas, db)
+	st.SetStreamer(storage.NewCARStreamer(ic, 0), maxUploadBytes)
 	q := messaging.NewQueue(db)
 	ms := messaging.NewService(q, kp)
 	s.StorageSvc = st
 	s.MessagingSvc = ms
 }
+
+// подключить офлайн уведомления к messaging, вызывается после WireStorageAndMessaging
+func (s *Server) WireNotifications(d *notify.Dispatcher, tokens *notify.Store, recipients messaging.RecipientResolver) {
+	s.PushTokens = tokens
+	ms, ok := s.MessagingSvc.(*messaging.Service)
+	if !ok {
+		return
+	}
+	var pc messaging.PresenceChecker
+	if s.PresenceHub != nil {
+		pc = s.PresenceHub
+	}
+	ms.SetNotifier(d, recipients, pc)
+}
+
+// хранилище car вместо kubo, вызывается сразу после WireStorageAndMessaging
+func (s *Server) WireBlobStore(backend, dir string, s3 storage.S3Options) error {
+	st, ok := s.StorageSvc.(*storage.Service)
+	if !ok {
+		return nil
+	}
+	switch backend {
+	case "", "kubo":
+		return nil
+	case "local":
+		ls, err := storage.NewLocalStore(dir)
+		if err != nil {
+			return err
+		}
+		st.SetBlobStore(ls)
+	case "s3":
+		ss, err := storage.NewS3Store(s3)
+		if err != nil {
+			return err
+		}
+		st.SetBlobStore(ss)
+	default:
+		return fmt.Errorf("unknown blob backend %q", backend)
+	}
+	return nil
+}
+
+// ограничения на блоки загружаемых car, codecs - имена из multicodec
+func (s *Server) WireCARPolicy(maxBlockBytes int, codecs []string) error {
+	st, ok := s.StorageSvc.(*storage.Service)
+	if !ok {
+		return nil
+	}
+	p, err := storage.NewCARPolicy(maxBlockBytes, codecs)
+	if err != nil {
+		return err
+	}
+	st.SetCARPolicy(p)
+	return nil
+}
+
+// возобновляемые загрузки поверх storage, вызывается после WireStorageAndMessaging
+func (s *Server) WireUploads(ctx context.Context, db *sql.DB, stagingDir string, ttl time.Duration) error {
+	st, ok := s.StorageSvc.(*storage.Service)
+	if !ok {
+		return nil
+	}
+	up, err := storage.NewUploads(st, db, stagingDir, ttl)
+	if err != nil {
+		return err
+	}
+	up.StartJanitor(ctx, 10*time.Minute)
+	s.Uploads = up
+	return nil
+}
+
+// bao outboard для проверяемой отдачи, вызывается после WireStorageAndMessaging
+func (s *Server) WireOutboards(ctx context.Context, db *sql.DB, dir string) error {
+	st, ok := s.StorageSvc.(*storage.Service)
+	if !ok {
+		return nil
+	}
+	ob, err := storage.NewOutboards(st, db, dir)
+	if err != nil {
+		return err
+	}
+	ob.Start(ctx)
+	st.SetOutboards(ob)
+	return nil
+}
+
+// кэш car на диске для GetFile, вызывается после WireBlobStore и WireReplication
+func (s *Server) WireBlobCache(dir string, maxBytes, maxEntryBytes int64) error {
+	st, ok := s.StorageSvc.(*storage.Service)
+	if !ok {
+		return nil
+	}
+	c, err := storage.NewBlobCache(dir, maxBytes, maxEntryBytes)
+	if err != nil {
+		return err
+	}
+	if s.Collector != nil {
+		c.OnLookup(s.Collector.RecordBlobCache)
+		size, n := c.Size()
+		s.Collector.RecordBlobCache("", size, n)
+	}
+	st.SetBlobCache(c)
+	return nil
+}
+
+// квоты на объем загрузок, вызывается после WireStorageAndMessaging. сводка по тарифам
+// пересчитывается в метрики раз в interval
+func (s *Server) WireQuotas(ctx context.Context, db *sql.DB, plans map[string]int64, defaultPlan string, interval time.Duration) error {
+	st, ok := s.StorageSvc.(*storage.Service)
+	if !ok {
+		return nil
+	}
+	q, err := storage.NewQuotas(db, plans, defaultPlan)
+	if err != nil {
+		return err
+	}
+	st.SetQuotas(q)
+	s.Quotas = q
+	if s.Collector == nil {
+		return nil
+	}
+	report := func() {
+		byPlan, stored, err := q.Report(ctx)
+		if err != nil {
+			if ctx.Err() == nil { slog.Warn("quotas: usage report", "error", err) }
+			return
+		}
+		users := make(map[string]int, len(byPlan))
+		used := make(map[string]int64, len(byPlan))
+		for _, p := range byPlan {
+			name := p.Plan
+			if name == "" { name = "none" }
+			users[name] += p.Users
+			used[name] += p.UsedBytes
+		}
+		s.Collector.SetStorageUsage(users, used, stored)
+	}
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		report()
+		for {
+			select {
+			case <-t.C:
+				report()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// сверка пинов kubo с files, вызывается после WireStorageAndMessaging и WireOutboards,
+// чтобы открепление сирот убирало и их outboard
+func (s *Server) WireReconciler(ctx context.Context, db *sql.DB, interval, grace time.Duration) {
+	st, ok := s.StorageSvc.(*storage.Service)
+	if !ok {
+		return
+	}
+	r := storage.NewReconciler(st, db, grace)
+	if s.Collector != nil {
+		r.OnReport(func(rep storage.PinReport) {
+			if rep.Err == "" { s.Collector.RecordPinReconcile(rep.Missing, rep.Orphaned, rep.Repinned, rep.Unpinned) }
+		})
+	}
+	r.Start(ctx, interval)
+	s.Reconciler = r
+}
+
+// индекс блоков загрузок с gc блоков без ссылок, вызывается после WireBlobStore и
+// WireReplication, чтобы gc удалял блоки в выбранном хранилище
+func (s *Server) WireBlockIndex(ctx context.Context, db *sql.DB, interval time.Duration) {
+	st, ok := s.StorageSvc.(*storage.Service)
+	if !ok {
+		return
+	}
+	b := storage.NewBlockIndex(st, db)
+	if s.Collector != nil {
+		b.OnReport(func(rep storage.BlockReport) {
+			if rep.Err == "" { s.Collector.RecordBlockIndex(rep.LogicalBytes, rep.UniqueBytes, rep.UniqueBlocks, rep.DedupRatio(), rep.Collected) }
+		})
+	}
+	b.Start(ctx, interval)
+	st.SetBlockIndex(b)
+}
+
+// хранение версий файлов: старые версии сверх правил удаляются при каждой новой
+// версии и проходом раз в час, чтобы истекали и версии нетронутых файлов
+func (s *Server) WireVersionRetention(ctx context.Context, keep, keepDays int) {
+	st, ok := s.StorageSvc.(*storage.Service)
+	if !ok {
+		return
+	}
+	st.SetVersionRetention(storage.VersionRetention{Keep: keep, KeepFor: time.Duration(keepDays) * 24 * time.Hour})
+	if keep > 0 || keepDays > 0 {
+		st.StartVersionPruning(ctx, time.Hour)
+	}
+}
+
+// несколько узлов ipfs с репликацией, вызывается после WireStorageAndMessaging и до
+// остальных Wire*, которые читают через storage. здоровье узлов проверяется раз в healthInterval
+func (s *Server) WireReplication(ctx context.Context, db *sql.DB, endpoints []string, replicas, quorum int, healthInterval, repairInterval time.Duration) error {
+	st, ok := s.StorageSvc.(*storage.Service)
+	if !ok {
+		return nil
+	}
+	n, err := storage.NewNodes(db, endpoints, replicas, quorum, s.ipfsOpts)
+	if err != nil {
+		return err
+	}
+	if err := st.SetNodes(n); err != nil {
+		return err
+	}
+	if s.Collector != nil {
+		n.OnHealth(func(node *storage.Node) { s.recordIPFSHealth(node.Health()) })
+		n.OnRepair(func(rep storage.RepairReport) {
+			if rep.Err == "" { s.Collector.RecordReplicaRepair(rep.Under, rep.Unavailable, rep.Copied, rep.Adopted, rep.Lost) }
+		})
+	}
+	n.Start(ctx, healthInterval, repairInterval)
+	return nil
+}
+
+// проверка единственного узла kubo, вызывается после WireReplication и WireBlobStore.
+// узлы репликации проверяет сама репликация, local и s3 без kubo не проверяются
+func (s *Server) WireIPFSHealth(ctx context.Context, interval time.Duration) {
+	st, ok := s.StorageSvc.(*storage.Service)
+	if !ok || st.Nodes() != nil {
+		return
+	}
+	ic := st.IPFS()
+	if ic == nil {
+		return
+	}
+	ic.StartHealthChecks(ctx, interval, func(h ipfs.Health) {
+		if s.Collector != nil { s.recordIPFSHealth(h) }
+	})
+}
+
+// здоровье узлов kubo для /healthz
+func (s *Server) IPFSHealth() []ipfs.Health {
+	st, ok := s.StorageSvc.(*storage.Service)
+	if !ok {
+		return nil
+	}
+	return st.IPFSHealth()
+}
+
+func (s *Server) recordIPFSHealth(h ipfs.Health) {
+	level := 0
+	switch h.Breaker {
+	case ipfs.BreakerHalfOpen:
+		level = 1
+	case ipfs.BreakerOpen:
+		level = 2
+	}
+	s.Collector.SetIPFSHealth(h.Endpoint, h.Up, level, h.Latency)
+}
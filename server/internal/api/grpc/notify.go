@@ -0,0 +1,39 @@
+package grpcapi
+
+import (
+	"context"
+
+	msgv1 "dev.c0rex64.heroin/internal/gen/shared/proto/messaging/v1"
+	"dev.c0rex64.heroin/internal/notify"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) RegisterPushToken(ctx context.Context, req *msgv1.RegisterPushTokenRequest) (*msgv1.RegisterPushTokenResponse, error) {
+	if s.PushTokens == nil {
+		return nil, status.Error(codes.Unimplemented, "notifications not configured")
+	}
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	err := s.PushTokens.RegisterToken(ctx, notify.PushToken{UserID: userID, DeviceID: req.DeviceId, Platform: req.Platform, Token: req.Token})
+	if err != nil {
+		return nil, err
+	}
+	return &msgv1.RegisterPushTokenResponse{Success: true}, nil
+}
+
+func (s *Server) UnregisterPushToken(ctx context.Context, req *msgv1.UnregisterPushTokenRequest) (*msgv1.UnregisterPushTokenResponse, error) {
+	if s.PushTokens == nil {
+		return nil, status.Error(codes.Unimplemented, "notifications not configured")
+	}
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	if err := s.PushTokens.UnregisterToken(ctx, userID, req.DeviceId); err != nil {
+		return nil, err
+	}
+	return &msgv1.UnregisterPushTokenResponse{Success: true}, nil
+}
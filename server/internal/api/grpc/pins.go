@@ -0,0 +1,77 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	stgv1 "dev.c0rex64.heroin/internal/gen/shared/proto/storage/v1"
+	"dev.c0rex64.heroin/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// сколько расхождений отдавать, если клиент не указал
+const defaultDriftLimit = 100
+
+func (s *Server) GetPinReport(ctx context.Context, req *stgv1.GetPinReportRequest) (*stgv1.GetPinReportResponse, error) {
+	if s.Reconciler == nil {
+		return nil, status.Error(codes.Unimplemented, "pin reconciliation not configured")
+	}
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	rep := s.Reconciler.Last()
+	if req.RunNow {
+		var err error
+		rep, err = s.Reconciler.Run(ctx)
+		if errors.Is(err, storage.ErrReconcileRunning) {
+			return nil, status.Error(codes.Aborted, err.Error())
+		}
+	}
+	limit := int(req.DriftLimit)
+	if limit <= 0 {
+		limit = defaultDriftLimit
+	}
+	drift, err := s.Reconciler.Drift(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	resp := &stgv1.GetPinReportResponse{
+		DurationMs: rep.Duration.Milliseconds(),
+		Pinned:     int64(rep.Pinned),
+		Referenced: int64(rep.Referenced),
+		Missing:    int64(rep.Missing),
+		Orphaned:   int64(rep.Orphaned),
+		Repinned:   int64(rep.Repinned),
+		Unpinned:   int64(rep.Unpinned),
+		Error:      rep.Err,
+		Drift:      make([]*stgv1.PinDrift, 0, len(drift)),
+	}
+	if !rep.RunAt.IsZero() {
+		resp.RunAtUnix = rep.RunAt.Unix()
+	}
+	for _, d := range drift {
+		resp.Drift = append(resp.Drift, &stgv1.PinDrift{
+			Cid:           d.CID,
+			Kind:          d.Kind,
+			FirstSeenUnix: d.FirstSeen.Unix(),
+			LastSeenUnix:  d.LastSeen.Unix(),
+			Attempts:      int32(d.Attempts),
+			LastError:     d.LastError,
+		})
+	}
+	return resp, nil
+}
+
+// админские rpc доступны пользователям из security.admin_user_ids
+func (s *Server) requireAdmin(ctx context.Context) error {
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		return status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	if !slices.Contains(s.AdminIDs, userID) {
+		return status.Error(codes.PermissionDenied, "admin only")
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	msgv1 "dev.c0rex64.heroin/internal/gen/shared/proto/messaging/v1"
+	"dev.c0rex64.heroin/internal/presence"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// двунаправленный стрим присутствия: клиент шлет subscribe, typing и статусы, сервер раздает события участникам
+func (s *Server) Presence(stream msgv1.MessagingService_PresenceServer) error {
+	if s.PresenceHub == nil {
+		return status.Error(codes.Unimplemented, "presence not configured")
+	}
+	ctx := stream.Context()
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		return status.Error(codes.Unauthenticated, "unauthorized")
+	}
+
+	sub := s.PresenceHub.Connect(userID)
+	defer s.PresenceHub.Disconnect(sub)
+
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			ev, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			if err := s.handlePresenceEvent(ctx, sub, ev); err != nil {
+				recvErr <- err
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case ev := <-sub.C:
+			if err := stream.Send(presenceEventToProto(ev)); err != nil {
+				return err
+			}
+		case err := <-recvErr:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *Server) handlePresenceEvent(ctx context.Context, sub *presence.Subscriber, ev *msgv1.PresenceEvent) error {
+	switch ev.Kind {
+	case msgv1.PresenceKind_PRESENCE_KIND_SUBSCRIBE:
+		if err := s.PresenceHub.Join(ctx, sub, ev.ConversationId); err != nil {
+			if errors.Is(err, presence.ErrNotParticipant) {
+				return status.Error(codes.PermissionDenied, err.Error())
+			}
+			return err
+		}
+	case msgv1.PresenceKind_PRESENCE_KIND_TYPING, msgv1.PresenceKind_PRESENCE_KIND_STOPPED_TYPING:
+		typing := ev.Kind == msgv1.PresenceKind_PRESENCE_KIND_TYPING
+		if err := s.PresenceHub.Typing(sub, ev.ConversationId, typing); err != nil {
+			return status.Error(codes.PermissionDenied, err.Error())
+		}
+	case msgv1.PresenceKind_PRESENCE_KIND_ONLINE, msgv1.PresenceKind_PRESENCE_KIND_AWAY, msgv1.PresenceKind_PRESENCE_KIND_OFFLINE:
+		if ev.PeerId != "" {
+			if err := s.PresenceHub.BindPeer(ev.PeerId, sub.UserID, ev.AtUnix, ev.PeerSignature); err != nil {
+				return status.Error(codes.PermissionDenied, err.Error())
+			}
+		}
+		s.PresenceHub.SetStatus(sub.UserID, presence.Kind(ev.Kind))
+	default:
+		return status.Error(codes.InvalidArgument, "unknown presence kind")
+	}
+	return nil
+}
+
+func (s *Server) SetPresencePrivacy(ctx context.Context, req *msgv1.SetPresencePrivacyRequest) (*msgv1.SetPresencePrivacyResponse, error) {
+	if s.PresenceStore == nil {
+		return nil, status.Error(codes.Unimplemented, "presence not configured")
+	}
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	if err := s.PresenceStore.SetVisibility(ctx, userID, presence.Visibility(req.LastSeen)); err != nil {
+		return nil, err
+	}
+	return &msgv1.SetPresencePrivacyResponse{Success: true}, nil
+}
+
+func presenceEventToProto(ev presence.Event) *msgv1.PresenceEvent {
+	out := &msgv1.PresenceEvent{
+		Kind:           msgv1.PresenceKind(ev.Kind),
+		ConversationId: ev.ConversationID,
+		UserId:         ev.UserID,
+		AtUnix:         ev.At.Unix(),
+	}
+	if !ev.LastSeen.IsZero() {
+		out.LastSeenUnix = ev.LastSeen.Unix()
+	}
+	if out.AtUnix <= 0 {
+		out.AtUnix = time.Now().Unix()
+	}
+	return out
+}
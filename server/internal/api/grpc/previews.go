@@ -0,0 +1,78 @@
+package grpcapi
+
+import (
+	"bytes"
+	"context"
+
+	stgv1 "dev.c0rex64.heroin/internal/gen/shared/proto/storage/v1"
+	"dev.c0rex64.heroin/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var previewKinds = map[stgv1.PreviewKind]string{
+	stgv1.PreviewKind_PREVIEW_KIND_THUMBNAIL: storage.PreviewThumbnail,
+	stgv1.PreviewKind_PREVIEW_KIND_PREVIEW:   storage.PreviewLowRes,
+	stgv1.PreviewKind_PREVIEW_KIND_POSTER:    storage.PreviewPoster,
+}
+
+func (s *Server) PutPreview(ctx context.Context, req *stgv1.PutPreviewRequest) (*stgv1.PutPreviewResponse, error) {
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	kind, ok := previewKinds[req.Kind]
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, storage.ErrBadPreview.Error())
+	}
+	p := storage.Preview{
+		FileID:     req.FileId,
+		Kind:       kind,
+		Mime:       req.Mime,
+		Size:       int64(len(req.EncryptedCar)),
+		Width:      int(req.Width),
+		Height:     int(req.Height),
+		WrappedKey: req.WrappedKey,
+	}
+	out, err := s.StorageSvc.PutPreview(ctx, userID, p, bytes.NewReader(req.EncryptedCar))
+	if err != nil {
+		s.recordQuotaRejection(err)
+		return nil, fileError(uploadError(err))
+	}
+	if s.Collector != nil { s.Collector.RecordFileOp("preview", "success") }
+	return &stgv1.PutPreviewResponse{Preview: previewInfo(out)}, nil
+}
+
+func (s *Server) DeletePreview(ctx context.Context, req *stgv1.DeletePreviewRequest) (*stgv1.DeletePreviewResponse, error) {
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	kind, ok := previewKinds[req.Kind]
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, storage.ErrBadPreview.Error())
+	}
+	if err := s.StorageSvc.DeletePreview(ctx, userID, req.FileId, kind); err != nil {
+		return nil, fileError(err)
+	}
+	return &stgv1.DeletePreviewResponse{Deleted: true}, nil
+}
+
+func previewInfo(p *storage.Preview) *stgv1.FilePreview {
+	var kind stgv1.PreviewKind
+	for k, v := range previewKinds {
+		if v == p.Kind {
+			kind = k
+		}
+	}
+	return &stgv1.FilePreview{
+		Kind:          kind,
+		Cid:           p.CID,
+		Mime:          p.Mime,
+		SizeBytes:     p.Size,
+		Width:         int32(p.Width),
+		Height:        int32(p.Height),
+		WrappedKey:    p.WrappedKey,
+		CreatedAtUnix: p.CreatedAt.Unix(),
+	}
+}
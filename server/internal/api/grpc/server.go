@@ -1,12 +1,16 @@
 package grpcapi
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	authv1 "dev.c0rex64.heroin/internal/gen/shared/proto/auth/v1"
@@ -17,24 +21,47 @@ import (
 	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/codes"
 
+	"dev.c0rex64.heroin/internal/account"
+	"dev.c0rex64.heroin/internal/ipfs"
+	"dev.c0rex64.heroin/internal/messaging"
 	"dev.c0rex64.heroin/internal/metrics"
+	"dev.c0rex64.heroin/internal/notify"
 	"dev.c0rex64.heroin/internal/p2p"
+	"dev.c0rex64.heroin/internal/presence"
 	"dev.c0rex64.heroin/internal/relay"
 	"dev.c0rex64.heroin/internal/routing"
+	"dev.c0rex64.heroin/internal/sharing"
+	"dev.c0rex64.heroin/internal/storage"
+	"dev.c0rex64.heroin/internal/vfs"
+	"dev.c0rex64.heroin/pkg/verify"
+	"lukechampine.com/blake3"
 )
 
 type Server struct {
 	gs  *grpc.Server
 	lis net.Listener
 
-	AuthSvc      AuthService
-	MessagingSvc MessagingService
-	StorageSvc   StorageService
-	GroupSvc     GroupService
-	Collector    *metrics.Collector
-	StreamMgr    *p2p.StreamManager
-	RelayMgr     *relay.RelayManager
-	Router       *routing.AdaptiveRouter
+	AuthSvc       AuthService
+	MessagingSvc  MessagingService
+	StorageSvc    StorageService
+	GroupSvc      GroupService
+	Collector     *metrics.Collector
+	StreamMgr     *p2p.StreamManager
+	RelayMgr      *relay.RelayManager
+	Router        *routing.AdaptiveRouter
+	PresenceHub   *presence.Hub
+	PresenceStore *presence.Store
+	PushTokens    *notify.Store
+	AccountSvc    *account.Service
+	Deleter       *account.Deleter
+	Uploads       *storage.Uploads
+	VFS           *vfs.Service
+	Sharing       *sharing.Service
+	Quotas        *storage.Quotas
+	Reconciler    *storage.Reconciler
+	AdminIDs      []string
+
+	ipfsOpts ipfs.Options
 
 	authv1.UnimplementedAuthServiceServer
 	msgv1.UnimplementedMessagingServiceServer
@@ -46,6 +73,8 @@ type AuthService interface {
 	Login(ctx context.Context, username string, passwordProof []byte, deviceID string, secondCode string, now time.Time) (string, string, time.Time, error)
 	Refresh(ctx context.Context, refreshToken string, deviceID string, now time.Time) (string, time.Time, error)
 	GetPublicKey(ctx context.Context, userID string) ([]byte, error)
+	Reauthenticate(ctx context.Context, username string, passwordProof []byte, secondCode string, now time.Time) (string, error)
+	VerifyAccessToken(token string, now time.Time) (string, string, error)
 }
 
 type MessagingService interface {
@@ -55,8 +84,21 @@ type MessagingService interface {
 }
 
 type StorageService interface {
-	PutCAR(ctx context.Context, name, mime string, size int64, carChunks [][]byte, totalBlake3 []byte) (fileID string, cid string, err error)
-	GetCAR(ctx context.Context, cid string) ([][]byte, error)
+	PutCAR(ctx context.Context, userID, deviceID, name, mime string, size int64, car io.Reader, totalBlake3 []byte) (fileID string, cid string, err error)
+	PutVersion(ctx context.Context, userID, deviceID, fileID, mime string, size int64, car io.Reader, totalBlake3 []byte) (*storage.File, *storage.Version, error)
+	StreamCAR(ctx context.Context, cid string) (<-chan []byte, <-chan error)
+	StreamCARRange(ctx context.Context, cid string, offset, length int64) (<-chan []byte, <-chan error)
+	BlockCIDs(ctx context.Context, cid string) ([]string, error)
+	Block(ctx context.Context, root, cid string) ([]byte, error)
+	Outboard(ctx context.Context, cid string) (storage.OutboardReader, [32]byte, error)
+	ListFiles(ctx context.Context, userID string, f storage.FileFilter, pageToken string, limit int) ([]storage.File, string, error)
+	StatFile(ctx context.Context, userID, fileID string) (*storage.File, error)
+	RenameFile(ctx context.Context, userID, fileID, name string) (*storage.File, error)
+	DeleteFile(ctx context.Context, userID, fileID string) error
+	PutPreview(ctx context.Context, userID string, p storage.Preview, car io.Reader) (*storage.Preview, error)
+	DeletePreview(ctx context.Context, userID, fileID, kind string) error
+	ListVersions(ctx context.Context, userID, fileID string) ([]storage.Version, error)
+	RestoreVersion(ctx context.Context, userID, deviceID, fileID string, version int64) (*storage.File, *storage.Version, error)
 }
 
 var (
@@ -64,38 +106,55 @@ var (
 	rateMap = map[string][]time.Time{}
 )
 
+// скачивания по ссылке в минуту с одного адреса: каждое проверяет пароль ссылки
+const linkDownloadsPerMinute = 60
+
 func rateLimitUnaryInterceptor(maxPerMinute int) grpc.UnaryServerInterceptor {
-	window := time.Minute
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		p, _ := peer.FromContext(ctx)
-		key := "unknown"
-		if p != nil && p.Addr != nil { key = p.Addr.String() }
-		if isAuthMethod(info.FullMethod) {
-			rateMu.Lock()
-			t := time.Now()
-			v := rateMap[key]
-			var vv []time.Time
-			for _, ts := range v { if t.Sub(ts) < window { vv = append(vv, ts) } }
-			if len(vv) >= maxPerMinute {
-				rateMu.Unlock()
-				return nil, status.Error(codes.ResourceExhausted, "rate limit")
-			}
-			vv = append(vv, t)
-			rateMap[key] = vv
-			rateMu.Unlock()
+		if isAuthMethod(info.FullMethod) && !allowRate(peerKey(ctx), maxPerMinute) {
+			return nil, status.Error(codes.ResourceExhausted, "rate limit")
 		}
 		return handler(ctx, req)
 	}
 }
 
+// учесть запрос в окне в минуту, false если лимит уже выбран
+func allowRate(key string, maxPerMinute int) bool {
+	window := time.Minute
+	rateMu.Lock()
+	defer rateMu.Unlock()
+	t := time.Now()
+	v := rateMap[key]
+	var vv []time.Time
+	for _, ts := range v { if t.Sub(ts) < window { vv = append(vv, ts) } }
+	if len(vv) >= maxPerMinute {
+		rateMap[key] = vv
+		return false
+	}
+	rateMap[key] = append(vv, t)
+	return true
+}
+
+// адрес клиента без порта, иначе новое соединение начинает окно заново
+func peerKey(ctx context.Context) string {
+	p, _ := peer.FromContext(ctx)
+	if p == nil || p.Addr == nil { return "unknown" }
+	if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil { return host }
+	return p.Addr.String()
+}
+
 func isAuthMethod(method string) bool {
-	return method == "/auth.v1.AuthService/Login" || method == "/auth.v1.AuthService/Register" || method == "/auth.v1.AuthService/Refresh" || method == "/auth.v1.AuthService/GetPublicKey"
+	return method == "/auth.v1.AuthService/Login" || method == "/auth.v1.AuthService/Register" || method == "/auth.v1.AuthService/Refresh" || method == "/auth.v1.AuthService/GetPublicKey" || method == "/auth.v1.AuthService/DeleteAccount" || method == "/storage.v1.StorageService/ResolveLink"
 }
 
 func New(addr string) (*Server, error) {
 	lis, err := net.Listen("tcp", addr)
 	if err != nil { return nil, fmt.Errorf("listen: %w", err) }
-	s := &Server{gs: grpc.NewServer(grpc.UnaryInterceptor(rateLimitUnaryInterceptor(60))), lis: lis}
+	s := &Server{lis: lis}
+	s.gs = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.sessionUnaryInterceptor, rateLimitUnaryInterceptor(60)),
+		grpc.StreamInterceptor(s.sessionStreamInterceptor),
+	)
 	authv1.RegisterAuthServiceServer(s.gs, s)
 	msgv1.RegisterMessagingServiceServer(s.gs, s)
 	stgv1.RegisterStorageServiceServer(s.gs, s)
@@ -145,9 +204,17 @@ func (s *Server) GetPublicKey(ctx context.Context, req *authv1.GetPublicKeyReque
 
 func (s *Server) Send(ctx context.Context, req *msgv1.SendRequest) (*msgv1.SendResponse, error) {
 	if s.Collector != nil { s.Collector.RecordMessage("msg", "send") }
-	b, err := json.Marshal(req.Envelope)
-	if err != nil { if s.Collector != nil { s.Collector.RecordMessage("msg", "send_failed") }; return nil, err }
-	if err := s.MessagingSvc.Send(ctx, b); err != nil { if s.Collector != nil { s.Collector.RecordMessage("msg", "send_failed") }; return nil, err }
+	// envelope уже сериализованный json конверта, в том же виде он лежит в очереди
+	if err := s.MessagingSvc.Send(ctx, req.Envelope); err != nil {
+		if s.Collector != nil { s.Collector.RecordMessage("msg", "send_failed") }
+		switch {
+		case errors.Is(err, messaging.ErrNotSender), errors.Is(err, messaging.ErrNotParticipant):
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		case errors.Is(err, messaging.ErrTargetNotFound):
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, err
+	}
 	return &msgv1.SendResponse{Success: true}, nil
 }
 
@@ -170,39 +237,159 @@ func (s *Server) Pull(ctx context.Context, req *msgv1.PullRequest) (*msgv1.PullR
 
 func (s *Server) PutFile(stream stgv1.StorageService_PutFileServer) error {
 	if s.Collector != nil { s.Collector.RecordFileOp("upload", "start") }
-	var chunks [][]byte
-	var name, mime string
-	var size int64
-	var b3 []byte
-	var totalBytes int64
-	for {
-		req, err := stream.Recv()
-		if err != nil { break }
-		name, mime, size = req.Name, req.Mime, req.SizeBytes
-		if len(b3) == 0 && len(req.TotalBlake3) > 0 { b3 = append([]byte(nil), req.TotalBlake3...) }
-		chunks = append(chunks, req.EncryptedCarChunk)
-		totalBytes += int64(len(req.EncryptedCarChunk))
-		if req.LastChunk { break }
-	}
-	fileID, cid, err := s.StorageSvc.PutCAR(stream.Context(), name, mime, size, chunks, b3)
+	// метаданные берем из первого сообщения, данные идут в storage через pipe без накопления
+	userID := getUserIDFromContext(stream.Context())
+	if userID == "" { return status.Error(codes.Unauthenticated, "unauthorized") }
+	first, err := stream.Recv()
 	if err != nil { if s.Collector != nil { s.Collector.RecordFileOp("upload", "failed") }; return err }
-	if s.Collector != nil { s.Collector.RecordFileOp("upload", "success"); s.Collector.AddCARBytes(totalBytes) }
-	return stream.SendAndClose(&stgv1.PutFileResponse{Accepted: true, FileId: fileID, Cid: cid})
+	pr, pw := io.Pipe()
+	var totalBytes atomic.Int64
+	go func() {
+		req := first
+		for {
+			if len(req.EncryptedCarChunk) > 0 {
+				// чанк с bao доказательством проверяется до отправки в kubo, порча обрывает загрузку
+				if len(req.BaoProof) > 0 {
+					if err := verifyUploadChunk(req.BaoProof, req.EncryptedCarChunk, totalBytes.Load(), first.SizeBytes, first.TotalBlake3); err != nil { pw.CloseWithError(err); return }
+				}
+				if _, err := pw.Write(req.EncryptedCarChunk); err != nil { return }
+				totalBytes.Add(int64(len(req.EncryptedCarChunk)))
+			}
+			if req.LastChunk { pw.Close(); return }
+			var err error
+			req, err = stream.Recv()
+			if err == io.EOF { pw.Close(); return }
+			if err != nil { pw.CloseWithError(err); return }
+		}
+	}()
+	// с file_id загрузка становится новой версией существующего файла
+	fileID, cid, version := first.FileId, "", int64(1)
+	if fileID != "" {
+		var f *storage.File
+		f, _, err = s.StorageSvc.PutVersion(stream.Context(), userID, first.DeviceId, fileID, first.Mime, first.SizeBytes, pr, first.TotalBlake3)
+		if f != nil { cid, version = f.CID, f.Version }
+	} else {
+		fileID, cid, err = s.StorageSvc.PutCAR(stream.Context(), userID, first.DeviceId, first.Name, first.Mime, first.SizeBytes, pr, first.TotalBlake3)
+	}
+	// отпускаем читателя стрима, если storage завершился раньше
+	pr.CloseWithError(io.ErrClosedPipe)
+	if err != nil {
+		if s.Collector != nil { s.Collector.RecordFileOp("upload", "failed") }
+		s.recordQuotaRejection(err)
+		var le *storage.LimitError
+		var qe *storage.QuotaError
+		var ce *verify.CorruptionError
+		switch {
+		case errors.As(err, &le), errors.As(err, &qe):
+			return status.Error(codes.ResourceExhausted, err.Error())
+		case errors.As(err, &ce):
+			return status.Error(codes.DataLoss, err.Error())
+		case errors.Is(err, storage.ErrBlake3Mismatch), errors.Is(err, storage.ErrBadCARHeader), errors.Is(err, storage.ErrBadCARBlock), errors.Is(err, storage.ErrCARCodec), errors.Is(err, storage.ErrCARBlockTooLarge), errors.Is(err, storage.ErrSizeMismatch),
+			errors.Is(err, verify.ErrBadProof), errors.Is(err, verify.ErrMisaligned), errors.Is(err, verify.ErrSliceOutside):
+			return status.Error(codes.InvalidArgument, err.Error())
+		case errors.Is(err, storage.ErrQuorum), errors.Is(err, storage.ErrContentReleasing):
+			return status.Error(codes.Unavailable, err.Error())
+		case errors.Is(err, storage.ErrFileNotFound):
+			return status.Error(codes.NotFound, err.Error())
+		}
+		return err
+	}
+	if s.Collector != nil { s.Collector.RecordFileOp("upload", "success"); s.Collector.AddCARBytes(totalBytes.Load()) }
+	return stream.SendAndClose(&stgv1.PutFileResponse{Accepted: true, FileId: fileID, Cid: cid, Version: version})
+}
+
+func verifyUploadChunk(proof, chunk []byte, offset, size int64, total []byte) error {
+	if len(total) != 32 { return verify.ErrBadProof }
+	if n, err := verify.ProofSize(proof); err != nil || n != size { return verify.ErrBadProof }
+	return verify.Verify(proof, chunk, offset, [32]byte(total))
 }
 
+// кусок с доказательством, выровнен по группам bao
+const proofSliceSize = 256 * 1024
+
 func (s *Server) GetFile(req *stgv1.GetFileRequest, stream stgv1.StorageService_GetFileServer) error {
 	if s.Collector != nil { s.Collector.RecordFileOp("download", "start") }
-	chunks, err := s.StorageSvc.GetCAR(stream.Context(), req.Cid)
-	if err != nil { if s.Collector != nil { s.Collector.RecordFileOp("download", "failed") }; return err }
-	var totalBytes int64
-	for i, ch := range chunks {
-		totalBytes += int64(len(ch))
-		if err := stream.Send(&stgv1.GetFileResponse{EncryptedCarChunk: ch, LastChunk: i == len(chunks)-1}); err != nil {
-			if s.Collector != nil { s.Collector.RecordFileOp("download", "failed") }
-			return err
+	// по ссылке отдается только car целиком, иначе лимит скачиваний обходится диапазонами.
+	// перебор пароля ссылки ограничен так же, как ResolveLink
+	if req.LinkToken != "" {
+		if req.Offset != 0 || req.Length != 0 { return status.Error(codes.InvalidArgument, "link downloads must fetch the whole file") }
+		if !allowRate("link:"+peerKey(stream.Context()), linkDownloadsPerMinute) { return status.Error(codes.ResourceExhausted, "rate limit") }
+	}
+	link, err := s.authorizeRead(stream.Context(), req.Cid, req.LinkToken, req.LinkPassword)
+	if err != nil { return err }
+	// скачивание засчитывается до первого байта: оборванный клиентом поток тоже тратит лимит
+	if link != nil {
+		preview, err := s.Sharing.IsPreview(stream.Context(), req.Cid)
+		if err != nil { return err }
+		if !preview {
+			if err := s.Sharing.ConsumeDownload(stream.Context(), link); err != nil { return shareError(err) }
+		}
+	}
+	var outboard storage.OutboardReader
+	var root [32]byte
+	if req.BaoProofs {
+		if req.Offset%verify.GroupSize != 0 || req.Length%verify.GroupSize != 0 { return status.Error(codes.InvalidArgument, verify.ErrMisaligned.Error()) }
+		ob, r, err := s.StorageSvc.Outboard(stream.Context(), req.Cid)
+		if errors.Is(err, storage.ErrOutboardNotReady) { return status.Error(codes.Unavailable, err.Error()) }
+		if err != nil { return err }
+		defer ob.Close()
+		outboard, root = ob, r
+	}
+	// контекст стрима отменяется при обрыве клиента или выходе из хендлера, вместе с ним и запрос к kubo
+	chunks, errs := s.StorageSvc.StreamCARRange(stream.Context(), req.Cid, req.Offset, req.Length)
+	pos := req.Offset
+	send := func(chunk []byte, last bool) error {
+		resp := &stgv1.GetFileResponse{EncryptedCarChunk: chunk, LastChunk: last, Offset: pos}
+		if req.ChunkTags {
+			sum := blake3.Sum256(chunk)
+			resp.ChunkBlake3 = sum[:]
 		}
+		if outboard != nil {
+			var proof bytes.Buffer
+			if err := verify.Proof(&proof, outboard, pos, int64(len(chunk))); err != nil { return err }
+			resp.BaoProof = proof.Bytes()
+			if pos == req.Offset { resp.BaoRoot = root[:] }
+		}
+		if err := stream.Send(resp); err != nil { return err }
+		pos += int64(len(chunk))
+		if s.Collector != nil { s.Collector.AddCARBytes(int64(len(chunk))) }
+		return nil
+	}
+	// держим один чанк впереди, чтобы пометить последний. с доказательствами чанки
+	// перекладываются в куски фиксированного размера, выровненные по группам
+	var pending []byte
+	for ch := range chunks {
+		if outboard != nil {
+			pending = append(pending, ch...)
+			for len(pending) > proofSliceSize {
+				if err := send(pending[:proofSliceSize], false); err != nil {
+					if s.Collector != nil { s.Collector.RecordFileOp("download", "failed") }
+					return err
+				}
+				pending = pending[proofSliceSize:]
+			}
+			continue
+		}
+		if pending != nil {
+			if err := send(pending, false); err != nil {
+				if s.Collector != nil { s.Collector.RecordFileOp("download", "failed") }
+				return err
+			}
+		}
+		pending = ch
+	}
+	if err := <-errs; err != nil {
+		if s.Collector != nil { s.Collector.RecordFileOp("download", "failed") }
+		if errors.Is(err, storage.ErrRangeNotSatisfiable) { return status.Error(codes.OutOfRange, err.Error()) }
+		// битая запись кэша уже удалена, повтор прочитает car из хранилища
+		if errors.Is(err, storage.ErrCacheCorrupt) { return status.Error(codes.Unavailable, err.Error()) }
+		return err
+	}
+	if err := send(pending, true); err != nil {
+		if s.Collector != nil { s.Collector.RecordFileOp("download", "failed") }
+		return err
 	}
-	if s.Collector != nil { s.Collector.RecordFileOp("download", "success"); s.Collector.AddCARBytes(totalBytes) }
+	if s.Collector != nil { s.Collector.RecordFileOp("download", "success") }
 	return nil
 }
 
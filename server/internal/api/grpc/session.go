@@ -0,0 +1,65 @@
+package grpcapi
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type sessionKey struct{}
+
+// пользователь и устройство из проверенного access токена
+type session struct {
+	userID   string
+	deviceID string
+}
+
+// access токен из Login/Refresh приходит в метаданных "authorization: Bearer <token>".
+// без токена или с негодным токеном вызов идет дальше без сессии, методы, которым нужен
+// пользователь, отвечают Unauthenticated
+func (s *Server) authenticate(ctx context.Context) context.Context {
+	if s.AuthSvc == nil {
+		return ctx
+	}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	vals := md.Get("authorization")
+	if len(vals) == 0 {
+		return ctx
+	}
+	scheme, tok, ok := strings.Cut(vals[0], " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") || tok == "" {
+		return ctx
+	}
+	userID, deviceID, err := s.AuthSvc.VerifyAccessToken(strings.TrimSpace(tok), time.Now())
+	if err != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, sessionKey{}, session{userID: userID, deviceID: deviceID})
+}
+
+func (s *Server) sessionUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(s.authenticate(ctx), req)
+}
+
+func (s *Server) sessionStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &sessionStream{ServerStream: ss, ctx: s.authenticate(ss.Context())})
+}
+
+type sessionStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *sessionStream) Context() context.Context { return s.ctx }
+
+// id пользователя текущей сессии, пустая строка без сессии
+func getUserIDFromContext(ctx context.Context) string {
+	sess, _ := ctx.Value(sessionKey{}).(session)
+	return sess.userID
+}
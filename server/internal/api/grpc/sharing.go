@@ -0,0 +1,259 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	stgv1 "dev.c0rex64.heroin/internal/gen/shared/proto/storage/v1"
+	"dev.c0rex64.heroin/internal/sharing"
+	"dev.c0rex64.heroin/internal/vfs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) Share(ctx context.Context, req *stgv1.ShareRequest) (*stgv1.ShareResponse, error) {
+	userID, err := s.sharingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var kind sharing.GranteeKind
+	switch req.GranteeKind {
+	case stgv1.GranteeKind_GRANTEE_KIND_USER:
+		kind = sharing.GranteeUser
+	case stgv1.GranteeKind_GRANTEE_KIND_GROUP:
+		kind = sharing.GranteeGroup
+	default:
+		return nil, status.Error(codes.InvalidArgument, sharing.ErrBadGrantee.Error())
+	}
+	g, err := s.Sharing.Grant(ctx, userID, sharing.Target{FileID: req.FileId, NodeID: req.NodeId}, kind, req.GranteeId, req.WrappedKey)
+	if err != nil {
+		return nil, shareError(err)
+	}
+	return &stgv1.ShareResponse{Grant: grantToProto(g)}, nil
+}
+
+func (s *Server) RevokeShare(ctx context.Context, req *stgv1.RevokeShareRequest) (*stgv1.RevokeShareResponse, error) {
+	userID, err := s.sharingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Sharing.Revoke(ctx, userID, req.GrantId); err != nil {
+		return nil, shareError(err)
+	}
+	return &stgv1.RevokeShareResponse{Revoked: true}, nil
+}
+
+func (s *Server) ListShares(ctx context.Context, req *stgv1.ListSharesRequest) (*stgv1.ListSharesResponse, error) {
+	userID, err := s.sharingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var grants []sharing.Grant
+	if req.SharedWithMe {
+		grants, err = s.Sharing.SharedWith(ctx, userID)
+	} else {
+		grants, err = s.Sharing.ListGrants(ctx, userID, sharing.Target{FileID: req.FileId, NodeID: req.NodeId})
+	}
+	if err != nil {
+		return nil, shareError(err)
+	}
+	resp := &stgv1.ListSharesResponse{Grants: make([]*stgv1.ShareGrant, 0, len(grants))}
+	for i := range grants {
+		resp.Grants = append(resp.Grants, grantToProto(&grants[i]))
+	}
+	return resp, nil
+}
+
+func (s *Server) CreateLink(ctx context.Context, req *stgv1.CreateLinkRequest) (*stgv1.CreateLinkResponse, error) {
+	userID, err := s.sharingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var expires time.Time
+	if req.ExpiresAtUnix > 0 {
+		expires = time.Unix(req.ExpiresAtUnix, 0)
+		if !expires.After(time.Now()) {
+			return nil, status.Error(codes.InvalidArgument, "expiry must be in the future")
+		}
+	}
+	if req.MaxDownloads < 0 {
+		return nil, status.Error(codes.InvalidArgument, "max downloads must not be negative")
+	}
+	l, token, err := s.Sharing.CreateLink(ctx, userID, sharing.Target{FileID: req.FileId, NodeID: req.NodeId}, req.Password, expires, int(req.MaxDownloads))
+	if err != nil {
+		return nil, shareError(err)
+	}
+	return &stgv1.CreateLinkResponse{Link: linkToProto(l), Token: token}, nil
+}
+
+func (s *Server) RevokeLink(ctx context.Context, req *stgv1.RevokeLinkRequest) (*stgv1.RevokeLinkResponse, error) {
+	userID, err := s.sharingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Sharing.RevokeLink(ctx, userID, req.LinkId); err != nil {
+		return nil, shareError(err)
+	}
+	return &stgv1.RevokeLinkResponse{Revoked: true}, nil
+}
+
+func (s *Server) ListLinks(ctx context.Context, req *stgv1.ListLinksRequest) (*stgv1.ListLinksResponse, error) {
+	userID, err := s.sharingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	links, err := s.Sharing.ListLinks(ctx, userID)
+	if err != nil {
+		return nil, shareError(err)
+	}
+	resp := &stgv1.ListLinksResponse{Links: make([]*stgv1.ShareLink, 0, len(links))}
+	for i := range links {
+		resp.Links = append(resp.Links, linkToProto(&links[i]))
+	}
+	return resp, nil
+}
+
+// открытие ссылки без входа: метаданные файла или страница общего каталога
+// от имени владельца. скачивание не засчитывается, это делает GetFile
+func (s *Server) ResolveLink(ctx context.Context, req *stgv1.ResolveLinkRequest) (*stgv1.ResolveLinkResponse, error) {
+	if s.Sharing == nil {
+		return nil, status.Error(codes.Unimplemented, "sharing not configured")
+	}
+	l, err := s.Sharing.OpenLink(ctx, req.Token, req.Password)
+	if err != nil {
+		return nil, shareError(err)
+	}
+	resp := &stgv1.ResolveLinkResponse{Link: linkToProto(l)}
+	if l.Target.FileID != "" {
+		f, err := s.StorageSvc.StatFile(ctx, l.OwnerID, l.Target.FileID)
+		if err != nil {
+			return nil, fileError(err)
+		}
+		resp.File = fileInfo(f)
+		return resp, nil
+	}
+	if s.VFS == nil {
+		return nil, status.Error(codes.Unimplemented, "vfs not configured")
+	}
+	dir, err := s.Sharing.LinkDir(ctx, l, req.ParentId)
+	if err != nil {
+		return nil, shareError(err)
+	}
+	nodes, next, err := s.VFS.List(ctx, l.OwnerID, dir, req.PageToken, int(req.PageSize))
+	if err != nil {
+		return nil, vfsError(err)
+	}
+	resp.NextPageToken = next
+	for i := range nodes {
+		resp.Nodes = append(resp.Nodes, nodeToProto(&nodes[i]))
+		if nodes[i].Kind != vfs.KindFile {
+			continue
+		}
+		f, err := s.StorageSvc.StatFile(ctx, l.OwnerID, nodes[i].FileID)
+		if err != nil {
+			return nil, fileError(err)
+		}
+		resp.Files = append(resp.Files, fileInfo(f))
+	}
+	return resp, nil
+}
+
+// проверить доступ к cid по ссылке или по выданным пользователю правам.
+// без настроенного sharing доступ не ограничивается
+func (s *Server) authorizeRead(ctx context.Context, cid, token, password string) (*sharing.Link, error) {
+	if s.Sharing == nil {
+		return nil, nil
+	}
+	if cid == "" {
+		return nil, status.Error(codes.InvalidArgument, "cid required")
+	}
+	if token != "" {
+		l, err := s.Sharing.OpenLink(ctx, token, password)
+		if err != nil {
+			return nil, shareError(err)
+		}
+		ok, err := s.Sharing.LinkCovers(ctx, l, cid)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, status.Error(codes.PermissionDenied, sharing.ErrOutsideLink.Error())
+		}
+		return l, nil
+	}
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	ok, err := s.Sharing.CanRead(ctx, userID, cid)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "access denied")
+	}
+	return nil, nil
+}
+
+func (s *Server) sharingUser(ctx context.Context) (string, error) {
+	if s.Sharing == nil {
+		return "", status.Error(codes.Unimplemented, "sharing not configured")
+	}
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		return "", status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	return userID, nil
+}
+
+func grantToProto(g *sharing.Grant) *stgv1.ShareGrant {
+	p := &stgv1.ShareGrant{
+		GrantId:       g.ID,
+		OwnerId:       g.OwnerID,
+		FileId:        g.Target.FileID,
+		NodeId:        g.Target.NodeID,
+		GranteeId:     g.GranteeID,
+		WrappedKey:    g.WrappedKey,
+		CreatedAtUnix: g.CreatedAt.Unix(),
+	}
+	switch g.GranteeKind {
+	case sharing.GranteeUser:
+		p.GranteeKind = stgv1.GranteeKind_GRANTEE_KIND_USER
+	case sharing.GranteeGroup:
+		p.GranteeKind = stgv1.GranteeKind_GRANTEE_KIND_GROUP
+	}
+	return p
+}
+
+func linkToProto(l *sharing.Link) *stgv1.ShareLink {
+	p := &stgv1.ShareLink{
+		LinkId:        l.ID,
+		FileId:        l.Target.FileID,
+		NodeId:        l.Target.NodeID,
+		HasPassword:   l.HasPassword,
+		MaxDownloads:  int32(l.MaxDownloads),
+		Downloads:     int32(l.Downloads),
+		CreatedAtUnix: l.CreatedAt.Unix(),
+	}
+	if !l.ExpiresAt.IsZero() {
+		p.ExpiresAtUnix = l.ExpiresAt.Unix()
+	}
+	return p
+}
+
+func shareError(err error) error {
+	switch {
+	case errors.Is(err, sharing.ErrNotFound), errors.Is(err, sharing.ErrTargetMissing), errors.Is(err, sharing.ErrLinkInvalid):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, sharing.ErrExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, sharing.ErrBadTarget), errors.Is(err, sharing.ErrBadGrantee), errors.Is(err, sharing.ErrBadKey), errors.Is(err, sharing.ErrBadPassword):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, sharing.ErrPassword), errors.Is(err, sharing.ErrOutsideLink):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, sharing.ErrLinkExhausted):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,109 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	stgv1 "dev.c0rex64.heroin/internal/gen/shared/proto/storage/v1"
+	"dev.c0rex64.heroin/internal/storage"
+	"dev.c0rex64.heroin/pkg/verify"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) BeginUpload(ctx context.Context, req *stgv1.BeginUploadRequest) (*stgv1.BeginUploadResponse, error) {
+	if s.Uploads == nil {
+		return nil, status.Error(codes.Unimplemented, "resumable uploads not configured")
+	}
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	up, err := s.Uploads.Begin(ctx, userID, req.Name, req.Mime, req.SizeBytes, req.TotalBlake3)
+	if err != nil {
+		s.recordQuotaRejection(err)
+		return nil, uploadError(err)
+	}
+	if s.Collector != nil { s.Collector.RecordFileOp("upload", "begin") }
+	return &stgv1.BeginUploadResponse{UploadId: up.ID, ExpiresAtUnix: up.ExpiresAt.Unix(), MaxChunkBytes: storage.MaxUploadChunk}, nil
+}
+
+func (s *Server) UploadChunk(ctx context.Context, req *stgv1.UploadChunkRequest) (*stgv1.UploadChunkResponse, error) {
+	if s.Uploads == nil {
+		return nil, status.Error(codes.Unimplemented, "resumable uploads not configured")
+	}
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	n, err := s.Uploads.WriteChunk(ctx, userID, req.UploadId, req.Offset, req.Data, req.BaoProof)
+	if err != nil {
+		return nil, uploadError(err)
+	}
+	if s.Collector != nil { s.Collector.AddCARBytes(int64(len(req.Data))) }
+	return &stgv1.UploadChunkResponse{ReceivedBytes: n}, nil
+}
+
+func (s *Server) QueryUpload(ctx context.Context, req *stgv1.QueryUploadRequest) (*stgv1.QueryUploadResponse, error) {
+	if s.Uploads == nil {
+		return nil, status.Error(codes.Unimplemented, "resumable uploads not configured")
+	}
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	up, err := s.Uploads.Query(ctx, userID, req.UploadId)
+	if err != nil {
+		return nil, uploadError(err)
+	}
+	resp := &stgv1.QueryUploadResponse{SizeBytes: up.Size, ExpiresAtUnix: up.ExpiresAt.Unix()}
+	for _, r := range up.Ranges {
+		resp.Received = append(resp.Received, &stgv1.ByteRange{Start: r.Start, End: r.End})
+	}
+	return resp, nil
+}
+
+func (s *Server) CommitUpload(ctx context.Context, req *stgv1.CommitUploadRequest) (*stgv1.CommitUploadResponse, error) {
+	if s.Uploads == nil {
+		return nil, status.Error(codes.Unimplemented, "resumable uploads not configured")
+	}
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	fileID, cid, err := s.Uploads.Commit(ctx, userID, req.UploadId)
+	if err != nil {
+		if s.Collector != nil { s.Collector.RecordFileOp("upload", "failed") }
+		s.recordQuotaRejection(err)
+		return nil, uploadError(err)
+	}
+	if s.Collector != nil { s.Collector.RecordFileOp("upload", "success") }
+	return &stgv1.CommitUploadResponse{FileId: fileID, Cid: cid}, nil
+}
+
+func uploadError(err error) error {
+	var le *storage.LimitError
+	var qe *storage.QuotaError
+	var ce *verify.CorruptionError
+	switch {
+	case errors.As(err, &ce):
+		return status.Error(codes.DataLoss, err.Error())
+	case errors.Is(err, verify.ErrBadProof), errors.Is(err, verify.ErrMisaligned), errors.Is(err, verify.ErrSliceOutside):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, storage.ErrUploadNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.As(err, &le), errors.As(err, &qe), errors.Is(err, storage.ErrChunkTooLarge):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, storage.ErrChunkOutOfRange):
+		return status.Error(codes.OutOfRange, err.Error())
+	case errors.Is(err, storage.ErrUploadCommitting):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, storage.ErrUploadIncomplete), errors.Is(err, storage.ErrUploadCommitted):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, storage.ErrBadUploadSize), errors.Is(err, storage.ErrBlake3Mismatch), errors.Is(err, storage.ErrBadCARHeader), errors.Is(err, storage.ErrBadCARBlock), errors.Is(err, storage.ErrCARCodec), errors.Is(err, storage.ErrCARBlockTooLarge), errors.Is(err, storage.ErrSizeMismatch):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, storage.ErrQuorum), errors.Is(err, storage.ErrContentReleasing):
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return err
+}
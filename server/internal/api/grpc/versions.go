@@ -0,0 +1,51 @@
+package grpcapi
+
+import (
+	"context"
+
+	stgv1 "dev.c0rex64.heroin/internal/gen/shared/proto/storage/v1"
+	"dev.c0rex64.heroin/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) ListVersions(ctx context.Context, req *stgv1.ListVersionsRequest) (*stgv1.ListVersionsResponse, error) {
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	versions, err := s.StorageSvc.ListVersions(ctx, userID, req.FileId)
+	if err != nil {
+		return nil, fileError(err)
+	}
+	resp := &stgv1.ListVersionsResponse{Versions: make([]*stgv1.FileVersion, 0, len(versions))}
+	for i := range versions {
+		resp.Versions = append(resp.Versions, versionInfo(&versions[i]))
+	}
+	return resp, nil
+}
+
+func (s *Server) RestoreVersion(ctx context.Context, req *stgv1.RestoreVersionRequest) (*stgv1.RestoreVersionResponse, error) {
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	f, v, err := s.StorageSvc.RestoreVersion(ctx, userID, req.DeviceId, req.FileId, req.Version)
+	if err != nil {
+		return nil, fileError(err)
+	}
+	if s.Collector != nil { s.Collector.RecordFileOp("restore_version", "success") }
+	return &stgv1.RestoreVersionResponse{File: fileInfo(f), Version: versionInfo(v)}, nil
+}
+
+func versionInfo(v *storage.Version) *stgv1.FileVersion {
+	return &stgv1.FileVersion{
+		Version:       v.Version,
+		Cid:           v.CID,
+		SizeBytes:     v.Size,
+		Blake3:        v.Blake3,
+		DeviceId:      v.DeviceID,
+		CreatedAtUnix: v.CreatedAt.Unix(),
+		Current:       v.Current,
+	}
+}
@@ -0,0 +1,155 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	stgv1 "dev.c0rex64.heroin/internal/gen/shared/proto/storage/v1"
+	"dev.c0rex64.heroin/internal/vfs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) MkDir(ctx context.Context, req *stgv1.MkDirRequest) (*stgv1.MkDirResponse, error) {
+	userID, err := s.vfsUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	n, err := s.VFS.MkDir(ctx, userID, req.ParentId, req.NameBlob, req.NameHash)
+	if err != nil {
+		return nil, vfsError(err)
+	}
+	return &stgv1.MkDirResponse{Node: nodeToProto(n)}, nil
+}
+
+func (s *Server) LinkFile(ctx context.Context, req *stgv1.LinkFileRequest) (*stgv1.LinkFileResponse, error) {
+	userID, err := s.vfsUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	n, err := s.VFS.LinkFile(ctx, userID, req.FileId, req.ParentId, req.NameBlob, req.NameHash)
+	if err != nil {
+		return nil, vfsError(err)
+	}
+	return &stgv1.LinkFileResponse{Node: nodeToProto(n)}, nil
+}
+
+func (s *Server) Move(ctx context.Context, req *stgv1.MoveRequest) (*stgv1.MoveResponse, error) {
+	userID, err := s.vfsUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	n, err := s.VFS.Move(ctx, userID, req.NodeId, req.NewParentId, req.NameBlob, req.NameHash, req.ExpectedVersion)
+	if err != nil {
+		return nil, vfsError(err)
+	}
+	return &stgv1.MoveResponse{Node: nodeToProto(n)}, nil
+}
+
+func (s *Server) ListDir(ctx context.Context, req *stgv1.ListDirRequest) (*stgv1.ListDirResponse, error) {
+	userID, err := s.vfsUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var nodes []vfs.Node
+	var next string
+	if req.Trash {
+		nodes, next, err = s.VFS.ListTrash(ctx, userID, req.PageToken, int(req.PageSize))
+	} else {
+		nodes, next, err = s.VFS.List(ctx, userID, req.ParentId, req.PageToken, int(req.PageSize))
+	}
+	if err != nil {
+		return nil, vfsError(err)
+	}
+	resp := &stgv1.ListDirResponse{Nodes: make([]*stgv1.Node, 0, len(nodes)), NextPageToken: next}
+	for i := range nodes {
+		resp.Nodes = append(resp.Nodes, nodeToProto(&nodes[i]))
+	}
+	return resp, nil
+}
+
+func (s *Server) ResolvePath(ctx context.Context, req *stgv1.ResolvePathRequest) (*stgv1.ResolvePathResponse, error) {
+	userID, err := s.vfsUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	n, err := s.VFS.Resolve(ctx, userID, req.NameHashes)
+	if err != nil {
+		return nil, vfsError(err)
+	}
+	return &stgv1.ResolvePathResponse{Node: nodeToProto(n)}, nil
+}
+
+func (s *Server) Trash(ctx context.Context, req *stgv1.TrashRequest) (*stgv1.TrashResponse, error) {
+	userID, err := s.vfsUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	n, err := s.VFS.Trash(ctx, userID, req.NodeId, req.ExpectedVersion)
+	if err != nil {
+		return nil, vfsError(err)
+	}
+	return &stgv1.TrashResponse{Node: nodeToProto(n)}, nil
+}
+
+func (s *Server) Restore(ctx context.Context, req *stgv1.RestoreRequest) (*stgv1.RestoreResponse, error) {
+	userID, err := s.vfsUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	n, err := s.VFS.Restore(ctx, userID, req.NodeId, req.NameBlob, req.NameHash)
+	if err != nil {
+		return nil, vfsError(err)
+	}
+	return &stgv1.RestoreResponse{Node: nodeToProto(n)}, nil
+}
+
+func (s *Server) vfsUser(ctx context.Context) (string, error) {
+	if s.VFS == nil {
+		return "", status.Error(codes.Unimplemented, "vfs not configured")
+	}
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		return "", status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	return userID, nil
+}
+
+func nodeToProto(n *vfs.Node) *stgv1.Node {
+	p := &stgv1.Node{
+		NodeId:        n.ID,
+		ParentId:      n.ParentID,
+		FileId:        n.FileID,
+		NameBlob:      n.NameBlob,
+		NameHash:      n.NameHash,
+		Version:       n.Version,
+		CreatedAtUnix: n.CreatedAt.Unix(),
+		UpdatedAtUnix: n.UpdatedAt.Unix(),
+	}
+	switch n.Kind {
+	case vfs.KindDir:
+		p.Kind = stgv1.NodeKind_NODE_KIND_DIR
+	case vfs.KindFile:
+		p.Kind = stgv1.NodeKind_NODE_KIND_FILE
+	}
+	if !n.TrashedAt.IsZero() {
+		p.TrashedAtUnix = n.TrashedAt.Unix()
+	}
+	return p
+}
+
+func vfsError(err error) error {
+	switch {
+	case errors.Is(err, vfs.ErrNodeNotFound), errors.Is(err, vfs.ErrFileNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, vfs.ErrNameConflict):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, vfs.ErrVersionConflict):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, vfs.ErrNotDir), errors.Is(err, vfs.ErrCycle), errors.Is(err, vfs.ErrParentTrashed):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, vfs.ErrBadName):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return err
+}
@@ -1,22 +1,78 @@
 package httpapi
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+
+	"dev.c0rex64.heroin/internal/ipfs"
 )
 
 type Server struct {
 	addr string
+	ipfs func() []ipfs.Health
 }
 
 func New(addr string) *Server { return &Server{addr: addr} }
 
+// узлы kubo в /healthz. без них /healthz отвечает просто ok
+func (s *Server) SetIPFSHealth(f func() []ipfs.Health) {
+	s.ipfs = f
+}
+
 func (s *Server) Start() error {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprint(w, "ok")
-	})
+	mux.HandleFunc("/healthz", s.healthz)
 	log.Printf("http listening on %s", s.addr)
 	return http.ListenAndServe(s.addr, mux)
 }
+
+type nodeHealth struct {
+	Endpoint  string  `json:"endpoint"`
+	Up        bool    `json:"up"`
+	Breaker   string  `json:"breaker"`
+	PeerID    string  `json:"peer_id,omitempty"`
+	Agent     string  `json:"agent,omitempty"`
+	LatencyMs float64 `json:"latency_ms"`
+	CheckedAt int64   `json:"checked_at,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// ok - все узлы живы, degraded - часть узлов лежит, unavailable - ни одного живого,
+// тогда 503
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	var nodes []ipfs.Health
+	if s.ipfs != nil {
+		nodes = s.ipfs()
+	}
+	if len(nodes) == 0 {
+		fmt.Fprint(w, "ok")
+		return
+	}
+	out := struct {
+		Status string       `json:"status"`
+		IPFS   []nodeHealth `json:"ipfs"`
+	}{Status: "ok"}
+	up := 0
+	for _, h := range nodes {
+		n := nodeHealth{Endpoint: h.Endpoint, Up: h.Up, Breaker: h.Breaker, PeerID: h.PeerID, Agent: h.Agent, LatencyMs: float64(h.Latency.Microseconds()) / 1000, Error: h.Err}
+		if !h.CheckedAt.IsZero() {
+			n.CheckedAt = h.CheckedAt.Unix()
+		}
+		if h.Up && h.Breaker != ipfs.BreakerOpen {
+			up++
+		}
+		out.IPFS = append(out.IPFS, n)
+	}
+	code := http.StatusOK
+	switch {
+	case up == 0:
+		out.Status, code = "unavailable", http.StatusServiceUnavailable
+	case up < len(nodes):
+		out.Status = "degraded"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(out)
+}
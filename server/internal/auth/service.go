@@ -61,6 +61,18 @@ func (ti TokenIssuer) Issue(subject string, deviceID string, now time.Time) (str
 	return tok, exp, nil
 }
 
+// проверить access токен, вернуть пользователя и устройство
+func (ti TokenIssuer) Verify(token string, now time.Time) (string, string, error) {
+	raw, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(token)
+	if err != nil || len(raw) <= sha256.Size { return "", "", ErrInvalidToken }
+	b, sig := raw[:len(raw)-sha256.Size], raw[len(raw)-sha256.Size:]
+	if !hmac.Equal(hmacSign(ti.key, b), sig) { return "", "", ErrInvalidToken }
+	var p tokenPayload
+	if err := json.Unmarshal(b, &p); err != nil { return "", "", ErrInvalidToken }
+	if p.Sub == "" || p.Iss != ti.issuer || now.Unix() >= p.Exp { return "", "", ErrInvalidToken }
+	return p.Sub, p.Dev, nil
+}
+
 func hmacSign(key, msg []byte) []byte {
 	h := hmac.New(sha256.New, key)
 	h.Write(msg)
@@ -130,7 +142,10 @@ type Store interface {
 	GetPublicKey(ctx context.Context, userID string) ([]byte, error)
 }
 
-var ErrInvalidCredentials = errors.New("invalid credentials")
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidToken       = errors.New("invalid or expired access token")
+)
 
 // SRP/PAKE will be integrated later, we keep proof as opaque bytes
 
@@ -189,13 +204,20 @@ func (s *Service) LoginSRP(ctx context.Context, username string, clientPub []byt
 	return M2, nil
 }
 
-func (s *Service) Register(ctx context.Context, username string, passwordProof []byte, clientPub []byte) (string, error) {
-	if username == "" { return "", fmt.Errorf("username empty") }
+// соль и хеш пароля из proof регистрации "hex(salt):hex(hash)"
+func ParsePasswordProof(passwordProof []byte) ([]byte, []byte, error) {
 	parts := strings.Split(string(passwordProof), ":")
-	if len(parts) != 2 { return "", fmt.Errorf("invalid password proof format") }
+	if len(parts) != 2 { return nil, nil, fmt.Errorf("invalid password proof format") }
 	salt, err := hex.DecodeString(parts[0])
-	if err != nil { return "", err }
+	if err != nil { return nil, nil, err }
 	hash, err := hex.DecodeString(parts[1])
+	if err != nil { return nil, nil, err }
+	return salt, hash, nil
+}
+
+func (s *Service) Register(ctx context.Context, username string, passwordProof []byte, clientPub []byte) (string, error) {
+	if username == "" { return "", fmt.Errorf("username empty") }
+	salt, hash, err := ParsePasswordProof(passwordProof)
 	if err != nil { return "", err }
 	id := generateUUIDv7()
 	secret := randomStrongBytes(32)
@@ -230,6 +252,18 @@ func (s *Service) Login(ctx context.Context, username string, passwordProof []by
 	return access, refreshStr, exp, nil
 }
 
+// повторная проверка пароля и второго фактора для опасных операций, сессия не создается
+func (s *Service) Reauthenticate(ctx context.Context, username string, passwordProof []byte, secondCode string, now time.Time) (string, error) {
+	u, err := s.store.FindUserByUsername(ctx, username)
+	if err != nil || u == nil { return "", ErrInvalidCredentials }
+	calc := s.hasher.Hash(passwordProof, u.ServerSalt)
+	if !hmac.Equal(calc, u.PasswordHash) {
+		return "", ErrInvalidCredentials
+	}
+	if !s.second.Verify(now, secondCode) { return "", ErrInvalidCredentials }
+	return u.ID, nil
+}
+
 func (s *Service) NewRefreshToken() (string, []byte) {
 	b := randomStrongBytes(32)
 	tokenStr := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)
@@ -248,6 +282,11 @@ func (s *Service) Refresh(ctx context.Context, refreshToken string, deviceID str
 	return tok, exp, nil
 }
 
+// сессия по access токену из Login или Refresh
+func (s *Service) VerifyAccessToken(token string, now time.Time) (string, string, error) {
+	return s.issuer.Verify(token, now)
+}
+
 func (s *Service) GetPublicKey(ctx context.Context, userID string) ([]byte, error) {
 	return s.store.GetPublicKey(ctx, userID)
 }
@@ -53,6 +53,97 @@ type IPFSConfig struct {
 	Endpoint          string `yaml:"endpoint"`
 	PinningEnabled    bool   `yaml:"pinning_enabled"`
 	ReplicationFactor int    `yaml:"replication_factor"`
+	MaxUploadBytes    int64  `yaml:"max_upload_bytes"`
+	UploadStagingDir  string `yaml:"upload_staging_dir"`
+	UploadTTLHours    int    `yaml:"upload_ttl_hours"`
+	OutboardDir       string `yaml:"outboard_dir"`
+	// тарифы квот: имя -> лимит в байтах, 0 без лимита. пустой quota_default_plan
+	// не ограничивает пользователей без назначенного тарифа. тариф группы не общий
+	// объем на группу, а лимит каждого ее участника без своего тарифа
+	QuotaPlans       map[string]int64 `yaml:"quota_plans"`
+	QuotaDefaultPlan string           `yaml:"quota_default_plan"`
+	// сверка пинов с files, 0 отключает. сироты открепляются не раньше грейс периода
+	ReconcileIntervalMin int `yaml:"reconcile_interval_min"`
+	OrphanGraceHours     int `yaml:"orphan_grace_hours"`
+	// несколько узлов kubo для replication_factor. endpoint тогда - первый из них,
+	// write_quorum 0 значит большинство от replication_factor
+	Endpoints         []string `yaml:"endpoints"`
+	WriteQuorum       int      `yaml:"write_quorum"`
+	RepairIntervalMin int      `yaml:"repair_interval_min"`
+	// где хранить car: kubo (по умолчанию), local - файлы в blob_dir, s3 - бакет из s3
+	Backend string   `yaml:"backend"`
+	BlobDir string   `yaml:"blob_dir"`
+	S3      S3Config `yaml:"s3"`
+	// проверка загружаемых car: блоки крупнее max_block_bytes и кодеки не из
+	// allowed_codecs отклоняются до хранилища
+	MaxBlockBytes int      `yaml:"max_block_bytes"`
+	AllowedCodecs []string `yaml:"allowed_codecs"`
+	// проход gc блоков без ссылок и пересчет метрик дедупликации
+	BlockGCIntervalMin int `yaml:"block_gc_interval_min"`
+	// хранение старых версий файлов: последние version_keep или моложе
+	// version_keep_days, 0 - правило не действует, оба 0 - история хранится целиком
+	VersionKeep     int `yaml:"version_keep"`
+	VersionKeepDays int `yaml:"version_keep_days"`
+	// таймауты, повторы и предохранитель запросов к kubo, проверка /api/v0/id
+	Client IPFSClientConfig `yaml:"client"`
+	// доступ к удаленным kubo, общий для endpoint и endpoints. endpoint может быть
+	// и multiaddr: /dns4/kubo/tcp/5001/https, /unix/run/kubo/api.sock
+	Auth IPFSAuthConfig `yaml:"auth"`
+	TLS  IPFSTLSConfig  `yaml:"tls"`
+	// кэш экспортов car на диске для GetFile, пустой cache_dir отключает. car больше
+	// cache_max_entry_bytes читаются мимо кэша
+	CacheDir           string `yaml:"cache_dir"`
+	CacheMaxBytes      int64  `yaml:"cache_max_bytes"`
+	CacheMaxEntryBytes int64  `yaml:"cache_max_entry_bytes"`
+}
+
+// под API.Authorizations kubo: bearer token или basic username/password, не оба сразу
+type IPFSAuthConfig struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	Token    string `yaml:"token"`
+}
+
+// ca_file вместо системных корней, cert_file и key_file - клиентский сертификат для mtls
+type IPFSTLSConfig struct {
+	CAFile     string `yaml:"ca_file"`
+	CertFile   string `yaml:"cert_file"`
+	KeyFile    string `yaml:"key_file"`
+	ServerName string `yaml:"server_name"`
+}
+
+// повторяются только pin/add и установка потока dag/export, retries -1 отключает повторы.
+// export_timeout_sec ждет начала ответа, сам поток не ограничен
+type IPFSClientConfig struct {
+	TimeoutSec         int `yaml:"timeout_sec"`
+	PinTimeoutSec      int `yaml:"pin_timeout_sec"`
+	ImportTimeoutSec   int `yaml:"import_timeout_sec"`
+	ExportTimeoutSec   int `yaml:"export_timeout_sec"`
+	Retries            int `yaml:"retries"`
+	RetryBaseMs        int `yaml:"retry_base_ms"`
+	RetryMaxMs         int `yaml:"retry_max_ms"`
+	BreakerFailures    int `yaml:"breaker_failures"`
+	BreakerCooldownSec int `yaml:"breaker_cooldown_sec"`
+	HealthIntervalSec  int `yaml:"health_interval_sec"`
+}
+
+type S3Config struct {
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+	Prefix    string `yaml:"prefix"`
+	PathStyle bool   `yaml:"path_style"`
+	SpoolDir  string `yaml:"spool_dir"`
+}
+
+type NotificationsConfig struct {
+	WebhookURL    string `yaml:"webhook_url"`
+	WebhookSecret string `yaml:"webhook_secret"`
+	TimeoutSec    int    `yaml:"timeout_sec"`
+	MaxAttempts   int    `yaml:"max_attempts"`
+	Workers       int    `yaml:"workers"`
 }
 
 type KDFConfig struct {
@@ -76,11 +167,26 @@ type SecondaryKeyConfig struct {
 	AllowedClockSkewSec int `yaml:"allowed_clock_skew_sec"`
 }
 
+// подпись архивов аккаунта, ключи ed25519 в base64: приватный как 32 байта seed
+type ArchiveConfig struct {
+	SigningKeyBase64  string   `yaml:"signing_key_base64"`
+	TrustedKeysBase64 []string `yaml:"trusted_keys_base64"`
+}
+
+// удаление аккаунта: записи аудита моложе legal hold сохраняются
+type DeletionConfig struct {
+	AuditLegalHoldDays int `yaml:"audit_legal_hold_days"`
+	MaxAttempts        int `yaml:"max_attempts"`
+}
+
 type SecurityConfig struct {
 	KDF            KDFConfig          `yaml:"kdf"`
 	Token          TokenConfig        `yaml:"token"`
 	SecondaryKey   SecondaryKeyConfig `yaml:"secondary_key"`
 	TLSFingerprint string            `yaml:"tls_fingerprint"`
+	Archive        ArchiveConfig      `yaml:"archive"`
+	Deletion       DeletionConfig     `yaml:"deletion"`
+	AdminUserIDs   []string           `yaml:"admin_user_ids"`
 }
 
 type DatabaseConfig struct {
@@ -104,11 +210,13 @@ type Config struct {
 	Database      DatabaseConfig     `yaml:"database"`
 	Logging       LoggingConfig      `yaml:"logging"`
 	Observability ObservabilityConfig `yaml:"observability"`
+	Notifications NotificationsConfig `yaml:"notifications"`
 
 	// derived
 	pasetoSymmetricKey []byte
 	issuerEd25519Priv  ed25519.PrivateKey
 	issuerEd25519Pub   ed25519.PublicKey
+	trustedArchiveKeys []ed25519.PublicKey
 }
 
 func Load(path string) (*Config, error) {
@@ -151,6 +259,119 @@ func (c *Config) populateDerived() error {
 	if c.Security.Token.LifetimeMin <= 0 {
 		c.Security.Token.LifetimeMin = 30
 	}
+	if c.IPFS.OrphanGraceHours <= 0 {
+		c.IPFS.OrphanGraceHours = 24
+	}
+	switch c.IPFS.Backend {
+	case "":
+		c.IPFS.Backend = "kubo"
+	case "kubo":
+	case "local":
+		if c.IPFS.BlobDir == "" {
+			return fmt.Errorf("ipfs.blob_dir required for local backend")
+		}
+	case "s3":
+		if c.IPFS.S3.Endpoint == "" || c.IPFS.S3.Bucket == "" {
+			return fmt.Errorf("ipfs.s3.endpoint and ipfs.s3.bucket required for s3 backend")
+		}
+	default:
+		return fmt.Errorf("unknown ipfs.backend %q", c.IPFS.Backend)
+	}
+	// репликация пишет car прямо в узлы kubo и заменила бы local или s3 хранилище
+	if len(c.IPFS.Endpoints) > 0 && c.IPFS.Backend != "kubo" {
+		return fmt.Errorf("ipfs.endpoints replicates across kubo nodes and cannot be combined with ipfs.backend %q", c.IPFS.Backend)
+	}
+	if c.IPFS.VersionKeep < 0 || c.IPFS.VersionKeepDays < 0 {
+		return fmt.Errorf("ipfs.version_keep and ipfs.version_keep_days must not be negative")
+	}
+	cl := &c.IPFS.Client
+	if cl.TimeoutSec <= 0 {
+		cl.TimeoutSec = 30
+	}
+	if cl.PinTimeoutSec <= 0 {
+		cl.PinTimeoutSec = 600
+	}
+	if cl.ImportTimeoutSec <= 0 {
+		cl.ImportTimeoutSec = 3600
+	}
+	if cl.ExportTimeoutSec <= 0 {
+		cl.ExportTimeoutSec = 30
+	}
+	if cl.Retries == 0 {
+		cl.Retries = 3
+	}
+	if cl.RetryBaseMs <= 0 {
+		cl.RetryBaseMs = 200
+	}
+	if cl.RetryMaxMs <= 0 {
+		cl.RetryMaxMs = 5000
+	}
+	if cl.BreakerFailures <= 0 {
+		cl.BreakerFailures = 5
+	}
+	if cl.BreakerCooldownSec <= 0 {
+		cl.BreakerCooldownSec = 30
+	}
+	if cl.HealthIntervalSec <= 0 {
+		cl.HealthIntervalSec = 30
+	}
+	if a := c.IPFS.Auth; a.Token != "" && (a.Username != "" || a.Password != "") {
+		return fmt.Errorf("ipfs.auth: token and username/password are mutually exclusive")
+	}
+	if t := c.IPFS.TLS; (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("ipfs.tls.cert_file and ipfs.tls.key_file must be set together")
+	}
+	if c.IPFS.CacheMaxBytes <= 0 {
+		c.IPFS.CacheMaxBytes = 10 << 30
+	}
+	if c.IPFS.CacheMaxEntryBytes <= 0 {
+		c.IPFS.CacheMaxEntryBytes = 512 << 20
+	}
+	if c.IPFS.BlockGCIntervalMin <= 0 {
+		c.IPFS.BlockGCIntervalMin = 10
+	}
+	if c.IPFS.MaxBlockBytes <= 0 {
+		c.IPFS.MaxBlockBytes = 2 << 20
+	}
+	if len(c.IPFS.AllowedCodecs) == 0 {
+		c.IPFS.AllowedCodecs = []string{"raw", "dag-pb", "dag-cbor", "dag-json"}
+	}
+	if len(c.IPFS.Endpoints) > 0 {
+		if c.IPFS.Endpoint == "" {
+			c.IPFS.Endpoint = c.IPFS.Endpoints[0]
+		}
+		if c.IPFS.RepairIntervalMin <= 0 {
+			c.IPFS.RepairIntervalMin = 10
+		}
+	}
+	if c.Security.Archive.SigningKeyBase64 != "" {
+		seed, err := base64.StdEncoding.DecodeString(c.Security.Archive.SigningKeyBase64)
+		if err != nil {
+			return fmt.Errorf("decode archive signing key: %w", err)
+		}
+		if len(seed) != ed25519.SeedSize {
+			return fmt.Errorf("archive signing key must be 32 bytes after base64 decoding")
+		}
+		c.issuerEd25519Priv = ed25519.NewKeyFromSeed(seed)
+	} else {
+		// без ключа в конфиге архивы проверяются только этим же процессом
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return fmt.Errorf("generate archive signing key: %w", err)
+		}
+		c.issuerEd25519Priv = priv
+	}
+	c.issuerEd25519Pub = c.issuerEd25519Priv.Public().(ed25519.PublicKey)
+	for _, k := range c.Security.Archive.TrustedKeysBase64 {
+		pub, err := base64.StdEncoding.DecodeString(k)
+		if err != nil {
+			return fmt.Errorf("decode trusted archive key: %w", err)
+		}
+		if len(pub) != ed25519.PublicKeySize {
+			return fmt.Errorf("trusted archive key must be 32 bytes after base64 decoding")
+		}
+		c.trustedArchiveKeys = append(c.trustedArchiveKeys, ed25519.PublicKey(pub))
+	}
 	return nil
 }
 
@@ -161,3 +382,15 @@ func (c *Config) AccessTokenTTL() time.Duration {
 func (c *Config) PasetoKey() []byte {
 	return c.pasetoSymmetricKey
 }
+
+func (c *Config) AuditLegalHold() time.Duration {
+	return time.Duration(c.Security.Deletion.AuditLegalHoldDays) * 24 * time.Hour
+}
+
+func (c *Config) SigningKey() ed25519.PrivateKey {
+	return c.issuerEd25519Priv
+}
+
+func (c *Config) TrustedArchiveKeys() []ed25519.PublicKey {
+	return c.trustedArchiveKeys
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func load(t *testing.T, yaml string) (*Config, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+func TestEndpointsRequireKuboBackend(t *testing.T) {
+	for _, backend := range []string{"local", "s3"} {
+		_, err := load(t, `
+ipfs:
+  backend: `+backend+`
+  blob_dir: /tmp/blobs
+  s3: {endpoint: "http://minio:9000", bucket: cars}
+  endpoints: ["http://kubo-a:5001", "http://kubo-b:5001"]
+`)
+		if err == nil || !strings.Contains(err.Error(), "ipfs.endpoints") {
+			t.Fatalf("%s backend with endpoints: %v", backend, err)
+		}
+	}
+	for _, backend := range []string{"", "kubo"} {
+		c, err := load(t, `
+ipfs:
+  backend: "`+backend+`"
+  endpoints: ["http://kubo-a:5001", "http://kubo-b:5001"]
+`)
+		if err != nil {
+			t.Fatalf("kubo backend %q with endpoints: %v", backend, err)
+		}
+		if c.IPFS.Endpoint != "http://kubo-a:5001" {
+			t.Fatalf("endpoint %q", c.IPFS.Endpoint)
+		}
+	}
+}
@@ -21,6 +21,61 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type DeletionState int32
+
+const (
+	DeletionState_DELETION_STATE_UNSPECIFIED DeletionState = 0
+	DeletionState_DELETION_STATE_PENDING     DeletionState = 1
+	DeletionState_DELETION_STATE_RUNNING     DeletionState = 2
+	DeletionState_DELETION_STATE_DONE        DeletionState = 3
+	DeletionState_DELETION_STATE_FAILED      DeletionState = 4
+)
+
+// Enum value maps for DeletionState.
+var (
+	DeletionState_name = map[int32]string{
+		0: "DELETION_STATE_UNSPECIFIED",
+		1: "DELETION_STATE_PENDING",
+		2: "DELETION_STATE_RUNNING",
+		3: "DELETION_STATE_DONE",
+		4: "DELETION_STATE_FAILED",
+	}
+	DeletionState_value = map[string]int32{
+		"DELETION_STATE_UNSPECIFIED": 0,
+		"DELETION_STATE_PENDING":     1,
+		"DELETION_STATE_RUNNING":     2,
+		"DELETION_STATE_DONE":        3,
+		"DELETION_STATE_FAILED":      4,
+	}
+)
+
+func (x DeletionState) Enum() *DeletionState {
+	p := new(DeletionState)
+	*p = x
+	return p
+}
+
+func (x DeletionState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DeletionState) Descriptor() protoreflect.EnumDescriptor {
+	return file_shared_proto_auth_v1_auth_proto_enumTypes[0].Descriptor()
+}
+
+func (DeletionState) Type() protoreflect.EnumType {
+	return &file_shared_proto_auth_v1_auth_proto_enumTypes[0]
+}
+
+func (x DeletionState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DeletionState.Descriptor instead.
+func (DeletionState) EnumDescriptor() ([]byte, []int) {
+	return file_shared_proto_auth_v1_auth_proto_rawDescGZIP(), []int{0}
+}
+
 type RegisterRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Username      string                 `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
@@ -445,6 +500,540 @@ func (x *GetPublicKeyResponse) GetPublicKey() []byte {
 	return nil
 }
 
+// архив аккаунта отдается потоком сырых байт, формат описан в internal/account/archive.go
+type ExportAccountRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	IncludeCarData bool                   `protobuf:"varint,1,opt,name=include_car_data,json=includeCarData,proto3" json:"include_car_data,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ExportAccountRequest) Reset() {
+	*x = ExportAccountRequest{}
+	mi := &file_shared_proto_auth_v1_auth_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportAccountRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportAccountRequest) ProtoMessage() {}
+
+func (x *ExportAccountRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_auth_v1_auth_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportAccountRequest.ProtoReflect.Descriptor instead.
+func (*ExportAccountRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_auth_v1_auth_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ExportAccountRequest) GetIncludeCarData() bool {
+	if x != nil {
+		return x.IncludeCarData
+	}
+	return false
+}
+
+type ExportAccountChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	LastChunk     bool                   `protobuf:"varint,2,opt,name=last_chunk,json=lastChunk,proto3" json:"last_chunk,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportAccountChunk) Reset() {
+	*x = ExportAccountChunk{}
+	mi := &file_shared_proto_auth_v1_auth_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportAccountChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportAccountChunk) ProtoMessage() {}
+
+func (x *ExportAccountChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_auth_v1_auth_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportAccountChunk.ProtoReflect.Descriptor instead.
+func (*ExportAccountChunk) Descriptor() ([]byte, []int) {
+	return file_shared_proto_auth_v1_auth_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ExportAccountChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *ExportAccountChunk) GetLastChunk() bool {
+	if x != nil {
+		return x.LastChunk
+	}
+	return false
+}
+
+type ImportAccountRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Data      []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	LastChunk bool                   `protobuf:"varint,2,opt,name=last_chunk,json=lastChunk,proto3" json:"last_chunk,omitempty"`
+	// только в первом сообщении: новые учетные данные в формате RegisterRequest.password_proof,
+	// архив их не переносит
+	PasswordProof []byte `protobuf:"bytes,3,opt,name=password_proof,json=passwordProof,proto3" json:"password_proof,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportAccountRequest) Reset() {
+	*x = ImportAccountRequest{}
+	mi := &file_shared_proto_auth_v1_auth_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportAccountRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportAccountRequest) ProtoMessage() {}
+
+func (x *ImportAccountRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_auth_v1_auth_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportAccountRequest.ProtoReflect.Descriptor instead.
+func (*ImportAccountRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_auth_v1_auth_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ImportAccountRequest) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *ImportAccountRequest) GetLastChunk() bool {
+	if x != nil {
+		return x.LastChunk
+	}
+	return false
+}
+
+func (x *ImportAccountRequest) GetPasswordProof() []byte {
+	if x != nil {
+		return x.PasswordProof
+	}
+	return nil
+}
+
+type ImportAccountResponse struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	UserId   string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Username string                 `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty"`
+	Devices  int32                  `protobuf:"varint,3,opt,name=devices,proto3" json:"devices,omitempty"`
+	Groups   int32                  `protobuf:"varint,4,opt,name=groups,proto3" json:"groups,omitempty"`
+	Messages int32                  `protobuf:"varint,5,opt,name=messages,proto3" json:"messages,omitempty"`
+	Files    int32                  `protobuf:"varint,6,opt,name=files,proto3" json:"files,omitempty"`
+	Blobs    int32                  `protobuf:"varint,7,opt,name=blobs,proto3" json:"blobs,omitempty"`
+	// группы, которые импорт не восстановил: созданные другими или уже существующие
+	// на этом сервере. в них вступают заново по приглашению админа
+	SkippedGroupIds []string `protobuf:"bytes,8,rep,name=skipped_group_ids,json=skippedGroupIds,proto3" json:"skipped_group_ids,omitempty"`
+	Versions        int32    `protobuf:"varint,9,opt,name=versions,proto3" json:"versions,omitempty"`
+	Previews        int32    `protobuf:"varint,10,opt,name=previews,proto3" json:"previews,omitempty"`
+	VfsNodes        int32    `protobuf:"varint,11,opt,name=vfs_nodes,json=vfsNodes,proto3" json:"vfs_nodes,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ImportAccountResponse) Reset() {
+	*x = ImportAccountResponse{}
+	mi := &file_shared_proto_auth_v1_auth_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportAccountResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportAccountResponse) ProtoMessage() {}
+
+func (x *ImportAccountResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_auth_v1_auth_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportAccountResponse.ProtoReflect.Descriptor instead.
+func (*ImportAccountResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_auth_v1_auth_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ImportAccountResponse) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *ImportAccountResponse) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *ImportAccountResponse) GetDevices() int32 {
+	if x != nil {
+		return x.Devices
+	}
+	return 0
+}
+
+func (x *ImportAccountResponse) GetGroups() int32 {
+	if x != nil {
+		return x.Groups
+	}
+	return 0
+}
+
+func (x *ImportAccountResponse) GetMessages() int32 {
+	if x != nil {
+		return x.Messages
+	}
+	return 0
+}
+
+func (x *ImportAccountResponse) GetFiles() int32 {
+	if x != nil {
+		return x.Files
+	}
+	return 0
+}
+
+func (x *ImportAccountResponse) GetBlobs() int32 {
+	if x != nil {
+		return x.Blobs
+	}
+	return 0
+}
+
+func (x *ImportAccountResponse) GetSkippedGroupIds() []string {
+	if x != nil {
+		return x.SkippedGroupIds
+	}
+	return nil
+}
+
+func (x *ImportAccountResponse) GetVersions() int32 {
+	if x != nil {
+		return x.Versions
+	}
+	return 0
+}
+
+func (x *ImportAccountResponse) GetPreviews() int32 {
+	if x != nil {
+		return x.Previews
+	}
+	return 0
+}
+
+func (x *ImportAccountResponse) GetVfsNodes() int32 {
+	if x != nil {
+		return x.VfsNodes
+	}
+	return 0
+}
+
+// удаление аккаунта требует повторного входа и выполняется фоновым заданием
+type DeleteAccountRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Username      string                 `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
+	PasswordProof []byte                 `protobuf:"bytes,2,opt,name=password_proof,json=passwordProof,proto3" json:"password_proof,omitempty"`
+	SecondaryCode string                 `protobuf:"bytes,3,opt,name=secondary_code,json=secondaryCode,proto3" json:"secondary_code,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteAccountRequest) Reset() {
+	*x = DeleteAccountRequest{}
+	mi := &file_shared_proto_auth_v1_auth_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteAccountRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteAccountRequest) ProtoMessage() {}
+
+func (x *DeleteAccountRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_auth_v1_auth_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteAccountRequest.ProtoReflect.Descriptor instead.
+func (*DeleteAccountRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_auth_v1_auth_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *DeleteAccountRequest) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *DeleteAccountRequest) GetPasswordProof() []byte {
+	if x != nil {
+		return x.PasswordProof
+	}
+	return nil
+}
+
+func (x *DeleteAccountRequest) GetSecondaryCode() string {
+	if x != nil {
+		return x.SecondaryCode
+	}
+	return ""
+}
+
+type DeleteAccountResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	State         DeletionState          `protobuf:"varint,2,opt,name=state,proto3,enum=auth.v1.DeletionState" json:"state,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteAccountResponse) Reset() {
+	*x = DeleteAccountResponse{}
+	mi := &file_shared_proto_auth_v1_auth_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteAccountResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteAccountResponse) ProtoMessage() {}
+
+func (x *DeleteAccountResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_auth_v1_auth_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteAccountResponse.ProtoReflect.Descriptor instead.
+func (*DeleteAccountResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_auth_v1_auth_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *DeleteAccountResponse) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *DeleteAccountResponse) GetState() DeletionState {
+	if x != nil {
+		return x.State
+	}
+	return DeletionState_DELETION_STATE_UNSPECIFIED
+}
+
+type GetDeletionStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDeletionStatusRequest) Reset() {
+	*x = GetDeletionStatusRequest{}
+	mi := &file_shared_proto_auth_v1_auth_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDeletionStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDeletionStatusRequest) ProtoMessage() {}
+
+func (x *GetDeletionStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_auth_v1_auth_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDeletionStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetDeletionStatusRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_auth_v1_auth_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetDeletionStatusRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+type GetDeletionStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	State         DeletionState          `protobuf:"varint,2,opt,name=state,proto3,enum=auth.v1.DeletionState" json:"state,omitempty"`
+	Step          string                 `protobuf:"bytes,3,opt,name=step,proto3" json:"step,omitempty"`
+	Attempts      int32                  `protobuf:"varint,4,opt,name=attempts,proto3" json:"attempts,omitempty"`
+	LastError     string                 `protobuf:"bytes,5,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	CreatedAtUnix int64                  `protobuf:"varint,6,opt,name=created_at_unix,json=createdAtUnix,proto3" json:"created_at_unix,omitempty"`
+	UpdatedAtUnix int64                  `protobuf:"varint,7,opt,name=updated_at_unix,json=updatedAtUnix,proto3" json:"updated_at_unix,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDeletionStatusResponse) Reset() {
+	*x = GetDeletionStatusResponse{}
+	mi := &file_shared_proto_auth_v1_auth_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDeletionStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDeletionStatusResponse) ProtoMessage() {}
+
+func (x *GetDeletionStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_auth_v1_auth_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDeletionStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetDeletionStatusResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_auth_v1_auth_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *GetDeletionStatusResponse) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *GetDeletionStatusResponse) GetState() DeletionState {
+	if x != nil {
+		return x.State
+	}
+	return DeletionState_DELETION_STATE_UNSPECIFIED
+}
+
+func (x *GetDeletionStatusResponse) GetStep() string {
+	if x != nil {
+		return x.Step
+	}
+	return ""
+}
+
+func (x *GetDeletionStatusResponse) GetAttempts() int32 {
+	if x != nil {
+		return x.Attempts
+	}
+	return 0
+}
+
+func (x *GetDeletionStatusResponse) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+func (x *GetDeletionStatusResponse) GetCreatedAtUnix() int64 {
+	if x != nil {
+		return x.CreatedAtUnix
+	}
+	return 0
+}
+
+func (x *GetDeletionStatusResponse) GetUpdatedAtUnix() int64 {
+	if x != nil {
+		return x.UpdatedAtUnix
+	}
+	return 0
+}
+
 var File_shared_proto_auth_v1_auth_proto protoreflect.FileDescriptor
 
 const file_shared_proto_auth_v1_auth_proto_rawDesc = "" +
@@ -475,12 +1064,64 @@ const file_shared_proto_auth_v1_auth_proto_rawDesc = "" +
 	"\auser_id\x18\x01 \x01(\tR\x06userId\"5\n" +
 	"\x14GetPublicKeyResponse\x12\x1d\n" +
 	"\n" +
-	"public_key\x18\x01 \x01(\fR\tpublicKey2\x91\x02\n" +
+	"public_key\x18\x01 \x01(\fR\tpublicKey\"@\n" +
+	"\x14ExportAccountRequest\x12(\n" +
+	"\x10include_car_data\x18\x01 \x01(\bR\x0eincludeCarData\"G\n" +
+	"\x12ExportAccountChunk\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\x12\x1d\n" +
+	"\n" +
+	"last_chunk\x18\x02 \x01(\bR\tlastChunk\"p\n" +
+	"\x14ImportAccountRequest\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\x12\x1d\n" +
+	"\n" +
+	"last_chunk\x18\x02 \x01(\bR\tlastChunk\x12%\n" +
+	"\x0epassword_proof\x18\x03 \x01(\fR\rpasswordProof\"\xc7\x02\n" +
+	"\x15ImportAccountResponse\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1a\n" +
+	"\busername\x18\x02 \x01(\tR\busername\x12\x18\n" +
+	"\adevices\x18\x03 \x01(\x05R\adevices\x12\x16\n" +
+	"\x06groups\x18\x04 \x01(\x05R\x06groups\x12\x1a\n" +
+	"\bmessages\x18\x05 \x01(\x05R\bmessages\x12\x14\n" +
+	"\x05files\x18\x06 \x01(\x05R\x05files\x12\x14\n" +
+	"\x05blobs\x18\a \x01(\x05R\x05blobs\x12*\n" +
+	"\x11skipped_group_ids\x18\b \x03(\tR\x0fskippedGroupIds\x12\x1a\n" +
+	"\bversions\x18\t \x01(\x05R\bversions\x12\x1a\n" +
+	"\bpreviews\x18\n" +
+	" \x01(\x05R\bpreviews\x12\x1b\n" +
+	"\tvfs_nodes\x18\v \x01(\x05R\bvfsNodes\"\x80\x01\n" +
+	"\x14DeleteAccountRequest\x12\x1a\n" +
+	"\busername\x18\x01 \x01(\tR\busername\x12%\n" +
+	"\x0epassword_proof\x18\x02 \x01(\fR\rpasswordProof\x12%\n" +
+	"\x0esecondary_code\x18\x03 \x01(\tR\rsecondaryCode\"\\\n" +
+	"\x15DeleteAccountResponse\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12,\n" +
+	"\x05state\x18\x02 \x01(\x0e2\x16.auth.v1.DeletionStateR\x05state\"1\n" +
+	"\x18GetDeletionStatusRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\"\xff\x01\n" +
+	"\x19GetDeletionStatusResponse\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12,\n" +
+	"\x05state\x18\x02 \x01(\x0e2\x16.auth.v1.DeletionStateR\x05state\x12\x12\n" +
+	"\x04step\x18\x03 \x01(\tR\x04step\x12\x1a\n" +
+	"\battempts\x18\x04 \x01(\x05R\battempts\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\x05 \x01(\tR\tlastError\x12&\n" +
+	"\x0fcreated_at_unix\x18\x06 \x01(\x03R\rcreatedAtUnix\x12&\n" +
+	"\x0fupdated_at_unix\x18\a \x01(\x03R\rupdatedAtUnix*\x9b\x01\n" +
+	"\rDeletionState\x12\x1e\n" +
+	"\x1aDELETION_STATE_UNSPECIFIED\x10\x00\x12\x1a\n" +
+	"\x16DELETION_STATE_PENDING\x10\x01\x12\x1a\n" +
+	"\x16DELETION_STATE_RUNNING\x10\x02\x12\x17\n" +
+	"\x13DELETION_STATE_DONE\x10\x03\x12\x19\n" +
+	"\x15DELETION_STATE_FAILED\x10\x042\xde\x04\n" +
 	"\vAuthService\x12?\n" +
 	"\bRegister\x12\x18.auth.v1.RegisterRequest\x1a\x19.auth.v1.RegisterResponse\x126\n" +
 	"\x05Login\x12\x15.auth.v1.LoginRequest\x1a\x16.auth.v1.LoginResponse\x12<\n" +
 	"\aRefresh\x12\x17.auth.v1.RefreshRequest\x1a\x18.auth.v1.RefreshResponse\x12K\n" +
-	"\fGetPublicKey\x12\x1c.auth.v1.GetPublicKeyRequest\x1a\x1d.auth.v1.GetPublicKeyResponseB0Z.dev.c0rex64.heroin/shared/proto/auth/v1;authv1b\x06proto3"
+	"\fGetPublicKey\x12\x1c.auth.v1.GetPublicKeyRequest\x1a\x1d.auth.v1.GetPublicKeyResponse\x12M\n" +
+	"\rExportAccount\x12\x1d.auth.v1.ExportAccountRequest\x1a\x1b.auth.v1.ExportAccountChunk0\x01\x12P\n" +
+	"\rImportAccount\x12\x1d.auth.v1.ImportAccountRequest\x1a\x1e.auth.v1.ImportAccountResponse(\x01\x12N\n" +
+	"\rDeleteAccount\x12\x1d.auth.v1.DeleteAccountRequest\x1a\x1e.auth.v1.DeleteAccountResponse\x12Z\n" +
+	"\x11GetDeletionStatus\x12!.auth.v1.GetDeletionStatusRequest\x1a\".auth.v1.GetDeletionStatusResponseB0Z.dev.c0rex64.heroin/shared/proto/auth/v1;authv1b\x06proto3"
 
 var (
 	file_shared_proto_auth_v1_auth_proto_rawDescOnce sync.Once
@@ -494,31 +1135,51 @@ func file_shared_proto_auth_v1_auth_proto_rawDescGZIP() []byte {
 	return file_shared_proto_auth_v1_auth_proto_rawDescData
 }
 
-var file_shared_proto_auth_v1_auth_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_shared_proto_auth_v1_auth_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_shared_proto_auth_v1_auth_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
 var file_shared_proto_auth_v1_auth_proto_goTypes = []any{
-	(*RegisterRequest)(nil),      // 0: auth.v1.RegisterRequest
-	(*RegisterResponse)(nil),     // 1: auth.v1.RegisterResponse
-	(*LoginRequest)(nil),         // 2: auth.v1.LoginRequest
-	(*LoginResponse)(nil),        // 3: auth.v1.LoginResponse
-	(*RefreshRequest)(nil),       // 4: auth.v1.RefreshRequest
-	(*RefreshResponse)(nil),      // 5: auth.v1.RefreshResponse
-	(*GetPublicKeyRequest)(nil),  // 6: auth.v1.GetPublicKeyRequest
-	(*GetPublicKeyResponse)(nil), // 7: auth.v1.GetPublicKeyResponse
+	(DeletionState)(0),                // 0: auth.v1.DeletionState
+	(*RegisterRequest)(nil),           // 1: auth.v1.RegisterRequest
+	(*RegisterResponse)(nil),          // 2: auth.v1.RegisterResponse
+	(*LoginRequest)(nil),              // 3: auth.v1.LoginRequest
+	(*LoginResponse)(nil),             // 4: auth.v1.LoginResponse
+	(*RefreshRequest)(nil),            // 5: auth.v1.RefreshRequest
+	(*RefreshResponse)(nil),           // 6: auth.v1.RefreshResponse
+	(*GetPublicKeyRequest)(nil),       // 7: auth.v1.GetPublicKeyRequest
+	(*GetPublicKeyResponse)(nil),      // 8: auth.v1.GetPublicKeyResponse
+	(*ExportAccountRequest)(nil),      // 9: auth.v1.ExportAccountRequest
+	(*ExportAccountChunk)(nil),        // 10: auth.v1.ExportAccountChunk
+	(*ImportAccountRequest)(nil),      // 11: auth.v1.ImportAccountRequest
+	(*ImportAccountResponse)(nil),     // 12: auth.v1.ImportAccountResponse
+	(*DeleteAccountRequest)(nil),      // 13: auth.v1.DeleteAccountRequest
+	(*DeleteAccountResponse)(nil),     // 14: auth.v1.DeleteAccountResponse
+	(*GetDeletionStatusRequest)(nil),  // 15: auth.v1.GetDeletionStatusRequest
+	(*GetDeletionStatusResponse)(nil), // 16: auth.v1.GetDeletionStatusResponse
 }
 var file_shared_proto_auth_v1_auth_proto_depIdxs = []int32{
-	0, // 0: auth.v1.AuthService.Register:input_type -> auth.v1.RegisterRequest
-	2, // 1: auth.v1.AuthService.Login:input_type -> auth.v1.LoginRequest
-	4, // 2: auth.v1.AuthService.Refresh:input_type -> auth.v1.RefreshRequest
-	6, // 3: auth.v1.AuthService.GetPublicKey:input_type -> auth.v1.GetPublicKeyRequest
-	1, // 4: auth.v1.AuthService.Register:output_type -> auth.v1.RegisterResponse
-	3, // 5: auth.v1.AuthService.Login:output_type -> auth.v1.LoginResponse
-	5, // 6: auth.v1.AuthService.Refresh:output_type -> auth.v1.RefreshResponse
-	7, // 7: auth.v1.AuthService.GetPublicKey:output_type -> auth.v1.GetPublicKeyResponse
-	4, // [4:8] is the sub-list for method output_type
-	0, // [0:4] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0,  // 0: auth.v1.DeleteAccountResponse.state:type_name -> auth.v1.DeletionState
+	0,  // 1: auth.v1.GetDeletionStatusResponse.state:type_name -> auth.v1.DeletionState
+	1,  // 2: auth.v1.AuthService.Register:input_type -> auth.v1.RegisterRequest
+	3,  // 3: auth.v1.AuthService.Login:input_type -> auth.v1.LoginRequest
+	5,  // 4: auth.v1.AuthService.Refresh:input_type -> auth.v1.RefreshRequest
+	7,  // 5: auth.v1.AuthService.GetPublicKey:input_type -> auth.v1.GetPublicKeyRequest
+	9,  // 6: auth.v1.AuthService.ExportAccount:input_type -> auth.v1.ExportAccountRequest
+	11, // 7: auth.v1.AuthService.ImportAccount:input_type -> auth.v1.ImportAccountRequest
+	13, // 8: auth.v1.AuthService.DeleteAccount:input_type -> auth.v1.DeleteAccountRequest
+	15, // 9: auth.v1.AuthService.GetDeletionStatus:input_type -> auth.v1.GetDeletionStatusRequest
+	2,  // 10: auth.v1.AuthService.Register:output_type -> auth.v1.RegisterResponse
+	4,  // 11: auth.v1.AuthService.Login:output_type -> auth.v1.LoginResponse
+	6,  // 12: auth.v1.AuthService.Refresh:output_type -> auth.v1.RefreshResponse
+	8,  // 13: auth.v1.AuthService.GetPublicKey:output_type -> auth.v1.GetPublicKeyResponse
+	10, // 14: auth.v1.AuthService.ExportAccount:output_type -> auth.v1.ExportAccountChunk
+	12, // 15: auth.v1.AuthService.ImportAccount:output_type -> auth.v1.ImportAccountResponse
+	14, // 16: auth.v1.AuthService.DeleteAccount:output_type -> auth.v1.DeleteAccountResponse
+	16, // 17: auth.v1.AuthService.GetDeletionStatus:output_type -> auth.v1.GetDeletionStatusResponse
+	10, // [10:18] is the sub-list for method output_type
+	2,  // [2:10] is the sub-list for method input_type
+	2,  // [2:2] is the sub-list for extension type_name
+	2,  // [2:2] is the sub-list for extension extendee
+	0,  // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_shared_proto_auth_v1_auth_proto_init() }
@@ -531,13 +1192,14 @@ func file_shared_proto_auth_v1_auth_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_shared_proto_auth_v1_auth_proto_rawDesc), len(file_shared_proto_auth_v1_auth_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   8,
+			NumEnums:      1,
+			NumMessages:   16,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_shared_proto_auth_v1_auth_proto_goTypes,
 		DependencyIndexes: file_shared_proto_auth_v1_auth_proto_depIdxs,
+		EnumInfos:         file_shared_proto_auth_v1_auth_proto_enumTypes,
 		MessageInfos:      file_shared_proto_auth_v1_auth_proto_msgTypes,
 	}.Build()
 	File_shared_proto_auth_v1_auth_proto = out.File
@@ -21,17 +21,186 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// тип конверта: обычное сообщение или управляющее действие над другим сообщением
+type EnvelopeKind int32
+
+const (
+	EnvelopeKind_ENVELOPE_KIND_MESSAGE EnvelopeKind = 0
+	EnvelopeKind_ENVELOPE_KIND_EDIT    EnvelopeKind = 1 // новая версия сообщения, ciphertext содержит новый текст
+	EnvelopeKind_ENVELOPE_KIND_DELETE  EnvelopeKind = 2 // удаление у всех, ciphertext может быть пустым
+	EnvelopeKind_ENVELOPE_KIND_REACT   EnvelopeKind = 3 // реакция, сама реакция внутри ciphertext
+	EnvelopeKind_ENVELOPE_KIND_UNREACT EnvelopeKind = 4 // снятие реакции
+)
+
+// Enum value maps for EnvelopeKind.
+var (
+	EnvelopeKind_name = map[int32]string{
+		0: "ENVELOPE_KIND_MESSAGE",
+		1: "ENVELOPE_KIND_EDIT",
+		2: "ENVELOPE_KIND_DELETE",
+		3: "ENVELOPE_KIND_REACT",
+		4: "ENVELOPE_KIND_UNREACT",
+	}
+	EnvelopeKind_value = map[string]int32{
+		"ENVELOPE_KIND_MESSAGE": 0,
+		"ENVELOPE_KIND_EDIT":    1,
+		"ENVELOPE_KIND_DELETE":  2,
+		"ENVELOPE_KIND_REACT":   3,
+		"ENVELOPE_KIND_UNREACT": 4,
+	}
+)
+
+func (x EnvelopeKind) Enum() *EnvelopeKind {
+	p := new(EnvelopeKind)
+	*p = x
+	return p
+}
+
+func (x EnvelopeKind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EnvelopeKind) Descriptor() protoreflect.EnumDescriptor {
+	return file_shared_proto_messaging_v1_messaging_proto_enumTypes[0].Descriptor()
+}
+
+func (EnvelopeKind) Type() protoreflect.EnumType {
+	return &file_shared_proto_messaging_v1_messaging_proto_enumTypes[0]
+}
+
+func (x EnvelopeKind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EnvelopeKind.Descriptor instead.
+func (EnvelopeKind) EnumDescriptor() ([]byte, []int) {
+	return file_shared_proto_messaging_v1_messaging_proto_rawDescGZIP(), []int{0}
+}
+
+// присутствие
+type PresenceKind int32
+
+const (
+	PresenceKind_PRESENCE_KIND_UNSPECIFIED    PresenceKind = 0
+	PresenceKind_PRESENCE_KIND_SUBSCRIBE      PresenceKind = 1 // клиент подписывается на события беседы conversation_id
+	PresenceKind_PRESENCE_KIND_TYPING         PresenceKind = 2
+	PresenceKind_PRESENCE_KIND_STOPPED_TYPING PresenceKind = 3
+	PresenceKind_PRESENCE_KIND_ONLINE         PresenceKind = 4
+	PresenceKind_PRESENCE_KIND_AWAY           PresenceKind = 5
+	PresenceKind_PRESENCE_KIND_OFFLINE        PresenceKind = 6
+)
+
+// Enum value maps for PresenceKind.
+var (
+	PresenceKind_name = map[int32]string{
+		0: "PRESENCE_KIND_UNSPECIFIED",
+		1: "PRESENCE_KIND_SUBSCRIBE",
+		2: "PRESENCE_KIND_TYPING",
+		3: "PRESENCE_KIND_STOPPED_TYPING",
+		4: "PRESENCE_KIND_ONLINE",
+		5: "PRESENCE_KIND_AWAY",
+		6: "PRESENCE_KIND_OFFLINE",
+	}
+	PresenceKind_value = map[string]int32{
+		"PRESENCE_KIND_UNSPECIFIED":    0,
+		"PRESENCE_KIND_SUBSCRIBE":      1,
+		"PRESENCE_KIND_TYPING":         2,
+		"PRESENCE_KIND_STOPPED_TYPING": 3,
+		"PRESENCE_KIND_ONLINE":         4,
+		"PRESENCE_KIND_AWAY":           5,
+		"PRESENCE_KIND_OFFLINE":        6,
+	}
+)
+
+func (x PresenceKind) Enum() *PresenceKind {
+	p := new(PresenceKind)
+	*p = x
+	return p
+}
+
+func (x PresenceKind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PresenceKind) Descriptor() protoreflect.EnumDescriptor {
+	return file_shared_proto_messaging_v1_messaging_proto_enumTypes[1].Descriptor()
+}
+
+func (PresenceKind) Type() protoreflect.EnumType {
+	return &file_shared_proto_messaging_v1_messaging_proto_enumTypes[1]
+}
+
+func (x PresenceKind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PresenceKind.Descriptor instead.
+func (PresenceKind) EnumDescriptor() ([]byte, []int) {
+	return file_shared_proto_messaging_v1_messaging_proto_rawDescGZIP(), []int{1}
+}
+
+type LastSeenVisibility int32
+
+const (
+	LastSeenVisibility_LAST_SEEN_VISIBILITY_EVERYONE LastSeenVisibility = 0
+	LastSeenVisibility_LAST_SEEN_VISIBILITY_CONTACTS LastSeenVisibility = 1 // только те, с кем есть общая группа
+	LastSeenVisibility_LAST_SEEN_VISIBILITY_NOBODY   LastSeenVisibility = 2
+)
+
+// Enum value maps for LastSeenVisibility.
+var (
+	LastSeenVisibility_name = map[int32]string{
+		0: "LAST_SEEN_VISIBILITY_EVERYONE",
+		1: "LAST_SEEN_VISIBILITY_CONTACTS",
+		2: "LAST_SEEN_VISIBILITY_NOBODY",
+	}
+	LastSeenVisibility_value = map[string]int32{
+		"LAST_SEEN_VISIBILITY_EVERYONE": 0,
+		"LAST_SEEN_VISIBILITY_CONTACTS": 1,
+		"LAST_SEEN_VISIBILITY_NOBODY":   2,
+	}
+)
+
+func (x LastSeenVisibility) Enum() *LastSeenVisibility {
+	p := new(LastSeenVisibility)
+	*p = x
+	return p
+}
+
+func (x LastSeenVisibility) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LastSeenVisibility) Descriptor() protoreflect.EnumDescriptor {
+	return file_shared_proto_messaging_v1_messaging_proto_enumTypes[2].Descriptor()
+}
+
+func (LastSeenVisibility) Type() protoreflect.EnumType {
+	return &file_shared_proto_messaging_v1_messaging_proto_enumTypes[2]
+}
+
+func (x LastSeenVisibility) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LastSeenVisibility.Descriptor instead.
+func (LastSeenVisibility) EnumDescriptor() ([]byte, []int) {
+	return file_shared_proto_messaging_v1_messaging_proto_rawDescGZIP(), []int{2}
+}
+
 type Envelope struct {
-	state          protoimpl.MessageState `protogen:"open.v1"`
-	ConversationId string                 `protobuf:"bytes,1,opt,name=conversation_id,json=conversationId,proto3" json:"conversation_id,omitempty"`
-	MessageId      string                 `protobuf:"bytes,2,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
-	Ciphertext     []byte                 `protobuf:"bytes,3,opt,name=ciphertext,proto3" json:"ciphertext,omitempty"`
-	Signature      []byte                 `protobuf:"bytes,4,opt,name=signature,proto3" json:"signature,omitempty"`
-	SentAtUnix     int64                  `protobuf:"varint,5,opt,name=sent_at_unix,json=sentAtUnix,proto3" json:"sent_at_unix,omitempty"`
-	SenderId       string                 `protobuf:"bytes,6,opt,name=sender_id,json=senderId,proto3" json:"sender_id,omitempty"`
-	IsGroup        bool                   `protobuf:"varint,7,opt,name=is_group,json=isGroup,proto3" json:"is_group,omitempty"` // флаг группового сообщения
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	ConversationId  string                 `protobuf:"bytes,1,opt,name=conversation_id,json=conversationId,proto3" json:"conversation_id,omitempty"` // id группы или личной беседы "dm:<user_a>:<user_b>", id участников по возрастанию
+	MessageId       string                 `protobuf:"bytes,2,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	Ciphertext      []byte                 `protobuf:"bytes,3,opt,name=ciphertext,proto3" json:"ciphertext,omitempty"`
+	Signature       []byte                 `protobuf:"bytes,4,opt,name=signature,proto3" json:"signature,omitempty"`
+	SentAtUnix      int64                  `protobuf:"varint,5,opt,name=sent_at_unix,json=sentAtUnix,proto3" json:"sent_at_unix,omitempty"`
+	SenderId        string                 `protobuf:"bytes,6,opt,name=sender_id,json=senderId,proto3" json:"sender_id,omitempty"`
+	IsGroup         bool                   `protobuf:"varint,7,opt,name=is_group,json=isGroup,proto3" json:"is_group,omitempty"` // флаг группового сообщения
+	Kind            EnvelopeKind           `protobuf:"varint,8,opt,name=kind,proto3,enum=heroin.messaging.v1.EnvelopeKind" json:"kind,omitempty"`
+	TargetMessageId string                 `protobuf:"bytes,9,opt,name=target_message_id,json=targetMessageId,proto3" json:"target_message_id,omitempty"` // для управляющих конвертов: id исходного сообщения
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *Envelope) Reset() {
@@ -113,6 +282,20 @@ func (x *Envelope) GetIsGroup() bool {
 	return false
 }
 
+func (x *Envelope) GetKind() EnvelopeKind {
+	if x != nil {
+		return x.Kind
+	}
+	return EnvelopeKind_ENVELOPE_KIND_MESSAGE
+}
+
+func (x *Envelope) GetTargetMessageId() string {
+	if x != nil {
+		return x.TargetMessageId
+	}
+	return ""
+}
+
 type SendRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Envelope      []byte                 `protobuf:"bytes,1,opt,name=envelope,proto3" json:"envelope,omitempty"`
@@ -1315,11 +1498,386 @@ func (x *GetRoutingMetricsResponse) GetTransports() []*TransportMetrics {
 	return nil
 }
 
+type PresenceEvent struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Kind           PresenceKind           `protobuf:"varint,1,opt,name=kind,proto3,enum=heroin.messaging.v1.PresenceKind" json:"kind,omitempty"`
+	ConversationId string                 `protobuf:"bytes,2,opt,name=conversation_id,json=conversationId,proto3" json:"conversation_id,omitempty"` // для typing и subscribe
+	UserId         string                 `protobuf:"bytes,3,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`                         // проставляется сервером
+	LastSeenUnix   int64                  `protobuf:"varint,4,opt,name=last_seen_unix,json=lastSeenUnix,proto3" json:"last_seen_unix,omitempty"`    // 0 если скрыто настройками приватности
+	PeerId         string                 `protobuf:"bytes,5,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`                         // libp2p peer id клиента, передается вместе с online
+	AtUnix         int64                  `protobuf:"varint,6,opt,name=at_unix,json=atUnix,proto3" json:"at_unix,omitempty"`
+	// подпись ключом peer_id над "heroin presence peer v1", user_id и at_unix (uint64 big endian),
+	// без нее peer_id не привязывается. at_unix не дальше 5 минут от времени сервера
+	PeerSignature []byte `protobuf:"bytes,7,opt,name=peer_signature,json=peerSignature,proto3" json:"peer_signature,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PresenceEvent) Reset() {
+	*x = PresenceEvent{}
+	mi := &file_shared_proto_messaging_v1_messaging_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PresenceEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PresenceEvent) ProtoMessage() {}
+
+func (x *PresenceEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_messaging_v1_messaging_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PresenceEvent.ProtoReflect.Descriptor instead.
+func (*PresenceEvent) Descriptor() ([]byte, []int) {
+	return file_shared_proto_messaging_v1_messaging_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *PresenceEvent) GetKind() PresenceKind {
+	if x != nil {
+		return x.Kind
+	}
+	return PresenceKind_PRESENCE_KIND_UNSPECIFIED
+}
+
+func (x *PresenceEvent) GetConversationId() string {
+	if x != nil {
+		return x.ConversationId
+	}
+	return ""
+}
+
+func (x *PresenceEvent) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *PresenceEvent) GetLastSeenUnix() int64 {
+	if x != nil {
+		return x.LastSeenUnix
+	}
+	return 0
+}
+
+func (x *PresenceEvent) GetPeerId() string {
+	if x != nil {
+		return x.PeerId
+	}
+	return ""
+}
+
+func (x *PresenceEvent) GetAtUnix() int64 {
+	if x != nil {
+		return x.AtUnix
+	}
+	return 0
+}
+
+func (x *PresenceEvent) GetPeerSignature() []byte {
+	if x != nil {
+		return x.PeerSignature
+	}
+	return nil
+}
+
+type SetPresencePrivacyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	LastSeen      LastSeenVisibility     `protobuf:"varint,1,opt,name=last_seen,json=lastSeen,proto3,enum=heroin.messaging.v1.LastSeenVisibility" json:"last_seen,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetPresencePrivacyRequest) Reset() {
+	*x = SetPresencePrivacyRequest{}
+	mi := &file_shared_proto_messaging_v1_messaging_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetPresencePrivacyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetPresencePrivacyRequest) ProtoMessage() {}
+
+func (x *SetPresencePrivacyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_messaging_v1_messaging_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetPresencePrivacyRequest.ProtoReflect.Descriptor instead.
+func (*SetPresencePrivacyRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_messaging_v1_messaging_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *SetPresencePrivacyRequest) GetLastSeen() LastSeenVisibility {
+	if x != nil {
+		return x.LastSeen
+	}
+	return LastSeenVisibility_LAST_SEEN_VISIBILITY_EVERYONE
+}
+
+type SetPresencePrivacyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetPresencePrivacyResponse) Reset() {
+	*x = SetPresencePrivacyResponse{}
+	mi := &file_shared_proto_messaging_v1_messaging_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetPresencePrivacyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetPresencePrivacyResponse) ProtoMessage() {}
+
+func (x *SetPresencePrivacyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_messaging_v1_messaging_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetPresencePrivacyResponse.ProtoReflect.Descriptor instead.
+func (*SetPresencePrivacyResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_messaging_v1_messaging_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *SetPresencePrivacyResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+// push уведомления
+type RegisterPushTokenRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DeviceId      string                 `protobuf:"bytes,1,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	Platform      string                 `protobuf:"bytes,2,opt,name=platform,proto3" json:"platform,omitempty"` // fcm, apns, unifiedpush или свой relay
+	Token         string                 `protobuf:"bytes,3,opt,name=token,proto3" json:"token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterPushTokenRequest) Reset() {
+	*x = RegisterPushTokenRequest{}
+	mi := &file_shared_proto_messaging_v1_messaging_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterPushTokenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterPushTokenRequest) ProtoMessage() {}
+
+func (x *RegisterPushTokenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_messaging_v1_messaging_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterPushTokenRequest.ProtoReflect.Descriptor instead.
+func (*RegisterPushTokenRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_messaging_v1_messaging_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *RegisterPushTokenRequest) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *RegisterPushTokenRequest) GetPlatform() string {
+	if x != nil {
+		return x.Platform
+	}
+	return ""
+}
+
+func (x *RegisterPushTokenRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+type RegisterPushTokenResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterPushTokenResponse) Reset() {
+	*x = RegisterPushTokenResponse{}
+	mi := &file_shared_proto_messaging_v1_messaging_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterPushTokenResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterPushTokenResponse) ProtoMessage() {}
+
+func (x *RegisterPushTokenResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_messaging_v1_messaging_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterPushTokenResponse.ProtoReflect.Descriptor instead.
+func (*RegisterPushTokenResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_messaging_v1_messaging_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *RegisterPushTokenResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+type UnregisterPushTokenRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DeviceId      string                 `protobuf:"bytes,1,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnregisterPushTokenRequest) Reset() {
+	*x = UnregisterPushTokenRequest{}
+	mi := &file_shared_proto_messaging_v1_messaging_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnregisterPushTokenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnregisterPushTokenRequest) ProtoMessage() {}
+
+func (x *UnregisterPushTokenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_messaging_v1_messaging_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnregisterPushTokenRequest.ProtoReflect.Descriptor instead.
+func (*UnregisterPushTokenRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_messaging_v1_messaging_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *UnregisterPushTokenRequest) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+type UnregisterPushTokenResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnregisterPushTokenResponse) Reset() {
+	*x = UnregisterPushTokenResponse{}
+	mi := &file_shared_proto_messaging_v1_messaging_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnregisterPushTokenResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnregisterPushTokenResponse) ProtoMessage() {}
+
+func (x *UnregisterPushTokenResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_messaging_v1_messaging_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnregisterPushTokenResponse.ProtoReflect.Descriptor instead.
+func (*UnregisterPushTokenResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_messaging_v1_messaging_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *UnregisterPushTokenResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
 var File_shared_proto_messaging_v1_messaging_proto protoreflect.FileDescriptor
 
 const file_shared_proto_messaging_v1_messaging_proto_rawDesc = "" +
 	"\n" +
-	")shared/proto/messaging/v1/messaging.proto\x12\x13heroin.messaging.v1\"\xea\x01\n" +
+	")shared/proto/messaging/v1/messaging.proto\x12\x13heroin.messaging.v1\"\xcd\x02\n" +
 	"\bEnvelope\x12'\n" +
 	"\x0fconversation_id\x18\x01 \x01(\tR\x0econversationId\x12\x1d\n" +
 	"\n" +
@@ -1331,7 +1889,9 @@ const file_shared_proto_messaging_v1_messaging_proto_rawDesc = "" +
 	"\fsent_at_unix\x18\x05 \x01(\x03R\n" +
 	"sentAtUnix\x12\x1b\n" +
 	"\tsender_id\x18\x06 \x01(\tR\bsenderId\x12\x19\n" +
-	"\bis_group\x18\a \x01(\bR\aisGroup\")\n" +
+	"\bis_group\x18\a \x01(\bR\aisGroup\x125\n" +
+	"\x04kind\x18\b \x01(\x0e2!.heroin.messaging.v1.EnvelopeKindR\x04kind\x12*\n" +
+	"\x11target_message_id\x18\t \x01(\tR\x0ftargetMessageId\")\n" +
 	"\vSendRequest\x12\x1a\n" +
 	"\benvelope\x18\x01 \x01(\fR\benvelope\"(\n" +
 	"\fSendResponse\x12\x18\n" +
@@ -1406,7 +1966,47 @@ const file_shared_proto_messaging_v1_messaging_proto_rawDesc = "" +
 	"\x19GetRoutingMetricsResponse\x12E\n" +
 	"\n" +
 	"transports\x18\x01 \x03(\v2%.heroin.messaging.v1.TransportMetricsR\n" +
-	"transports2\x81\b\n" +
+	"transports\"\x87\x02\n" +
+	"\rPresenceEvent\x125\n" +
+	"\x04kind\x18\x01 \x01(\x0e2!.heroin.messaging.v1.PresenceKindR\x04kind\x12'\n" +
+	"\x0fconversation_id\x18\x02 \x01(\tR\x0econversationId\x12\x17\n" +
+	"\auser_id\x18\x03 \x01(\tR\x06userId\x12$\n" +
+	"\x0elast_seen_unix\x18\x04 \x01(\x03R\flastSeenUnix\x12\x17\n" +
+	"\apeer_id\x18\x05 \x01(\tR\x06peerId\x12\x17\n" +
+	"\aat_unix\x18\x06 \x01(\x03R\x06atUnix\x12%\n" +
+	"\x0epeer_signature\x18\a \x01(\fR\rpeerSignature\"a\n" +
+	"\x19SetPresencePrivacyRequest\x12D\n" +
+	"\tlast_seen\x18\x01 \x01(\x0e2'.heroin.messaging.v1.LastSeenVisibilityR\blastSeen\"6\n" +
+	"\x1aSetPresencePrivacyResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"i\n" +
+	"\x18RegisterPushTokenRequest\x12\x1b\n" +
+	"\tdevice_id\x18\x01 \x01(\tR\bdeviceId\x12\x1a\n" +
+	"\bplatform\x18\x02 \x01(\tR\bplatform\x12\x14\n" +
+	"\x05token\x18\x03 \x01(\tR\x05token\"5\n" +
+	"\x19RegisterPushTokenResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"9\n" +
+	"\x1aUnregisterPushTokenRequest\x12\x1b\n" +
+	"\tdevice_id\x18\x01 \x01(\tR\bdeviceId\"7\n" +
+	"\x1bUnregisterPushTokenResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess*\x8f\x01\n" +
+	"\fEnvelopeKind\x12\x19\n" +
+	"\x15ENVELOPE_KIND_MESSAGE\x10\x00\x12\x16\n" +
+	"\x12ENVELOPE_KIND_EDIT\x10\x01\x12\x18\n" +
+	"\x14ENVELOPE_KIND_DELETE\x10\x02\x12\x17\n" +
+	"\x13ENVELOPE_KIND_REACT\x10\x03\x12\x19\n" +
+	"\x15ENVELOPE_KIND_UNREACT\x10\x04*\xd3\x01\n" +
+	"\fPresenceKind\x12\x1d\n" +
+	"\x19PRESENCE_KIND_UNSPECIFIED\x10\x00\x12\x1b\n" +
+	"\x17PRESENCE_KIND_SUBSCRIBE\x10\x01\x12\x18\n" +
+	"\x14PRESENCE_KIND_TYPING\x10\x02\x12 \n" +
+	"\x1cPRESENCE_KIND_STOPPED_TYPING\x10\x03\x12\x18\n" +
+	"\x14PRESENCE_KIND_ONLINE\x10\x04\x12\x16\n" +
+	"\x12PRESENCE_KIND_AWAY\x10\x05\x12\x19\n" +
+	"\x15PRESENCE_KIND_OFFLINE\x10\x06*{\n" +
+	"\x12LastSeenVisibility\x12!\n" +
+	"\x1dLAST_SEEN_VISIBILITY_EVERYONE\x10\x00\x12!\n" +
+	"\x1dLAST_SEEN_VISIBILITY_CONTACTS\x10\x01\x12\x1f\n" +
+	"\x1bLAST_SEEN_VISIBILITY_NOBODY\x10\x022\xbe\v\n" +
 	"\x10MessagingService\x12K\n" +
 	"\x04Send\x12 .heroin.messaging.v1.SendRequest\x1a!.heroin.messaging.v1.SendResponse\x12K\n" +
 	"\x04Pull\x12 .heroin.messaging.v1.PullRequest\x1a!.heroin.messaging.v1.PullResponse\x12`\n" +
@@ -1417,7 +2017,11 @@ const file_shared_proto_messaging_v1_messaging_proto_rawDesc = "" +
 	"\x0fGetGroupMembers\x12+.heroin.messaging.v1.GetGroupMembersRequest\x1a,.heroin.messaging.v1.GetGroupMembersResponse\x12i\n" +
 	"\x0eGetActivePeers\x12*.heroin.messaging.v1.GetActivePeersRequest\x1a+.heroin.messaging.v1.GetActivePeersResponse\x12i\n" +
 	"\x0eGetRelayChains\x12*.heroin.messaging.v1.GetRelayChainsRequest\x1a+.heroin.messaging.v1.GetRelayChainsResponse\x12r\n" +
-	"\x11GetRoutingMetrics\x12-.heroin.messaging.v1.GetRoutingMetricsRequest\x1a..heroin.messaging.v1.GetRoutingMetricsResponseB+Z)dev.c0rex64.heroin/api/messaging/v1;msgv1b\x06proto3"
+	"\x11GetRoutingMetrics\x12-.heroin.messaging.v1.GetRoutingMetricsRequest\x1a..heroin.messaging.v1.GetRoutingMetricsResponse\x12V\n" +
+	"\bPresence\x12\".heroin.messaging.v1.PresenceEvent\x1a\".heroin.messaging.v1.PresenceEvent(\x010\x01\x12u\n" +
+	"\x12SetPresencePrivacy\x12..heroin.messaging.v1.SetPresencePrivacyRequest\x1a/.heroin.messaging.v1.SetPresencePrivacyResponse\x12r\n" +
+	"\x11RegisterPushToken\x12-.heroin.messaging.v1.RegisterPushTokenRequest\x1a..heroin.messaging.v1.RegisterPushTokenResponse\x12x\n" +
+	"\x13UnregisterPushToken\x12/.heroin.messaging.v1.UnregisterPushTokenRequest\x1a0.heroin.messaging.v1.UnregisterPushTokenResponseB+Z)dev.c0rex64.heroin/api/messaging/v1;msgv1b\x06proto3"
 
 var (
 	file_shared_proto_messaging_v1_messaging_proto_rawDescOnce sync.Once
@@ -1431,65 +2035,87 @@ func file_shared_proto_messaging_v1_messaging_proto_rawDescGZIP() []byte {
 	return file_shared_proto_messaging_v1_messaging_proto_rawDescData
 }
 
-var file_shared_proto_messaging_v1_messaging_proto_msgTypes = make([]protoimpl.MessageInfo, 25)
+var file_shared_proto_messaging_v1_messaging_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_shared_proto_messaging_v1_messaging_proto_msgTypes = make([]protoimpl.MessageInfo, 32)
 var file_shared_proto_messaging_v1_messaging_proto_goTypes = []any{
-	(*Envelope)(nil),                  // 0: heroin.messaging.v1.Envelope
-	(*SendRequest)(nil),               // 1: heroin.messaging.v1.SendRequest
-	(*SendResponse)(nil),              // 2: heroin.messaging.v1.SendResponse
-	(*PullRequest)(nil),               // 3: heroin.messaging.v1.PullRequest
-	(*PullResponse)(nil),              // 4: heroin.messaging.v1.PullResponse
-	(*CreateGroupRequest)(nil),        // 5: heroin.messaging.v1.CreateGroupRequest
-	(*CreateGroupResponse)(nil),       // 6: heroin.messaging.v1.CreateGroupResponse
-	(*AddGroupMemberRequest)(nil),     // 7: heroin.messaging.v1.AddGroupMemberRequest
-	(*AddGroupMemberResponse)(nil),    // 8: heroin.messaging.v1.AddGroupMemberResponse
-	(*RemoveGroupMemberRequest)(nil),  // 9: heroin.messaging.v1.RemoveGroupMemberRequest
-	(*RemoveGroupMemberResponse)(nil), // 10: heroin.messaging.v1.RemoveGroupMemberResponse
-	(*GetGroupsRequest)(nil),          // 11: heroin.messaging.v1.GetGroupsRequest
-	(*Group)(nil),                     // 12: heroin.messaging.v1.Group
-	(*GetGroupsResponse)(nil),         // 13: heroin.messaging.v1.GetGroupsResponse
-	(*GetGroupMembersRequest)(nil),    // 14: heroin.messaging.v1.GetGroupMembersRequest
-	(*GroupMember)(nil),               // 15: heroin.messaging.v1.GroupMember
-	(*GetGroupMembersResponse)(nil),   // 16: heroin.messaging.v1.GetGroupMembersResponse
-	(*GetActivePeersRequest)(nil),     // 17: heroin.messaging.v1.GetActivePeersRequest
-	(*GetActivePeersResponse)(nil),    // 18: heroin.messaging.v1.GetActivePeersResponse
-	(*GetRelayChainsRequest)(nil),     // 19: heroin.messaging.v1.GetRelayChainsRequest
-	(*RelayChain)(nil),                // 20: heroin.messaging.v1.RelayChain
-	(*GetRelayChainsResponse)(nil),    // 21: heroin.messaging.v1.GetRelayChainsResponse
-	(*GetRoutingMetricsRequest)(nil),  // 22: heroin.messaging.v1.GetRoutingMetricsRequest
-	(*TransportMetrics)(nil),          // 23: heroin.messaging.v1.TransportMetrics
-	(*GetRoutingMetricsResponse)(nil), // 24: heroin.messaging.v1.GetRoutingMetricsResponse
+	(EnvelopeKind)(0),                   // 0: heroin.messaging.v1.EnvelopeKind
+	(PresenceKind)(0),                   // 1: heroin.messaging.v1.PresenceKind
+	(LastSeenVisibility)(0),             // 2: heroin.messaging.v1.LastSeenVisibility
+	(*Envelope)(nil),                    // 3: heroin.messaging.v1.Envelope
+	(*SendRequest)(nil),                 // 4: heroin.messaging.v1.SendRequest
+	(*SendResponse)(nil),                // 5: heroin.messaging.v1.SendResponse
+	(*PullRequest)(nil),                 // 6: heroin.messaging.v1.PullRequest
+	(*PullResponse)(nil),                // 7: heroin.messaging.v1.PullResponse
+	(*CreateGroupRequest)(nil),          // 8: heroin.messaging.v1.CreateGroupRequest
+	(*CreateGroupResponse)(nil),         // 9: heroin.messaging.v1.CreateGroupResponse
+	(*AddGroupMemberRequest)(nil),       // 10: heroin.messaging.v1.AddGroupMemberRequest
+	(*AddGroupMemberResponse)(nil),      // 11: heroin.messaging.v1.AddGroupMemberResponse
+	(*RemoveGroupMemberRequest)(nil),    // 12: heroin.messaging.v1.RemoveGroupMemberRequest
+	(*RemoveGroupMemberResponse)(nil),   // 13: heroin.messaging.v1.RemoveGroupMemberResponse
+	(*GetGroupsRequest)(nil),            // 14: heroin.messaging.v1.GetGroupsRequest
+	(*Group)(nil),                       // 15: heroin.messaging.v1.Group
+	(*GetGroupsResponse)(nil),           // 16: heroin.messaging.v1.GetGroupsResponse
+	(*GetGroupMembersRequest)(nil),      // 17: heroin.messaging.v1.GetGroupMembersRequest
+	(*GroupMember)(nil),                 // 18: heroin.messaging.v1.GroupMember
+	(*GetGroupMembersResponse)(nil),     // 19: heroin.messaging.v1.GetGroupMembersResponse
+	(*GetActivePeersRequest)(nil),       // 20: heroin.messaging.v1.GetActivePeersRequest
+	(*GetActivePeersResponse)(nil),      // 21: heroin.messaging.v1.GetActivePeersResponse
+	(*GetRelayChainsRequest)(nil),       // 22: heroin.messaging.v1.GetRelayChainsRequest
+	(*RelayChain)(nil),                  // 23: heroin.messaging.v1.RelayChain
+	(*GetRelayChainsResponse)(nil),      // 24: heroin.messaging.v1.GetRelayChainsResponse
+	(*GetRoutingMetricsRequest)(nil),    // 25: heroin.messaging.v1.GetRoutingMetricsRequest
+	(*TransportMetrics)(nil),            // 26: heroin.messaging.v1.TransportMetrics
+	(*GetRoutingMetricsResponse)(nil),   // 27: heroin.messaging.v1.GetRoutingMetricsResponse
+	(*PresenceEvent)(nil),               // 28: heroin.messaging.v1.PresenceEvent
+	(*SetPresencePrivacyRequest)(nil),   // 29: heroin.messaging.v1.SetPresencePrivacyRequest
+	(*SetPresencePrivacyResponse)(nil),  // 30: heroin.messaging.v1.SetPresencePrivacyResponse
+	(*RegisterPushTokenRequest)(nil),    // 31: heroin.messaging.v1.RegisterPushTokenRequest
+	(*RegisterPushTokenResponse)(nil),   // 32: heroin.messaging.v1.RegisterPushTokenResponse
+	(*UnregisterPushTokenRequest)(nil),  // 33: heroin.messaging.v1.UnregisterPushTokenRequest
+	(*UnregisterPushTokenResponse)(nil), // 34: heroin.messaging.v1.UnregisterPushTokenResponse
 }
 var file_shared_proto_messaging_v1_messaging_proto_depIdxs = []int32{
-	0,  // 0: heroin.messaging.v1.PullResponse.envelopes:type_name -> heroin.messaging.v1.Envelope
-	12, // 1: heroin.messaging.v1.GetGroupsResponse.groups:type_name -> heroin.messaging.v1.Group
-	15, // 2: heroin.messaging.v1.GetGroupMembersResponse.members:type_name -> heroin.messaging.v1.GroupMember
-	20, // 3: heroin.messaging.v1.GetRelayChainsResponse.chains:type_name -> heroin.messaging.v1.RelayChain
-	23, // 4: heroin.messaging.v1.GetRoutingMetricsResponse.transports:type_name -> heroin.messaging.v1.TransportMetrics
-	1,  // 5: heroin.messaging.v1.MessagingService.Send:input_type -> heroin.messaging.v1.SendRequest
-	3,  // 6: heroin.messaging.v1.MessagingService.Pull:input_type -> heroin.messaging.v1.PullRequest
-	5,  // 7: heroin.messaging.v1.MessagingService.CreateGroup:input_type -> heroin.messaging.v1.CreateGroupRequest
-	7,  // 8: heroin.messaging.v1.MessagingService.AddGroupMember:input_type -> heroin.messaging.v1.AddGroupMemberRequest
-	9,  // 9: heroin.messaging.v1.MessagingService.RemoveGroupMember:input_type -> heroin.messaging.v1.RemoveGroupMemberRequest
-	11, // 10: heroin.messaging.v1.MessagingService.GetGroups:input_type -> heroin.messaging.v1.GetGroupsRequest
-	14, // 11: heroin.messaging.v1.MessagingService.GetGroupMembers:input_type -> heroin.messaging.v1.GetGroupMembersRequest
-	17, // 12: heroin.messaging.v1.MessagingService.GetActivePeers:input_type -> heroin.messaging.v1.GetActivePeersRequest
-	19, // 13: heroin.messaging.v1.MessagingService.GetRelayChains:input_type -> heroin.messaging.v1.GetRelayChainsRequest
-	22, // 14: heroin.messaging.v1.MessagingService.GetRoutingMetrics:input_type -> heroin.messaging.v1.GetRoutingMetricsRequest
-	2,  // 15: heroin.messaging.v1.MessagingService.Send:output_type -> heroin.messaging.v1.SendResponse
-	4,  // 16: heroin.messaging.v1.MessagingService.Pull:output_type -> heroin.messaging.v1.PullResponse
-	6,  // 17: heroin.messaging.v1.MessagingService.CreateGroup:output_type -> heroin.messaging.v1.CreateGroupResponse
-	8,  // 18: heroin.messaging.v1.MessagingService.AddGroupMember:output_type -> heroin.messaging.v1.AddGroupMemberResponse
-	10, // 19: heroin.messaging.v1.MessagingService.RemoveGroupMember:output_type -> heroin.messaging.v1.RemoveGroupMemberResponse
-	13, // 20: heroin.messaging.v1.MessagingService.GetGroups:output_type -> heroin.messaging.v1.GetGroupsResponse
-	16, // 21: heroin.messaging.v1.MessagingService.GetGroupMembers:output_type -> heroin.messaging.v1.GetGroupMembersResponse
-	18, // 22: heroin.messaging.v1.MessagingService.GetActivePeers:output_type -> heroin.messaging.v1.GetActivePeersResponse
-	21, // 23: heroin.messaging.v1.MessagingService.GetRelayChains:output_type -> heroin.messaging.v1.GetRelayChainsResponse
-	24, // 24: heroin.messaging.v1.MessagingService.GetRoutingMetrics:output_type -> heroin.messaging.v1.GetRoutingMetricsResponse
-	15, // [15:25] is the sub-list for method output_type
-	5,  // [5:15] is the sub-list for method input_type
-	5,  // [5:5] is the sub-list for extension type_name
-	5,  // [5:5] is the sub-list for extension extendee
-	0,  // [0:5] is the sub-list for field type_name
+	0,  // 0: heroin.messaging.v1.Envelope.kind:type_name -> heroin.messaging.v1.EnvelopeKind
+	3,  // 1: heroin.messaging.v1.PullResponse.envelopes:type_name -> heroin.messaging.v1.Envelope
+	15, // 2: heroin.messaging.v1.GetGroupsResponse.groups:type_name -> heroin.messaging.v1.Group
+	18, // 3: heroin.messaging.v1.GetGroupMembersResponse.members:type_name -> heroin.messaging.v1.GroupMember
+	23, // 4: heroin.messaging.v1.GetRelayChainsResponse.chains:type_name -> heroin.messaging.v1.RelayChain
+	26, // 5: heroin.messaging.v1.GetRoutingMetricsResponse.transports:type_name -> heroin.messaging.v1.TransportMetrics
+	1,  // 6: heroin.messaging.v1.PresenceEvent.kind:type_name -> heroin.messaging.v1.PresenceKind
+	2,  // 7: heroin.messaging.v1.SetPresencePrivacyRequest.last_seen:type_name -> heroin.messaging.v1.LastSeenVisibility
+	4,  // 8: heroin.messaging.v1.MessagingService.Send:input_type -> heroin.messaging.v1.SendRequest
+	6,  // 9: heroin.messaging.v1.MessagingService.Pull:input_type -> heroin.messaging.v1.PullRequest
+	8,  // 10: heroin.messaging.v1.MessagingService.CreateGroup:input_type -> heroin.messaging.v1.CreateGroupRequest
+	10, // 11: heroin.messaging.v1.MessagingService.AddGroupMember:input_type -> heroin.messaging.v1.AddGroupMemberRequest
+	12, // 12: heroin.messaging.v1.MessagingService.RemoveGroupMember:input_type -> heroin.messaging.v1.RemoveGroupMemberRequest
+	14, // 13: heroin.messaging.v1.MessagingService.GetGroups:input_type -> heroin.messaging.v1.GetGroupsRequest
+	17, // 14: heroin.messaging.v1.MessagingService.GetGroupMembers:input_type -> heroin.messaging.v1.GetGroupMembersRequest
+	20, // 15: heroin.messaging.v1.MessagingService.GetActivePeers:input_type -> heroin.messaging.v1.GetActivePeersRequest
+	22, // 16: heroin.messaging.v1.MessagingService.GetRelayChains:input_type -> heroin.messaging.v1.GetRelayChainsRequest
+	25, // 17: heroin.messaging.v1.MessagingService.GetRoutingMetrics:input_type -> heroin.messaging.v1.GetRoutingMetricsRequest
+	28, // 18: heroin.messaging.v1.MessagingService.Presence:input_type -> heroin.messaging.v1.PresenceEvent
+	29, // 19: heroin.messaging.v1.MessagingService.SetPresencePrivacy:input_type -> heroin.messaging.v1.SetPresencePrivacyRequest
+	31, // 20: heroin.messaging.v1.MessagingService.RegisterPushToken:input_type -> heroin.messaging.v1.RegisterPushTokenRequest
+	33, // 21: heroin.messaging.v1.MessagingService.UnregisterPushToken:input_type -> heroin.messaging.v1.UnregisterPushTokenRequest
+	5,  // 22: heroin.messaging.v1.MessagingService.Send:output_type -> heroin.messaging.v1.SendResponse
+	7,  // 23: heroin.messaging.v1.MessagingService.Pull:output_type -> heroin.messaging.v1.PullResponse
+	9,  // 24: heroin.messaging.v1.MessagingService.CreateGroup:output_type -> heroin.messaging.v1.CreateGroupResponse
+	11, // 25: heroin.messaging.v1.MessagingService.AddGroupMember:output_type -> heroin.messaging.v1.AddGroupMemberResponse
+	13, // 26: heroin.messaging.v1.MessagingService.RemoveGroupMember:output_type -> heroin.messaging.v1.RemoveGroupMemberResponse
+	16, // 27: heroin.messaging.v1.MessagingService.GetGroups:output_type -> heroin.messaging.v1.GetGroupsResponse
+	19, // 28: heroin.messaging.v1.MessagingService.GetGroupMembers:output_type -> heroin.messaging.v1.GetGroupMembersResponse
+	21, // 29: heroin.messaging.v1.MessagingService.GetActivePeers:output_type -> heroin.messaging.v1.GetActivePeersResponse
+	24, // 30: heroin.messaging.v1.MessagingService.GetRelayChains:output_type -> heroin.messaging.v1.GetRelayChainsResponse
+	27, // 31: heroin.messaging.v1.MessagingService.GetRoutingMetrics:output_type -> heroin.messaging.v1.GetRoutingMetricsResponse
+	28, // 32: heroin.messaging.v1.MessagingService.Presence:output_type -> heroin.messaging.v1.PresenceEvent
+	30, // 33: heroin.messaging.v1.MessagingService.SetPresencePrivacy:output_type -> heroin.messaging.v1.SetPresencePrivacyResponse
+	32, // 34: heroin.messaging.v1.MessagingService.RegisterPushToken:output_type -> heroin.messaging.v1.RegisterPushTokenResponse
+	34, // 35: heroin.messaging.v1.MessagingService.UnregisterPushToken:output_type -> heroin.messaging.v1.UnregisterPushTokenResponse
+	22, // [22:36] is the sub-list for method output_type
+	8,  // [8:22] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
 }
 
 func init() { file_shared_proto_messaging_v1_messaging_proto_init() }
@@ -1502,13 +2128,14 @@ func file_shared_proto_messaging_v1_messaging_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_shared_proto_messaging_v1_messaging_proto_rawDesc), len(file_shared_proto_messaging_v1_messaging_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   25,
+			NumEnums:      3,
+			NumMessages:   32,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_shared_proto_messaging_v1_messaging_proto_goTypes,
 		DependencyIndexes: file_shared_proto_messaging_v1_messaging_proto_depIdxs,
+		EnumInfos:         file_shared_proto_messaging_v1_messaging_proto_enumTypes,
 		MessageInfos:      file_shared_proto_messaging_v1_messaging_proto_msgTypes,
 	}.Build()
 	File_shared_proto_messaging_v1_messaging_proto = out.File
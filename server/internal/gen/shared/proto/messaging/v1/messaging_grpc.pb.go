@@ -19,16 +19,20 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	MessagingService_Send_FullMethodName              = "/heroin.messaging.v1.MessagingService/Send"
-	MessagingService_Pull_FullMethodName              = "/heroin.messaging.v1.MessagingService/Pull"
-	MessagingService_CreateGroup_FullMethodName       = "/heroin.messaging.v1.MessagingService/CreateGroup"
-	MessagingService_AddGroupMember_FullMethodName    = "/heroin.messaging.v1.MessagingService/AddGroupMember"
-	MessagingService_RemoveGroupMember_FullMethodName = "/heroin.messaging.v1.MessagingService/RemoveGroupMember"
-	MessagingService_GetGroups_FullMethodName         = "/heroin.messaging.v1.MessagingService/GetGroups"
-	MessagingService_GetGroupMembers_FullMethodName   = "/heroin.messaging.v1.MessagingService/GetGroupMembers"
-	MessagingService_GetActivePeers_FullMethodName    = "/heroin.messaging.v1.MessagingService/GetActivePeers"
-	MessagingService_GetRelayChains_FullMethodName    = "/heroin.messaging.v1.MessagingService/GetRelayChains"
-	MessagingService_GetRoutingMetrics_FullMethodName = "/heroin.messaging.v1.MessagingService/GetRoutingMetrics"
+	MessagingService_Send_FullMethodName                = "/heroin.messaging.v1.MessagingService/Send"
+	MessagingService_Pull_FullMethodName                = "/heroin.messaging.v1.MessagingService/Pull"
+	MessagingService_CreateGroup_FullMethodName         = "/heroin.messaging.v1.MessagingService/CreateGroup"
+	MessagingService_AddGroupMember_FullMethodName      = "/heroin.messaging.v1.MessagingService/AddGroupMember"
+	MessagingService_RemoveGroupMember_FullMethodName   = "/heroin.messaging.v1.MessagingService/RemoveGroupMember"
+	MessagingService_GetGroups_FullMethodName           = "/heroin.messaging.v1.MessagingService/GetGroups"
+	MessagingService_GetGroupMembers_FullMethodName     = "/heroin.messaging.v1.MessagingService/GetGroupMembers"
+	MessagingService_GetActivePeers_FullMethodName      = "/heroin.messaging.v1.MessagingService/GetActivePeers"
+	MessagingService_GetRelayChains_FullMethodName      = "/heroin.messaging.v1.MessagingService/GetRelayChains"
+	MessagingService_GetRoutingMetrics_FullMethodName   = "/heroin.messaging.v1.MessagingService/GetRoutingMetrics"
+	MessagingService_Presence_FullMethodName            = "/heroin.messaging.v1.MessagingService/Presence"
+	MessagingService_SetPresencePrivacy_FullMethodName  = "/heroin.messaging.v1.MessagingService/SetPresencePrivacy"
+	MessagingService_RegisterPushToken_FullMethodName   = "/heroin.messaging.v1.MessagingService/RegisterPushToken"
+	MessagingService_UnregisterPushToken_FullMethodName = "/heroin.messaging.v1.MessagingService/UnregisterPushToken"
 )
 
 // MessagingServiceClient is the client API for MessagingService service.
@@ -47,6 +51,12 @@ type MessagingServiceClient interface {
 	GetActivePeers(ctx context.Context, in *GetActivePeersRequest, opts ...grpc.CallOption) (*GetActivePeersResponse, error)
 	GetRelayChains(ctx context.Context, in *GetRelayChainsRequest, opts ...grpc.CallOption) (*GetRelayChainsResponse, error)
 	GetRoutingMetrics(ctx context.Context, in *GetRoutingMetricsRequest, opts ...grpc.CallOption) (*GetRoutingMetricsResponse, error)
+	// присутствие: эфемерные события, на сервере не сохраняются
+	Presence(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[PresenceEvent, PresenceEvent], error)
+	SetPresencePrivacy(ctx context.Context, in *SetPresencePrivacyRequest, opts ...grpc.CallOption) (*SetPresencePrivacyResponse, error)
+	// push токены для офлайн уведомлений
+	RegisterPushToken(ctx context.Context, in *RegisterPushTokenRequest, opts ...grpc.CallOption) (*RegisterPushTokenResponse, error)
+	UnregisterPushToken(ctx context.Context, in *UnregisterPushTokenRequest, opts ...grpc.CallOption) (*UnregisterPushTokenResponse, error)
 }
 
 type messagingServiceClient struct {
@@ -157,6 +167,49 @@ func (c *messagingServiceClient) GetRoutingMetrics(ctx context.Context, in *GetR
 	return out, nil
 }
 
+func (c *messagingServiceClient) Presence(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[PresenceEvent, PresenceEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MessagingService_ServiceDesc.Streams[0], MessagingService_Presence_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PresenceEvent, PresenceEvent]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MessagingService_PresenceClient = grpc.BidiStreamingClient[PresenceEvent, PresenceEvent]
+
+func (c *messagingServiceClient) SetPresencePrivacy(ctx context.Context, in *SetPresencePrivacyRequest, opts ...grpc.CallOption) (*SetPresencePrivacyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetPresencePrivacyResponse)
+	err := c.cc.Invoke(ctx, MessagingService_SetPresencePrivacy_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *messagingServiceClient) RegisterPushToken(ctx context.Context, in *RegisterPushTokenRequest, opts ...grpc.CallOption) (*RegisterPushTokenResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RegisterPushTokenResponse)
+	err := c.cc.Invoke(ctx, MessagingService_RegisterPushToken_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *messagingServiceClient) UnregisterPushToken(ctx context.Context, in *UnregisterPushTokenRequest, opts ...grpc.CallOption) (*UnregisterPushTokenResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UnregisterPushTokenResponse)
+	err := c.cc.Invoke(ctx, MessagingService_UnregisterPushToken_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MessagingServiceServer is the server API for MessagingService service.
 // All implementations must embed UnimplementedMessagingServiceServer
 // for forward compatibility.
@@ -173,6 +226,12 @@ type MessagingServiceServer interface {
 	GetActivePeers(context.Context, *GetActivePeersRequest) (*GetActivePeersResponse, error)
 	GetRelayChains(context.Context, *GetRelayChainsRequest) (*GetRelayChainsResponse, error)
 	GetRoutingMetrics(context.Context, *GetRoutingMetricsRequest) (*GetRoutingMetricsResponse, error)
+	// присутствие: эфемерные события, на сервере не сохраняются
+	Presence(grpc.BidiStreamingServer[PresenceEvent, PresenceEvent]) error
+	SetPresencePrivacy(context.Context, *SetPresencePrivacyRequest) (*SetPresencePrivacyResponse, error)
+	// push токены для офлайн уведомлений
+	RegisterPushToken(context.Context, *RegisterPushTokenRequest) (*RegisterPushTokenResponse, error)
+	UnregisterPushToken(context.Context, *UnregisterPushTokenRequest) (*UnregisterPushTokenResponse, error)
 	mustEmbedUnimplementedMessagingServiceServer()
 }
 
@@ -213,6 +272,18 @@ func (UnimplementedMessagingServiceServer) GetRelayChains(context.Context, *GetR
 func (UnimplementedMessagingServiceServer) GetRoutingMetrics(context.Context, *GetRoutingMetricsRequest) (*GetRoutingMetricsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetRoutingMetrics not implemented")
 }
+func (UnimplementedMessagingServiceServer) Presence(grpc.BidiStreamingServer[PresenceEvent, PresenceEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method Presence not implemented")
+}
+func (UnimplementedMessagingServiceServer) SetPresencePrivacy(context.Context, *SetPresencePrivacyRequest) (*SetPresencePrivacyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetPresencePrivacy not implemented")
+}
+func (UnimplementedMessagingServiceServer) RegisterPushToken(context.Context, *RegisterPushTokenRequest) (*RegisterPushTokenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterPushToken not implemented")
+}
+func (UnimplementedMessagingServiceServer) UnregisterPushToken(context.Context, *UnregisterPushTokenRequest) (*UnregisterPushTokenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnregisterPushToken not implemented")
+}
 func (UnimplementedMessagingServiceServer) mustEmbedUnimplementedMessagingServiceServer() {}
 func (UnimplementedMessagingServiceServer) testEmbeddedByValue()                          {}
 
@@ -414,6 +485,67 @@ func _MessagingService_GetRoutingMetrics_Handler(srv interface{}, ctx context.Co
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MessagingService_Presence_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MessagingServiceServer).Presence(&grpc.GenericServerStream[PresenceEvent, PresenceEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MessagingService_PresenceServer = grpc.BidiStreamingServer[PresenceEvent, PresenceEvent]
+
+func _MessagingService_SetPresencePrivacy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetPresencePrivacyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MessagingServiceServer).SetPresencePrivacy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MessagingService_SetPresencePrivacy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MessagingServiceServer).SetPresencePrivacy(ctx, req.(*SetPresencePrivacyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MessagingService_RegisterPushToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterPushTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MessagingServiceServer).RegisterPushToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MessagingService_RegisterPushToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MessagingServiceServer).RegisterPushToken(ctx, req.(*RegisterPushTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MessagingService_UnregisterPushToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnregisterPushTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MessagingServiceServer).UnregisterPushToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MessagingService_UnregisterPushToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MessagingServiceServer).UnregisterPushToken(ctx, req.(*UnregisterPushTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MessagingService_ServiceDesc is the grpc.ServiceDesc for MessagingService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -461,7 +593,26 @@ var MessagingService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetRoutingMetrics",
 			Handler:    _MessagingService_GetRoutingMetrics_Handler,
 		},
+		{
+			MethodName: "SetPresencePrivacy",
+			Handler:    _MessagingService_SetPresencePrivacy_Handler,
+		},
+		{
+			MethodName: "RegisterPushToken",
+			Handler:    _MessagingService_RegisterPushToken_Handler,
+		},
+		{
+			MethodName: "UnregisterPushToken",
+			Handler:    _MessagingService_UnregisterPushToken_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Presence",
+			Handler:       _MessagingService_Presence_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "shared/proto/messaging/v1/messaging.proto",
 }
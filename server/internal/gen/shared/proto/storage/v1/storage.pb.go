@@ -21,6 +21,165 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type PreviewKind int32
+
+const (
+	PreviewKind_PREVIEW_KIND_UNSPECIFIED PreviewKind = 0
+	PreviewKind_PREVIEW_KIND_THUMBNAIL   PreviewKind = 1
+	PreviewKind_PREVIEW_KIND_PREVIEW     PreviewKind = 2
+	PreviewKind_PREVIEW_KIND_POSTER      PreviewKind = 3
+)
+
+// Enum value maps for PreviewKind.
+var (
+	PreviewKind_name = map[int32]string{
+		0: "PREVIEW_KIND_UNSPECIFIED",
+		1: "PREVIEW_KIND_THUMBNAIL",
+		2: "PREVIEW_KIND_PREVIEW",
+		3: "PREVIEW_KIND_POSTER",
+	}
+	PreviewKind_value = map[string]int32{
+		"PREVIEW_KIND_UNSPECIFIED": 0,
+		"PREVIEW_KIND_THUMBNAIL":   1,
+		"PREVIEW_KIND_PREVIEW":     2,
+		"PREVIEW_KIND_POSTER":      3,
+	}
+)
+
+func (x PreviewKind) Enum() *PreviewKind {
+	p := new(PreviewKind)
+	*p = x
+	return p
+}
+
+func (x PreviewKind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PreviewKind) Descriptor() protoreflect.EnumDescriptor {
+	return file_shared_proto_storage_v1_storage_proto_enumTypes[0].Descriptor()
+}
+
+func (PreviewKind) Type() protoreflect.EnumType {
+	return &file_shared_proto_storage_v1_storage_proto_enumTypes[0]
+}
+
+func (x PreviewKind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PreviewKind.Descriptor instead.
+func (PreviewKind) EnumDescriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{0}
+}
+
+// виртуальная файловая система. имена шифруются на клиенте: name_blob непрозрачен для сервера,
+// name_hash это ключевой хеш имени (32 байта), по нему ищутся конфликты и разрешаются пути.
+// пустой parent_id означает корень
+type NodeKind int32
+
+const (
+	NodeKind_NODE_KIND_UNSPECIFIED NodeKind = 0
+	NodeKind_NODE_KIND_DIR         NodeKind = 1
+	NodeKind_NODE_KIND_FILE        NodeKind = 2
+)
+
+// Enum value maps for NodeKind.
+var (
+	NodeKind_name = map[int32]string{
+		0: "NODE_KIND_UNSPECIFIED",
+		1: "NODE_KIND_DIR",
+		2: "NODE_KIND_FILE",
+	}
+	NodeKind_value = map[string]int32{
+		"NODE_KIND_UNSPECIFIED": 0,
+		"NODE_KIND_DIR":         1,
+		"NODE_KIND_FILE":        2,
+	}
+)
+
+func (x NodeKind) Enum() *NodeKind {
+	p := new(NodeKind)
+	*p = x
+	return p
+}
+
+func (x NodeKind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (NodeKind) Descriptor() protoreflect.EnumDescriptor {
+	return file_shared_proto_storage_v1_storage_proto_enumTypes[1].Descriptor()
+}
+
+func (NodeKind) Type() protoreflect.EnumType {
+	return &file_shared_proto_storage_v1_storage_proto_enumTypes[1]
+}
+
+func (x NodeKind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use NodeKind.Descriptor instead.
+func (NodeKind) EnumDescriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{1}
+}
+
+// общий доступ. wrapped_key - ключ файла или каталога, зашифрованный клиентом для получателя
+// (открытым ключом пользователя или ключом группы), сервер его не разбирает.
+// цель задается ровно одним из file_id и node_id
+type GranteeKind int32
+
+const (
+	GranteeKind_GRANTEE_KIND_UNSPECIFIED GranteeKind = 0
+	GranteeKind_GRANTEE_KIND_USER        GranteeKind = 1
+	GranteeKind_GRANTEE_KIND_GROUP       GranteeKind = 2
+)
+
+// Enum value maps for GranteeKind.
+var (
+	GranteeKind_name = map[int32]string{
+		0: "GRANTEE_KIND_UNSPECIFIED",
+		1: "GRANTEE_KIND_USER",
+		2: "GRANTEE_KIND_GROUP",
+	}
+	GranteeKind_value = map[string]int32{
+		"GRANTEE_KIND_UNSPECIFIED": 0,
+		"GRANTEE_KIND_USER":        1,
+		"GRANTEE_KIND_GROUP":       2,
+	}
+)
+
+func (x GranteeKind) Enum() *GranteeKind {
+	p := new(GranteeKind)
+	*p = x
+	return p
+}
+
+func (x GranteeKind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (GranteeKind) Descriptor() protoreflect.EnumDescriptor {
+	return file_shared_proto_storage_v1_storage_proto_enumTypes[2].Descriptor()
+}
+
+func (GranteeKind) Type() protoreflect.EnumType {
+	return &file_shared_proto_storage_v1_storage_proto_enumTypes[2]
+}
+
+func (x GranteeKind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use GranteeKind.Descriptor instead.
+func (GranteeKind) EnumDescriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{2}
+}
+
+// encrypted_car_chunk - кусок car, который клиент собрал из контейнера docs/filecrypt.md.
+// bao_proof необязателен: доказательство для чанка по дереву над всем car с корнем total_blake3,
+// чанк с доказательством должен быть выровнен по группам в 1 кб
 type PutFileRequest struct {
 	state             protoimpl.MessageState `protogen:"open.v1"`
 	Name              string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
@@ -29,8 +188,13 @@ type PutFileRequest struct {
 	EncryptedCarChunk []byte                 `protobuf:"bytes,4,opt,name=encrypted_car_chunk,json=encryptedCarChunk,proto3" json:"encrypted_car_chunk,omitempty"`
 	LastChunk         bool                   `protobuf:"varint,5,opt,name=last_chunk,json=lastChunk,proto3" json:"last_chunk,omitempty"`
 	TotalBlake3       []byte                 `protobuf:"bytes,6,opt,name=total_blake3,json=totalBlake3,proto3" json:"total_blake3,omitempty"`
-	unknownFields     protoimpl.UnknownFields
-	sizeCache         protoimpl.SizeCache
+	BaoProof          []byte                 `protobuf:"bytes,7,opt,name=bao_proof,json=baoProof,proto3" json:"bao_proof,omitempty"`
+	// непустой - загрузка становится новой версией этого файла, а не новым файлом
+	FileId string `protobuf:"bytes,8,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	// устройство-автор версии
+	DeviceId      string `protobuf:"bytes,9,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *PutFileRequest) Reset() {
@@ -105,11 +269,33 @@ func (x *PutFileRequest) GetTotalBlake3() []byte {
 	return nil
 }
 
+func (x *PutFileRequest) GetBaoProof() []byte {
+	if x != nil {
+		return x.BaoProof
+	}
+	return nil
+}
+
+func (x *PutFileRequest) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *PutFileRequest) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
 type PutFileResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Accepted      bool                   `protobuf:"varint,1,opt,name=accepted,proto3" json:"accepted,omitempty"`
 	FileId        string                 `protobuf:"bytes,2,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
 	Cid           string                 `protobuf:"bytes,3,opt,name=cid,proto3" json:"cid,omitempty"`
+	Version       int64                  `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -165,9 +351,25 @@ func (x *PutFileResponse) GetCid() string {
 	return ""
 }
 
+func (x *PutFileResponse) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+// offset и length задают диапазон байт экспортируемого car, length 0 значит до конца.
+// при bao_proofs диапазон должен быть выровнен по 1 кб. link_token дает доступ по публичной ссылке,
+// скачивание засчитывается ссылке, когда отдан последний чанк car
 type GetFileRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Cid           string                 `protobuf:"bytes,1,opt,name=cid,proto3" json:"cid,omitempty"`
+	Offset        int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Length        int64                  `protobuf:"varint,3,opt,name=length,proto3" json:"length,omitempty"`
+	ChunkTags     bool                   `protobuf:"varint,4,opt,name=chunk_tags,json=chunkTags,proto3" json:"chunk_tags,omitempty"`
+	BaoProofs     bool                   `protobuf:"varint,5,opt,name=bao_proofs,json=baoProofs,proto3" json:"bao_proofs,omitempty"`
+	LinkToken     string                 `protobuf:"bytes,6,opt,name=link_token,json=linkToken,proto3" json:"link_token,omitempty"`
+	LinkPassword  string                 `protobuf:"bytes,7,opt,name=link_password,json=linkPassword,proto3" json:"link_password,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -209,10 +411,58 @@ func (x *GetFileRequest) GetCid() string {
 	return ""
 }
 
+func (x *GetFileRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *GetFileRequest) GetLength() int64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+func (x *GetFileRequest) GetChunkTags() bool {
+	if x != nil {
+		return x.ChunkTags
+	}
+	return false
+}
+
+func (x *GetFileRequest) GetBaoProofs() bool {
+	if x != nil {
+		return x.BaoProofs
+	}
+	return false
+}
+
+func (x *GetFileRequest) GetLinkToken() string {
+	if x != nil {
+		return x.LinkToken
+	}
+	return ""
+}
+
+func (x *GetFileRequest) GetLinkPassword() string {
+	if x != nil {
+		return x.LinkPassword
+	}
+	return ""
+}
+
+// offset это позиция чанка в car, chunk_blake3 заполняется при chunk_tags,
+// bao_proof при bao_proofs, bao_root приходит в первом сообщении
 type GetFileResponse struct {
 	state             protoimpl.MessageState `protogen:"open.v1"`
 	EncryptedCarChunk []byte                 `protobuf:"bytes,1,opt,name=encrypted_car_chunk,json=encryptedCarChunk,proto3" json:"encrypted_car_chunk,omitempty"`
 	LastChunk         bool                   `protobuf:"varint,2,opt,name=last_chunk,json=lastChunk,proto3" json:"last_chunk,omitempty"`
+	Offset            int64                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	ChunkBlake3       []byte                 `protobuf:"bytes,4,opt,name=chunk_blake3,json=chunkBlake3,proto3" json:"chunk_blake3,omitempty"`
+	BaoProof          []byte                 `protobuf:"bytes,5,opt,name=bao_proof,json=baoProof,proto3" json:"bao_proof,omitempty"`
+	BaoRoot           []byte                 `protobuf:"bytes,6,opt,name=bao_root,json=baoRoot,proto3" json:"bao_root,omitempty"`
 	unknownFields     protoimpl.UnknownFields
 	sizeCache         protoimpl.SizeCache
 }
@@ -261,34 +511,4475 @@ func (x *GetFileResponse) GetLastChunk() bool {
 	return false
 }
 
-var File_shared_proto_storage_v1_storage_proto protoreflect.FileDescriptor
+func (x *GetFileResponse) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
 
-const file_shared_proto_storage_v1_storage_proto_rawDesc = "" +
-	"\n" +
-	"%shared/proto/storage/v1/storage.proto\x12\n" +
-	"storage.v1\"\xc9\x01\n" +
-	"\x0ePutFileRequest\x12\x12\n" +
-	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
-	"\x04mime\x18\x02 \x01(\tR\x04mime\x12\x1d\n" +
-	"\n" +
-	"size_bytes\x18\x03 \x01(\x03R\tsizeBytes\x12.\n" +
-	"\x13encrypted_car_chunk\x18\x04 \x01(\fR\x11encryptedCarChunk\x12\x1d\n" +
-	"\n" +
-	"last_chunk\x18\x05 \x01(\bR\tlastChunk\x12!\n" +
-	"\ftotal_blake3\x18\x06 \x01(\fR\vtotalBlake3\"X\n" +
-	"\x0fPutFileResponse\x12\x1a\n" +
-	"\baccepted\x18\x01 \x01(\bR\baccepted\x12\x17\n" +
-	"\afile_id\x18\x02 \x01(\tR\x06fileId\x12\x10\n" +
-	"\x03cid\x18\x03 \x01(\tR\x03cid\"\"\n" +
-	"\x0eGetFileRequest\x12\x10\n" +
-	"\x03cid\x18\x01 \x01(\tR\x03cid\"`\n" +
-	"\x0fGetFileResponse\x12.\n" +
-	"\x13encrypted_car_chunk\x18\x01 \x01(\fR\x11encryptedCarChunk\x12\x1d\n" +
-	"\n" +
-	"last_chunk\x18\x02 \x01(\bR\tlastChunk2\x9c\x01\n" +
-	"\x0eStorageService\x12D\n" +
-	"\aPutFile\x12\x1a.storage.v1.PutFileRequest\x1a\x1b.storage.v1.PutFileResponse(\x01\x12D\n" +
-	"\aGetFile\x12\x1a.storage.v1.GetFileRequest\x1a\x1b.storage.v1.GetFileResponse0\x01B2Z0dev.c0rex64.heroin/shared/proto/storage/v1;stgv1b\x06proto3"
+func (x *GetFileResponse) GetChunkBlake3() []byte {
+	if x != nil {
+		return x.ChunkBlake3
+	}
+	return nil
+}
+
+func (x *GetFileResponse) GetBaoProof() []byte {
+	if x != nil {
+		return x.BaoProof
+	}
+	return nil
+}
+
+func (x *GetFileResponse) GetBaoRoot() []byte {
+	if x != nil {
+		return x.BaoRoot
+	}
+	return nil
+}
+
+// докачка по блокам: клиент получает список блоков dag и запрашивает недостающие
+type ListBlocksRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cid           string                 `protobuf:"bytes,1,opt,name=cid,proto3" json:"cid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBlocksRequest) Reset() {
+	*x = ListBlocksRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBlocksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBlocksRequest) ProtoMessage() {}
+
+func (x *ListBlocksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBlocksRequest.ProtoReflect.Descriptor instead.
+func (*ListBlocksRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListBlocksRequest) GetCid() string {
+	if x != nil {
+		return x.Cid
+	}
+	return ""
+}
+
+type ListBlocksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cids          []string               `protobuf:"bytes,1,rep,name=cids,proto3" json:"cids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBlocksResponse) Reset() {
+	*x = ListBlocksResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBlocksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBlocksResponse) ProtoMessage() {}
+
+func (x *ListBlocksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBlocksResponse.ProtoReflect.Descriptor instead.
+func (*ListBlocksResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListBlocksResponse) GetCids() []string {
+	if x != nil {
+		return x.Cids
+	}
+	return nil
+}
+
+// блоки должны принадлежать dag с корнем root_cid, доступ проверяется по корню.
+// публичные ссылки блоками не читаются, только через GetFile. хранилищам без
+// доступа к отдельным блокам (local, s3) корень нужен и без проверки доступа
+type GetBlocksRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cids          []string               `protobuf:"bytes,1,rep,name=cids,proto3" json:"cids,omitempty"`
+	RootCid       string                 `protobuf:"bytes,2,opt,name=root_cid,json=rootCid,proto3" json:"root_cid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBlocksRequest) Reset() {
+	*x = GetBlocksRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBlocksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBlocksRequest) ProtoMessage() {}
+
+func (x *GetBlocksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBlocksRequest.ProtoReflect.Descriptor instead.
+func (*GetBlocksRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetBlocksRequest) GetCids() []string {
+	if x != nil {
+		return x.Cids
+	}
+	return nil
+}
+
+func (x *GetBlocksRequest) GetRootCid() string {
+	if x != nil {
+		return x.RootCid
+	}
+	return ""
+}
+
+type GetBlocksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cid           string                 `protobuf:"bytes,1,opt,name=cid,proto3" json:"cid,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	Blake3        []byte                 `protobuf:"bytes,3,opt,name=blake3,proto3" json:"blake3,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBlocksResponse) Reset() {
+	*x = GetBlocksResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBlocksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBlocksResponse) ProtoMessage() {}
+
+func (x *GetBlocksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBlocksResponse.ProtoReflect.Descriptor instead.
+func (*GetBlocksResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetBlocksResponse) GetCid() string {
+	if x != nil {
+		return x.Cid
+	}
+	return ""
+}
+
+func (x *GetBlocksResponse) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *GetBlocksResponse) GetBlake3() []byte {
+	if x != nil {
+		return x.Blake3
+	}
+	return nil
+}
+
+// возобновляемая загрузка: чанки адресуются смещением, повтор чанка безопасен
+type BeginUploadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Mime          string                 `protobuf:"bytes,2,opt,name=mime,proto3" json:"mime,omitempty"`
+	SizeBytes     int64                  `protobuf:"varint,3,opt,name=size_bytes,json=sizeBytes,proto3" json:"size_bytes,omitempty"`
+	TotalBlake3   []byte                 `protobuf:"bytes,4,opt,name=total_blake3,json=totalBlake3,proto3" json:"total_blake3,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BeginUploadRequest) Reset() {
+	*x = BeginUploadRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BeginUploadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BeginUploadRequest) ProtoMessage() {}
+
+func (x *BeginUploadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BeginUploadRequest.ProtoReflect.Descriptor instead.
+func (*BeginUploadRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *BeginUploadRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *BeginUploadRequest) GetMime() string {
+	if x != nil {
+		return x.Mime
+	}
+	return ""
+}
+
+func (x *BeginUploadRequest) GetSizeBytes() int64 {
+	if x != nil {
+		return x.SizeBytes
+	}
+	return 0
+}
+
+func (x *BeginUploadRequest) GetTotalBlake3() []byte {
+	if x != nil {
+		return x.TotalBlake3
+	}
+	return nil
+}
+
+type BeginUploadResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UploadId      string                 `protobuf:"bytes,1,opt,name=upload_id,json=uploadId,proto3" json:"upload_id,omitempty"`
+	ExpiresAtUnix int64                  `protobuf:"varint,2,opt,name=expires_at_unix,json=expiresAtUnix,proto3" json:"expires_at_unix,omitempty"`
+	MaxChunkBytes int32                  `protobuf:"varint,3,opt,name=max_chunk_bytes,json=maxChunkBytes,proto3" json:"max_chunk_bytes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BeginUploadResponse) Reset() {
+	*x = BeginUploadResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BeginUploadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BeginUploadResponse) ProtoMessage() {}
+
+func (x *BeginUploadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BeginUploadResponse.ProtoReflect.Descriptor instead.
+func (*BeginUploadResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *BeginUploadResponse) GetUploadId() string {
+	if x != nil {
+		return x.UploadId
+	}
+	return ""
+}
+
+func (x *BeginUploadResponse) GetExpiresAtUnix() int64 {
+	if x != nil {
+		return x.ExpiresAtUnix
+	}
+	return 0
+}
+
+func (x *BeginUploadResponse) GetMaxChunkBytes() int32 {
+	if x != nil {
+		return x.MaxChunkBytes
+	}
+	return 0
+}
+
+// bao_proof необязателен, с ним битый чанк отвергается до записи
+type UploadChunkRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UploadId      string                 `protobuf:"bytes,1,opt,name=upload_id,json=uploadId,proto3" json:"upload_id,omitempty"`
+	Offset        int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	BaoProof      []byte                 `protobuf:"bytes,4,opt,name=bao_proof,json=baoProof,proto3" json:"bao_proof,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadChunkRequest) Reset() {
+	*x = UploadChunkRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadChunkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadChunkRequest) ProtoMessage() {}
+
+func (x *UploadChunkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadChunkRequest.ProtoReflect.Descriptor instead.
+func (*UploadChunkRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *UploadChunkRequest) GetUploadId() string {
+	if x != nil {
+		return x.UploadId
+	}
+	return ""
+}
+
+func (x *UploadChunkRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *UploadChunkRequest) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *UploadChunkRequest) GetBaoProof() []byte {
+	if x != nil {
+		return x.BaoProof
+	}
+	return nil
+}
+
+type UploadChunkResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ReceivedBytes int64                  `protobuf:"varint,1,opt,name=received_bytes,json=receivedBytes,proto3" json:"received_bytes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadChunkResponse) Reset() {
+	*x = UploadChunkResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadChunkResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadChunkResponse) ProtoMessage() {}
+
+func (x *UploadChunkResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadChunkResponse.ProtoReflect.Descriptor instead.
+func (*UploadChunkResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *UploadChunkResponse) GetReceivedBytes() int64 {
+	if x != nil {
+		return x.ReceivedBytes
+	}
+	return 0
+}
+
+type QueryUploadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UploadId      string                 `protobuf:"bytes,1,opt,name=upload_id,json=uploadId,proto3" json:"upload_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryUploadRequest) Reset() {
+	*x = QueryUploadRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryUploadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryUploadRequest) ProtoMessage() {}
+
+func (x *QueryUploadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryUploadRequest.ProtoReflect.Descriptor instead.
+func (*QueryUploadRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *QueryUploadRequest) GetUploadId() string {
+	if x != nil {
+		return x.UploadId
+	}
+	return ""
+}
+
+type ByteRange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         int64                  `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	End           int64                  `protobuf:"varint,2,opt,name=end,proto3" json:"end,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ByteRange) Reset() {
+	*x = ByteRange{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ByteRange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ByteRange) ProtoMessage() {}
+
+func (x *ByteRange) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ByteRange.ProtoReflect.Descriptor instead.
+func (*ByteRange) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ByteRange) GetStart() int64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *ByteRange) GetEnd() int64 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+type QueryUploadResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SizeBytes     int64                  `protobuf:"varint,1,opt,name=size_bytes,json=sizeBytes,proto3" json:"size_bytes,omitempty"`
+	Received      []*ByteRange           `protobuf:"bytes,2,rep,name=received,proto3" json:"received,omitempty"`
+	ExpiresAtUnix int64                  `protobuf:"varint,3,opt,name=expires_at_unix,json=expiresAtUnix,proto3" json:"expires_at_unix,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryUploadResponse) Reset() {
+	*x = QueryUploadResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryUploadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryUploadResponse) ProtoMessage() {}
+
+func (x *QueryUploadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryUploadResponse.ProtoReflect.Descriptor instead.
+func (*QueryUploadResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *QueryUploadResponse) GetSizeBytes() int64 {
+	if x != nil {
+		return x.SizeBytes
+	}
+	return 0
+}
+
+func (x *QueryUploadResponse) GetReceived() []*ByteRange {
+	if x != nil {
+		return x.Received
+	}
+	return nil
+}
+
+func (x *QueryUploadResponse) GetExpiresAtUnix() int64 {
+	if x != nil {
+		return x.ExpiresAtUnix
+	}
+	return 0
+}
+
+type CommitUploadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UploadId      string                 `protobuf:"bytes,1,opt,name=upload_id,json=uploadId,proto3" json:"upload_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommitUploadRequest) Reset() {
+	*x = CommitUploadRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommitUploadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommitUploadRequest) ProtoMessage() {}
+
+func (x *CommitUploadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommitUploadRequest.ProtoReflect.Descriptor instead.
+func (*CommitUploadRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *CommitUploadRequest) GetUploadId() string {
+	if x != nil {
+		return x.UploadId
+	}
+	return ""
+}
+
+type CommitUploadResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	Cid           string                 `protobuf:"bytes,2,opt,name=cid,proto3" json:"cid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommitUploadResponse) Reset() {
+	*x = CommitUploadResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommitUploadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommitUploadResponse) ProtoMessage() {}
+
+func (x *CommitUploadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommitUploadResponse.ProtoReflect.Descriptor instead.
+func (*CommitUploadResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *CommitUploadResponse) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *CommitUploadResponse) GetCid() string {
+	if x != nil {
+		return x.Cid
+	}
+	return ""
+}
+
+// файлы принадлежат пользователю, одинаковое содержимое разных файлов хранится один раз
+type FileInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	Cid           string                 `protobuf:"bytes,2,opt,name=cid,proto3" json:"cid,omitempty"`
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Mime          string                 `protobuf:"bytes,4,opt,name=mime,proto3" json:"mime,omitempty"`
+	SizeBytes     int64                  `protobuf:"varint,5,opt,name=size_bytes,json=sizeBytes,proto3" json:"size_bytes,omitempty"`
+	CreatedAtUnix int64                  `protobuf:"varint,6,opt,name=created_at_unix,json=createdAtUnix,proto3" json:"created_at_unix,omitempty"`
+	// блоков в car по проверке при загрузке, 0 у файлов, загруженных до нее
+	BlockCount int64 `protobuf:"varint,7,opt,name=block_count,json=blockCount,proto3" json:"block_count,omitempty"`
+	// в StatFile всегда, в ListFiles при include_previews
+	Previews []*FilePreview `protobuf:"bytes,8,rep,name=previews,proto3" json:"previews,omitempty"`
+	// номер текущей версии
+	Version       int64 `protobuf:"varint,9,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileInfo) Reset() {
+	*x = FileInfo{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileInfo) ProtoMessage() {}
+
+func (x *FileInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileInfo.ProtoReflect.Descriptor instead.
+func (*FileInfo) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *FileInfo) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *FileInfo) GetCid() string {
+	if x != nil {
+		return x.Cid
+	}
+	return ""
+}
+
+func (x *FileInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *FileInfo) GetMime() string {
+	if x != nil {
+		return x.Mime
+	}
+	return ""
+}
+
+func (x *FileInfo) GetSizeBytes() int64 {
+	if x != nil {
+		return x.SizeBytes
+	}
+	return 0
+}
+
+func (x *FileInfo) GetCreatedAtUnix() int64 {
+	if x != nil {
+		return x.CreatedAtUnix
+	}
+	return 0
+}
+
+func (x *FileInfo) GetBlockCount() int64 {
+	if x != nil {
+		return x.BlockCount
+	}
+	return 0
+}
+
+func (x *FileInfo) GetPreviews() []*FilePreview {
+	if x != nil {
+		return x.Previews
+	}
+	return nil
+}
+
+func (x *FileInfo) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+// миниатюра, превью низкого разрешения или постер видео. строит и шифрует клиент,
+// wrapped_key завернут так же, как ключ файла. скачивается через GetFile по cid
+type FilePreview struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Kind          PreviewKind            `protobuf:"varint,1,opt,name=kind,proto3,enum=storage.v1.PreviewKind" json:"kind,omitempty"`
+	Cid           string                 `protobuf:"bytes,2,opt,name=cid,proto3" json:"cid,omitempty"`
+	Mime          string                 `protobuf:"bytes,3,opt,name=mime,proto3" json:"mime,omitempty"`
+	SizeBytes     int64                  `protobuf:"varint,4,opt,name=size_bytes,json=sizeBytes,proto3" json:"size_bytes,omitempty"`
+	Width         int32                  `protobuf:"varint,5,opt,name=width,proto3" json:"width,omitempty"`
+	Height        int32                  `protobuf:"varint,6,opt,name=height,proto3" json:"height,omitempty"`
+	WrappedKey    []byte                 `protobuf:"bytes,7,opt,name=wrapped_key,json=wrappedKey,proto3" json:"wrapped_key,omitempty"`
+	CreatedAtUnix int64                  `protobuf:"varint,8,opt,name=created_at_unix,json=createdAtUnix,proto3" json:"created_at_unix,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FilePreview) Reset() {
+	*x = FilePreview{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FilePreview) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FilePreview) ProtoMessage() {}
+
+func (x *FilePreview) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FilePreview.ProtoReflect.Descriptor instead.
+func (*FilePreview) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *FilePreview) GetKind() PreviewKind {
+	if x != nil {
+		return x.Kind
+	}
+	return PreviewKind_PREVIEW_KIND_UNSPECIFIED
+}
+
+func (x *FilePreview) GetCid() string {
+	if x != nil {
+		return x.Cid
+	}
+	return ""
+}
+
+func (x *FilePreview) GetMime() string {
+	if x != nil {
+		return x.Mime
+	}
+	return ""
+}
+
+func (x *FilePreview) GetSizeBytes() int64 {
+	if x != nil {
+		return x.SizeBytes
+	}
+	return 0
+}
+
+func (x *FilePreview) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *FilePreview) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *FilePreview) GetWrappedKey() []byte {
+	if x != nil {
+		return x.WrappedKey
+	}
+	return nil
+}
+
+func (x *FilePreview) GetCreatedAtUnix() int64 {
+	if x != nil {
+		return x.CreatedAtUnix
+	}
+	return 0
+}
+
+// car превью целиком в одном сообщении, не больше 2 МиБ. превью того же вида заменяется
+type PutPreviewRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	Kind          PreviewKind            `protobuf:"varint,2,opt,name=kind,proto3,enum=storage.v1.PreviewKind" json:"kind,omitempty"`
+	Mime          string                 `protobuf:"bytes,3,opt,name=mime,proto3" json:"mime,omitempty"`
+	Width         int32                  `protobuf:"varint,4,opt,name=width,proto3" json:"width,omitempty"`
+	Height        int32                  `protobuf:"varint,5,opt,name=height,proto3" json:"height,omitempty"`
+	WrappedKey    []byte                 `protobuf:"bytes,6,opt,name=wrapped_key,json=wrappedKey,proto3" json:"wrapped_key,omitempty"`
+	EncryptedCar  []byte                 `protobuf:"bytes,7,opt,name=encrypted_car,json=encryptedCar,proto3" json:"encrypted_car,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PutPreviewRequest) Reset() {
+	*x = PutPreviewRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PutPreviewRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PutPreviewRequest) ProtoMessage() {}
+
+func (x *PutPreviewRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PutPreviewRequest.ProtoReflect.Descriptor instead.
+func (*PutPreviewRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *PutPreviewRequest) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *PutPreviewRequest) GetKind() PreviewKind {
+	if x != nil {
+		return x.Kind
+	}
+	return PreviewKind_PREVIEW_KIND_UNSPECIFIED
+}
+
+func (x *PutPreviewRequest) GetMime() string {
+	if x != nil {
+		return x.Mime
+	}
+	return ""
+}
+
+func (x *PutPreviewRequest) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *PutPreviewRequest) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *PutPreviewRequest) GetWrappedKey() []byte {
+	if x != nil {
+		return x.WrappedKey
+	}
+	return nil
+}
+
+func (x *PutPreviewRequest) GetEncryptedCar() []byte {
+	if x != nil {
+		return x.EncryptedCar
+	}
+	return nil
+}
+
+type PutPreviewResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Preview       *FilePreview           `protobuf:"bytes,1,opt,name=preview,proto3" json:"preview,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PutPreviewResponse) Reset() {
+	*x = PutPreviewResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PutPreviewResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PutPreviewResponse) ProtoMessage() {}
+
+func (x *PutPreviewResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PutPreviewResponse.ProtoReflect.Descriptor instead.
+func (*PutPreviewResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *PutPreviewResponse) GetPreview() *FilePreview {
+	if x != nil {
+		return x.Preview
+	}
+	return nil
+}
+
+type DeletePreviewRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	Kind          PreviewKind            `protobuf:"varint,2,opt,name=kind,proto3,enum=storage.v1.PreviewKind" json:"kind,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletePreviewRequest) Reset() {
+	*x = DeletePreviewRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletePreviewRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletePreviewRequest) ProtoMessage() {}
+
+func (x *DeletePreviewRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletePreviewRequest.ProtoReflect.Descriptor instead.
+func (*DeletePreviewRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *DeletePreviewRequest) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *DeletePreviewRequest) GetKind() PreviewKind {
+	if x != nil {
+		return x.Kind
+	}
+	return PreviewKind_PREVIEW_KIND_UNSPECIFIED
+}
+
+type DeletePreviewResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Deleted       bool                   `protobuf:"varint,1,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletePreviewResponse) Reset() {
+	*x = DeletePreviewResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletePreviewResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletePreviewResponse) ProtoMessage() {}
+
+func (x *DeletePreviewResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletePreviewResponse.ProtoReflect.Descriptor instead.
+func (*DeletePreviewResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *DeletePreviewResponse) GetDeleted() bool {
+	if x != nil {
+		return x.Deleted
+	}
+	return false
+}
+
+type FileVersion struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Version   int64                  `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Cid       string                 `protobuf:"bytes,2,opt,name=cid,proto3" json:"cid,omitempty"`
+	SizeBytes int64                  `protobuf:"varint,3,opt,name=size_bytes,json=sizeBytes,proto3" json:"size_bytes,omitempty"`
+	// blake3 car, пустой у версий, загруженных до истории
+	Blake3        []byte `protobuf:"bytes,4,opt,name=blake3,proto3" json:"blake3,omitempty"`
+	DeviceId      string `protobuf:"bytes,5,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	CreatedAtUnix int64  `protobuf:"varint,6,opt,name=created_at_unix,json=createdAtUnix,proto3" json:"created_at_unix,omitempty"`
+	Current       bool   `protobuf:"varint,7,opt,name=current,proto3" json:"current,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileVersion) Reset() {
+	*x = FileVersion{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileVersion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileVersion) ProtoMessage() {}
+
+func (x *FileVersion) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileVersion.ProtoReflect.Descriptor instead.
+func (*FileVersion) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *FileVersion) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *FileVersion) GetCid() string {
+	if x != nil {
+		return x.Cid
+	}
+	return ""
+}
+
+func (x *FileVersion) GetSizeBytes() int64 {
+	if x != nil {
+		return x.SizeBytes
+	}
+	return 0
+}
+
+func (x *FileVersion) GetBlake3() []byte {
+	if x != nil {
+		return x.Blake3
+	}
+	return nil
+}
+
+func (x *FileVersion) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *FileVersion) GetCreatedAtUnix() int64 {
+	if x != nil {
+		return x.CreatedAtUnix
+	}
+	return 0
+}
+
+func (x *FileVersion) GetCurrent() bool {
+	if x != nil {
+		return x.Current
+	}
+	return false
+}
+
+type ListVersionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListVersionsRequest) Reset() {
+	*x = ListVersionsRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListVersionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListVersionsRequest) ProtoMessage() {}
+
+func (x *ListVersionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListVersionsRequest.ProtoReflect.Descriptor instead.
+func (*ListVersionsRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *ListVersionsRequest) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+type ListVersionsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// новые сначала
+	Versions      []*FileVersion `protobuf:"bytes,1,rep,name=versions,proto3" json:"versions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListVersionsResponse) Reset() {
+	*x = ListVersionsResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListVersionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListVersionsResponse) ProtoMessage() {}
+
+func (x *ListVersionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListVersionsResponse.ProtoReflect.Descriptor instead.
+func (*ListVersionsResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ListVersionsResponse) GetVersions() []*FileVersion {
+	if x != nil {
+		return x.Versions
+	}
+	return nil
+}
+
+type RestoreVersionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	Version       int64                  `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	DeviceId      string                 `protobuf:"bytes,3,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreVersionRequest) Reset() {
+	*x = RestoreVersionRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreVersionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreVersionRequest) ProtoMessage() {}
+
+func (x *RestoreVersionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreVersionRequest.ProtoReflect.Descriptor instead.
+func (*RestoreVersionRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *RestoreVersionRequest) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *RestoreVersionRequest) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *RestoreVersionRequest) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+type RestoreVersionResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	File  *FileInfo              `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`
+	// новая версия с содержимым восстановленной
+	Version       *FileVersion `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreVersionResponse) Reset() {
+	*x = RestoreVersionResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreVersionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreVersionResponse) ProtoMessage() {}
+
+func (x *RestoreVersionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreVersionResponse.ProtoReflect.Descriptor instead.
+func (*RestoreVersionResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *RestoreVersionResponse) GetFile() *FileInfo {
+	if x != nil {
+		return x.File
+	}
+	return nil
+}
+
+func (x *RestoreVersionResponse) GetVersion() *FileVersion {
+	if x != nil {
+		return x.Version
+	}
+	return nil
+}
+
+// новые сначала. mime_prefix вида "image/", границы дат включительно, 0 - без границы
+type ListFilesRequest struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	PageSize          int32                  `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	PageToken         string                 `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	MimePrefix        string                 `protobuf:"bytes,3,opt,name=mime_prefix,json=mimePrefix,proto3" json:"mime_prefix,omitempty"`
+	CreatedAfterUnix  int64                  `protobuf:"varint,4,opt,name=created_after_unix,json=createdAfterUnix,proto3" json:"created_after_unix,omitempty"`
+	CreatedBeforeUnix int64                  `protobuf:"varint,5,opt,name=created_before_unix,json=createdBeforeUnix,proto3" json:"created_before_unix,omitempty"`
+	IncludePreviews   bool                   `protobuf:"varint,6,opt,name=include_previews,json=includePreviews,proto3" json:"include_previews,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *ListFilesRequest) Reset() {
+	*x = ListFilesRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFilesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFilesRequest) ProtoMessage() {}
+
+func (x *ListFilesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFilesRequest.ProtoReflect.Descriptor instead.
+func (*ListFilesRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *ListFilesRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListFilesRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListFilesRequest) GetMimePrefix() string {
+	if x != nil {
+		return x.MimePrefix
+	}
+	return ""
+}
+
+func (x *ListFilesRequest) GetCreatedAfterUnix() int64 {
+	if x != nil {
+		return x.CreatedAfterUnix
+	}
+	return 0
+}
+
+func (x *ListFilesRequest) GetCreatedBeforeUnix() int64 {
+	if x != nil {
+		return x.CreatedBeforeUnix
+	}
+	return 0
+}
+
+func (x *ListFilesRequest) GetIncludePreviews() bool {
+	if x != nil {
+		return x.IncludePreviews
+	}
+	return false
+}
+
+type ListFilesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Files         []*FileInfo            `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFilesResponse) Reset() {
+	*x = ListFilesResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFilesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFilesResponse) ProtoMessage() {}
+
+func (x *ListFilesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFilesResponse.ProtoReflect.Descriptor instead.
+func (*ListFilesResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *ListFilesResponse) GetFiles() []*FileInfo {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+func (x *ListFilesResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type StatFileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatFileRequest) Reset() {
+	*x = StatFileRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatFileRequest) ProtoMessage() {}
+
+func (x *StatFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatFileRequest.ProtoReflect.Descriptor instead.
+func (*StatFileRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *StatFileRequest) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+type StatFileResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	File          *FileInfo              `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatFileResponse) Reset() {
+	*x = StatFileResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatFileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatFileResponse) ProtoMessage() {}
+
+func (x *StatFileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatFileResponse.ProtoReflect.Descriptor instead.
+func (*StatFileResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *StatFileResponse) GetFile() *FileInfo {
+	if x != nil {
+		return x.File
+	}
+	return nil
+}
+
+type RenameFileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenameFileRequest) Reset() {
+	*x = RenameFileRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenameFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenameFileRequest) ProtoMessage() {}
+
+func (x *RenameFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenameFileRequest.ProtoReflect.Descriptor instead.
+func (*RenameFileRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *RenameFileRequest) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *RenameFileRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type RenameFileResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	File          *FileInfo              `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenameFileResponse) Reset() {
+	*x = RenameFileResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenameFileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenameFileResponse) ProtoMessage() {}
+
+func (x *RenameFileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenameFileResponse.ProtoReflect.Descriptor instead.
+func (*RenameFileResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *RenameFileResponse) GetFile() *FileInfo {
+	if x != nil {
+		return x.File
+	}
+	return nil
+}
+
+type DeleteFileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteFileRequest) Reset() {
+	*x = DeleteFileRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteFileRequest) ProtoMessage() {}
+
+func (x *DeleteFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteFileRequest.ProtoReflect.Descriptor instead.
+func (*DeleteFileRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *DeleteFileRequest) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+type DeleteFileResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Deleted       bool                   `protobuf:"varint,1,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteFileResponse) Reset() {
+	*x = DeleteFileResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteFileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteFileResponse) ProtoMessage() {}
+
+func (x *DeleteFileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteFileResponse.ProtoReflect.Descriptor instead.
+func (*DeleteFileResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *DeleteFileResponse) GetDeleted() bool {
+	if x != nil {
+		return x.Deleted
+	}
+	return false
+}
+
+type Node struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	ParentId      string                 `protobuf:"bytes,2,opt,name=parent_id,json=parentId,proto3" json:"parent_id,omitempty"`
+	Kind          NodeKind               `protobuf:"varint,3,opt,name=kind,proto3,enum=storage.v1.NodeKind" json:"kind,omitempty"`
+	FileId        string                 `protobuf:"bytes,4,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	NameBlob      []byte                 `protobuf:"bytes,5,opt,name=name_blob,json=nameBlob,proto3" json:"name_blob,omitempty"`
+	NameHash      []byte                 `protobuf:"bytes,6,opt,name=name_hash,json=nameHash,proto3" json:"name_hash,omitempty"`
+	Version       int64                  `protobuf:"varint,7,opt,name=version,proto3" json:"version,omitempty"`
+	CreatedAtUnix int64                  `protobuf:"varint,8,opt,name=created_at_unix,json=createdAtUnix,proto3" json:"created_at_unix,omitempty"`
+	UpdatedAtUnix int64                  `protobuf:"varint,9,opt,name=updated_at_unix,json=updatedAtUnix,proto3" json:"updated_at_unix,omitempty"`
+	TrashedAtUnix int64                  `protobuf:"varint,10,opt,name=trashed_at_unix,json=trashedAtUnix,proto3" json:"trashed_at_unix,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Node) Reset() {
+	*x = Node{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Node) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Node) ProtoMessage() {}
+
+func (x *Node) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Node.ProtoReflect.Descriptor instead.
+func (*Node) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *Node) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *Node) GetParentId() string {
+	if x != nil {
+		return x.ParentId
+	}
+	return ""
+}
+
+func (x *Node) GetKind() NodeKind {
+	if x != nil {
+		return x.Kind
+	}
+	return NodeKind_NODE_KIND_UNSPECIFIED
+}
+
+func (x *Node) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *Node) GetNameBlob() []byte {
+	if x != nil {
+		return x.NameBlob
+	}
+	return nil
+}
+
+func (x *Node) GetNameHash() []byte {
+	if x != nil {
+		return x.NameHash
+	}
+	return nil
+}
+
+func (x *Node) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Node) GetCreatedAtUnix() int64 {
+	if x != nil {
+		return x.CreatedAtUnix
+	}
+	return 0
+}
+
+func (x *Node) GetUpdatedAtUnix() int64 {
+	if x != nil {
+		return x.UpdatedAtUnix
+	}
+	return 0
+}
+
+func (x *Node) GetTrashedAtUnix() int64 {
+	if x != nil {
+		return x.TrashedAtUnix
+	}
+	return 0
+}
+
+type MkDirRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ParentId      string                 `protobuf:"bytes,1,opt,name=parent_id,json=parentId,proto3" json:"parent_id,omitempty"`
+	NameBlob      []byte                 `protobuf:"bytes,2,opt,name=name_blob,json=nameBlob,proto3" json:"name_blob,omitempty"`
+	NameHash      []byte                 `protobuf:"bytes,3,opt,name=name_hash,json=nameHash,proto3" json:"name_hash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MkDirRequest) Reset() {
+	*x = MkDirRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MkDirRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MkDirRequest) ProtoMessage() {}
+
+func (x *MkDirRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MkDirRequest.ProtoReflect.Descriptor instead.
+func (*MkDirRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *MkDirRequest) GetParentId() string {
+	if x != nil {
+		return x.ParentId
+	}
+	return ""
+}
+
+func (x *MkDirRequest) GetNameBlob() []byte {
+	if x != nil {
+		return x.NameBlob
+	}
+	return nil
+}
+
+func (x *MkDirRequest) GetNameHash() []byte {
+	if x != nil {
+		return x.NameHash
+	}
+	return nil
+}
+
+type MkDirResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Node          *Node                  `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MkDirResponse) Reset() {
+	*x = MkDirResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MkDirResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MkDirResponse) ProtoMessage() {}
+
+func (x *MkDirResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MkDirResponse.ProtoReflect.Descriptor instead.
+func (*MkDirResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *MkDirResponse) GetNode() *Node {
+	if x != nil {
+		return x.Node
+	}
+	return nil
+}
+
+type LinkFileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	ParentId      string                 `protobuf:"bytes,2,opt,name=parent_id,json=parentId,proto3" json:"parent_id,omitempty"`
+	NameBlob      []byte                 `protobuf:"bytes,3,opt,name=name_blob,json=nameBlob,proto3" json:"name_blob,omitempty"`
+	NameHash      []byte                 `protobuf:"bytes,4,opt,name=name_hash,json=nameHash,proto3" json:"name_hash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LinkFileRequest) Reset() {
+	*x = LinkFileRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LinkFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LinkFileRequest) ProtoMessage() {}
+
+func (x *LinkFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LinkFileRequest.ProtoReflect.Descriptor instead.
+func (*LinkFileRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *LinkFileRequest) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *LinkFileRequest) GetParentId() string {
+	if x != nil {
+		return x.ParentId
+	}
+	return ""
+}
+
+func (x *LinkFileRequest) GetNameBlob() []byte {
+	if x != nil {
+		return x.NameBlob
+	}
+	return nil
+}
+
+func (x *LinkFileRequest) GetNameHash() []byte {
+	if x != nil {
+		return x.NameHash
+	}
+	return nil
+}
+
+type LinkFileResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Node          *Node                  `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LinkFileResponse) Reset() {
+	*x = LinkFileResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LinkFileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LinkFileResponse) ProtoMessage() {}
+
+func (x *LinkFileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LinkFileResponse.ProtoReflect.Descriptor instead.
+func (*LinkFileResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *LinkFileResponse) GetNode() *Node {
+	if x != nil {
+		return x.Node
+	}
+	return nil
+}
+
+// пустое имя оставляет текущее, expected_version 0 отключает проверку версии
+type MoveRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	NodeId          string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	NewParentId     string                 `protobuf:"bytes,2,opt,name=new_parent_id,json=newParentId,proto3" json:"new_parent_id,omitempty"`
+	NameBlob        []byte                 `protobuf:"bytes,3,opt,name=name_blob,json=nameBlob,proto3" json:"name_blob,omitempty"`
+	NameHash        []byte                 `protobuf:"bytes,4,opt,name=name_hash,json=nameHash,proto3" json:"name_hash,omitempty"`
+	ExpectedVersion int64                  `protobuf:"varint,5,opt,name=expected_version,json=expectedVersion,proto3" json:"expected_version,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *MoveRequest) Reset() {
+	*x = MoveRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MoveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MoveRequest) ProtoMessage() {}
+
+func (x *MoveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MoveRequest.ProtoReflect.Descriptor instead.
+func (*MoveRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *MoveRequest) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *MoveRequest) GetNewParentId() string {
+	if x != nil {
+		return x.NewParentId
+	}
+	return ""
+}
+
+func (x *MoveRequest) GetNameBlob() []byte {
+	if x != nil {
+		return x.NameBlob
+	}
+	return nil
+}
+
+func (x *MoveRequest) GetNameHash() []byte {
+	if x != nil {
+		return x.NameHash
+	}
+	return nil
+}
+
+func (x *MoveRequest) GetExpectedVersion() int64 {
+	if x != nil {
+		return x.ExpectedVersion
+	}
+	return 0
+}
+
+type MoveResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Node          *Node                  `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MoveResponse) Reset() {
+	*x = MoveResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MoveResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MoveResponse) ProtoMessage() {}
+
+func (x *MoveResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MoveResponse.ProtoReflect.Descriptor instead.
+func (*MoveResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *MoveResponse) GetNode() *Node {
+	if x != nil {
+		return x.Node
+	}
+	return nil
+}
+
+// trash выводит содержимое корзины вместо каталога
+type ListDirRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ParentId      string                 `protobuf:"bytes,1,opt,name=parent_id,json=parentId,proto3" json:"parent_id,omitempty"`
+	PageSize      int32                  `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	PageToken     string                 `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	Trash         bool                   `protobuf:"varint,4,opt,name=trash,proto3" json:"trash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDirRequest) Reset() {
+	*x = ListDirRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDirRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDirRequest) ProtoMessage() {}
+
+func (x *ListDirRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDirRequest.ProtoReflect.Descriptor instead.
+func (*ListDirRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *ListDirRequest) GetParentId() string {
+	if x != nil {
+		return x.ParentId
+	}
+	return ""
+}
+
+func (x *ListDirRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListDirRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListDirRequest) GetTrash() bool {
+	if x != nil {
+		return x.Trash
+	}
+	return false
+}
+
+type ListDirResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Nodes         []*Node                `protobuf:"bytes,1,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDirResponse) Reset() {
+	*x = ListDirResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDirResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDirResponse) ProtoMessage() {}
+
+func (x *ListDirResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[44]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDirResponse.ProtoReflect.Descriptor instead.
+func (*ListDirResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *ListDirResponse) GetNodes() []*Node {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+func (x *ListDirResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type ResolvePathRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NameHashes    [][]byte               `protobuf:"bytes,1,rep,name=name_hashes,json=nameHashes,proto3" json:"name_hashes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResolvePathRequest) Reset() {
+	*x = ResolvePathRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResolvePathRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResolvePathRequest) ProtoMessage() {}
+
+func (x *ResolvePathRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResolvePathRequest.ProtoReflect.Descriptor instead.
+func (*ResolvePathRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *ResolvePathRequest) GetNameHashes() [][]byte {
+	if x != nil {
+		return x.NameHashes
+	}
+	return nil
+}
+
+type ResolvePathResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Node          *Node                  `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResolvePathResponse) Reset() {
+	*x = ResolvePathResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[46]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResolvePathResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResolvePathResponse) ProtoMessage() {}
+
+func (x *ResolvePathResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[46]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResolvePathResponse.ProtoReflect.Descriptor instead.
+func (*ResolvePathResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *ResolvePathResponse) GetNode() *Node {
+	if x != nil {
+		return x.Node
+	}
+	return nil
+}
+
+type TrashRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	NodeId          string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	ExpectedVersion int64                  `protobuf:"varint,2,opt,name=expected_version,json=expectedVersion,proto3" json:"expected_version,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *TrashRequest) Reset() {
+	*x = TrashRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[47]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TrashRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TrashRequest) ProtoMessage() {}
+
+func (x *TrashRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[47]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TrashRequest.ProtoReflect.Descriptor instead.
+func (*TrashRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{47}
+}
+
+func (x *TrashRequest) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *TrashRequest) GetExpectedVersion() int64 {
+	if x != nil {
+		return x.ExpectedVersion
+	}
+	return 0
+}
+
+type TrashResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Node          *Node                  `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TrashResponse) Reset() {
+	*x = TrashResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[48]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TrashResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TrashResponse) ProtoMessage() {}
+
+func (x *TrashResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[48]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TrashResponse.ProtoReflect.Descriptor instead.
+func (*TrashResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{48}
+}
+
+func (x *TrashResponse) GetNode() *Node {
+	if x != nil {
+		return x.Node
+	}
+	return nil
+}
+
+// новое имя нужно, если прежнее место уже занято
+type RestoreRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	NameBlob      []byte                 `protobuf:"bytes,2,opt,name=name_blob,json=nameBlob,proto3" json:"name_blob,omitempty"`
+	NameHash      []byte                 `protobuf:"bytes,3,opt,name=name_hash,json=nameHash,proto3" json:"name_hash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreRequest) Reset() {
+	*x = RestoreRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[49]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreRequest) ProtoMessage() {}
+
+func (x *RestoreRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[49]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreRequest.ProtoReflect.Descriptor instead.
+func (*RestoreRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{49}
+}
+
+func (x *RestoreRequest) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *RestoreRequest) GetNameBlob() []byte {
+	if x != nil {
+		return x.NameBlob
+	}
+	return nil
+}
+
+func (x *RestoreRequest) GetNameHash() []byte {
+	if x != nil {
+		return x.NameHash
+	}
+	return nil
+}
+
+type RestoreResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Node          *Node                  `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreResponse) Reset() {
+	*x = RestoreResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[50]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreResponse) ProtoMessage() {}
+
+func (x *RestoreResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[50]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreResponse.ProtoReflect.Descriptor instead.
+func (*RestoreResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{50}
+}
+
+func (x *RestoreResponse) GetNode() *Node {
+	if x != nil {
+		return x.Node
+	}
+	return nil
+}
+
+type ShareGrant struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GrantId       string                 `protobuf:"bytes,1,opt,name=grant_id,json=grantId,proto3" json:"grant_id,omitempty"`
+	OwnerId       string                 `protobuf:"bytes,2,opt,name=owner_id,json=ownerId,proto3" json:"owner_id,omitempty"`
+	FileId        string                 `protobuf:"bytes,3,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	NodeId        string                 `protobuf:"bytes,4,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	GranteeKind   GranteeKind            `protobuf:"varint,5,opt,name=grantee_kind,json=granteeKind,proto3,enum=storage.v1.GranteeKind" json:"grantee_kind,omitempty"`
+	GranteeId     string                 `protobuf:"bytes,6,opt,name=grantee_id,json=granteeId,proto3" json:"grantee_id,omitempty"`
+	WrappedKey    []byte                 `protobuf:"bytes,7,opt,name=wrapped_key,json=wrappedKey,proto3" json:"wrapped_key,omitempty"`
+	CreatedAtUnix int64                  `protobuf:"varint,8,opt,name=created_at_unix,json=createdAtUnix,proto3" json:"created_at_unix,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShareGrant) Reset() {
+	*x = ShareGrant{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[51]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShareGrant) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShareGrant) ProtoMessage() {}
+
+func (x *ShareGrant) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[51]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShareGrant.ProtoReflect.Descriptor instead.
+func (*ShareGrant) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{51}
+}
+
+func (x *ShareGrant) GetGrantId() string {
+	if x != nil {
+		return x.GrantId
+	}
+	return ""
+}
+
+func (x *ShareGrant) GetOwnerId() string {
+	if x != nil {
+		return x.OwnerId
+	}
+	return ""
+}
+
+func (x *ShareGrant) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *ShareGrant) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *ShareGrant) GetGranteeKind() GranteeKind {
+	if x != nil {
+		return x.GranteeKind
+	}
+	return GranteeKind_GRANTEE_KIND_UNSPECIFIED
+}
+
+func (x *ShareGrant) GetGranteeId() string {
+	if x != nil {
+		return x.GranteeId
+	}
+	return ""
+}
+
+func (x *ShareGrant) GetWrappedKey() []byte {
+	if x != nil {
+		return x.WrappedKey
+	}
+	return nil
+}
+
+func (x *ShareGrant) GetCreatedAtUnix() int64 {
+	if x != nil {
+		return x.CreatedAtUnix
+	}
+	return 0
+}
+
+type ShareRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	NodeId        string                 `protobuf:"bytes,2,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	GranteeKind   GranteeKind            `protobuf:"varint,3,opt,name=grantee_kind,json=granteeKind,proto3,enum=storage.v1.GranteeKind" json:"grantee_kind,omitempty"`
+	GranteeId     string                 `protobuf:"bytes,4,opt,name=grantee_id,json=granteeId,proto3" json:"grantee_id,omitempty"`
+	WrappedKey    []byte                 `protobuf:"bytes,5,opt,name=wrapped_key,json=wrappedKey,proto3" json:"wrapped_key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShareRequest) Reset() {
+	*x = ShareRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[52]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShareRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShareRequest) ProtoMessage() {}
+
+func (x *ShareRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[52]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShareRequest.ProtoReflect.Descriptor instead.
+func (*ShareRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{52}
+}
+
+func (x *ShareRequest) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *ShareRequest) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *ShareRequest) GetGranteeKind() GranteeKind {
+	if x != nil {
+		return x.GranteeKind
+	}
+	return GranteeKind_GRANTEE_KIND_UNSPECIFIED
+}
+
+func (x *ShareRequest) GetGranteeId() string {
+	if x != nil {
+		return x.GranteeId
+	}
+	return ""
+}
+
+func (x *ShareRequest) GetWrappedKey() []byte {
+	if x != nil {
+		return x.WrappedKey
+	}
+	return nil
+}
+
+type ShareResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Grant         *ShareGrant            `protobuf:"bytes,1,opt,name=grant,proto3" json:"grant,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShareResponse) Reset() {
+	*x = ShareResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[53]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShareResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShareResponse) ProtoMessage() {}
+
+func (x *ShareResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[53]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShareResponse.ProtoReflect.Descriptor instead.
+func (*ShareResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{53}
+}
+
+func (x *ShareResponse) GetGrant() *ShareGrant {
+	if x != nil {
+		return x.Grant
+	}
+	return nil
+}
+
+type RevokeShareRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GrantId       string                 `protobuf:"bytes,1,opt,name=grant_id,json=grantId,proto3" json:"grant_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokeShareRequest) Reset() {
+	*x = RevokeShareRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[54]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevokeShareRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevokeShareRequest) ProtoMessage() {}
+
+func (x *RevokeShareRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[54]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevokeShareRequest.ProtoReflect.Descriptor instead.
+func (*RevokeShareRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{54}
+}
+
+func (x *RevokeShareRequest) GetGrantId() string {
+	if x != nil {
+		return x.GrantId
+	}
+	return ""
+}
+
+type RevokeShareResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Revoked       bool                   `protobuf:"varint,1,opt,name=revoked,proto3" json:"revoked,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokeShareResponse) Reset() {
+	*x = RevokeShareResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[55]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevokeShareResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevokeShareResponse) ProtoMessage() {}
+
+func (x *RevokeShareResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[55]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevokeShareResponse.ProtoReflect.Descriptor instead.
+func (*RevokeShareResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{55}
+}
+
+func (x *RevokeShareResponse) GetRevoked() bool {
+	if x != nil {
+		return x.Revoked
+	}
+	return false
+}
+
+// без цели и shared_with_me выводятся все выданные пользователем доступы
+type ListSharesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	NodeId        string                 `protobuf:"bytes,2,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	SharedWithMe  bool                   `protobuf:"varint,3,opt,name=shared_with_me,json=sharedWithMe,proto3" json:"shared_with_me,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSharesRequest) Reset() {
+	*x = ListSharesRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[56]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSharesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSharesRequest) ProtoMessage() {}
+
+func (x *ListSharesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[56]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSharesRequest.ProtoReflect.Descriptor instead.
+func (*ListSharesRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{56}
+}
+
+func (x *ListSharesRequest) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *ListSharesRequest) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *ListSharesRequest) GetSharedWithMe() bool {
+	if x != nil {
+		return x.SharedWithMe
+	}
+	return false
+}
+
+type ListSharesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Grants        []*ShareGrant          `protobuf:"bytes,1,rep,name=grants,proto3" json:"grants,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSharesResponse) Reset() {
+	*x = ListSharesResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[57]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSharesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSharesResponse) ProtoMessage() {}
+
+func (x *ListSharesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[57]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSharesResponse.ProtoReflect.Descriptor instead.
+func (*ListSharesResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{57}
+}
+
+func (x *ListSharesResponse) GetGrants() []*ShareGrant {
+	if x != nil {
+		return x.Grants
+	}
+	return nil
+}
+
+// публичная ссылка. ключ расшифровки сервер не видит: клиент кладет его во фрагмент url
+// после токена. нулевые expires_at_unix и max_downloads снимают ограничения
+type ShareLink struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	LinkId        string                 `protobuf:"bytes,1,opt,name=link_id,json=linkId,proto3" json:"link_id,omitempty"`
+	FileId        string                 `protobuf:"bytes,2,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	NodeId        string                 `protobuf:"bytes,3,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	HasPassword   bool                   `protobuf:"varint,4,opt,name=has_password,json=hasPassword,proto3" json:"has_password,omitempty"`
+	ExpiresAtUnix int64                  `protobuf:"varint,5,opt,name=expires_at_unix,json=expiresAtUnix,proto3" json:"expires_at_unix,omitempty"`
+	MaxDownloads  int32                  `protobuf:"varint,6,opt,name=max_downloads,json=maxDownloads,proto3" json:"max_downloads,omitempty"`
+	Downloads     int32                  `protobuf:"varint,7,opt,name=downloads,proto3" json:"downloads,omitempty"`
+	CreatedAtUnix int64                  `protobuf:"varint,8,opt,name=created_at_unix,json=createdAtUnix,proto3" json:"created_at_unix,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShareLink) Reset() {
+	*x = ShareLink{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[58]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShareLink) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShareLink) ProtoMessage() {}
+
+func (x *ShareLink) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[58]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShareLink.ProtoReflect.Descriptor instead.
+func (*ShareLink) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{58}
+}
+
+func (x *ShareLink) GetLinkId() string {
+	if x != nil {
+		return x.LinkId
+	}
+	return ""
+}
+
+func (x *ShareLink) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *ShareLink) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *ShareLink) GetHasPassword() bool {
+	if x != nil {
+		return x.HasPassword
+	}
+	return false
+}
+
+func (x *ShareLink) GetExpiresAtUnix() int64 {
+	if x != nil {
+		return x.ExpiresAtUnix
+	}
+	return 0
+}
+
+func (x *ShareLink) GetMaxDownloads() int32 {
+	if x != nil {
+		return x.MaxDownloads
+	}
+	return 0
+}
+
+func (x *ShareLink) GetDownloads() int32 {
+	if x != nil {
+		return x.Downloads
+	}
+	return 0
+}
+
+func (x *ShareLink) GetCreatedAtUnix() int64 {
+	if x != nil {
+		return x.CreatedAtUnix
+	}
+	return 0
+}
+
+type CreateLinkRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileId        string                 `protobuf:"bytes,1,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	NodeId        string                 `protobuf:"bytes,2,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Password      string                 `protobuf:"bytes,3,opt,name=password,proto3" json:"password,omitempty"`
+	ExpiresAtUnix int64                  `protobuf:"varint,4,opt,name=expires_at_unix,json=expiresAtUnix,proto3" json:"expires_at_unix,omitempty"`
+	MaxDownloads  int32                  `protobuf:"varint,5,opt,name=max_downloads,json=maxDownloads,proto3" json:"max_downloads,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateLinkRequest) Reset() {
+	*x = CreateLinkRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[59]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateLinkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateLinkRequest) ProtoMessage() {}
+
+func (x *CreateLinkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[59]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateLinkRequest.ProtoReflect.Descriptor instead.
+func (*CreateLinkRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{59}
+}
+
+func (x *CreateLinkRequest) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+func (x *CreateLinkRequest) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *CreateLinkRequest) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *CreateLinkRequest) GetExpiresAtUnix() int64 {
+	if x != nil {
+		return x.ExpiresAtUnix
+	}
+	return 0
+}
+
+func (x *CreateLinkRequest) GetMaxDownloads() int32 {
+	if x != nil {
+		return x.MaxDownloads
+	}
+	return 0
+}
+
+// token возвращается только здесь, сервер хранит лишь его хеш
+type CreateLinkResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Link          *ShareLink             `protobuf:"bytes,1,opt,name=link,proto3" json:"link,omitempty"`
+	Token         string                 `protobuf:"bytes,2,opt,name=token,proto3" json:"token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateLinkResponse) Reset() {
+	*x = CreateLinkResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[60]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateLinkResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateLinkResponse) ProtoMessage() {}
+
+func (x *CreateLinkResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[60]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateLinkResponse.ProtoReflect.Descriptor instead.
+func (*CreateLinkResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{60}
+}
+
+func (x *CreateLinkResponse) GetLink() *ShareLink {
+	if x != nil {
+		return x.Link
+	}
+	return nil
+}
+
+func (x *CreateLinkResponse) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+type RevokeLinkRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	LinkId        string                 `protobuf:"bytes,1,opt,name=link_id,json=linkId,proto3" json:"link_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokeLinkRequest) Reset() {
+	*x = RevokeLinkRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[61]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevokeLinkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevokeLinkRequest) ProtoMessage() {}
+
+func (x *RevokeLinkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[61]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevokeLinkRequest.ProtoReflect.Descriptor instead.
+func (*RevokeLinkRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{61}
+}
+
+func (x *RevokeLinkRequest) GetLinkId() string {
+	if x != nil {
+		return x.LinkId
+	}
+	return ""
+}
+
+type RevokeLinkResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Revoked       bool                   `protobuf:"varint,1,opt,name=revoked,proto3" json:"revoked,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokeLinkResponse) Reset() {
+	*x = RevokeLinkResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[62]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevokeLinkResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevokeLinkResponse) ProtoMessage() {}
+
+func (x *RevokeLinkResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[62]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevokeLinkResponse.ProtoReflect.Descriptor instead.
+func (*RevokeLinkResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{62}
+}
+
+func (x *RevokeLinkResponse) GetRevoked() bool {
+	if x != nil {
+		return x.Revoked
+	}
+	return false
+}
+
+type ListLinksRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLinksRequest) Reset() {
+	*x = ListLinksRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[63]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLinksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLinksRequest) ProtoMessage() {}
+
+func (x *ListLinksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[63]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLinksRequest.ProtoReflect.Descriptor instead.
+func (*ListLinksRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{63}
+}
+
+type ListLinksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Links         []*ShareLink           `protobuf:"bytes,1,rep,name=links,proto3" json:"links,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLinksResponse) Reset() {
+	*x = ListLinksResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[64]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLinksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLinksResponse) ProtoMessage() {}
+
+func (x *ListLinksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[64]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLinksResponse.ProtoReflect.Descriptor instead.
+func (*ListLinksResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{64}
+}
+
+func (x *ListLinksResponse) GetLinks() []*ShareLink {
+	if x != nil {
+		return x.Links
+	}
+	return nil
+}
+
+// для ссылки на файл приходит file, для ссылки на каталог - страница его содержимого.
+// parent_id выбирает подкаталог внутри общего каталога, пустой значит сам каталог
+type ResolveLinkRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Token         string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	Password      string                 `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	ParentId      string                 `protobuf:"bytes,3,opt,name=parent_id,json=parentId,proto3" json:"parent_id,omitempty"`
+	PageSize      int32                  `protobuf:"varint,4,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	PageToken     string                 `protobuf:"bytes,5,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResolveLinkRequest) Reset() {
+	*x = ResolveLinkRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[65]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResolveLinkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResolveLinkRequest) ProtoMessage() {}
+
+func (x *ResolveLinkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[65]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResolveLinkRequest.ProtoReflect.Descriptor instead.
+func (*ResolveLinkRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{65}
+}
+
+func (x *ResolveLinkRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *ResolveLinkRequest) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *ResolveLinkRequest) GetParentId() string {
+	if x != nil {
+		return x.ParentId
+	}
+	return ""
+}
+
+func (x *ResolveLinkRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ResolveLinkRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type ResolveLinkResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Link          *ShareLink             `protobuf:"bytes,1,opt,name=link,proto3" json:"link,omitempty"`
+	File          *FileInfo              `protobuf:"bytes,2,opt,name=file,proto3" json:"file,omitempty"`
+	Nodes         []*Node                `protobuf:"bytes,3,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	Files         []*FileInfo            `protobuf:"bytes,4,rep,name=files,proto3" json:"files,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,5,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResolveLinkResponse) Reset() {
+	*x = ResolveLinkResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[66]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResolveLinkResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResolveLinkResponse) ProtoMessage() {}
+
+func (x *ResolveLinkResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[66]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResolveLinkResponse.ProtoReflect.Descriptor instead.
+func (*ResolveLinkResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{66}
+}
+
+func (x *ResolveLinkResponse) GetLink() *ShareLink {
+	if x != nil {
+		return x.Link
+	}
+	return nil
+}
+
+func (x *ResolveLinkResponse) GetFile() *FileInfo {
+	if x != nil {
+		return x.File
+	}
+	return nil
+}
+
+func (x *ResolveLinkResponse) GetNodes() []*Node {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+func (x *ResolveLinkResponse) GetFiles() []*FileInfo {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+func (x *ResolveLinkResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+// квота пользователя. used_bytes считается по принятым байтам car, одинаковое содержимое
+// учитывается один раз. limit_bytes 0 значит без лимита, reserved_bytes - незавершенные загрузки
+type GetUsageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUsageRequest) Reset() {
+	*x = GetUsageRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[67]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUsageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUsageRequest) ProtoMessage() {}
+
+func (x *GetUsageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[67]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUsageRequest.ProtoReflect.Descriptor instead.
+func (*GetUsageRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{67}
+}
+
+type GetUsageResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Plan          string                 `protobuf:"bytes,1,opt,name=plan,proto3" json:"plan,omitempty"`
+	LimitBytes    int64                  `protobuf:"varint,2,opt,name=limit_bytes,json=limitBytes,proto3" json:"limit_bytes,omitempty"`
+	UsedBytes     int64                  `protobuf:"varint,3,opt,name=used_bytes,json=usedBytes,proto3" json:"used_bytes,omitempty"`
+	ReservedBytes int64                  `protobuf:"varint,4,opt,name=reserved_bytes,json=reservedBytes,proto3" json:"reserved_bytes,omitempty"`
+	Files         int64                  `protobuf:"varint,5,opt,name=files,proto3" json:"files,omitempty"`
+	Contents      int64                  `protobuf:"varint,6,opt,name=contents,proto3" json:"contents,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUsageResponse) Reset() {
+	*x = GetUsageResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[68]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUsageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUsageResponse) ProtoMessage() {}
+
+func (x *GetUsageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[68]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUsageResponse.ProtoReflect.Descriptor instead.
+func (*GetUsageResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{68}
+}
+
+func (x *GetUsageResponse) GetPlan() string {
+	if x != nil {
+		return x.Plan
+	}
+	return ""
+}
+
+func (x *GetUsageResponse) GetLimitBytes() int64 {
+	if x != nil {
+		return x.LimitBytes
+	}
+	return 0
+}
+
+func (x *GetUsageResponse) GetUsedBytes() int64 {
+	if x != nil {
+		return x.UsedBytes
+	}
+	return 0
+}
+
+func (x *GetUsageResponse) GetReservedBytes() int64 {
+	if x != nil {
+		return x.ReservedBytes
+	}
+	return 0
+}
+
+func (x *GetUsageResponse) GetFiles() int64 {
+	if x != nil {
+		return x.Files
+	}
+	return 0
+}
+
+func (x *GetUsageResponse) GetContents() int64 {
+	if x != nil {
+		return x.Contents
+	}
+	return 0
+}
+
+// сверка пинов kubo с файлами, только для администраторов (security.admin_user_ids).
+// run_now запускает проход сразу, иначе возвращается итог последнего
+type GetPinReportRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RunNow        bool                   `protobuf:"varint,1,opt,name=run_now,json=runNow,proto3" json:"run_now,omitempty"`
+	DriftLimit    int32                  `protobuf:"varint,2,opt,name=drift_limit,json=driftLimit,proto3" json:"drift_limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPinReportRequest) Reset() {
+	*x = GetPinReportRequest{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[69]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPinReportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPinReportRequest) ProtoMessage() {}
+
+func (x *GetPinReportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[69]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPinReportRequest.ProtoReflect.Descriptor instead.
+func (*GetPinReportRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{69}
+}
+
+func (x *GetPinReportRequest) GetRunNow() bool {
+	if x != nil {
+		return x.RunNow
+	}
+	return false
+}
+
+func (x *GetPinReportRequest) GetDriftLimit() int32 {
+	if x != nil {
+		return x.DriftLimit
+	}
+	return 0
+}
+
+type PinDrift struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cid           string                 `protobuf:"bytes,1,opt,name=cid,proto3" json:"cid,omitempty"`
+	Kind          string                 `protobuf:"bytes,2,opt,name=kind,proto3" json:"kind,omitempty"`
+	FirstSeenUnix int64                  `protobuf:"varint,3,opt,name=first_seen_unix,json=firstSeenUnix,proto3" json:"first_seen_unix,omitempty"`
+	LastSeenUnix  int64                  `protobuf:"varint,4,opt,name=last_seen_unix,json=lastSeenUnix,proto3" json:"last_seen_unix,omitempty"`
+	Attempts      int32                  `protobuf:"varint,5,opt,name=attempts,proto3" json:"attempts,omitempty"`
+	LastError     string                 `protobuf:"bytes,6,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PinDrift) Reset() {
+	*x = PinDrift{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[70]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PinDrift) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PinDrift) ProtoMessage() {}
+
+func (x *PinDrift) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[70]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PinDrift.ProtoReflect.Descriptor instead.
+func (*PinDrift) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{70}
+}
+
+func (x *PinDrift) GetCid() string {
+	if x != nil {
+		return x.Cid
+	}
+	return ""
+}
+
+func (x *PinDrift) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *PinDrift) GetFirstSeenUnix() int64 {
+	if x != nil {
+		return x.FirstSeenUnix
+	}
+	return 0
+}
+
+func (x *PinDrift) GetLastSeenUnix() int64 {
+	if x != nil {
+		return x.LastSeenUnix
+	}
+	return 0
+}
+
+func (x *PinDrift) GetAttempts() int32 {
+	if x != nil {
+		return x.Attempts
+	}
+	return 0
+}
+
+func (x *PinDrift) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+type GetPinReportResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RunAtUnix     int64                  `protobuf:"varint,1,opt,name=run_at_unix,json=runAtUnix,proto3" json:"run_at_unix,omitempty"`
+	DurationMs    int64                  `protobuf:"varint,2,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	Pinned        int64                  `protobuf:"varint,3,opt,name=pinned,proto3" json:"pinned,omitempty"`
+	Referenced    int64                  `protobuf:"varint,4,opt,name=referenced,proto3" json:"referenced,omitempty"`
+	Missing       int64                  `protobuf:"varint,5,opt,name=missing,proto3" json:"missing,omitempty"`
+	Orphaned      int64                  `protobuf:"varint,6,opt,name=orphaned,proto3" json:"orphaned,omitempty"`
+	Repinned      int64                  `protobuf:"varint,7,opt,name=repinned,proto3" json:"repinned,omitempty"`
+	Unpinned      int64                  `protobuf:"varint,8,opt,name=unpinned,proto3" json:"unpinned,omitempty"`
+	Error         string                 `protobuf:"bytes,9,opt,name=error,proto3" json:"error,omitempty"`
+	Drift         []*PinDrift            `protobuf:"bytes,10,rep,name=drift,proto3" json:"drift,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPinReportResponse) Reset() {
+	*x = GetPinReportResponse{}
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[71]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPinReportResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPinReportResponse) ProtoMessage() {}
+
+func (x *GetPinReportResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_storage_v1_storage_proto_msgTypes[71]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPinReportResponse.ProtoReflect.Descriptor instead.
+func (*GetPinReportResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_storage_v1_storage_proto_rawDescGZIP(), []int{71}
+}
+
+func (x *GetPinReportResponse) GetRunAtUnix() int64 {
+	if x != nil {
+		return x.RunAtUnix
+	}
+	return 0
+}
+
+func (x *GetPinReportResponse) GetDurationMs() int64 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+func (x *GetPinReportResponse) GetPinned() int64 {
+	if x != nil {
+		return x.Pinned
+	}
+	return 0
+}
+
+func (x *GetPinReportResponse) GetReferenced() int64 {
+	if x != nil {
+		return x.Referenced
+	}
+	return 0
+}
+
+func (x *GetPinReportResponse) GetMissing() int64 {
+	if x != nil {
+		return x.Missing
+	}
+	return 0
+}
+
+func (x *GetPinReportResponse) GetOrphaned() int64 {
+	if x != nil {
+		return x.Orphaned
+	}
+	return 0
+}
+
+func (x *GetPinReportResponse) GetRepinned() int64 {
+	if x != nil {
+		return x.Repinned
+	}
+	return 0
+}
+
+func (x *GetPinReportResponse) GetUnpinned() int64 {
+	if x != nil {
+		return x.Unpinned
+	}
+	return 0
+}
+
+func (x *GetPinReportResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *GetPinReportResponse) GetDrift() []*PinDrift {
+	if x != nil {
+		return x.Drift
+	}
+	return nil
+}
+
+var File_shared_proto_storage_v1_storage_proto protoreflect.FileDescriptor
+
+const file_shared_proto_storage_v1_storage_proto_rawDesc = "" +
+	"\n" +
+	"%shared/proto/storage/v1/storage.proto\x12\n" +
+	"storage.v1\"\x9c\x02\n" +
+	"\x0ePutFileRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04mime\x18\x02 \x01(\tR\x04mime\x12\x1d\n" +
+	"\n" +
+	"size_bytes\x18\x03 \x01(\x03R\tsizeBytes\x12.\n" +
+	"\x13encrypted_car_chunk\x18\x04 \x01(\fR\x11encryptedCarChunk\x12\x1d\n" +
+	"\n" +
+	"last_chunk\x18\x05 \x01(\bR\tlastChunk\x12!\n" +
+	"\ftotal_blake3\x18\x06 \x01(\fR\vtotalBlake3\x12\x1b\n" +
+	"\tbao_proof\x18\a \x01(\fR\bbaoProof\x12\x17\n" +
+	"\afile_id\x18\b \x01(\tR\x06fileId\x12\x1b\n" +
+	"\tdevice_id\x18\t \x01(\tR\bdeviceId\"r\n" +
+	"\x0fPutFileResponse\x12\x1a\n" +
+	"\baccepted\x18\x01 \x01(\bR\baccepted\x12\x17\n" +
+	"\afile_id\x18\x02 \x01(\tR\x06fileId\x12\x10\n" +
+	"\x03cid\x18\x03 \x01(\tR\x03cid\x12\x18\n" +
+	"\aversion\x18\x04 \x01(\x03R\aversion\"\xd4\x01\n" +
+	"\x0eGetFileRequest\x12\x10\n" +
+	"\x03cid\x18\x01 \x01(\tR\x03cid\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x16\n" +
+	"\x06length\x18\x03 \x01(\x03R\x06length\x12\x1d\n" +
+	"\n" +
+	"chunk_tags\x18\x04 \x01(\bR\tchunkTags\x12\x1d\n" +
+	"\n" +
+	"bao_proofs\x18\x05 \x01(\bR\tbaoProofs\x12\x1d\n" +
+	"\n" +
+	"link_token\x18\x06 \x01(\tR\tlinkToken\x12#\n" +
+	"\rlink_password\x18\a \x01(\tR\flinkPassword\"\xd3\x01\n" +
+	"\x0fGetFileResponse\x12.\n" +
+	"\x13encrypted_car_chunk\x18\x01 \x01(\fR\x11encryptedCarChunk\x12\x1d\n" +
+	"\n" +
+	"last_chunk\x18\x02 \x01(\bR\tlastChunk\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x03R\x06offset\x12!\n" +
+	"\fchunk_blake3\x18\x04 \x01(\fR\vchunkBlake3\x12\x1b\n" +
+	"\tbao_proof\x18\x05 \x01(\fR\bbaoProof\x12\x19\n" +
+	"\bbao_root\x18\x06 \x01(\fR\abaoRoot\"%\n" +
+	"\x11ListBlocksRequest\x12\x10\n" +
+	"\x03cid\x18\x01 \x01(\tR\x03cid\"(\n" +
+	"\x12ListBlocksResponse\x12\x12\n" +
+	"\x04cids\x18\x01 \x03(\tR\x04cids\"A\n" +
+	"\x10GetBlocksRequest\x12\x12\n" +
+	"\x04cids\x18\x01 \x03(\tR\x04cids\x12\x19\n" +
+	"\broot_cid\x18\x02 \x01(\tR\arootCid\"Q\n" +
+	"\x11GetBlocksResponse\x12\x10\n" +
+	"\x03cid\x18\x01 \x01(\tR\x03cid\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\x12\x16\n" +
+	"\x06blake3\x18\x03 \x01(\fR\x06blake3\"~\n" +
+	"\x12BeginUploadRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04mime\x18\x02 \x01(\tR\x04mime\x12\x1d\n" +
+	"\n" +
+	"size_bytes\x18\x03 \x01(\x03R\tsizeBytes\x12!\n" +
+	"\ftotal_blake3\x18\x04 \x01(\fR\vtotalBlake3\"\x82\x01\n" +
+	"\x13BeginUploadResponse\x12\x1b\n" +
+	"\tupload_id\x18\x01 \x01(\tR\buploadId\x12&\n" +
+	"\x0fexpires_at_unix\x18\x02 \x01(\x03R\rexpiresAtUnix\x12&\n" +
+	"\x0fmax_chunk_bytes\x18\x03 \x01(\x05R\rmaxChunkBytes\"z\n" +
+	"\x12UploadChunkRequest\x12\x1b\n" +
+	"\tupload_id\x18\x01 \x01(\tR\buploadId\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\x12\x1b\n" +
+	"\tbao_proof\x18\x04 \x01(\fR\bbaoProof\"<\n" +
+	"\x13UploadChunkResponse\x12%\n" +
+	"\x0ereceived_bytes\x18\x01 \x01(\x03R\rreceivedBytes\"1\n" +
+	"\x12QueryUploadRequest\x12\x1b\n" +
+	"\tupload_id\x18\x01 \x01(\tR\buploadId\"3\n" +
+	"\tByteRange\x12\x14\n" +
+	"\x05start\x18\x01 \x01(\x03R\x05start\x12\x10\n" +
+	"\x03end\x18\x02 \x01(\x03R\x03end\"\x8f\x01\n" +
+	"\x13QueryUploadResponse\x12\x1d\n" +
+	"\n" +
+	"size_bytes\x18\x01 \x01(\x03R\tsizeBytes\x121\n" +
+	"\breceived\x18\x02 \x03(\v2\x15.storage.v1.ByteRangeR\breceived\x12&\n" +
+	"\x0fexpires_at_unix\x18\x03 \x01(\x03R\rexpiresAtUnix\"2\n" +
+	"\x13CommitUploadRequest\x12\x1b\n" +
+	"\tupload_id\x18\x01 \x01(\tR\buploadId\"A\n" +
+	"\x14CommitUploadResponse\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12\x10\n" +
+	"\x03cid\x18\x02 \x01(\tR\x03cid\"\x94\x02\n" +
+	"\bFileInfo\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12\x10\n" +
+	"\x03cid\x18\x02 \x01(\tR\x03cid\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x12\n" +
+	"\x04mime\x18\x04 \x01(\tR\x04mime\x12\x1d\n" +
+	"\n" +
+	"size_bytes\x18\x05 \x01(\x03R\tsizeBytes\x12&\n" +
+	"\x0fcreated_at_unix\x18\x06 \x01(\x03R\rcreatedAtUnix\x12\x1f\n" +
+	"\vblock_count\x18\a \x01(\x03R\n" +
+	"blockCount\x123\n" +
+	"\bpreviews\x18\b \x03(\v2\x17.storage.v1.FilePreviewR\bpreviews\x12\x18\n" +
+	"\aversion\x18\t \x01(\x03R\aversion\"\xf6\x01\n" +
+	"\vFilePreview\x12+\n" +
+	"\x04kind\x18\x01 \x01(\x0e2\x17.storage.v1.PreviewKindR\x04kind\x12\x10\n" +
+	"\x03cid\x18\x02 \x01(\tR\x03cid\x12\x12\n" +
+	"\x04mime\x18\x03 \x01(\tR\x04mime\x12\x1d\n" +
+	"\n" +
+	"size_bytes\x18\x04 \x01(\x03R\tsizeBytes\x12\x14\n" +
+	"\x05width\x18\x05 \x01(\x05R\x05width\x12\x16\n" +
+	"\x06height\x18\x06 \x01(\x05R\x06height\x12\x1f\n" +
+	"\vwrapped_key\x18\a \x01(\fR\n" +
+	"wrappedKey\x12&\n" +
+	"\x0fcreated_at_unix\x18\b \x01(\x03R\rcreatedAtUnix\"\xe1\x01\n" +
+	"\x11PutPreviewRequest\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12+\n" +
+	"\x04kind\x18\x02 \x01(\x0e2\x17.storage.v1.PreviewKindR\x04kind\x12\x12\n" +
+	"\x04mime\x18\x03 \x01(\tR\x04mime\x12\x14\n" +
+	"\x05width\x18\x04 \x01(\x05R\x05width\x12\x16\n" +
+	"\x06height\x18\x05 \x01(\x05R\x06height\x12\x1f\n" +
+	"\vwrapped_key\x18\x06 \x01(\fR\n" +
+	"wrappedKey\x12#\n" +
+	"\rencrypted_car\x18\a \x01(\fR\fencryptedCar\"G\n" +
+	"\x12PutPreviewResponse\x121\n" +
+	"\apreview\x18\x01 \x01(\v2\x17.storage.v1.FilePreviewR\apreview\"\\\n" +
+	"\x14DeletePreviewRequest\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12+\n" +
+	"\x04kind\x18\x02 \x01(\x0e2\x17.storage.v1.PreviewKindR\x04kind\"1\n" +
+	"\x15DeletePreviewResponse\x12\x18\n" +
+	"\adeleted\x18\x01 \x01(\bR\adeleted\"\xcf\x01\n" +
+	"\vFileVersion\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x03R\aversion\x12\x10\n" +
+	"\x03cid\x18\x02 \x01(\tR\x03cid\x12\x1d\n" +
+	"\n" +
+	"size_bytes\x18\x03 \x01(\x03R\tsizeBytes\x12\x16\n" +
+	"\x06blake3\x18\x04 \x01(\fR\x06blake3\x12\x1b\n" +
+	"\tdevice_id\x18\x05 \x01(\tR\bdeviceId\x12&\n" +
+	"\x0fcreated_at_unix\x18\x06 \x01(\x03R\rcreatedAtUnix\x12\x18\n" +
+	"\acurrent\x18\a \x01(\bR\acurrent\".\n" +
+	"\x13ListVersionsRequest\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\"K\n" +
+	"\x14ListVersionsResponse\x123\n" +
+	"\bversions\x18\x01 \x03(\v2\x17.storage.v1.FileVersionR\bversions\"g\n" +
+	"\x15RestoreVersionRequest\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x03R\aversion\x12\x1b\n" +
+	"\tdevice_id\x18\x03 \x01(\tR\bdeviceId\"u\n" +
+	"\x16RestoreVersionResponse\x12(\n" +
+	"\x04file\x18\x01 \x01(\v2\x14.storage.v1.FileInfoR\x04file\x121\n" +
+	"\aversion\x18\x02 \x01(\v2\x17.storage.v1.FileVersionR\aversion\"\xf8\x01\n" +
+	"\x10ListFilesRequest\x12\x1b\n" +
+	"\tpage_size\x18\x01 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x02 \x01(\tR\tpageToken\x12\x1f\n" +
+	"\vmime_prefix\x18\x03 \x01(\tR\n" +
+	"mimePrefix\x12,\n" +
+	"\x12created_after_unix\x18\x04 \x01(\x03R\x10createdAfterUnix\x12.\n" +
+	"\x13created_before_unix\x18\x05 \x01(\x03R\x11createdBeforeUnix\x12)\n" +
+	"\x10include_previews\x18\x06 \x01(\bR\x0fincludePreviews\"g\n" +
+	"\x11ListFilesResponse\x12*\n" +
+	"\x05files\x18\x01 \x03(\v2\x14.storage.v1.FileInfoR\x05files\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"*\n" +
+	"\x0fStatFileRequest\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\"<\n" +
+	"\x10StatFileResponse\x12(\n" +
+	"\x04file\x18\x01 \x01(\v2\x14.storage.v1.FileInfoR\x04file\"@\n" +
+	"\x11RenameFileRequest\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\">\n" +
+	"\x12RenameFileResponse\x12(\n" +
+	"\x04file\x18\x01 \x01(\v2\x14.storage.v1.FileInfoR\x04file\",\n" +
+	"\x11DeleteFileRequest\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\".\n" +
+	"\x12DeleteFileResponse\x12\x18\n" +
+	"\adeleted\x18\x01 \x01(\bR\adeleted\"\xcb\x02\n" +
+	"\x04Node\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x1b\n" +
+	"\tparent_id\x18\x02 \x01(\tR\bparentId\x12(\n" +
+	"\x04kind\x18\x03 \x01(\x0e2\x14.storage.v1.NodeKindR\x04kind\x12\x17\n" +
+	"\afile_id\x18\x04 \x01(\tR\x06fileId\x12\x1b\n" +
+	"\tname_blob\x18\x05 \x01(\fR\bnameBlob\x12\x1b\n" +
+	"\tname_hash\x18\x06 \x01(\fR\bnameHash\x12\x18\n" +
+	"\aversion\x18\a \x01(\x03R\aversion\x12&\n" +
+	"\x0fcreated_at_unix\x18\b \x01(\x03R\rcreatedAtUnix\x12&\n" +
+	"\x0fupdated_at_unix\x18\t \x01(\x03R\rupdatedAtUnix\x12&\n" +
+	"\x0ftrashed_at_unix\x18\n" +
+	" \x01(\x03R\rtrashedAtUnix\"e\n" +
+	"\fMkDirRequest\x12\x1b\n" +
+	"\tparent_id\x18\x01 \x01(\tR\bparentId\x12\x1b\n" +
+	"\tname_blob\x18\x02 \x01(\fR\bnameBlob\x12\x1b\n" +
+	"\tname_hash\x18\x03 \x01(\fR\bnameHash\"5\n" +
+	"\rMkDirResponse\x12$\n" +
+	"\x04node\x18\x01 \x01(\v2\x10.storage.v1.NodeR\x04node\"\x81\x01\n" +
+	"\x0fLinkFileRequest\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12\x1b\n" +
+	"\tparent_id\x18\x02 \x01(\tR\bparentId\x12\x1b\n" +
+	"\tname_blob\x18\x03 \x01(\fR\bnameBlob\x12\x1b\n" +
+	"\tname_hash\x18\x04 \x01(\fR\bnameHash\"8\n" +
+	"\x10LinkFileResponse\x12$\n" +
+	"\x04node\x18\x01 \x01(\v2\x10.storage.v1.NodeR\x04node\"\xaf\x01\n" +
+	"\vMoveRequest\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\"\n" +
+	"\rnew_parent_id\x18\x02 \x01(\tR\vnewParentId\x12\x1b\n" +
+	"\tname_blob\x18\x03 \x01(\fR\bnameBlob\x12\x1b\n" +
+	"\tname_hash\x18\x04 \x01(\fR\bnameHash\x12)\n" +
+	"\x10expected_version\x18\x05 \x01(\x03R\x0fexpectedVersion\"4\n" +
+	"\fMoveResponse\x12$\n" +
+	"\x04node\x18\x01 \x01(\v2\x10.storage.v1.NodeR\x04node\"\x7f\n" +
+	"\x0eListDirRequest\x12\x1b\n" +
+	"\tparent_id\x18\x01 \x01(\tR\bparentId\x12\x1b\n" +
+	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x03 \x01(\tR\tpageToken\x12\x14\n" +
+	"\x05trash\x18\x04 \x01(\bR\x05trash\"a\n" +
+	"\x0fListDirResponse\x12&\n" +
+	"\x05nodes\x18\x01 \x03(\v2\x10.storage.v1.NodeR\x05nodes\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"5\n" +
+	"\x12ResolvePathRequest\x12\x1f\n" +
+	"\vname_hashes\x18\x01 \x03(\fR\n" +
+	"nameHashes\";\n" +
+	"\x13ResolvePathResponse\x12$\n" +
+	"\x04node\x18\x01 \x01(\v2\x10.storage.v1.NodeR\x04node\"R\n" +
+	"\fTrashRequest\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12)\n" +
+	"\x10expected_version\x18\x02 \x01(\x03R\x0fexpectedVersion\"5\n" +
+	"\rTrashResponse\x12$\n" +
+	"\x04node\x18\x01 \x01(\v2\x10.storage.v1.NodeR\x04node\"c\n" +
+	"\x0eRestoreRequest\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x1b\n" +
+	"\tname_blob\x18\x02 \x01(\fR\bnameBlob\x12\x1b\n" +
+	"\tname_hash\x18\x03 \x01(\fR\bnameHash\"7\n" +
+	"\x0fRestoreResponse\x12$\n" +
+	"\x04node\x18\x01 \x01(\v2\x10.storage.v1.NodeR\x04node\"\x98\x02\n" +
+	"\n" +
+	"ShareGrant\x12\x19\n" +
+	"\bgrant_id\x18\x01 \x01(\tR\agrantId\x12\x19\n" +
+	"\bowner_id\x18\x02 \x01(\tR\aownerId\x12\x17\n" +
+	"\afile_id\x18\x03 \x01(\tR\x06fileId\x12\x17\n" +
+	"\anode_id\x18\x04 \x01(\tR\x06nodeId\x12:\n" +
+	"\fgrantee_kind\x18\x05 \x01(\x0e2\x17.storage.v1.GranteeKindR\vgranteeKind\x12\x1d\n" +
+	"\n" +
+	"grantee_id\x18\x06 \x01(\tR\tgranteeId\x12\x1f\n" +
+	"\vwrapped_key\x18\a \x01(\fR\n" +
+	"wrappedKey\x12&\n" +
+	"\x0fcreated_at_unix\x18\b \x01(\x03R\rcreatedAtUnix\"\xbc\x01\n" +
+	"\fShareRequest\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12\x17\n" +
+	"\anode_id\x18\x02 \x01(\tR\x06nodeId\x12:\n" +
+	"\fgrantee_kind\x18\x03 \x01(\x0e2\x17.storage.v1.GranteeKindR\vgranteeKind\x12\x1d\n" +
+	"\n" +
+	"grantee_id\x18\x04 \x01(\tR\tgranteeId\x12\x1f\n" +
+	"\vwrapped_key\x18\x05 \x01(\fR\n" +
+	"wrappedKey\"=\n" +
+	"\rShareResponse\x12,\n" +
+	"\x05grant\x18\x01 \x01(\v2\x16.storage.v1.ShareGrantR\x05grant\"/\n" +
+	"\x12RevokeShareRequest\x12\x19\n" +
+	"\bgrant_id\x18\x01 \x01(\tR\agrantId\"/\n" +
+	"\x13RevokeShareResponse\x12\x18\n" +
+	"\arevoked\x18\x01 \x01(\bR\arevoked\"k\n" +
+	"\x11ListSharesRequest\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12\x17\n" +
+	"\anode_id\x18\x02 \x01(\tR\x06nodeId\x12$\n" +
+	"\x0eshared_with_me\x18\x03 \x01(\bR\fsharedWithMe\"D\n" +
+	"\x12ListSharesResponse\x12.\n" +
+	"\x06grants\x18\x01 \x03(\v2\x16.storage.v1.ShareGrantR\x06grants\"\x8c\x02\n" +
+	"\tShareLink\x12\x17\n" +
+	"\alink_id\x18\x01 \x01(\tR\x06linkId\x12\x17\n" +
+	"\afile_id\x18\x02 \x01(\tR\x06fileId\x12\x17\n" +
+	"\anode_id\x18\x03 \x01(\tR\x06nodeId\x12!\n" +
+	"\fhas_password\x18\x04 \x01(\bR\vhasPassword\x12&\n" +
+	"\x0fexpires_at_unix\x18\x05 \x01(\x03R\rexpiresAtUnix\x12#\n" +
+	"\rmax_downloads\x18\x06 \x01(\x05R\fmaxDownloads\x12\x1c\n" +
+	"\tdownloads\x18\a \x01(\x05R\tdownloads\x12&\n" +
+	"\x0fcreated_at_unix\x18\b \x01(\x03R\rcreatedAtUnix\"\xae\x01\n" +
+	"\x11CreateLinkRequest\x12\x17\n" +
+	"\afile_id\x18\x01 \x01(\tR\x06fileId\x12\x17\n" +
+	"\anode_id\x18\x02 \x01(\tR\x06nodeId\x12\x1a\n" +
+	"\bpassword\x18\x03 \x01(\tR\bpassword\x12&\n" +
+	"\x0fexpires_at_unix\x18\x04 \x01(\x03R\rexpiresAtUnix\x12#\n" +
+	"\rmax_downloads\x18\x05 \x01(\x05R\fmaxDownloads\"U\n" +
+	"\x12CreateLinkResponse\x12)\n" +
+	"\x04link\x18\x01 \x01(\v2\x15.storage.v1.ShareLinkR\x04link\x12\x14\n" +
+	"\x05token\x18\x02 \x01(\tR\x05token\",\n" +
+	"\x11RevokeLinkRequest\x12\x17\n" +
+	"\alink_id\x18\x01 \x01(\tR\x06linkId\".\n" +
+	"\x12RevokeLinkResponse\x12\x18\n" +
+	"\arevoked\x18\x01 \x01(\bR\arevoked\"\x12\n" +
+	"\x10ListLinksRequest\"@\n" +
+	"\x11ListLinksResponse\x12+\n" +
+	"\x05links\x18\x01 \x03(\v2\x15.storage.v1.ShareLinkR\x05links\"\x9f\x01\n" +
+	"\x12ResolveLinkRequest\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\x12\x1a\n" +
+	"\bpassword\x18\x02 \x01(\tR\bpassword\x12\x1b\n" +
+	"\tparent_id\x18\x03 \x01(\tR\bparentId\x12\x1b\n" +
+	"\tpage_size\x18\x04 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x05 \x01(\tR\tpageToken\"\xe6\x01\n" +
+	"\x13ResolveLinkResponse\x12)\n" +
+	"\x04link\x18\x01 \x01(\v2\x15.storage.v1.ShareLinkR\x04link\x12(\n" +
+	"\x04file\x18\x02 \x01(\v2\x14.storage.v1.FileInfoR\x04file\x12&\n" +
+	"\x05nodes\x18\x03 \x03(\v2\x10.storage.v1.NodeR\x05nodes\x12*\n" +
+	"\x05files\x18\x04 \x03(\v2\x14.storage.v1.FileInfoR\x05files\x12&\n" +
+	"\x0fnext_page_token\x18\x05 \x01(\tR\rnextPageToken\"\x11\n" +
+	"\x0fGetUsageRequest\"\xbf\x01\n" +
+	"\x10GetUsageResponse\x12\x12\n" +
+	"\x04plan\x18\x01 \x01(\tR\x04plan\x12\x1f\n" +
+	"\vlimit_bytes\x18\x02 \x01(\x03R\n" +
+	"limitBytes\x12\x1d\n" +
+	"\n" +
+	"used_bytes\x18\x03 \x01(\x03R\tusedBytes\x12%\n" +
+	"\x0ereserved_bytes\x18\x04 \x01(\x03R\rreservedBytes\x12\x14\n" +
+	"\x05files\x18\x05 \x01(\x03R\x05files\x12\x1a\n" +
+	"\bcontents\x18\x06 \x01(\x03R\bcontents\"O\n" +
+	"\x13GetPinReportRequest\x12\x17\n" +
+	"\arun_now\x18\x01 \x01(\bR\x06runNow\x12\x1f\n" +
+	"\vdrift_limit\x18\x02 \x01(\x05R\n" +
+	"driftLimit\"\xb9\x01\n" +
+	"\bPinDrift\x12\x10\n" +
+	"\x03cid\x18\x01 \x01(\tR\x03cid\x12\x12\n" +
+	"\x04kind\x18\x02 \x01(\tR\x04kind\x12&\n" +
+	"\x0ffirst_seen_unix\x18\x03 \x01(\x03R\rfirstSeenUnix\x12$\n" +
+	"\x0elast_seen_unix\x18\x04 \x01(\x03R\flastSeenUnix\x12\x1a\n" +
+	"\battempts\x18\x05 \x01(\x05R\battempts\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\x06 \x01(\tR\tlastError\"\xbf\x02\n" +
+	"\x14GetPinReportResponse\x12\x1e\n" +
+	"\vrun_at_unix\x18\x01 \x01(\x03R\trunAtUnix\x12\x1f\n" +
+	"\vduration_ms\x18\x02 \x01(\x03R\n" +
+	"durationMs\x12\x16\n" +
+	"\x06pinned\x18\x03 \x01(\x03R\x06pinned\x12\x1e\n" +
+	"\n" +
+	"referenced\x18\x04 \x01(\x03R\n" +
+	"referenced\x12\x18\n" +
+	"\amissing\x18\x05 \x01(\x03R\amissing\x12\x1a\n" +
+	"\borphaned\x18\x06 \x01(\x03R\borphaned\x12\x1a\n" +
+	"\brepinned\x18\a \x01(\x03R\brepinned\x12\x1a\n" +
+	"\bunpinned\x18\b \x01(\x03R\bunpinned\x12\x14\n" +
+	"\x05error\x18\t \x01(\tR\x05error\x12*\n" +
+	"\x05drift\x18\n" +
+	" \x03(\v2\x14.storage.v1.PinDriftR\x05drift*z\n" +
+	"\vPreviewKind\x12\x1c\n" +
+	"\x18PREVIEW_KIND_UNSPECIFIED\x10\x00\x12\x1a\n" +
+	"\x16PREVIEW_KIND_THUMBNAIL\x10\x01\x12\x18\n" +
+	"\x14PREVIEW_KIND_PREVIEW\x10\x02\x12\x17\n" +
+	"\x13PREVIEW_KIND_POSTER\x10\x03*L\n" +
+	"\bNodeKind\x12\x19\n" +
+	"\x15NODE_KIND_UNSPECIFIED\x10\x00\x12\x11\n" +
+	"\rNODE_KIND_DIR\x10\x01\x12\x12\n" +
+	"\x0eNODE_KIND_FILE\x10\x02*Z\n" +
+	"\vGranteeKind\x12\x1c\n" +
+	"\x18GRANTEE_KIND_UNSPECIFIED\x10\x00\x12\x15\n" +
+	"\x11GRANTEE_KIND_USER\x10\x01\x12\x16\n" +
+	"\x12GRANTEE_KIND_GROUP\x10\x022\xf3\x12\n" +
+	"\x0eStorageService\x12D\n" +
+	"\aPutFile\x12\x1a.storage.v1.PutFileRequest\x1a\x1b.storage.v1.PutFileResponse(\x01\x12D\n" +
+	"\aGetFile\x12\x1a.storage.v1.GetFileRequest\x1a\x1b.storage.v1.GetFileResponse0\x01\x12N\n" +
+	"\vBeginUpload\x12\x1e.storage.v1.BeginUploadRequest\x1a\x1f.storage.v1.BeginUploadResponse\x12N\n" +
+	"\vUploadChunk\x12\x1e.storage.v1.UploadChunkRequest\x1a\x1f.storage.v1.UploadChunkResponse\x12N\n" +
+	"\vQueryUpload\x12\x1e.storage.v1.QueryUploadRequest\x1a\x1f.storage.v1.QueryUploadResponse\x12Q\n" +
+	"\fCommitUpload\x12\x1f.storage.v1.CommitUploadRequest\x1a .storage.v1.CommitUploadResponse\x12M\n" +
+	"\n" +
+	"ListBlocks\x12\x1d.storage.v1.ListBlocksRequest\x1a\x1e.storage.v1.ListBlocksResponse0\x01\x12J\n" +
+	"\tGetBlocks\x12\x1c.storage.v1.GetBlocksRequest\x1a\x1d.storage.v1.GetBlocksResponse0\x01\x12H\n" +
+	"\tListFiles\x12\x1c.storage.v1.ListFilesRequest\x1a\x1d.storage.v1.ListFilesResponse\x12E\n" +
+	"\bStatFile\x12\x1b.storage.v1.StatFileRequest\x1a\x1c.storage.v1.StatFileResponse\x12K\n" +
+	"\n" +
+	"RenameFile\x12\x1d.storage.v1.RenameFileRequest\x1a\x1e.storage.v1.RenameFileResponse\x12K\n" +
+	"\n" +
+	"DeleteFile\x12\x1d.storage.v1.DeleteFileRequest\x1a\x1e.storage.v1.DeleteFileResponse\x12K\n" +
+	"\n" +
+	"PutPreview\x12\x1d.storage.v1.PutPreviewRequest\x1a\x1e.storage.v1.PutPreviewResponse\x12T\n" +
+	"\rDeletePreview\x12 .storage.v1.DeletePreviewRequest\x1a!.storage.v1.DeletePreviewResponse\x12Q\n" +
+	"\fListVersions\x12\x1f.storage.v1.ListVersionsRequest\x1a .storage.v1.ListVersionsResponse\x12W\n" +
+	"\x0eRestoreVersion\x12!.storage.v1.RestoreVersionRequest\x1a\".storage.v1.RestoreVersionResponse\x12E\n" +
+	"\bGetUsage\x12\x1b.storage.v1.GetUsageRequest\x1a\x1c.storage.v1.GetUsageResponse\x12Q\n" +
+	"\fGetPinReport\x12\x1f.storage.v1.GetPinReportRequest\x1a .storage.v1.GetPinReportResponse\x12<\n" +
+	"\x05MkDir\x12\x18.storage.v1.MkDirRequest\x1a\x19.storage.v1.MkDirResponse\x12E\n" +
+	"\bLinkFile\x12\x1b.storage.v1.LinkFileRequest\x1a\x1c.storage.v1.LinkFileResponse\x129\n" +
+	"\x04Move\x12\x17.storage.v1.MoveRequest\x1a\x18.storage.v1.MoveResponse\x12B\n" +
+	"\aListDir\x12\x1a.storage.v1.ListDirRequest\x1a\x1b.storage.v1.ListDirResponse\x12N\n" +
+	"\vResolvePath\x12\x1e.storage.v1.ResolvePathRequest\x1a\x1f.storage.v1.ResolvePathResponse\x12<\n" +
+	"\x05Trash\x12\x18.storage.v1.TrashRequest\x1a\x19.storage.v1.TrashResponse\x12B\n" +
+	"\aRestore\x12\x1a.storage.v1.RestoreRequest\x1a\x1b.storage.v1.RestoreResponse\x12<\n" +
+	"\x05Share\x12\x18.storage.v1.ShareRequest\x1a\x19.storage.v1.ShareResponse\x12N\n" +
+	"\vRevokeShare\x12\x1e.storage.v1.RevokeShareRequest\x1a\x1f.storage.v1.RevokeShareResponse\x12K\n" +
+	"\n" +
+	"ListShares\x12\x1d.storage.v1.ListSharesRequest\x1a\x1e.storage.v1.ListSharesResponse\x12K\n" +
+	"\n" +
+	"CreateLink\x12\x1d.storage.v1.CreateLinkRequest\x1a\x1e.storage.v1.CreateLinkResponse\x12K\n" +
+	"\n" +
+	"RevokeLink\x12\x1d.storage.v1.RevokeLinkRequest\x1a\x1e.storage.v1.RevokeLinkResponse\x12H\n" +
+	"\tListLinks\x12\x1c.storage.v1.ListLinksRequest\x1a\x1d.storage.v1.ListLinksResponse\x12N\n" +
+	"\vResolveLink\x12\x1e.storage.v1.ResolveLinkRequest\x1a\x1f.storage.v1.ResolveLinkResponseB2Z0dev.c0rex64.heroin/shared/proto/storage/v1;stgv1b\x06proto3"
 
 var (
 	file_shared_proto_storage_v1_storage_proto_rawDescOnce sync.Once
@@ -302,23 +4993,186 @@ func file_shared_proto_storage_v1_storage_proto_rawDescGZIP() []byte {
 	return file_shared_proto_storage_v1_storage_proto_rawDescData
 }
 
-var file_shared_proto_storage_v1_storage_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_shared_proto_storage_v1_storage_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_shared_proto_storage_v1_storage_proto_msgTypes = make([]protoimpl.MessageInfo, 72)
 var file_shared_proto_storage_v1_storage_proto_goTypes = []any{
-	(*PutFileRequest)(nil),  // 0: storage.v1.PutFileRequest
-	(*PutFileResponse)(nil), // 1: storage.v1.PutFileResponse
-	(*GetFileRequest)(nil),  // 2: storage.v1.GetFileRequest
-	(*GetFileResponse)(nil), // 3: storage.v1.GetFileResponse
+	(PreviewKind)(0),               // 0: storage.v1.PreviewKind
+	(NodeKind)(0),                  // 1: storage.v1.NodeKind
+	(GranteeKind)(0),               // 2: storage.v1.GranteeKind
+	(*PutFileRequest)(nil),         // 3: storage.v1.PutFileRequest
+	(*PutFileResponse)(nil),        // 4: storage.v1.PutFileResponse
+	(*GetFileRequest)(nil),         // 5: storage.v1.GetFileRequest
+	(*GetFileResponse)(nil),        // 6: storage.v1.GetFileResponse
+	(*ListBlocksRequest)(nil),      // 7: storage.v1.ListBlocksRequest
+	(*ListBlocksResponse)(nil),     // 8: storage.v1.ListBlocksResponse
+	(*GetBlocksRequest)(nil),       // 9: storage.v1.GetBlocksRequest
+	(*GetBlocksResponse)(nil),      // 10: storage.v1.GetBlocksResponse
+	(*BeginUploadRequest)(nil),     // 11: storage.v1.BeginUploadRequest
+	(*BeginUploadResponse)(nil),    // 12: storage.v1.BeginUploadResponse
+	(*UploadChunkRequest)(nil),     // 13: storage.v1.UploadChunkRequest
+	(*UploadChunkResponse)(nil),    // 14: storage.v1.UploadChunkResponse
+	(*QueryUploadRequest)(nil),     // 15: storage.v1.QueryUploadRequest
+	(*ByteRange)(nil),              // 16: storage.v1.ByteRange
+	(*QueryUploadResponse)(nil),    // 17: storage.v1.QueryUploadResponse
+	(*CommitUploadRequest)(nil),    // 18: storage.v1.CommitUploadRequest
+	(*CommitUploadResponse)(nil),   // 19: storage.v1.CommitUploadResponse
+	(*FileInfo)(nil),               // 20: storage.v1.FileInfo
+	(*FilePreview)(nil),            // 21: storage.v1.FilePreview
+	(*PutPreviewRequest)(nil),      // 22: storage.v1.PutPreviewRequest
+	(*PutPreviewResponse)(nil),     // 23: storage.v1.PutPreviewResponse
+	(*DeletePreviewRequest)(nil),   // 24: storage.v1.DeletePreviewRequest
+	(*DeletePreviewResponse)(nil),  // 25: storage.v1.DeletePreviewResponse
+	(*FileVersion)(nil),            // 26: storage.v1.FileVersion
+	(*ListVersionsRequest)(nil),    // 27: storage.v1.ListVersionsRequest
+	(*ListVersionsResponse)(nil),   // 28: storage.v1.ListVersionsResponse
+	(*RestoreVersionRequest)(nil),  // 29: storage.v1.RestoreVersionRequest
+	(*RestoreVersionResponse)(nil), // 30: storage.v1.RestoreVersionResponse
+	(*ListFilesRequest)(nil),       // 31: storage.v1.ListFilesRequest
+	(*ListFilesResponse)(nil),      // 32: storage.v1.ListFilesResponse
+	(*StatFileRequest)(nil),        // 33: storage.v1.StatFileRequest
+	(*StatFileResponse)(nil),       // 34: storage.v1.StatFileResponse
+	(*RenameFileRequest)(nil),      // 35: storage.v1.RenameFileRequest
+	(*RenameFileResponse)(nil),     // 36: storage.v1.RenameFileResponse
+	(*DeleteFileRequest)(nil),      // 37: storage.v1.DeleteFileRequest
+	(*DeleteFileResponse)(nil),     // 38: storage.v1.DeleteFileResponse
+	(*Node)(nil),                   // 39: storage.v1.Node
+	(*MkDirRequest)(nil),           // 40: storage.v1.MkDirRequest
+	(*MkDirResponse)(nil),          // 41: storage.v1.MkDirResponse
+	(*LinkFileRequest)(nil),        // 42: storage.v1.LinkFileRequest
+	(*LinkFileResponse)(nil),       // 43: storage.v1.LinkFileResponse
+	(*MoveRequest)(nil),            // 44: storage.v1.MoveRequest
+	(*MoveResponse)(nil),           // 45: storage.v1.MoveResponse
+	(*ListDirRequest)(nil),         // 46: storage.v1.ListDirRequest
+	(*ListDirResponse)(nil),        // 47: storage.v1.ListDirResponse
+	(*ResolvePathRequest)(nil),     // 48: storage.v1.ResolvePathRequest
+	(*ResolvePathResponse)(nil),    // 49: storage.v1.ResolvePathResponse
+	(*TrashRequest)(nil),           // 50: storage.v1.TrashRequest
+	(*TrashResponse)(nil),          // 51: storage.v1.TrashResponse
+	(*RestoreRequest)(nil),         // 52: storage.v1.RestoreRequest
+	(*RestoreResponse)(nil),        // 53: storage.v1.RestoreResponse
+	(*ShareGrant)(nil),             // 54: storage.v1.ShareGrant
+	(*ShareRequest)(nil),           // 55: storage.v1.ShareRequest
+	(*ShareResponse)(nil),          // 56: storage.v1.ShareResponse
+	(*RevokeShareRequest)(nil),     // 57: storage.v1.RevokeShareRequest
+	(*RevokeShareResponse)(nil),    // 58: storage.v1.RevokeShareResponse
+	(*ListSharesRequest)(nil),      // 59: storage.v1.ListSharesRequest
+	(*ListSharesResponse)(nil),     // 60: storage.v1.ListSharesResponse
+	(*ShareLink)(nil),              // 61: storage.v1.ShareLink
+	(*CreateLinkRequest)(nil),      // 62: storage.v1.CreateLinkRequest
+	(*CreateLinkResponse)(nil),     // 63: storage.v1.CreateLinkResponse
+	(*RevokeLinkRequest)(nil),      // 64: storage.v1.RevokeLinkRequest
+	(*RevokeLinkResponse)(nil),     // 65: storage.v1.RevokeLinkResponse
+	(*ListLinksRequest)(nil),       // 66: storage.v1.ListLinksRequest
+	(*ListLinksResponse)(nil),      // 67: storage.v1.ListLinksResponse
+	(*ResolveLinkRequest)(nil),     // 68: storage.v1.ResolveLinkRequest
+	(*ResolveLinkResponse)(nil),    // 69: storage.v1.ResolveLinkResponse
+	(*GetUsageRequest)(nil),        // 70: storage.v1.GetUsageRequest
+	(*GetUsageResponse)(nil),       // 71: storage.v1.GetUsageResponse
+	(*GetPinReportRequest)(nil),    // 72: storage.v1.GetPinReportRequest
+	(*PinDrift)(nil),               // 73: storage.v1.PinDrift
+	(*GetPinReportResponse)(nil),   // 74: storage.v1.GetPinReportResponse
 }
 var file_shared_proto_storage_v1_storage_proto_depIdxs = []int32{
-	0, // 0: storage.v1.StorageService.PutFile:input_type -> storage.v1.PutFileRequest
-	2, // 1: storage.v1.StorageService.GetFile:input_type -> storage.v1.GetFileRequest
-	1, // 2: storage.v1.StorageService.PutFile:output_type -> storage.v1.PutFileResponse
-	3, // 3: storage.v1.StorageService.GetFile:output_type -> storage.v1.GetFileResponse
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	16, // 0: storage.v1.QueryUploadResponse.received:type_name -> storage.v1.ByteRange
+	21, // 1: storage.v1.FileInfo.previews:type_name -> storage.v1.FilePreview
+	0,  // 2: storage.v1.FilePreview.kind:type_name -> storage.v1.PreviewKind
+	0,  // 3: storage.v1.PutPreviewRequest.kind:type_name -> storage.v1.PreviewKind
+	21, // 4: storage.v1.PutPreviewResponse.preview:type_name -> storage.v1.FilePreview
+	0,  // 5: storage.v1.DeletePreviewRequest.kind:type_name -> storage.v1.PreviewKind
+	26, // 6: storage.v1.ListVersionsResponse.versions:type_name -> storage.v1.FileVersion
+	20, // 7: storage.v1.RestoreVersionResponse.file:type_name -> storage.v1.FileInfo
+	26, // 8: storage.v1.RestoreVersionResponse.version:type_name -> storage.v1.FileVersion
+	20, // 9: storage.v1.ListFilesResponse.files:type_name -> storage.v1.FileInfo
+	20, // 10: storage.v1.StatFileResponse.file:type_name -> storage.v1.FileInfo
+	20, // 11: storage.v1.RenameFileResponse.file:type_name -> storage.v1.FileInfo
+	1,  // 12: storage.v1.Node.kind:type_name -> storage.v1.NodeKind
+	39, // 13: storage.v1.MkDirResponse.node:type_name -> storage.v1.Node
+	39, // 14: storage.v1.LinkFileResponse.node:type_name -> storage.v1.Node
+	39, // 15: storage.v1.MoveResponse.node:type_name -> storage.v1.Node
+	39, // 16: storage.v1.ListDirResponse.nodes:type_name -> storage.v1.Node
+	39, // 17: storage.v1.ResolvePathResponse.node:type_name -> storage.v1.Node
+	39, // 18: storage.v1.TrashResponse.node:type_name -> storage.v1.Node
+	39, // 19: storage.v1.RestoreResponse.node:type_name -> storage.v1.Node
+	2,  // 20: storage.v1.ShareGrant.grantee_kind:type_name -> storage.v1.GranteeKind
+	2,  // 21: storage.v1.ShareRequest.grantee_kind:type_name -> storage.v1.GranteeKind
+	54, // 22: storage.v1.ShareResponse.grant:type_name -> storage.v1.ShareGrant
+	54, // 23: storage.v1.ListSharesResponse.grants:type_name -> storage.v1.ShareGrant
+	61, // 24: storage.v1.CreateLinkResponse.link:type_name -> storage.v1.ShareLink
+	61, // 25: storage.v1.ListLinksResponse.links:type_name -> storage.v1.ShareLink
+	61, // 26: storage.v1.ResolveLinkResponse.link:type_name -> storage.v1.ShareLink
+	20, // 27: storage.v1.ResolveLinkResponse.file:type_name -> storage.v1.FileInfo
+	39, // 28: storage.v1.ResolveLinkResponse.nodes:type_name -> storage.v1.Node
+	20, // 29: storage.v1.ResolveLinkResponse.files:type_name -> storage.v1.FileInfo
+	73, // 30: storage.v1.GetPinReportResponse.drift:type_name -> storage.v1.PinDrift
+	3,  // 31: storage.v1.StorageService.PutFile:input_type -> storage.v1.PutFileRequest
+	5,  // 32: storage.v1.StorageService.GetFile:input_type -> storage.v1.GetFileRequest
+	11, // 33: storage.v1.StorageService.BeginUpload:input_type -> storage.v1.BeginUploadRequest
+	13, // 34: storage.v1.StorageService.UploadChunk:input_type -> storage.v1.UploadChunkRequest
+	15, // 35: storage.v1.StorageService.QueryUpload:input_type -> storage.v1.QueryUploadRequest
+	18, // 36: storage.v1.StorageService.CommitUpload:input_type -> storage.v1.CommitUploadRequest
+	7,  // 37: storage.v1.StorageService.ListBlocks:input_type -> storage.v1.ListBlocksRequest
+	9,  // 38: storage.v1.StorageService.GetBlocks:input_type -> storage.v1.GetBlocksRequest
+	31, // 39: storage.v1.StorageService.ListFiles:input_type -> storage.v1.ListFilesRequest
+	33, // 40: storage.v1.StorageService.StatFile:input_type -> storage.v1.StatFileRequest
+	35, // 41: storage.v1.StorageService.RenameFile:input_type -> storage.v1.RenameFileRequest
+	37, // 42: storage.v1.StorageService.DeleteFile:input_type -> storage.v1.DeleteFileRequest
+	22, // 43: storage.v1.StorageService.PutPreview:input_type -> storage.v1.PutPreviewRequest
+	24, // 44: storage.v1.StorageService.DeletePreview:input_type -> storage.v1.DeletePreviewRequest
+	27, // 45: storage.v1.StorageService.ListVersions:input_type -> storage.v1.ListVersionsRequest
+	29, // 46: storage.v1.StorageService.RestoreVersion:input_type -> storage.v1.RestoreVersionRequest
+	70, // 47: storage.v1.StorageService.GetUsage:input_type -> storage.v1.GetUsageRequest
+	72, // 48: storage.v1.StorageService.GetPinReport:input_type -> storage.v1.GetPinReportRequest
+	40, // 49: storage.v1.StorageService.MkDir:input_type -> storage.v1.MkDirRequest
+	42, // 50: storage.v1.StorageService.LinkFile:input_type -> storage.v1.LinkFileRequest
+	44, // 51: storage.v1.StorageService.Move:input_type -> storage.v1.MoveRequest
+	46, // 52: storage.v1.StorageService.ListDir:input_type -> storage.v1.ListDirRequest
+	48, // 53: storage.v1.StorageService.ResolvePath:input_type -> storage.v1.ResolvePathRequest
+	50, // 54: storage.v1.StorageService.Trash:input_type -> storage.v1.TrashRequest
+	52, // 55: storage.v1.StorageService.Restore:input_type -> storage.v1.RestoreRequest
+	55, // 56: storage.v1.StorageService.Share:input_type -> storage.v1.ShareRequest
+	57, // 57: storage.v1.StorageService.RevokeShare:input_type -> storage.v1.RevokeShareRequest
+	59, // 58: storage.v1.StorageService.ListShares:input_type -> storage.v1.ListSharesRequest
+	62, // 59: storage.v1.StorageService.CreateLink:input_type -> storage.v1.CreateLinkRequest
+	64, // 60: storage.v1.StorageService.RevokeLink:input_type -> storage.v1.RevokeLinkRequest
+	66, // 61: storage.v1.StorageService.ListLinks:input_type -> storage.v1.ListLinksRequest
+	68, // 62: storage.v1.StorageService.ResolveLink:input_type -> storage.v1.ResolveLinkRequest
+	4,  // 63: storage.v1.StorageService.PutFile:output_type -> storage.v1.PutFileResponse
+	6,  // 64: storage.v1.StorageService.GetFile:output_type -> storage.v1.GetFileResponse
+	12, // 65: storage.v1.StorageService.BeginUpload:output_type -> storage.v1.BeginUploadResponse
+	14, // 66: storage.v1.StorageService.UploadChunk:output_type -> storage.v1.UploadChunkResponse
+	17, // 67: storage.v1.StorageService.QueryUpload:output_type -> storage.v1.QueryUploadResponse
+	19, // 68: storage.v1.StorageService.CommitUpload:output_type -> storage.v1.CommitUploadResponse
+	8,  // 69: storage.v1.StorageService.ListBlocks:output_type -> storage.v1.ListBlocksResponse
+	10, // 70: storage.v1.StorageService.GetBlocks:output_type -> storage.v1.GetBlocksResponse
+	32, // 71: storage.v1.StorageService.ListFiles:output_type -> storage.v1.ListFilesResponse
+	34, // 72: storage.v1.StorageService.StatFile:output_type -> storage.v1.StatFileResponse
+	36, // 73: storage.v1.StorageService.RenameFile:output_type -> storage.v1.RenameFileResponse
+	38, // 74: storage.v1.StorageService.DeleteFile:output_type -> storage.v1.DeleteFileResponse
+	23, // 75: storage.v1.StorageService.PutPreview:output_type -> storage.v1.PutPreviewResponse
+	25, // 76: storage.v1.StorageService.DeletePreview:output_type -> storage.v1.DeletePreviewResponse
+	28, // 77: storage.v1.StorageService.ListVersions:output_type -> storage.v1.ListVersionsResponse
+	30, // 78: storage.v1.StorageService.RestoreVersion:output_type -> storage.v1.RestoreVersionResponse
+	71, // 79: storage.v1.StorageService.GetUsage:output_type -> storage.v1.GetUsageResponse
+	74, // 80: storage.v1.StorageService.GetPinReport:output_type -> storage.v1.GetPinReportResponse
+	41, // 81: storage.v1.StorageService.MkDir:output_type -> storage.v1.MkDirResponse
+	43, // 82: storage.v1.StorageService.LinkFile:output_type -> storage.v1.LinkFileResponse
+	45, // 83: storage.v1.StorageService.Move:output_type -> storage.v1.MoveResponse
+	47, // 84: storage.v1.StorageService.ListDir:output_type -> storage.v1.ListDirResponse
+	49, // 85: storage.v1.StorageService.ResolvePath:output_type -> storage.v1.ResolvePathResponse
+	51, // 86: storage.v1.StorageService.Trash:output_type -> storage.v1.TrashResponse
+	53, // 87: storage.v1.StorageService.Restore:output_type -> storage.v1.RestoreResponse
+	56, // 88: storage.v1.StorageService.Share:output_type -> storage.v1.ShareResponse
+	58, // 89: storage.v1.StorageService.RevokeShare:output_type -> storage.v1.RevokeShareResponse
+	60, // 90: storage.v1.StorageService.ListShares:output_type -> storage.v1.ListSharesResponse
+	63, // 91: storage.v1.StorageService.CreateLink:output_type -> storage.v1.CreateLinkResponse
+	65, // 92: storage.v1.StorageService.RevokeLink:output_type -> storage.v1.RevokeLinkResponse
+	67, // 93: storage.v1.StorageService.ListLinks:output_type -> storage.v1.ListLinksResponse
+	69, // 94: storage.v1.StorageService.ResolveLink:output_type -> storage.v1.ResolveLinkResponse
+	63, // [63:95] is the sub-list for method output_type
+	31, // [31:63] is the sub-list for method input_type
+	31, // [31:31] is the sub-list for extension type_name
+	31, // [31:31] is the sub-list for extension extendee
+	0,  // [0:31] is the sub-list for field type_name
 }
 
 func init() { file_shared_proto_storage_v1_storage_proto_init() }
@@ -331,13 +5185,14 @@ func file_shared_proto_storage_v1_storage_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_shared_proto_storage_v1_storage_proto_rawDesc), len(file_shared_proto_storage_v1_storage_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   4,
+			NumEnums:      3,
+			NumMessages:   72,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_shared_proto_storage_v1_storage_proto_goTypes,
 		DependencyIndexes: file_shared_proto_storage_v1_storage_proto_depIdxs,
+		EnumInfos:         file_shared_proto_storage_v1_storage_proto_enumTypes,
 		MessageInfos:      file_shared_proto_storage_v1_storage_proto_msgTypes,
 	}.Build()
 	File_shared_proto_storage_v1_storage_proto = out.File
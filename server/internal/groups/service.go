@@ -7,6 +7,7 @@ import (
     "errors"
     "time"
     
+    "dev.c0rex64.heroin/internal/messaging"
     "github.com/google/uuid"
 )
 
@@ -220,6 +221,32 @@ func (s *Service) GetGroupMembers(ctx context.Context, groupID, requesterID stri
     return members, nil
 }
 
+// id участников беседы без проверки прав, для служебных нужд (уведомления)
+// у личной беседы участники берутся из ее id, у неизвестной беседы список пустой
+func (s *Service) ConversationMembers(ctx context.Context, conversationID string) ([]string, error) {
+    if a, b, ok := messaging.DirectParticipants(conversationID); ok {
+        return []string{a, b}, nil
+    }
+    rows, err := s.db.QueryContext(ctx, `
+        SELECT user_id FROM group_members WHERE group_id = ?
+    `, conversationID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+    
+    var ids []string
+    for rows.Next() {
+        var id string
+        if err := rows.Scan(&id); err != nil {
+            return nil, err
+        }
+        ids = append(ids, id)
+    }
+    
+    return ids, rows.Err()
+}
+
 // ротировать ключ группы
 func (s *Service) RotateGroupKey(ctx context.Context, groupID, initiatorID string) error {
     // только админ может ротировать
@@ -257,3 +284,70 @@ func (s *Service) RotateGroupKey(ctx context.Context, groupID, initiatorID strin
     
     return tx.Commit()
 }
+
+// группы пользователя без проверки прав, для служебных нужд (удаление аккаунта)
+func (s *Service) UserGroupIDs(ctx context.Context, userID string) ([]string, error) {
+    rows, err := s.db.QueryContext(ctx, `
+        SELECT group_id FROM group_members WHERE user_id = ?
+    `, userID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+    
+    var ids []string
+    for rows.Next() {
+        var id string
+        if err := rows.Scan(&id); err != nil {
+            return nil, err
+        }
+        ids = append(ids, id)
+    }
+    
+    return ids, rows.Err()
+}
+
+// исключить пользователя из группы без проверки прав и сразу ротировать ключ,
+// чтобы он не мог читать новые сообщения. опустевшая группа удаляется
+func (s *Service) RemoveUserAndRotate(ctx context.Context, groupID, userID string) error {
+    newKey := make([]byte, 32)
+    if _, err := rand.Read(newKey); err != nil {
+        return err
+    }
+    
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+    
+    _, err = tx.ExecContext(ctx, `
+        DELETE FROM group_members WHERE group_id = ? AND user_id = ?
+    `, groupID, userID)
+    if err != nil {
+        return err
+    }
+    
+    var left int
+    err = tx.QueryRowContext(ctx, `
+        SELECT COUNT(*) FROM group_members WHERE group_id = ?
+    `, groupID).Scan(&left)
+    if err != nil {
+        return err
+    }
+    
+    if left == 0 {
+        _, err = tx.ExecContext(ctx, `DELETE FROM groups WHERE id = ?`, groupID)
+    } else {
+        // участники перешифровывают ключ у себя, увидев новую версию
+        _, err = tx.ExecContext(ctx, `
+            UPDATE groups SET group_key = ?, key_version = key_version + 1
+            WHERE id = ?
+        `, newKey, groupID)
+    }
+    if err != nil {
+        return err
+    }
+    
+    return tx.Commit()
+}
@@ -0,0 +1,75 @@
+package ipfs
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// пауза предохранителя прошла, без ожидания в тесте
+func cooledDown(b *breaker) {
+	b.mu.Lock()
+	b.openedAt = time.Now().Add(-b.cooldown)
+	b.mu.Unlock()
+}
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	b := newBreaker(3, time.Hour)
+	b.record(false)
+	b.record(false)
+	// успех между неудачами сбрасывает счет
+	b.record(true)
+	b.record(false)
+	b.record(false)
+	if err := b.allow(); err != nil || b.State() != BreakerClosed {
+		t.Fatalf("after two failures in a row: %v %s", err, b.State())
+	}
+	b.record(false)
+	if b.State() != BreakerOpen {
+		t.Fatalf("after three failures in a row: %s", b.State())
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("open breaker allowed a request: %v", err)
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	b := newBreaker(1, time.Hour)
+	b.record(false)
+
+	// после паузы проходит ровно один пробный запрос
+	cooledDown(b)
+	if err := b.allow(); err != nil || b.State() != BreakerHalfOpen {
+		t.Fatalf("probe after cooldown: %v %s", err, b.State())
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second request during probe: %v", err)
+	}
+	// неудачная проба снова размыкает цепь на всю паузу
+	b.record(false)
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) || b.State() != BreakerOpen {
+		t.Fatalf("after failed probe: %v %s", err, b.State())
+	}
+
+	// отмененная проба освобождает слот, но цепь не замыкает
+	cooledDown(b)
+	if err := b.allow(); err != nil {
+		t.Fatal(err)
+	}
+	b.release()
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("after released probe: %s", b.State())
+	}
+	if err := b.allow(); err != nil {
+		t.Fatalf("probe after release: %v", err)
+	}
+	b.record(true)
+	if b.State() != BreakerClosed {
+		t.Fatalf("after successful probe: %s", b.State())
+	}
+	for i := 0; i < 3; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("closed breaker: %v", err)
+		}
+	}
+}
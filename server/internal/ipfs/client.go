@@ -3,19 +3,119 @@ package ipfs
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"mime/multipart"
 	"net/http"
 	"net/url"
 	"path"
+	"strings"
+	"sync"
 	"time"
 )
 
+// ошибка самого kubo: узел ответил, но команда не выполнилась
+type APIError struct {
+	Op      string
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s failed: %s", e.Op, e.Message)
+}
+
+// таймауты, повторы и предохранитель клиента. нулевые поля берутся из DefaultOptions
+type Options struct {
+	// короткие запросы: id, stat, refs, block, pin rm
+	Timeout time.Duration
+	// pin/add тянет dag целиком, pin/ls отдает все пины
+	PinTimeout time.Duration
+	// dag/import вместе с телом
+	ImportTimeout time.Duration
+	// dag/export до начала ответа, сам поток ограничивает только ctx
+	ExportTimeout time.Duration
+	// повторов сверх первой попытки для pin/add и dag/export, отрицательное отключает
+	Retries   int
+	RetryBase time.Duration
+	RetryMax  time.Duration
+	// неудач подряд до размыкания и пауза до пробного запроса
+	BreakerFailures int
+	BreakerCooldown time.Duration
+	// вызывается перед каждым повтором, например для метрик
+	OnRetry func(endpoint, op string)
+	// авторизация на узле: bearer token или basic, если token пуст
+	Username string
+	Password string
+	Token    string
+	// свой ca и клиентский сертификат для https узлов, см. LoadTLS
+	TLS *tls.Config
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Timeout:         30 * time.Second,
+		PinTimeout:      10 * time.Minute,
+		ImportTimeout:   time.Hour,
+		ExportTimeout:   30 * time.Second,
+		Retries:         3,
+		RetryBase:       200 * time.Millisecond,
+		RetryMax:        5 * time.Second,
+		BreakerFailures: 5,
+		BreakerCooldown: 30 * time.Second,
+	}
+}
+
+func (o Options) withDefaults() Options {
+	d := DefaultOptions()
+	if o.Timeout <= 0 {
+		o.Timeout = d.Timeout
+	}
+	if o.PinTimeout <= 0 {
+		o.PinTimeout = d.PinTimeout
+	}
+	if o.ImportTimeout <= 0 {
+		o.ImportTimeout = d.ImportTimeout
+	}
+	if o.ExportTimeout <= 0 {
+		o.ExportTimeout = d.ExportTimeout
+	}
+	if o.Retries == 0 {
+		o.Retries = d.Retries
+	} else if o.Retries < 0 {
+		o.Retries = 0
+	}
+	if o.RetryBase <= 0 {
+		o.RetryBase = d.RetryBase
+	}
+	if o.RetryMax < o.RetryBase {
+		o.RetryMax = max(d.RetryMax, o.RetryBase)
+	}
+	if o.BreakerFailures <= 0 {
+		o.BreakerFailures = d.BreakerFailures
+	}
+	if o.BreakerCooldown <= 0 {
+		o.BreakerCooldown = d.BreakerCooldown
+	}
+	return o
+}
+
 type Client struct {
 	endpoint string
-	http     *http.Client
+	// url запросов, для multiaddr собирается из него. ошибка разбора адреса
+	// возвращается из каждого запроса
+	base    string
+	baseErr error
+	http    *http.Client
+	opts    Options
+	breaker *breaker
+
+	mu     sync.Mutex
+	health Health
 }
 
 type PinAddResponse struct {
@@ -27,43 +127,209 @@ type PinAddRequest struct {
 }
 
 func New(endpoint string) *Client {
+	return NewWithOptions(endpoint, DefaultOptions())
+}
+
+// таймауты задаются на каждый запрос, у http.Client своего нет: иначе он обрывал бы
+// и долгий импорт, и поток экспорта
+func NewWithOptions(endpoint string, opts Options) *Client {
+	opts = opts.withDefaults()
+	base, socket, err := parseEndpoint(endpoint)
 	return &Client{
 		endpoint: endpoint,
-		http: &http.Client{Timeout: 30 * time.Second},
+		base:     base,
+		baseErr:  err,
+		http:     &http.Client{Transport: newTransport(socket, opts.TLS)},
+		opts:     opts,
+		breaker:  newBreaker(opts.BreakerFailures, opts.BreakerCooldown),
+		health:   Health{Endpoint: endpoint, Up: true, Breaker: BreakerClosed},
 	}
 }
 
-func (c *Client) PinAdd(ctx context.Context, cid string) error {
-	u, err := url.Parse(c.endpoint)
+func (c *Client) Endpoint() string {
+	return c.endpoint
+}
+
+// один вызов rpc kubo
+type request struct {
+	op          string
+	path        string
+	query       url.Values
+	body        io.Reader
+	contentType string
+	timeout     time.Duration
+	// повторять при недоступности узла, только для идемпотентных вызовов без тела
+	retry bool
+	// таймаут действует до начала ответа, тело читается сколько угодно
+	stream bool
+}
+
+// выполнить запрос с предохранителем и повторами. вернувший 200 ответ закрывает
+// вызывающий, Close снимает и таймаут попытки
+func (c *Client) do(ctx context.Context, r request) (*http.Response, error) {
+	u, err := c.url(r.path, r.query)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	u.Path = path.Join(u.Path, "/api/v0/pin/add")
-	q := u.Query()
-	q.Set("arg", cid)
+	attempts := 1
+	if r.retry {
+		attempts += c.opts.Retries
+	}
+	var last error
+	for i := 0; ; i++ {
+		if err := c.breaker.allow(); err != nil {
+			// цепь разомкнули наши же повторы: причина в последней ошибке узла
+			if last != nil {
+				return nil, last
+			}
+			return nil, fmt.Errorf("%s %s: %w", r.op, c.endpoint, err)
+		}
+		resp, err := c.attempt(ctx, u, r)
+		switch {
+		case ctx.Err() != nil:
+			// отмена вызывающим ничего не говорит о здоровье узла
+			c.breaker.release()
+			if err == nil {
+				resp.Body.Close()
+			}
+			return nil, ctx.Err()
+		case err == nil && resp.StatusCode == http.StatusOK:
+			c.breaker.record(true)
+			return resp, nil
+		case err == nil:
+			b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+			msg := string(bytes.TrimSpace(b))
+			if msg == "" {
+				msg = resp.Status
+			}
+			err = &APIError{Op: r.op, Status: resp.StatusCode, Message: msg}
+			if !unavailable(resp.StatusCode) {
+				c.breaker.record(true)
+				return nil, err
+			}
+		}
+		c.breaker.record(false)
+		last = err
+		if i+1 >= attempts {
+			return nil, err
+		}
+		if c.opts.OnRetry != nil {
+			c.opts.OnRetry(c.endpoint, r.op)
+		}
+		t := time.NewTimer(c.backoff(i))
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (c *Client) url(p string, q url.Values) (string, error) {
+	if c.baseErr != nil {
+		return "", c.baseErr
+	}
+	u, err := url.Parse(c.base)
+	if err != nil {
+		return "", err
+	}
+	u.Path = path.Join(u.Path, p)
 	u.RawQuery = q.Encode()
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), nil)
+	return u.String(), nil
+}
+
+func (c *Client) attempt(ctx context.Context, u string, r request) (*http.Response, error) {
+	actx, cancel := context.WithCancel(ctx)
+	timer := time.AfterFunc(r.timeout, cancel)
+	req, err := http.NewRequestWithContext(actx, http.MethodPost, u, r.body)
 	if err != nil {
-		return err
+		timer.Stop()
+		cancel()
+		return nil, err
+	}
+	if r.contentType != "" {
+		req.Header.Set("Content-Type", r.contentType)
 	}
+	c.opts.authorize(req)
 	resp, err := c.http.Do(req)
 	if err != nil {
-		return err
+		// Stop false - таймер уже сработал: таймаут попытки, ошибка узла, а не
+		// отмена вызывающим
+		fired := !timer.Stop()
+		cancel()
+		if ctx.Err() == nil && fired {
+			return nil, fmt.Errorf("%s: timeout after %s", r.op, r.timeout)
+		}
+		return nil, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("pin add failed: %s", string(b))
+	if r.stream {
+		timer.Stop()
 	}
-	return nil
+	resp.Body = &cancelBody{ReadCloser: resp.Body, timer: timer, cancel: cancel}
+	return resp, nil
 }
 
-func (c *Client) AddCAR(ctx context.Context, car []byte) (string, error) {
-	u, err := url.Parse(c.endpoint)
+// узел перегружен или за прокси нет живого kubo. 500 kubo отдает на ошибки команд,
+// такие не повторяются
+func unavailable(status int) bool {
+	switch status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return false
+}
+
+// половина экспоненциальной паузы фиксирована, вторая случайна, чтобы повторы
+// нескольких запросов не приходили к узлу разом
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.opts.RetryBase << min(attempt, 16)
+	if d > c.opts.RetryMax || d <= 0 {
+		d = c.opts.RetryMax
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	timer  *time.Timer
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.timer.Stop()
+	b.cancel()
+	return err
+}
+
+func arg(cid string) url.Values {
+	return url.Values{"arg": {cid}}
+}
+
+func (c *Client) PinAdd(ctx context.Context, cid string) error {
+	resp, err := c.do(ctx, request{op: "pin add", path: "/api/v0/pin/add", query: arg(cid), timeout: c.opts.PinTimeout, retry: true})
 	if err != nil {
-		return "", err
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (c *Client) PinRm(ctx context.Context, cid string) error {
+	resp, err := c.do(ctx, request{op: "pin rm", path: "/api/v0/pin/rm", query: arg(cid), timeout: c.opts.Timeout})
+	var ae *APIError
+	// уже откреплен, для повторных попыток это успех
+	if errors.As(err, &ae) && strings.Contains(ae.Message, "not pinned") {
+		return nil
+	}
+	if err != nil {
+		return err
 	}
-	u.Path = path.Join(u.Path, "/api/v0/dag/import")
+	return resp.Body.Close()
+}
+
+func (c *Client) AddCAR(ctx context.Context, car []byte) (string, error) {
 	buf := &bytes.Buffer{}
 	w := multipart.NewWriter(buf)
 	fw, err := w.CreateFormFile("file", "data.car")
@@ -74,53 +340,194 @@ func (c *Client) AddCAR(ctx context.Context, car []byte) (string, error) {
 		return "", err
 	}
 	w.Close()
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), buf)
+	body, err := c.DagImport(ctx, buf, w.FormDataContentType(), true)
 	if err != nil {
 		return "", err
 	}
-	req.Header.Set("Content-Type", w.FormDataContentType())
-	resp, err := c.http.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		b, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("dag import failed: %s", string(b))
-	}
+	defer body.Close()
+	// ответ вида {"Root":{"Cid":{"/":"bafy..."},"PinErrorMsg":""}}
 	var v struct {
 		Root struct {
-			Cid string `json:"/"`
+			Cid struct {
+				Path string `json:"/"`
+			} `json:"Cid"`
 		} `json:"Root"`
 	}
-	dec := json.NewDecoder(resp.Body)
+	dec := json.NewDecoder(body)
 	if err := dec.Decode(&v); err != nil {
 		return "", err
 	}
-	return v.Root.Cid, nil
+	return v.Root.Cid.Path, nil
+}
+
+// dag/import с готовым multipart телом. тело читается один раз, так что без повторов;
+// ответ ndjson разбирает вызывающий
+func (c *Client) DagImport(ctx context.Context, body io.Reader, contentType string, pinRoots bool) (io.ReadCloser, error) {
+	q := url.Values{"pin-roots": {fmt.Sprint(pinRoots)}}
+	resp, err := c.do(ctx, request{op: "dag import", path: "/api/v0/dag/import", query: q, body: body, contentType: contentType, timeout: c.opts.ImportTimeout})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
 }
 
+// поток car. повторяется только установка соединения: оборванный на середине поток
+// начинает заново вызывающий
 func (c *Client) ExportCAR(ctx context.Context, cid string) (io.ReadCloser, error) {
-	u, err := url.Parse(c.endpoint)
+	resp, err := c.do(ctx, request{op: "dag export", path: "/api/v0/dag/export", query: arg(cid), timeout: c.opts.ExportTimeout, retry: true, stream: true})
 	if err != nil {
 		return nil, err
 	}
-	u.Path = path.Join(u.Path, "/api/v0/dag/export")
-	q := u.Query()
-	q.Set("arg", cid)
-	u.RawQuery = q.Encode()
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), nil)
+	return resp.Body, nil
+}
+
+// блоки больше этого kubo не раздает по bitswap
+const MaxBlockSize = 2 << 20
+
+func (c *Client) BlockGet(ctx context.Context, cid string) ([]byte, error) {
+	resp, err := c.do(ctx, request{op: "block get", path: "/api/v0/block/get", query: arg(cid), timeout: c.opts.Timeout})
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.http.Do(req)
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, MaxBlockSize+1))
 	if err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != 200 {
-		b, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		return nil, fmt.Errorf("dag export failed: %s", string(b))
+	if len(b) > MaxBlockSize {
+		return nil, fmt.Errorf("block %s exceeds %d bytes", cid, MaxBlockSize)
 	}
-	return resp.Body, nil
+	return b, nil
+}
+
+// все уникальные cid под корнем, без самого корня
+func (c *Client) Refs(ctx context.Context, cid string) ([]string, error) {
+	q := arg(cid)
+	q.Set("recursive", "true")
+	q.Set("unique", "true")
+	resp, err := c.do(ctx, request{op: "refs", path: "/api/v0/refs", query: q, timeout: c.opts.Timeout})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var refs []string
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var v struct {
+			Ref string `json:"Ref"`
+			Err string `json:"Err"`
+		}
+		if err := dec.Decode(&v); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if v.Err != "" {
+			return nil, fmt.Errorf("refs: %s", v.Err)
+		}
+		refs = append(refs, v.Ref)
+	}
+	return refs, nil
+}
+
+// все рекурсивные пины узла, поток ndjson, чтобы не собирать огромный ответ целиком
+func (c *Client) PinLs(ctx context.Context) ([]string, error) {
+	q := url.Values{"type": {"recursive"}, "stream": {"true"}}
+	resp, err := c.do(ctx, request{op: "pin ls", path: "/api/v0/pin/ls", query: q, timeout: c.opts.PinTimeout})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var pins []string
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var v struct {
+			Cid     string `json:"Cid"`
+			Message string `json:"Message"`
+		}
+		if err := dec.Decode(&v); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if v.Message != "" {
+			return nil, fmt.Errorf("pin ls: %s", v.Message)
+		}
+		pins = append(pins, v.Cid)
+	}
+	return pins, nil
+}
+
+// версия kubo, заодно проверка, что узел отвечает
+func (c *Client) Version(ctx context.Context) (string, error) {
+	resp, err := c.do(ctx, request{op: "version", path: "/api/v0/version", timeout: c.opts.Timeout})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var v struct {
+		Version string `json:"Version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return "", err
+	}
+	return v.Version, nil
+}
+
+// размер dag и число блоков без пересчета по сети: offline, чтобы не тянуть
+// отсутствующие блоки из dht
+func (c *Client) DagStat(ctx context.Context, cid string) (int64, int64, error) {
+	q := arg(cid)
+	q.Set("progress", "false")
+	q.Set("offline", "true")
+	resp, err := c.do(ctx, request{op: "dag stat", path: "/api/v0/dag/stat", query: q, timeout: c.opts.Timeout})
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	// старые kubo отвечают {"Size","NumBlocks"}, новые {"TotalSize","DagStats":[...]}
+	var v struct {
+		Size      int64 `json:"Size"`
+		NumBlocks int64 `json:"NumBlocks"`
+		TotalSize int64 `json:"TotalSize"`
+		DagStats  []struct {
+			Size      int64 `json:"Size"`
+			NumBlocks int64 `json:"NumBlocks"`
+		} `json:"DagStats"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return 0, 0, err
+	}
+	if len(v.DagStats) > 0 {
+		return v.DagStats[0].Size, v.DagStats[0].NumBlocks, nil
+	}
+	return v.Size, v.NumBlocks, nil
+}
+
+// удалить блоки из blockstore. закрепленные kubo не удаляет и сообщает об этом по
+// каждому блоку, такие и отсутствующие блоки не ошибка. возвращает число удаленных
+func (c *Client) BlockRm(ctx context.Context, cids []string) (int, error) {
+	q := url.Values{"arg": cids, "force": {"false"}}
+	resp, err := c.do(ctx, request{op: "block rm", path: "/api/v0/block/rm", query: q, timeout: c.opts.Timeout})
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	removed := 0
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var v struct {
+			Hash  string `json:"Hash"`
+			Error string `json:"Error"`
+		}
+		if err := dec.Decode(&v); err == io.EOF {
+			break
+		} else if err != nil {
+			return removed, err
+		}
+		if v.Error == "" {
+			removed++
+		}
+	}
+	return removed, nil
 }
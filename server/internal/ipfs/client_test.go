@@ -0,0 +1,202 @@
+package ipfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// узел, который на первые fail запросов каждого пути отвечает status
+type flakyKubo struct {
+	mu     sync.Mutex
+	fail   int
+	status int
+	calls  map[string]int
+}
+
+func (k *flakyKubo) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	k.mu.Lock()
+	k.calls[r.URL.Path]++
+	n := k.calls[r.URL.Path]
+	k.mu.Unlock()
+	if n <= k.fail {
+		http.Error(w, "node busy", k.status)
+		return
+	}
+	switch r.URL.Path {
+	case "/api/v0/dag/import":
+		io.Copy(io.Discard, r.Body)
+		io.WriteString(w, `{"Root":{"Cid":{"/":"bafyroot"}}}`)
+	case "/api/v0/dag/export":
+		io.WriteString(w, "car bytes")
+	case "/api/v0/version":
+		io.WriteString(w, `{"Version":"0.27.0"}`)
+	}
+}
+
+func (k *flakyKubo) count(p string) int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.calls["/api/v0/"+p]
+}
+
+func newFlakyKubo(t *testing.T, fail, status int, opts Options) (*flakyKubo, *Client, *int) {
+	t.Helper()
+	k := &flakyKubo{fail: fail, status: status, calls: make(map[string]int)}
+	srv := httptest.NewServer(k)
+	t.Cleanup(srv.Close)
+	retries := 0
+	opts.RetryBase = time.Millisecond
+	opts.RetryMax = 2 * time.Millisecond
+	opts.OnRetry = func(string, string) { retries++ }
+	return k, NewWithOptions(srv.URL, opts), &retries
+}
+
+func TestClientRetriesIdempotentCalls(t *testing.T) {
+	ctx := context.Background()
+	k, c, retries := newFlakyKubo(t, 2, http.StatusServiceUnavailable, Options{Retries: 2, BreakerFailures: 100})
+
+	if err := c.PinAdd(ctx, "bafyroot"); err != nil {
+		t.Fatalf("pin add: %v", err)
+	}
+	rc, err := c.ExportCAR(ctx, "bafyroot")
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	b, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(b) != "car bytes" {
+		t.Fatalf("export body %q", b)
+	}
+	if k.count("pin/add") != 3 || k.count("dag/export") != 3 || *retries != 4 {
+		t.Fatalf("pin add %d calls, export %d calls, %d retries", k.count("pin/add"), k.count("dag/export"), *retries)
+	}
+
+	// тело импорта читается один раз, pin rm и короткие запросы тоже не повторяются
+	if _, err := c.AddCAR(ctx, []byte("car")); err == nil {
+		t.Fatal("import through a busy node succeeded")
+	}
+	if err := c.PinRm(ctx, "bafyroot"); err == nil {
+		t.Fatal("pin rm through a busy node succeeded")
+	}
+	if _, err := c.Version(ctx); err == nil {
+		t.Fatal("version through a busy node succeeded")
+	}
+	if k.count("dag/import") != 1 || k.count("pin/rm") != 1 || k.count("version") != 1 || *retries != 4 {
+		t.Fatalf("non-idempotent calls retried: import %d, pin rm %d, version %d", k.count("dag/import"), k.count("pin/rm"), k.count("version"))
+	}
+}
+
+func TestClientRetryLimits(t *testing.T) {
+	ctx := context.Background()
+
+	// повторы кончились: ошибка последней попытки
+	k, c, _ := newFlakyKubo(t, 100, http.StatusBadGateway, Options{Retries: 2, BreakerFailures: 100})
+	var ae *APIError
+	if err := c.PinAdd(ctx, "bafyroot"); !errors.As(err, &ae) || ae.Status != http.StatusBadGateway {
+		t.Fatalf("pin add: %v", err)
+	}
+	if k.count("pin/add") != 3 {
+		t.Fatalf("%d attempts, want 3", k.count("pin/add"))
+	}
+
+	// ошибка команды kubo не повторяется
+	k, c, _ = newFlakyKubo(t, 100, http.StatusInternalServerError, Options{Retries: 2})
+	if err := c.PinAdd(ctx, "bafyroot"); !errors.As(err, &ae) || ae.Status != http.StatusInternalServerError || ae.Message != "node busy" {
+		t.Fatalf("pin add: %v", err)
+	}
+	if k.count("pin/add") != 1 {
+		t.Fatalf("kubo command error retried: %d attempts", k.count("pin/add"))
+	}
+
+	// отрицательное число повторов отключает их
+	k, c, _ = newFlakyKubo(t, 100, http.StatusServiceUnavailable, Options{Retries: -1})
+	if err := c.PinAdd(ctx, "bafyroot"); err == nil || k.count("pin/add") != 1 {
+		t.Fatalf("pin add with retries off: %v, %d attempts", err, k.count("pin/add"))
+	}
+}
+
+func TestClientBreaker(t *testing.T) {
+	ctx := context.Background()
+	k, c, _ := newFlakyKubo(t, 2, http.StatusServiceUnavailable, Options{Retries: -1, BreakerFailures: 2})
+	for i := 0; i < 2; i++ {
+		if _, err := c.Version(ctx); err == nil {
+			t.Fatal("version through a busy node succeeded")
+		}
+	}
+	// разомкнутая цепь отклоняет запрос, не доходя до узла
+	if _, err := c.Version(ctx); !errors.Is(err, ErrCircuitOpen) || k.count("version") != 2 {
+		t.Fatalf("version with open breaker: %v, %d calls", err, k.count("version"))
+	}
+	cooledDown(c.breaker)
+	if v, err := c.Version(ctx); err != nil || v != "0.27.0" {
+		t.Fatalf("probe: %q %v", v, err)
+	}
+	if c.breaker.State() != BreakerClosed {
+		t.Fatalf("after successful probe: %s", c.breaker.State())
+	}
+
+	// ошибка команды kubo значит, что узел жив: цепь не размыкается
+	_, c, _ = newFlakyKubo(t, 100, http.StatusInternalServerError, Options{BreakerFailures: 1})
+	for i := 0; i < 3; i++ {
+		if _, err := c.Version(ctx); errors.Is(err, ErrCircuitOpen) {
+			t.Fatal("kubo command error opened the breaker")
+		}
+	}
+}
+
+func TestClientTimeouts(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v0/dag/export" {
+			// ответ начался вовремя, тело идет дольше таймаута
+			io.WriteString(w, "car ")
+			w.(http.Flusher).Flush()
+			time.Sleep(100 * time.Millisecond)
+			io.WriteString(w, "bytes")
+			return
+		}
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+	c := NewWithOptions(srv.URL, Options{Timeout: 30 * time.Millisecond, ExportTimeout: 30 * time.Millisecond, Retries: -1, BreakerFailures: 1})
+
+	if _, err := c.Version(context.Background()); err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Fatalf("hung version: %v", err)
+	}
+	if c.breaker.State() != BreakerOpen {
+		t.Fatalf("timeout did not count as a failure: %s", c.breaker.State())
+	}
+
+	c = NewWithOptions(srv.URL, Options{ExportTimeout: 30 * time.Millisecond, Retries: -1, BreakerFailures: 1})
+	rc, err := c.ExportCAR(context.Background(), "bafyroot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil || string(b) != "car bytes" {
+		t.Fatalf("slow export stream: %q %v", b, err)
+	}
+
+	// отмена вызывающим о здоровье узла ничего не говорит
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	c = NewWithOptions(srv.URL, Options{Timeout: time.Minute, Retries: -1, BreakerFailures: 1})
+	if _, err := c.Version(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("cancelled version: %v", err)
+	}
+	if c.breaker.State() != BreakerClosed {
+		t.Fatalf("caller cancellation opened the breaker: %s", c.breaker.State())
+	}
+}
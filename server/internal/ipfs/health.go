@@ -0,0 +1,101 @@
+package ipfs
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+)
+
+// проверка здоровья не ждет обычного таймаута запросов
+const probeTimeout = 5 * time.Second
+
+// состояние узла по последней проверке /api/v0/id
+type Health struct {
+	Endpoint  string
+	Up        bool
+	Breaker   string
+	PeerID    string
+	Agent     string
+	Latency   time.Duration
+	CheckedAt time.Time
+	Err       string
+}
+
+// проверить узел. проверка идет мимо предохранителя и сама его переключает:
+// после поднявшегося узла запросы проходят, не дожидаясь пробного
+func (c *Client) Probe(ctx context.Context) error {
+	start := time.Now()
+	peer, agent, err := c.id(ctx)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	c.breaker.record(err == nil)
+	h := Health{Endpoint: c.endpoint, Up: err == nil, Breaker: c.breaker.State(), PeerID: peer, Agent: agent, Latency: time.Since(start), CheckedAt: time.Now()}
+	if err != nil {
+		h.Err = err.Error()
+	}
+	c.mu.Lock()
+	was := c.health.Up
+	c.health = h
+	c.mu.Unlock()
+	if was != h.Up {
+		if err != nil {
+			slog.Warn("ipfs: node down", "node", c.endpoint, "error", err)
+		} else {
+			slog.Info("ipfs: node up", "node", c.endpoint)
+		}
+	}
+	return err
+}
+
+func (c *Client) id(ctx context.Context) (string, string, error) {
+	u, err := c.url("/api/v0/id", nil)
+	if err != nil {
+		return "", "", err
+	}
+	resp, err := c.attempt(ctx, u, request{op: "id", timeout: min(probeTimeout, c.opts.Timeout)})
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return "", "", &APIError{Op: "id", Status: resp.StatusCode, Message: resp.Status}
+	}
+	var v struct {
+		ID           string `json:"ID"`
+		AgentVersion string `json:"AgentVersion"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return "", "", err
+	}
+	return v.ID, v.AgentVersion, nil
+}
+
+// последняя проверка, предохранитель - текущий. до первой проверки узел считается живым
+func (c *Client) Health() Health {
+	c.mu.Lock()
+	h := c.health
+	c.mu.Unlock()
+	h.Breaker = c.breaker.State()
+	return h
+}
+
+// проверка раз в interval, первая сразу. f вызывается после каждой
+func (c *Client) StartHealthChecks(ctx context.Context, interval time.Duration, f func(Health)) {
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			c.Probe(ctx)
+			if f != nil && ctx.Err() == nil {
+				f(c.Health())
+			}
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,110 @@
+package ipfs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+// tls для удаленных узлов: свой ca вместо системных и клиентский сертификат для mtls.
+// пустые пути не меняют настроек по умолчанию, nil без всех четырех полей
+func LoadTLS(caFile, certFile, keyFile, serverName string) (*tls.Config, error) {
+	if caFile == "" && certFile == "" && keyFile == "" && serverName == "" {
+		return nil, nil
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: serverName}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read ipfs ca: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", caFile)
+		}
+	}
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("ipfs client cert and key must be set together")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load ipfs client cert: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// адрес узла: url (http://host:5001) или multiaddr, как в файле api kubo:
+// /ip4/10.0.0.5/tcp/5001, /dns4/kubo/tcp/5001/https (или /tls/http), /unix/run/kubo.sock.
+// возвращает базовый url запросов и сокет для unix, иначе пустой
+func parseEndpoint(endpoint string) (string, string, error) {
+	if !strings.HasPrefix(endpoint, "/") {
+		return endpoint, "", nil
+	}
+	addr, err := multiaddr.NewMultiaddr(endpoint)
+	if err != nil {
+		return "", "", fmt.Errorf("ipfs endpoint %q: %w", endpoint, err)
+	}
+	scheme := "http"
+	var rest []multiaddr.Multiaddr
+	for _, p := range multiaddr.Split(addr) {
+		switch p.Protocols()[0].Code {
+		case multiaddr.P_HTTPS, multiaddr.P_TLS:
+			scheme = "https"
+		case multiaddr.P_HTTP:
+		default:
+			rest = append(rest, p)
+		}
+	}
+	network, host, err := manet.DialArgs(multiaddr.Join(rest...))
+	if err != nil {
+		return "", "", fmt.Errorf("ipfs endpoint %q: %w", endpoint, err)
+	}
+	if network == "unix" {
+		if scheme != "http" {
+			return "", "", fmt.Errorf("ipfs endpoint %q: tls over unix socket is not supported", endpoint)
+		}
+		// хост в url только для заголовка Host, соединение идет в сокет
+		return "http://unix", host, nil
+	}
+	if !strings.HasPrefix(network, "tcp") {
+		return "", "", fmt.Errorf("ipfs endpoint %q: only tcp and unix are supported", endpoint)
+	}
+	return scheme + "://" + host, "", nil
+}
+
+// транспорт клиента. свой, а не DefaultTransport: tls и сокет у каждого узла свои
+func newTransport(socket string, cfg *tls.Config) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg != nil {
+		t.TLSClientConfig = cfg.Clone()
+	}
+	if socket != "" {
+		d := &net.Dialer{}
+		t.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return d.DialContext(ctx, "unix", socket)
+		}
+	}
+	return t
+}
+
+// заголовок Authorization под API.Authorizations kubo, token важнее пары логин/пароль
+func (o Options) authorize(req *http.Request) {
+	switch {
+	case o.Token != "":
+		req.Header.Set("Authorization", "Bearer "+o.Token)
+	case o.Username != "" || o.Password != "":
+		req.SetBasicAuth(o.Username, o.Password)
+	}
+}
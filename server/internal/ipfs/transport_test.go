@@ -0,0 +1,225 @@
+package ipfs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	cases := []struct {
+		endpoint     string
+		base, socket string
+	}{
+		{"http://127.0.0.1:5001", "http://127.0.0.1:5001", ""},
+		{"/ip4/10.0.0.5/tcp/5001", "http://10.0.0.5:5001", ""},
+		{"/ip4/10.0.0.5/tcp/5001/http", "http://10.0.0.5:5001", ""},
+		{"/ip6/::1/tcp/5001", "http://[::1]:5001", ""},
+		{"/dns4/kubo/tcp/5001/https", "https://kubo:5001", ""},
+		{"/dns/kubo/tcp/5001/tls/http", "https://kubo:5001", ""},
+		{"/unix/run/kubo/api.sock", "http://unix", "/run/kubo/api.sock"},
+	}
+	for _, tc := range cases {
+		base, socket, err := parseEndpoint(tc.endpoint)
+		if err != nil || base != tc.base || socket != tc.socket {
+			t.Errorf("%s: %q %q %v", tc.endpoint, base, socket, err)
+		}
+	}
+	for _, bad := range []string{"/ip4/10.0.0.5/udp/5001", "/ip4/10.0.0.5", "/nope/5001", "/ip4/999.0.0.1/tcp/5001"} {
+		if _, _, err := parseEndpoint(bad); err == nil {
+			t.Errorf("%s accepted", bad)
+		}
+	}
+
+	// плохой адрес не мешает создать клиент, ошибку отдает каждый запрос
+	c := New("/ip4/10.0.0.5/udp/5001")
+	if _, err := c.Version(context.Background()); err == nil {
+		t.Fatal("request through a bad endpoint succeeded")
+	}
+}
+
+func TestClientOverUnixSocket(t *testing.T) {
+	// путь сокета ограничен ~100 байтами, TempDir теста бывает длиннее
+	dir, err := os.MkdirTemp("", "kubo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	sock := filepath.Join(dir, "api.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"Version":"0.27.0"}`)
+	})}
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+
+	c := New("/unix" + sock)
+	if v, err := c.Version(context.Background()); err != nil || v != "0.27.0" {
+		t.Fatalf("version over unix socket: %q %v", v, err)
+	}
+}
+
+func TestClientAuthorization(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Authorization")
+		io.WriteString(w, `{"Version":"0.27.0"}`)
+	}))
+	t.Cleanup(srv.Close)
+	cases := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"none", Options{}, ""},
+		{"bearer", Options{Token: "secret"}, "Bearer secret"},
+		{"basic", Options{Username: "kubo", Password: "pass"}, "Basic a3VibzpwYXNz"},
+		{"token wins", Options{Token: "secret", Username: "kubo", Password: "pass"}, "Bearer secret"},
+	}
+	for _, tc := range cases {
+		got = ""
+		if _, err := NewWithOptions(srv.URL, tc.opts).Version(context.Background()); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: Authorization %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+// самоподписанный клиентский сертификат в pem файлах
+func writeClientCert(t *testing.T, dir string) (*x509.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return cert, certFile, keyFile
+}
+
+func writePEM(t *testing.T, path, typ string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	clientCert, certFile, keyFile := writeClientCert(t, dir)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"Version":"0.27.0"}`)
+	}))
+	clients := x509.NewCertPool()
+	clients.AddCert(clientCert)
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clients}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	caFile := filepath.Join(dir, "ca.pem")
+	writePEM(t, caFile, "CERTIFICATE", srv.Certificate().Raw)
+
+	version := func(endpoint string, cfg *tls.Config) error {
+		_, err := NewWithOptions(endpoint, Options{TLS: cfg, Retries: -1}).Version(context.Background())
+		return err
+	}
+	cfg, err := LoadTLS(caFile, certFile, keyFile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := version(srv.URL, cfg); err != nil {
+		t.Fatalf("mtls: %v", err)
+	}
+	// тот же узел multiaddr с /https, имя для проверки сертификата из server_name
+	addr := srv.Listener.Addr().(*net.TCPAddr)
+	named, err := LoadTLS(caFile, certFile, keyFile, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := version("/ip4/127.0.0.1/tcp/"+strconv.Itoa(addr.Port)+"/https", named); err != nil {
+		t.Fatalf("mtls via multiaddr: %v", err)
+	}
+
+	noCert, err := LoadTLS(caFile, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := version(srv.URL, noCert); err == nil {
+		t.Fatal("server accepted a client without a certificate")
+	}
+	noCA, err := LoadTLS("", certFile, keyFile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := version(srv.URL, noCA); err == nil {
+		t.Fatal("client trusted a server certificate outside its ca")
+	}
+	wrongName, err := LoadTLS(caFile, certFile, keyFile, "kubo.internal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := version(srv.URL, wrongName); err == nil {
+		t.Fatal("client accepted a certificate for another name")
+	}
+}
+
+func TestLoadTLS(t *testing.T) {
+	dir := t.TempDir()
+	_, certFile, keyFile := writeClientCert(t, dir)
+	if cfg, err := LoadTLS("", "", "", ""); cfg != nil || err != nil {
+		t.Fatalf("empty settings: %v %v", cfg, err)
+	}
+	empty := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(empty, []byte("no certs here"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for name, args := range map[string][4]string{
+		"cert without key": {"", certFile, "", ""},
+		"key without cert": {"", "", keyFile, ""},
+		"missing ca":       {filepath.Join(dir, "missing.pem"), "", "", ""},
+		"ca without certs": {empty, "", "", ""},
+		"key for cert":     {"", keyFile, certFile, ""},
+	} {
+		if _, err := LoadTLS(args[0], args[1], args[2], args[3]); err == nil {
+			t.Errorf("%s accepted", name)
+		}
+	}
+}
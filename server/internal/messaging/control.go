@@ -0,0 +1,64 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// тип конверта, совпадает с EnvelopeKind в messaging.proto
+type EnvelopeKind int32
+
+const (
+	KindMessage EnvelopeKind = iota
+	KindEdit
+	KindDelete
+	KindReact
+	KindUnreact
+)
+
+var (
+	ErrTargetNotFound = errors.New("target message not found")
+	ErrNotSender      = errors.New("only the original sender can edit or delete")
+	ErrNotParticipant = errors.New("sender is not a participant of the conversation")
+)
+
+func (k EnvelopeKind) valid() bool {
+	return k >= KindMessage && k <= KindUnreact
+}
+
+// править и удалять у всех может только автор исходного сообщения
+func (k EnvelopeKind) senderOnly() bool {
+	return k == KindEdit || k == KindDelete
+}
+
+// проверить и применить управляющий конверт
+func (s *Service) applyControl(ctx context.Context, env EnvelopeData, raw []byte) error {
+	if env.TargetMessageID == "" {
+		return errors.New("missing target message id")
+	}
+	if env.TargetMessageID == env.MessageID {
+		return errors.New("envelope cannot target itself")
+	}
+	b, err := s.q.Get(ctx, env.ConversationID, env.TargetMessageID)
+	if errors.Is(err, ErrNotFound) {
+		return ErrTargetNotFound
+	}
+	if err != nil {
+		return err
+	}
+	var target EnvelopeData
+	if err := json.Unmarshal(b, &target); err != nil {
+		return err
+	}
+	// управляющие конверты ссылаются только на обычные сообщения
+	if target.Kind != KindMessage {
+		return errors.New("target is not a message")
+	}
+	if env.Kind.senderOnly() && target.SenderID != env.SenderID {
+		return ErrNotSender
+	}
+	sentAt := time.Unix(env.SentAtUnix, 0)
+	return s.q.EnqueueControl(ctx, env.ConversationID, env.MessageID, env.TargetMessageID, env.Kind, env.SenderID, raw, sentAt)
+}
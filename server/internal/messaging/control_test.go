@@ -0,0 +1,152 @@
+package messaging
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"dev.c0rex64.heroin/internal/store"
+)
+
+const conv = "dm:alice:bob"
+
+type testKeys map[string]ed25519.PrivateKey
+
+func (k testKeys) GetPublicKey(_ context.Context, userID string) ([]byte, error) {
+	priv, ok := k[userID]
+	if !ok {
+		return nil, errors.New("unknown user")
+	}
+	return priv.Public().(ed25519.PublicKey), nil
+}
+
+func newTestService(t *testing.T) (*Service, testKeys) {
+	t.Helper()
+	db, err := store.Open(context.Background(), "file:"+t.TempDir()+"/messaging.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	keys := testKeys{}
+	for _, user := range []string{"alice", "bob"} {
+		_, priv, _ := ed25519.GenerateKey(nil)
+		keys[user] = priv
+	}
+	return NewService(NewQueue(db.SQL), keys), keys
+}
+
+// подписанный конверт от sender
+func send(t *testing.T, s *Service, keys testKeys, sender, id string, kind EnvelopeKind, target string) error {
+	t.Helper()
+	env := EnvelopeData{ConversationID: conv, MessageID: id, Ciphertext: []byte(id), SentAtUnix: 1, SenderID: sender, Kind: kind, TargetMessageID: target}
+	env.Signature = ed25519.Sign(keys[sender], signPayload(env))
+	b, err := json.Marshal(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.Send(context.Background(), b)
+}
+
+func stored(t *testing.T, s *Service, id string) bool {
+	t.Helper()
+	_, err := s.q.Get(context.Background(), conv, id)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		t.Fatal(err)
+	}
+	return err == nil
+}
+
+func TestControlOnlySenderEditsAndDeletes(t *testing.T) {
+	s, keys := newTestService(t)
+	if err := send(t, s, keys, "alice", "m1", KindMessage, ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, kind := range []EnvelopeKind{KindEdit, KindDelete} {
+		if err := send(t, s, keys, "bob", "bob-control", kind, "m1"); !errors.Is(err, ErrNotSender) {
+			t.Fatalf("kind %d by another participant: %v", kind, err)
+		}
+	}
+	if !stored(t, s, "m1") || stored(t, s, "bob-control") {
+		t.Fatal("rejected control changed the queue")
+	}
+	// реагировать может любой участник
+	if err := send(t, s, keys, "bob", "r1", KindReact, "m1"); err != nil {
+		t.Fatalf("react by another participant: %v", err)
+	}
+	if err := send(t, s, keys, "alice", "e1", KindEdit, "m1"); err != nil {
+		t.Fatalf("edit by sender: %v", err)
+	}
+}
+
+func TestControlTargets(t *testing.T) {
+	s, keys := newTestService(t)
+	if err := send(t, s, keys, "alice", "m1", KindMessage, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := send(t, s, keys, "alice", "e1", KindEdit, "m1"); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name   string
+		id     string
+		target string
+		want   error
+	}{
+		{"missing target", "c1", "nope", ErrTargetNotFound},
+		{"control as target", "c2", "e1", nil},
+		{"no target", "c3", "", nil},
+		{"itself", "c4", "c4", nil},
+	}
+	for _, tc := range cases {
+		err := send(t, s, keys, "alice", tc.id, KindEdit, tc.target)
+		if err == nil || tc.want != nil && !errors.Is(err, tc.want) {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+		if stored(t, s, tc.id) {
+			t.Errorf("%s: control stored", tc.name)
+		}
+	}
+}
+
+func TestDeletePurgesTargetAndItsControls(t *testing.T) {
+	s, keys := newTestService(t)
+	for _, m := range []string{"m1", "m2"} {
+		if err := send(t, s, keys, "alice", m, KindMessage, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := send(t, s, keys, "alice", "e1", KindEdit, "m1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := send(t, s, keys, "bob", "r1", KindReact, "m1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := send(t, s, keys, "bob", "r2", KindReact, "m2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := send(t, s, keys, "alice", "d1", KindDelete, "m1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"m1", "e1", "r1"} {
+		if stored(t, s, id) {
+			t.Errorf("%s kept after delete", id)
+		}
+	}
+	// сам delete остается для офлайн-получателей, чужое не трогается
+	for _, id := range []string{"d1", "m2", "r2"} {
+		if !stored(t, s, id) {
+			t.Errorf("%s purged by delete", id)
+		}
+	}
+	var refs int
+	if err := s.q.db.QueryRow(`SELECT COUNT(*) FROM message_refs WHERE target_message_id = 'm1'`).Scan(&refs); err != nil || refs != 1 {
+		t.Fatalf("%d refs to the deleted message, want only the delete: %v", refs, err)
+	}
+	// исходника больше нет: повторное удаление и правка его не находят
+	if err := send(t, s, keys, "alice", "e2", KindEdit, "m1"); !errors.Is(err, ErrTargetNotFound) {
+		t.Fatalf("edit of a deleted message: %v", err)
+	}
+}
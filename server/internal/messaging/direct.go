@@ -0,0 +1,27 @@
+package messaging
+
+import "strings"
+
+// личная беседа адресуется id двух участников, так сервер знает их без отдельной таблицы
+const directPrefix = "dm:"
+
+// id личной беседы a и b, от порядка аргументов не зависит
+func DirectConversationID(a, b string) string {
+	if b < a {
+		a, b = b, a
+	}
+	return directPrefix + a + ":" + b
+}
+
+// участники личной беседы. ok false, если id не в каноническом виде "dm:<a>:<b>", a < b
+func DirectParticipants(conversationID string) (a, b string, ok bool) {
+	rest, found := strings.CutPrefix(conversationID, directPrefix)
+	if !found {
+		return "", "", false
+	}
+	a, b, found = strings.Cut(rest, ":")
+	if !found || a == "" || b == "" || strings.Contains(b, ":") || a >= b {
+		return "", "", false
+	}
+	return a, b, true
+}
@@ -0,0 +1,50 @@
+package messaging
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// получатели беседы: участники группы или двое из id личной беседы
+type RecipientResolver interface {
+	ConversationMembers(ctx context.Context, conversationID string) ([]string, error)
+}
+
+// есть ли у пользователя активное подключение
+type PresenceChecker interface {
+	IsOnline(userID string) bool
+}
+
+// доставка уведомлений получателям без активного подключения
+type OfflineNotifier interface {
+	NotifyOffline(ctx context.Context, userID, conversationID, messageID string, sentAt time.Time)
+}
+
+// подключить офлайн уведомления, presence может быть nil, тогда уведомляются все получатели
+func (s *Service) SetNotifier(n OfflineNotifier, recipients RecipientResolver, presence PresenceChecker) {
+	s.notifier = n
+	s.recipients = recipients
+	s.presence = presence
+}
+
+func (s *Service) notifyOffline(ctx context.Context, env EnvelopeData) {
+	if s.notifier == nil || s.recipients == nil {
+		return
+	}
+	members, err := s.recipients.ConversationMembers(ctx, env.ConversationID)
+	if err != nil {
+		slog.Error("notify: resolve recipients", "conversation_id", env.ConversationID, "error", err)
+		return
+	}
+	sentAt := time.Unix(env.SentAtUnix, 0)
+	for _, userID := range members {
+		if userID == env.SenderID {
+			continue
+		}
+		if s.presence != nil && s.presence.IsOnline(userID) {
+			continue
+		}
+		s.notifier.NotifyOffline(ctx, userID, env.ConversationID, env.MessageID, sentAt)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"time"
 )
 
+var ErrNotFound = errors.New("not found")
+
 type Queue struct {
 	db *sql.DB
 }
@@ -68,7 +70,57 @@ func (q *Queue) Delete(ctx context.Context, conversationID, messageID string) er
 	}
 	affected, _ := res.RowsAffected()
 	if affected == 0 {
-		return errors.New("not found")
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (q *Queue) Get(ctx context.Context, conversationID, messageID string) ([]byte, error) {
+	var b []byte
+	err := q.db.QueryRowContext(ctx, `SELECT envelope FROM messages WHERE conversation_id = ? AND message_id = ?`, conversationID, messageID).Scan(&b)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get: %w", err)
+	}
+	return b, nil
+}
+
+// кладет управляющий конверт вместе со ссылкой на исходное сообщение. для удаления в той же
+// транзакции убираются исходник, его правки и реакции, сам delete остается в очереди
+// для офлайн-получателей
+func (q *Queue) EnqueueControl(ctx context.Context, conversationID, messageID, targetMessageID string, kind EnvelopeKind, senderID string, envelope []byte, sentAt time.Time) error {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("enqueue control: %w", err)
+	}
+	defer tx.Rollback()
+	if kind == KindDelete {
+		if err := purge(ctx, tx, conversationID, targetMessageID); err != nil {
+			return err
+		}
+	}
+	now := time.Now().Unix()
+	if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO messages (id, conversation_id, message_id, envelope, sent_at, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		messageID, conversationID, messageID, envelope, sentAt.Unix(), now); err != nil {
+		return fmt.Errorf("enqueue control: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO message_refs (conversation_id, message_id, target_message_id, kind, sender_id, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		conversationID, messageID, targetMessageID, int(kind), senderID, now); err != nil {
+		return fmt.Errorf("enqueue control ref: %w", err)
+	}
+	return tx.Commit()
+}
+
+// удаляет исходное сообщение и все ссылающиеся на него правки и реакции
+func purge(ctx context.Context, tx *sql.Tx, conversationID, targetMessageID string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM messages WHERE conversation_id = ? AND (message_id = ? OR message_id IN (SELECT message_id FROM message_refs WHERE conversation_id = ? AND target_message_id = ?))`,
+		conversationID, targetMessageID, conversationID, targetMessageID); err != nil {
+		return fmt.Errorf("purge: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM message_refs WHERE conversation_id = ? AND target_message_id = ?`, conversationID, targetMessageID); err != nil {
+		return fmt.Errorf("purge refs: %w", err)
 	}
 	return nil
 }
@@ -19,15 +19,21 @@ type Service struct {
 	q       *Queue
 	kp      PublicKeyProvider
 	ratchet *crypto.DoubleRatchet
+
+	notifier   OfflineNotifier
+	recipients RecipientResolver
+	presence   PresenceChecker
 }
 
 type EnvelopeData struct {
-	ConversationID string `json:"conversation_id"`
-	MessageID      string `json:"message_id"`
-	Ciphertext     []byte `json:"ciphertext"`
-	Signature      []byte `json:"signature"`
-	SentAtUnix     int64  `json:"sent_at_unix"`
-	SenderID       string `json:"sender_id"`
+	ConversationID  string       `json:"conversation_id"`
+	MessageID       string       `json:"message_id"`
+	Ciphertext      []byte       `json:"ciphertext"`
+	Signature       []byte       `json:"signature"`
+	SentAtUnix      int64        `json:"sent_at_unix"`
+	SenderID        string       `json:"sender_id"`
+	Kind            EnvelopeKind `json:"kind"`
+	TargetMessageID string       `json:"target_message_id"`
 }
 
 func NewService(q *Queue, kp PublicKeyProvider) *Service {
@@ -42,6 +48,11 @@ func (s *Service) Send(ctx context.Context, envelope []byte) error {
 	if env.ConversationID == "" || env.MessageID == "" { return errors.New("missing ids") }
 	if len(env.Signature) == 0 { return errors.New("missing signature") }
 	if env.SenderID == "" { return errors.New("missing sender") }
+	if !env.Kind.valid() { return errors.New("unknown envelope kind") }
+	// в личную беседу пишут только двое из ее id, иначе чужой мог бы слать им уведомления
+	if a, b, ok := DirectParticipants(env.ConversationID); ok && env.SenderID != a && env.SenderID != b {
+		return ErrNotParticipant
+	}
 	pk, err := s.kp.GetPublicKey(ctx, env.SenderID)
 	if err != nil { return err }
 	if len(pk) != ed25519.PublicKeySize { return errors.New("invalid public key") }
@@ -49,8 +60,15 @@ func (s *Service) Send(ctx context.Context, envelope []byte) error {
 	if !ed25519.Verify(ed25519.PublicKey(pk), payload, env.Signature) {
 		return errors.New("bad signature")
 	}
+	if env.Kind != KindMessage {
+		if err := s.applyControl(ctx, env, envelope); err != nil { return err }
+		if env.Kind == KindReact { s.notifyOffline(ctx, env) }
+		return nil
+	}
 	sentAt := time.Unix(env.SentAtUnix, 0)
-	return s.q.Enqueue(ctx, env.ConversationID, env.MessageID, envelope, sentAt)
+	if err := s.q.Enqueue(ctx, env.ConversationID, env.MessageID, envelope, sentAt); err != nil { return err }
+	s.notifyOffline(ctx, env)
+	return nil
 }
 
 func (s *Service) Pull(ctx context.Context, conversationID string, since int64) ([][]byte, error) {
@@ -92,5 +110,10 @@ func signPayload(e EnvelopeData) []byte {
 	binary.BigEndian.PutUint64(ts[:], uint64(e.SentAtUnix))
 	b = append(b, ts[:]...)
 	b = append(b, e.Ciphertext...)
+	// тип и цель подписываются только у управляющих конвертов, обычные сообщения совместимы со старыми клиентами
+	if e.Kind != KindMessage {
+		b = append(b, byte(e.Kind))
+		b = append(b, []byte(e.TargetMessageID)...)
+	}
 	return b
 }
@@ -71,6 +71,176 @@ var (
             Buckets: []float64{0, 1, 5, 10, 20, 50},
         },
     )
+    
+    StorageUsedBytes = prometheus.NewGaugeVec(
+        prometheus.GaugeOpts{
+            Name: "heroin_storage_used_bytes",
+            Help: "Bytes charged to users against their quota, by plan",
+        },
+        []string{"plan"},
+    )
+    
+    StorageUsers = prometheus.NewGaugeVec(
+        prometheus.GaugeOpts{
+            Name: "heroin_storage_users",
+            Help: "Users with stored files, by plan",
+        },
+        []string{"plan"},
+    )
+    
+    StorageStoredBytes = prometheus.NewGauge(
+        prometheus.GaugeOpts{
+            Name: "heroin_storage_stored_bytes",
+            Help: "Bytes of unique referenced content",
+        },
+    )
+    
+    QuotaRejections = prometheus.NewCounter(
+        prometheus.CounterOpts{
+            Name: "heroin_quota_rejections_total",
+            Help: "Uploads rejected by storage quota",
+        },
+    )
+    
+    PinDrift = prometheus.NewGaugeVec(
+        prometheus.GaugeOpts{
+            Name: "heroin_pin_drift",
+            Help: "CIDs where Kubo pins and stored files disagree, by kind",
+        },
+        []string{"kind"},
+    )
+    
+    PinRepairs = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "heroin_pin_repairs_total",
+            Help: "Pins restored or removed by reconciliation",
+        },
+        []string{"action"},
+    )
+    
+    ReplicasUnder = prometheus.NewGauge(
+        prometheus.GaugeOpts{
+            Name: "heroin_replicas_under",
+            Help: "CIDs with fewer live copies than the replication factor",
+        },
+    )
+    
+    ReplicasUnavailable = prometheus.NewGauge(
+        prometheus.GaugeOpts{
+            Name: "heroin_replicas_unavailable",
+            Help: "CIDs without a single copy on a healthy IPFS node",
+        },
+    )
+    
+    ReplicaRepairs = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "heroin_replica_repairs_total",
+            Help: "Replica placement changes made by repair, by action",
+        },
+        []string{"action"},
+    )
+    
+    IPFSNodeUp = prometheus.NewGaugeVec(
+        prometheus.GaugeOpts{
+            Name: "heroin_ipfs_node_up",
+            Help: "Whether an IPFS node passed its last health check",
+        },
+        []string{"node"},
+    )
+    
+    BlocksLogicalBytes = prometheus.NewGauge(
+        prometheus.GaugeOpts{
+            Name: "heroin_blocks_logical_bytes",
+            Help: "Bytes of indexed blocks summed over every stored content",
+        },
+    )
+    
+    BlocksUniqueBytes = prometheus.NewGauge(
+        prometheus.GaugeOpts{
+            Name: "heroin_blocks_unique_bytes",
+            Help: "Bytes of referenced blocks, each block counted once",
+        },
+    )
+    
+    BlocksUnique = prometheus.NewGauge(
+        prometheus.GaugeOpts{
+            Name: "heroin_blocks_unique",
+            Help: "Referenced blocks in the block index",
+        },
+    )
+    
+    BlockDedupRatio = prometheus.NewGauge(
+        prometheus.GaugeOpts{
+            Name: "heroin_block_dedup_ratio",
+            Help: "Logical block bytes divided by unique block bytes",
+        },
+    )
+    
+    BlocksCollected = prometheus.NewCounter(
+        prometheus.CounterOpts{
+            Name: "heroin_blocks_collected_total",
+            Help: "Unreferenced blocks dropped by block GC",
+        },
+    )
+    
+    IPFSBreakerState = prometheus.NewGaugeVec(
+        prometheus.GaugeOpts{
+            Name: "heroin_ipfs_breaker_state",
+            Help: "Circuit breaker of an IPFS node: 0 closed, 1 half-open, 2 open",
+        },
+        []string{"node"},
+    )
+    
+    IPFSProbeSeconds = prometheus.NewGaugeVec(
+        prometheus.GaugeOpts{
+            Name: "heroin_ipfs_probe_seconds",
+            Help: "Latency of the last /api/v0/id health check of an IPFS node",
+        },
+        []string{"node"},
+    )
+    
+    IPFSRetries = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "heroin_ipfs_retries_total",
+            Help: "Retried IPFS RPC calls, by node and operation",
+        },
+        []string{"node", "op"},
+    )
+    
+    BlobCacheHits = prometheus.NewCounter(
+        prometheus.CounterOpts{
+            Name: "heroin_blob_cache_hits_total",
+            Help: "CAR reads served from the on-disk blob cache",
+        },
+    )
+    
+    BlobCacheMisses = prometheus.NewCounter(
+        prometheus.CounterOpts{
+            Name: "heroin_blob_cache_misses_total",
+            Help: "CAR reads that went to the blob store",
+        },
+    )
+    
+    BlobCacheCorrupt = prometheus.NewCounter(
+        prometheus.CounterOpts{
+            Name: "heroin_blob_cache_corrupt_total",
+            Help: "Blob cache entries that failed BLAKE3 verification while served and were dropped",
+        },
+    )
+    
+    BlobCacheBytes = prometheus.NewGauge(
+        prometheus.GaugeOpts{
+            Name: "heroin_blob_cache_bytes",
+            Help: "Bytes held in the blob cache",
+        },
+    )
+    
+    BlobCacheEntries = prometheus.NewGauge(
+        prometheus.GaugeOpts{
+            Name: "heroin_blob_cache_entries",
+            Help: "CARs held in the blob cache",
+        },
+    )
 )
 
 func init() {
@@ -83,6 +253,29 @@ func init() {
         RelayHops,
         CARStreamBytes,
         DHTPeersFound,
+        StorageUsedBytes,
+        StorageUsers,
+        StorageStoredBytes,
+        QuotaRejections,
+        PinDrift,
+        PinRepairs,
+        ReplicasUnder,
+        ReplicasUnavailable,
+        ReplicaRepairs,
+        IPFSNodeUp,
+        BlocksLogicalBytes,
+        BlocksUniqueBytes,
+        BlocksUnique,
+        BlockDedupRatio,
+        BlocksCollected,
+        IPFSBreakerState,
+        IPFSProbeSeconds,
+        IPFSRetries,
+        BlobCacheHits,
+        BlobCacheMisses,
+        BlobCacheCorrupt,
+        BlobCacheBytes,
+        BlobCacheEntries,
     )
 }
 
@@ -129,6 +322,82 @@ func (c *Collector) RecordDHTPeers(count int) {
     DHTPeersFound.Observe(float64(count))
 }
 
+// использование квот по тарифам, тарифы без пользователей пропадают из метрик
+func (c *Collector) SetStorageUsage(users map[string]int, used map[string]int64, stored int64) {
+    StorageUsedBytes.Reset()
+    StorageUsers.Reset()
+    for plan, n := range users {
+        StorageUsers.WithLabelValues(plan).Set(float64(n))
+        StorageUsedBytes.WithLabelValues(plan).Set(float64(used[plan]))
+    }
+    StorageStoredBytes.Set(float64(stored))
+}
+
+func (c *Collector) RecordQuotaRejection() {
+    QuotaRejections.Inc()
+}
+
+// итог сверки пинов: найденные расхождения и исправления за проход
+func (c *Collector) RecordPinReconcile(missing, orphaned, repinned, unpinned int) {
+    PinDrift.WithLabelValues("missing").Set(float64(missing))
+    PinDrift.WithLabelValues("orphan").Set(float64(orphaned))
+    PinRepairs.WithLabelValues("repin").Add(float64(repinned))
+    PinRepairs.WithLabelValues("unpin").Add(float64(unpinned))
+}
+
+// итог прохода восстановления реплик
+func (c *Collector) RecordReplicaRepair(under, unavailable, copied, adopted, lost int) {
+    ReplicasUnder.Set(float64(under))
+    ReplicasUnavailable.Set(float64(unavailable))
+    ReplicaRepairs.WithLabelValues("copy").Add(float64(copied))
+    ReplicaRepairs.WithLabelValues("adopt").Add(float64(adopted))
+    ReplicaRepairs.WithLabelValues("lost").Add(float64(lost))
+}
+
+func (c *Collector) SetIPFSNodeUp(node string, up bool) {
+    v := 0.0
+    if up {
+        v = 1
+    }
+    IPFSNodeUp.WithLabelValues(node).Set(v)
+}
+
+// итог проверки здоровья узла ipfs, breaker - 0 замкнут, 1 пробный запрос, 2 разомкнут
+func (c *Collector) SetIPFSHealth(node string, up bool, breaker int, probe time.Duration) {
+    c.SetIPFSNodeUp(node, up)
+    IPFSBreakerState.WithLabelValues(node).Set(float64(breaker))
+    IPFSProbeSeconds.WithLabelValues(node).Set(probe.Seconds())
+}
+
+func (c *Collector) RecordIPFSRetry(node, op string) {
+    IPFSRetries.WithLabelValues(node, op).Inc()
+}
+
+// итог прохода gc блоков и сводка дедупликации
+func (c *Collector) RecordBlockIndex(logical, unique, blocks int64, ratio float64, collected int) {
+    BlocksLogicalBytes.Set(float64(logical))
+    BlocksUniqueBytes.Set(float64(unique))
+    BlocksUnique.Set(float64(blocks))
+    BlockDedupRatio.Set(ratio)
+    BlocksCollected.Add(float64(collected))
+}
+
+// обращение к кэшу car: hit, miss или corrupt (отданная запись не сошлась с BLAKE3, само
+// обращение уже засчитано как hit), и размер кэша после него.
+// пустой result только обновляет размер
+func (c *Collector) RecordBlobCache(result string, bytes int64, entries int) {
+    switch result {
+    case "hit":
+        BlobCacheHits.Inc()
+    case "corrupt":
+        BlobCacheCorrupt.Inc()
+    case "miss":
+        BlobCacheMisses.Inc()
+    }
+    BlobCacheBytes.Set(float64(bytes))
+    BlobCacheEntries.Set(float64(entries))
+}
+
 func (c *Collector) StartPeriodicCollection(ctx context.Context, interval time.Duration) {
     ticker := time.NewTicker(interval)
     go func() {
@@ -0,0 +1,135 @@
+package notify
+
+import (
+	"context"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+type job struct {
+	n       Notification
+	attempt int
+	lastErr error
+}
+
+// асинхронная доставка с повторами, экспоненциальной задержкой и dead letter
+type Dispatcher struct {
+	notifier    Notifier
+	store       *Store
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+
+	jobs chan job
+	wg   sync.WaitGroup
+}
+
+func NewDispatcher(n Notifier, store *Store, maxAttempts int) *Dispatcher {
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	return &Dispatcher{
+		notifier:    n,
+		store:       store,
+		maxAttempts: maxAttempts,
+		baseDelay:   time.Second,
+		maxDelay:    5 * time.Minute,
+		jobs:        make(chan job, 1024),
+	}
+}
+
+// запустить воркеры, останавливаются по отмене ctx
+func (d *Dispatcher) Start(ctx context.Context, workers int) {
+	if workers <= 0 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		d.wg.Add(1)
+		go d.worker(ctx)
+	}
+}
+
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// разослать уведомление на все устройства пользователя, вызывается когда у него нет активного подключения
+func (d *Dispatcher) NotifyOffline(ctx context.Context, userID, conversationID, messageID string, sentAt time.Time) {
+	tokens, err := d.store.TokensForUser(ctx, userID)
+	if err != nil {
+		slog.Error("notify: load push tokens", "user_id", userID, "error", err)
+		return
+	}
+	for _, t := range tokens {
+		d.enqueue(job{n: Notification{
+			UserID:         userID,
+			DeviceID:       t.DeviceID,
+			Platform:       t.Platform,
+			Token:          t.Token,
+			ConversationID: conversationID,
+			MessageID:      messageID,
+			SentAt:         sentAt,
+		}})
+	}
+}
+
+func (d *Dispatcher) enqueue(j job) {
+	select {
+	case d.jobs <- j:
+	default:
+		// очередь переполнена, не блокируем отправку сообщения
+		d.deadLetter(j)
+	}
+}
+
+func (d *Dispatcher) worker(ctx context.Context) {
+	defer d.wg.Done()
+	for {
+		select {
+		case j := <-d.jobs:
+			d.deliver(ctx, j)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, j job) {
+	j.attempt++
+	err := d.notifier.Notify(ctx, j.n)
+	if err == nil {
+		return
+	}
+	j.lastErr = err
+	if isPermanent(err) || j.attempt >= d.maxAttempts {
+		d.deadLetter(j)
+		return
+	}
+	delay := d.backoff(j.attempt)
+	time.AfterFunc(delay, func() {
+		if ctx.Err() != nil {
+			return
+		}
+		d.enqueue(j)
+	})
+}
+
+// экспоненциальная задержка с джиттером ±50%
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.baseDelay << uint(attempt-1)
+	if delay > d.maxDelay || delay <= 0 {
+		delay = d.maxDelay
+	}
+	half := int64(delay) / 2
+	return time.Duration(half + rand.Int63n(int64(delay)))
+}
+
+func (d *Dispatcher) deadLetter(j job) {
+	slog.Warn("notify: dead letter", "user_id", j.n.UserID, "device_id", j.n.DeviceID, "attempts", j.attempt, "error", j.lastErr)
+	// пишем даже при остановке сервера, чтобы не потерять уведомление
+	if err := d.store.DeadLetter(context.Background(), j.n, j.attempt, j.lastErr); err != nil {
+		slog.Error("notify: store dead letter", "error", err)
+	}
+}
@@ -0,0 +1,155 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"dev.c0rex64.heroin/internal/store"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	db, err := store.Open(context.Background(), "file:"+t.TempDir()+"/notify.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.SQL.Exec(`INSERT INTO users (id, username, server_salt, password_hash, second_factor_secret, created_at) VALUES ('u1', 'u1', x'00', x'00', x'00', 0)`); err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(db.SQL)
+	if err := s.RegisterToken(context.Background(), PushToken{UserID: "u1", DeviceID: "d1", Platform: "fcm", Token: "push-token"}); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// relay, который отвечает кодами из codes по очереди, последний повторяется
+func newRelay(codes ...int) (*httptest.Server, *atomic.Int32) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(hits.Add(1))
+		w.WriteHeader(codes[min(n, len(codes))-1])
+	}))
+	return srv, &hits
+}
+
+func newTestDispatcher(t *testing.T, s *Store, url string, maxAttempts int) *Dispatcher {
+	t.Helper()
+	d := NewDispatcher(NewWebhookNotifier(url, []byte("s"), time.Second), s, maxAttempts)
+	d.baseDelay = time.Millisecond
+	d.maxDelay = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	d.Start(ctx, 2)
+	t.Cleanup(func() {
+		cancel()
+		d.Wait()
+	})
+	return d
+}
+
+type deadLetter struct {
+	attempts int
+	lastErr  string
+}
+
+func deadLetters(t *testing.T, s *Store) []deadLetter {
+	t.Helper()
+	rows, err := s.db.Query(`SELECT attempts, last_error FROM notification_dead_letters WHERE user_id = 'u1' AND device_id = 'd1' AND message_id = 'm1'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var res []deadLetter
+	for rows.Next() {
+		var dl deadLetter
+		if err := rows.Scan(&dl.attempts, &dl.lastErr); err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, dl)
+	}
+	return res
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDispatcherRetriesUntilDelivered(t *testing.T) {
+	s := newTestStore(t)
+	relay, hits := newRelay(http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK)
+	defer relay.Close()
+	d := newTestDispatcher(t, s, relay.URL, 5)
+
+	d.NotifyOffline(context.Background(), "u1", "dm:u1:u2", "m1", time.Now())
+	waitFor(t, "delivery", func() bool { return hits.Load() == 3 })
+	time.Sleep(50 * time.Millisecond)
+	if n := hits.Load(); n != 3 {
+		t.Fatalf("relay hit %d times after success", n)
+	}
+	if dl := deadLetters(t, s); len(dl) != 0 {
+		t.Fatalf("dead letters %v", dl)
+	}
+}
+
+func TestDispatcherDeadLettersAfterMaxAttempts(t *testing.T) {
+	s := newTestStore(t)
+	relay, hits := newRelay(http.StatusBadGateway)
+	defer relay.Close()
+	d := newTestDispatcher(t, s, relay.URL, 3)
+
+	d.NotifyOffline(context.Background(), "u1", "dm:u1:u2", "m1", time.Now())
+	waitFor(t, "dead letter", func() bool { return len(deadLetters(t, s)) == 1 })
+	if n := hits.Load(); n != 3 {
+		t.Fatalf("relay hit %d times, want 3", n)
+	}
+	dl := deadLetters(t, s)[0]
+	if dl.attempts != 3 || dl.lastErr == "" {
+		t.Fatalf("dead letter %+v", dl)
+	}
+}
+
+func TestDispatcherPermanentErrorIsNotRetried(t *testing.T) {
+	s := newTestStore(t)
+	relay, hits := newRelay(http.StatusBadRequest)
+	defer relay.Close()
+	d := newTestDispatcher(t, s, relay.URL, 5)
+
+	d.NotifyOffline(context.Background(), "u1", "dm:u1:u2", "m1", time.Now())
+	waitFor(t, "dead letter", func() bool { return len(deadLetters(t, s)) == 1 })
+	time.Sleep(50 * time.Millisecond)
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("relay hit %d times, want 1", n)
+	}
+	if dl := deadLetters(t, s)[0]; dl.attempts != 1 {
+		t.Fatalf("dead letter %+v", dl)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDispatcher(nil, nil, 0)
+	for attempt := 1; attempt <= 20; attempt++ {
+		want := min(d.baseDelay<<uint(attempt-1), d.maxDelay)
+		for i := 0; i < 100; i++ {
+			got := d.backoff(attempt)
+			if got < want/2 || got >= want*3/2 {
+				t.Fatalf("attempt %d: delay %v outside [%v, %v)", attempt, got, want/2, want*3/2)
+			}
+		}
+	}
+	// сдвиг за пределы int64 не дает отрицательной задержки
+	if got := d.backoff(70); got < d.maxDelay/2 {
+		t.Fatalf("attempt 70: delay %v", got)
+	}
+}
@@ -0,0 +1,37 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// уведомление о новом конверте, без содержимого сообщения
+type Notification struct {
+	UserID         string
+	DeviceID       string
+	Platform       string
+	Token          string
+	ConversationID string
+	MessageID      string
+	SentAt         time.Time
+}
+
+// транспорт доставки уведомлений, реализации подключаются через конфиг
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// ошибка, которую нет смысла повторять (например 4xx от relay)
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+func isPermanent(err error) bool {
+	var p *PermanentError
+	return errors.As(err, &p)
+}
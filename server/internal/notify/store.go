@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// push токен устройства
+type PushToken struct {
+	UserID   string
+	DeviceID string
+	Platform string
+	Token    string
+}
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) RegisterToken(ctx context.Context, t PushToken) error {
+	if t.DeviceID == "" || t.Token == "" {
+		return errors.New("missing device id or token")
+	}
+	_, err := s.db.ExecContext(ctx, `INSERT INTO push_tokens (user_id, device_id, platform, token, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, device_id) DO UPDATE SET platform = excluded.platform, token = excluded.token, updated_at = excluded.updated_at`,
+		t.UserID, t.DeviceID, t.Platform, t.Token, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("register push token: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) UnregisterToken(ctx context.Context, userID, deviceID string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM push_tokens WHERE user_id = ? AND device_id = ?`, userID, deviceID)
+	if err != nil {
+		return fmt.Errorf("unregister push token: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) TokensForUser(ctx context.Context, userID string) ([]PushToken, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT device_id, platform, token FROM push_tokens WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list push tokens: %w", err)
+	}
+	defer rows.Close()
+	var res []PushToken
+	for rows.Next() {
+		t := PushToken{UserID: userID}
+		if err := rows.Scan(&t.DeviceID, &t.Platform, &t.Token); err != nil {
+			return nil, err
+		}
+		res = append(res, t)
+	}
+	return res, rows.Err()
+}
+
+func (s *Store) DeadLetter(ctx context.Context, n Notification, attempts int, lastErr error) error {
+	msg := ""
+	if lastErr != nil {
+		msg = lastErr.Error()
+	}
+	_, err := s.db.ExecContext(ctx, `INSERT INTO notification_dead_letters (id, user_id, device_id, conversation_id, message_id, attempts, last_error, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		uuid.NewString(), n.UserID, n.DeviceID, n.ConversationID, n.MessageID, attempts, msg, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("dead letter: %w", err)
+	}
+	return nil
+}
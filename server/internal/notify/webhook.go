@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Heroin-Signature"
+	TimestampHeader = "X-Heroin-Timestamp"
+)
+
+// тело вебхука, содержимое сообщения никогда не передается
+type webhookPayload struct {
+	Event          string `json:"event"`
+	UserID         string `json:"user_id"`
+	DeviceID       string `json:"device_id"`
+	Platform       string `json:"platform"`
+	Token          string `json:"token"`
+	ConversationID string `json:"conversation_id"`
+	MessageID      string `json:"message_id"`
+	SentAtUnix     int64  `json:"sent_at_unix"`
+}
+
+// универсальный вебхук: json с hmac-sha256 подписью, relay сам доставляет пуш в fcm/apns
+type WebhookNotifier struct {
+	url    string
+	secret []byte
+	http   *http.Client
+}
+
+func NewWebhookNotifier(url string, secret []byte, timeout time.Duration) *WebhookNotifier {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &WebhookNotifier{url: url, secret: secret, http: &http.Client{Timeout: timeout}}
+}
+
+func (w *WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(webhookPayload{
+		Event:          "message",
+		UserID:         n.UserID,
+		DeviceID:       n.DeviceID,
+		Platform:       n.Platform,
+		Token:          n.Token,
+		ConversationID: n.ConversationID,
+		MessageID:      n.MessageID,
+		SentAtUnix:     n.SentAt.Unix(),
+	})
+	if err != nil {
+		return &PermanentError{Err: err}
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return &PermanentError{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, ts)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(w.secret, ts, body))
+	resp, err := w.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("webhook %s: %s", resp.Status, string(b))
+	// 4xx кроме 429 повторять бесполезно
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return &PermanentError{Err: err}
+	}
+	return err
+}
+
+// подпись считается от "timestamp.body", relay проверяет ее тем же секретом
+func Sign(secret []byte, timestamp string, body []byte) string {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
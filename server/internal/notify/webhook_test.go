@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var testNotification = Notification{
+	UserID:         "u1",
+	DeviceID:       "d1",
+	Platform:       "fcm",
+	Token:          "push-token",
+	ConversationID: "dm:u1:u2",
+	MessageID:      "m1",
+	SentAt:         time.Unix(1700000000, 0),
+}
+
+func TestWebhookSignature(t *testing.T) {
+	secret := []byte("webhook-secret")
+	type request struct {
+		header http.Header
+		body   []byte
+	}
+	got := make(chan request, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- request{r.Header.Clone(), body}
+	}))
+	defer srv.Close()
+
+	if err := NewWebhookNotifier(srv.URL, secret, time.Second).Notify(context.Background(), testNotification); err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+	req := <-got
+
+	ts := req.header.Get(TimestampHeader)
+	sent, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || time.Since(time.Unix(sent, 0)).Abs() > time.Minute {
+		t.Fatalf("timestamp header %q", ts)
+	}
+	// подпись по описанию для relay: hmac-sha256 от "timestamp.body"
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts + "."))
+	mac.Write(req.body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if sig := req.header.Get(SignatureHeader); sig != want {
+		t.Fatalf("signature %q, want %q", sig, want)
+	}
+	if Sign([]byte("other"), ts, req.body) == want[len("sha256="):] {
+		t.Fatal("signature does not depend on secret")
+	}
+
+	var p map[string]any
+	if err := json.Unmarshal(req.body, &p); err != nil {
+		t.Fatalf("body: %v", err)
+	}
+	if p["conversation_id"] != "dm:u1:u2" || p["message_id"] != "m1" || p["token"] != "push-token" || p["sent_at_unix"] != float64(1700000000) {
+		t.Fatalf("payload %v", p)
+	}
+	for _, k := range []string{"ciphertext", "envelope", "text"} {
+		if _, ok := p[k]; ok {
+			t.Fatalf("payload carries message content %q", k)
+		}
+	}
+}
+
+func TestWebhookStatus(t *testing.T) {
+	for _, tc := range []struct {
+		code      int
+		ok        bool
+		permanent bool
+	}{
+		{http.StatusOK, true, false},
+		{http.StatusAccepted, true, false},
+		{http.StatusBadRequest, false, true},
+		{http.StatusGone, false, true},
+		{http.StatusTooManyRequests, false, false},
+		{http.StatusServiceUnavailable, false, false},
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tc.code)
+		}))
+		err := NewWebhookNotifier(srv.URL, []byte("s"), time.Second).Notify(context.Background(), testNotification)
+		srv.Close()
+		if (err == nil) != tc.ok {
+			t.Fatalf("%d: err %v", tc.code, err)
+		}
+		if isPermanent(err) != tc.permanent {
+			t.Fatalf("%d: permanent %v, want %v", tc.code, isPermanent(err), tc.permanent)
+		}
+	}
+}
+
+func TestWebhookUnreachableIsRetried(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+	err := NewWebhookNotifier(url, []byte("s"), time.Second).Notify(context.Background(), testNotification)
+	if err == nil || isPermanent(err) {
+		t.Fatalf("err %v", err)
+	}
+	var p *PermanentError
+	if errors.As(err, &p) {
+		t.Fatal("network error marked permanent")
+	}
+}
@@ -20,18 +20,27 @@ const (
 
 type StreamHandler func(peer.ID, []byte) error
 
+// вызывается при появлении и пропаже стрима с пиром
+type LivenessHandler func(peer.ID, bool)
+
 type StreamManager struct {
-    host    host.Host
-    handler StreamHandler
+    host     host.Host
+    handler  StreamHandler
+    liveness LivenessHandler
     
     mu      sync.RWMutex
     streams map[peer.ID]network.Stream
+
+    // пир жив, пока открыт хотя бы один стрим: исходящий из streams или входящие
+    liveMu sync.Mutex
+    live   map[peer.ID]int
 }
 
 func NewStreamManager(h host.Host) *StreamManager {
     sm := &StreamManager{
         host:    h,
         streams: make(map[peer.ID]network.Stream),
+        live:    make(map[peer.ID]int),
     }
     
     h.SetStreamHandler(protocol.ID(ProtocolID), sm.handleStream)
@@ -42,6 +51,35 @@ func (sm *StreamManager) SetHandler(h StreamHandler) {
     sm.handler = h
 }
 
+func (sm *StreamManager) SetLivenessHandler(h LivenessHandler) {
+    sm.liveness = h
+}
+
+// уведомления идут под liveMu, чтобы появление и пропажа пира не переставились
+func (sm *StreamManager) acquire(peerID peer.ID) {
+    sm.liveMu.Lock()
+    defer sm.liveMu.Unlock()
+    sm.live[peerID]++
+    if sm.live[peerID] == 1 && sm.liveness != nil {
+        sm.liveness(peerID, true)
+    }
+}
+
+func (sm *StreamManager) release(peerID peer.ID) {
+    sm.liveMu.Lock()
+    defer sm.liveMu.Unlock()
+    if sm.live[peerID] == 0 {
+        return
+    }
+    sm.live[peerID]--
+    if sm.live[peerID] == 0 {
+        delete(sm.live, peerID)
+        if sm.liveness != nil {
+            sm.liveness(peerID, false)
+        }
+    }
+}
+
 func (sm *StreamManager) SendMessage(ctx context.Context, peerID peer.ID, data []byte) error {
     if len(data) > MaxMessageSize {
         return errors.New("message too large")
@@ -83,26 +121,39 @@ func (sm *StreamManager) getOrCreateStream(ctx context.Context, peerID peer.ID)
     }
     
     sm.mu.Lock()
+    old, had := sm.streams[peerID]
     sm.streams[peerID] = newStream
     sm.mu.Unlock()
+    // исходящий стрим у пира один, замена закрытого не меняет счетчик
+    if had {
+        old.Close()
+    } else {
+        sm.acquire(peerID)
+    }
     
     return newStream, nil
 }
 
 func (sm *StreamManager) removeStream(peerID peer.ID) {
     sm.mu.Lock()
-    defer sm.mu.Unlock()
-    
-    if stream, exists := sm.streams[peerID]; exists {
+    stream, exists := sm.streams[peerID]
+    if exists {
         stream.Close()
         delete(sm.streams, peerID)
     }
+    sm.mu.Unlock()
+    
+    if exists {
+        sm.release(peerID)
+    }
 }
 
 func (sm *StreamManager) handleStream(stream network.Stream) {
     defer stream.Close()
     
     peerID := stream.Conn().RemotePeer()
+    sm.acquire(peerID)
+    defer sm.release(peerID)
     
     for {
         sizeBuf := make([]byte, 4)
@@ -130,12 +181,17 @@ func (sm *StreamManager) handleStream(stream network.Stream) {
 
 func (sm *StreamManager) Close() {
     sm.mu.Lock()
-    defer sm.mu.Unlock()
-
-    for _, stream := range sm.streams {
+    closed := make([]peer.ID, 0, len(sm.streams))
+    for peerID, stream := range sm.streams {
         stream.Close()
+        closed = append(closed, peerID)
     }
     sm.streams = make(map[peer.ID]network.Stream)
+    sm.mu.Unlock()
+
+    for _, peerID := range closed {
+        sm.release(peerID)
+    }
 }
 
 func (sm *StreamManager) GetActivePeers() []string {
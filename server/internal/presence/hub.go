@@ -0,0 +1,310 @@
+package presence
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// тип события, совпадает с PresenceKind в messaging.proto
+type Kind int32
+
+const (
+	KindUnspecified Kind = iota
+	KindSubscribe
+	KindTyping
+	KindStoppedTyping
+	KindOnline
+	KindAway
+	KindOffline
+)
+
+var ErrNotParticipant = errors.New("not a participant of the conversation")
+
+// эфемерное событие присутствия
+type Event struct {
+	Kind           Kind
+	ConversationID string
+	UserID         string
+	PeerID         string
+	LastSeen       time.Time
+	At             time.Time
+}
+
+// одно подключение клиента к стриму присутствия
+type Subscriber struct {
+	UserID string
+	C      chan Event
+
+	convs map[string]struct{}
+}
+
+// хаб раздает события участникам бесед, в бд пишет только last seen
+type Hub struct {
+	store *Store
+
+	mu        sync.RWMutex
+	users     map[string]map[*Subscriber]struct{}
+	convs     map[string]map[*Subscriber]struct{}
+	status    map[string]Kind
+	lastSeen  map[string]time.Time
+	peers     map[string]string // peer id -> user id
+	peerAlive map[string]bool
+}
+
+func NewHub(store *Store) *Hub {
+	return &Hub{
+		store:     store,
+		users:     make(map[string]map[*Subscriber]struct{}),
+		convs:     make(map[string]map[*Subscriber]struct{}),
+		status:    make(map[string]Kind),
+		lastSeen:  make(map[string]time.Time),
+		peers:     make(map[string]string),
+		peerAlive: make(map[string]bool),
+	}
+}
+
+// подключить клиента, первое подключение делает пользователя online
+func (h *Hub) Connect(userID string) *Subscriber {
+	sub := &Subscriber{UserID: userID, C: make(chan Event, 32), convs: make(map[string]struct{})}
+	h.mu.Lock()
+	if h.users[userID] == nil {
+		h.users[userID] = make(map[*Subscriber]struct{})
+	}
+	h.users[userID][sub] = struct{}{}
+	h.mu.Unlock()
+	h.SetStatus(userID, KindOnline)
+	return sub
+}
+
+// отключить клиента, после последнего подключения фиксируем last seen
+func (h *Hub) Disconnect(sub *Subscriber) {
+	h.mu.Lock()
+	delete(h.users[sub.UserID], sub)
+	convs := make([]string, 0, len(sub.convs))
+	for conv := range sub.convs {
+		convs = append(convs, conv)
+		delete(h.convs[conv], sub)
+		if len(h.convs[conv]) == 0 {
+			delete(h.convs, conv)
+		}
+	}
+	last := len(h.users[sub.UserID]) == 0
+	if last {
+		delete(h.users, sub.UserID)
+	}
+	h.mu.Unlock()
+	if last && !h.peerOnline(sub.UserID) {
+		// подписчик уже убран из бесед, поэтому его беседы передаем явно
+		h.setStatus(sub.UserID, KindOffline, convs)
+	}
+}
+
+// подписать клиента на события беседы
+func (h *Hub) Join(ctx context.Context, sub *Subscriber, conversationID string) error {
+	if conversationID == "" {
+		return errors.New("missing conversation id")
+	}
+	ok, err := h.store.CanJoin(ctx, conversationID, sub.UserID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotParticipant
+	}
+	participants, err := h.store.Participants(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	if h.convs[conversationID] == nil {
+		h.convs[conversationID] = make(map[*Subscriber]struct{})
+	}
+	h.convs[conversationID][sub] = struct{}{}
+	sub.convs[conversationID] = struct{}{}
+	// снимок статусов всех остальных участников, в том числе не подключенных с рестарта
+	others := make([]Event, 0, len(participants))
+	for _, userID := range participants {
+		if userID == sub.UserID {
+			continue
+		}
+		kind := h.status[userID]
+		if kind == KindUnspecified {
+			kind = KindOffline
+		}
+		others = append(others, Event{Kind: kind, UserID: userID, LastSeen: h.lastSeen[userID]})
+	}
+	own := Event{Kind: h.status[sub.UserID], UserID: sub.UserID, LastSeen: h.lastSeen[sub.UserID], At: time.Now()}
+	h.mu.Unlock()
+
+	for _, ev := range others {
+		ev.At = time.Now()
+		if ev.LastSeen.IsZero() && ev.Kind != KindOnline {
+			if ev.LastSeen, err = h.store.LastSeen(ctx, ev.UserID); err != nil {
+				slog.Error("presence last seen", "error", err)
+			}
+		}
+		if !ev.LastSeen.IsZero() && !h.lastSeenVisibleTo(ctx, ev.UserID, sub.UserID) {
+			ev.LastSeen = time.Time{}
+		}
+		select {
+		case sub.C <- ev:
+		default:
+		}
+	}
+	h.fanout(own, []string{conversationID})
+	return nil
+}
+
+// typing и stopped typing уходят только в одну беседу
+func (h *Hub) Typing(sub *Subscriber, conversationID string, typing bool) error {
+	h.mu.RLock()
+	_, joined := sub.convs[conversationID]
+	h.mu.RUnlock()
+	if !joined {
+		return ErrNotParticipant
+	}
+	kind := KindStoppedTyping
+	if typing {
+		kind = KindTyping
+	}
+	h.fanout(Event{Kind: kind, ConversationID: conversationID, UserID: sub.UserID, At: time.Now()}, []string{conversationID})
+	return nil
+}
+
+// сменить статус пользователя и разослать во все его беседы
+func (h *Hub) SetStatus(userID string, kind Kind) {
+	h.setStatus(userID, kind, nil)
+}
+
+func (h *Hub) setStatus(userID string, kind Kind, convs []string) {
+	now := time.Now()
+	h.mu.Lock()
+	if h.status[userID] == kind {
+		h.mu.Unlock()
+		return
+	}
+	h.status[userID] = kind
+	if kind != KindOnline {
+		h.lastSeen[userID] = now
+	}
+	for sub := range h.users[userID] {
+		for conv := range sub.convs {
+			convs = append(convs, conv)
+		}
+	}
+	lastSeen := h.lastSeen[userID]
+	h.mu.Unlock()
+	if kind != KindOnline {
+		if err := h.store.SetLastSeen(context.Background(), userID, now); err != nil {
+			slog.Error("presence last seen", "error", err)
+		}
+	}
+	h.fanout(Event{Kind: kind, UserID: userID, LastSeen: lastSeen, At: now}, convs)
+}
+
+// связать libp2p peer клиента с пользователем по подписи привязки, см. PeerBindingPayload
+func (h *Hub) BindPeer(peerID, userID string, atUnix int64, sig []byte) error {
+	if err := verifyPeerBinding(peerID, userID, atUnix, sig, time.Now()); err != nil {
+		return err
+	}
+	h.mu.Lock()
+	h.peers[peerID] = userID
+	h.mu.Unlock()
+	return nil
+}
+
+// живость пира из p2p.StreamManager, прямое соединение тоже считается online
+func (h *Hub) PeerLive(peerID string, alive bool) {
+	h.mu.Lock()
+	h.peerAlive[peerID] = alive
+	userID, bound := h.peers[peerID]
+	connected := len(h.users[userID]) > 0
+	h.mu.Unlock()
+	if !bound {
+		return
+	}
+	if alive {
+		h.SetStatus(userID, KindOnline)
+	} else if !connected && !h.peerOnline(userID) {
+		h.SetStatus(userID, KindOffline)
+	}
+}
+
+func (h *Hub) peerOnline(userID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for p, u := range h.peers {
+		if u == userID && h.peerAlive[p] {
+			return true
+		}
+	}
+	return false
+}
+
+// есть ли у пользователя активное подключение
+func (h *Hub) IsOnline(userID string) bool {
+	h.mu.RLock()
+	connected := len(h.users[userID]) > 0
+	h.mu.RUnlock()
+	return connected || h.peerOnline(userID)
+}
+
+// разослать событие подписчикам бесед, last seen фильтруется по настройкам автора
+func (h *Hub) fanout(ev Event, convs []string) {
+	recipients := make(map[*Subscriber]struct{})
+	h.mu.RLock()
+	for _, conv := range convs {
+		for sub := range h.convs[conv] {
+			if sub.UserID != ev.UserID {
+				recipients[sub] = struct{}{}
+			}
+		}
+	}
+	h.mu.RUnlock()
+	if len(recipients) == 0 {
+		return
+	}
+	ctx := context.Background()
+	vis := VisibleEveryone
+	if !ev.LastSeen.IsZero() {
+		v, err := h.store.Visibility(ctx, ev.UserID)
+		if err != nil {
+			slog.Error("presence visibility", "error", err)
+			v = VisibleNobody
+		}
+		vis = v
+	}
+	for sub := range recipients {
+		out := ev
+		if !out.LastSeen.IsZero() && !h.lastSeenVisible(ctx, vis, ev.UserID, sub.UserID) {
+			out.LastSeen = time.Time{}
+		}
+		// события эфемерные, медленный клиент просто теряет их
+		select {
+		case sub.C <- out:
+		default:
+		}
+	}
+}
+
+func (h *Hub) lastSeenVisibleTo(ctx context.Context, userID, viewerID string) bool {
+	vis, err := h.store.Visibility(ctx, userID)
+	if err != nil {
+		return false
+	}
+	return h.lastSeenVisible(ctx, vis, userID, viewerID)
+}
+
+func (h *Hub) lastSeenVisible(ctx context.Context, vis Visibility, userID, viewerID string) bool {
+	switch vis {
+	case VisibleEveryone:
+		return true
+	case VisibleContacts:
+		ok, err := h.store.AreContacts(ctx, userID, viewerID)
+		return err == nil && ok
+	}
+	return false
+}
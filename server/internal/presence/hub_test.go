@@ -0,0 +1,147 @@
+package presence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"dev.c0rex64.heroin/internal/messaging"
+	"dev.c0rex64.heroin/internal/store"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	db, err := store.Open(context.Background(), "file:"+t.TempDir()+"/presence.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	// alice и carol в одной группе, то есть контакты; bob с alice только в личной беседе
+	for _, stmt := range []string{
+		`INSERT INTO users (id, username, server_salt, password_hash, second_factor_secret, created_at) VALUES ('alice', 'alice', x'00', x'00', x'00', 0)`,
+		`INSERT INTO users (id, username, server_salt, password_hash, second_factor_secret, created_at) VALUES ('bob', 'bob', x'00', x'00', x'00', 0)`,
+		`INSERT INTO users (id, username, server_salt, password_hash, second_factor_secret, created_at) VALUES ('carol', 'carol', x'00', x'00', x'00', 0)`,
+		`INSERT INTO groups (id, name, creator_id, created_at, group_key) VALUES ('g1', 'g1', 'alice', 0, x'00')`,
+		`INSERT INTO group_members (group_id, user_id, joined_at, role, encrypted_key, key_version) VALUES ('g1', 'alice', 0, 'admin', x'00', 1)`,
+		`INSERT INTO group_members (group_id, user_id, joined_at, role, encrypted_key, key_version) VALUES ('g1', 'carol', 0, 'member', x'00', 1)`,
+	} {
+		if _, err := db.SQL.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewStore(db.SQL)
+}
+
+var dm = messaging.DirectConversationID("alice", "bob")
+
+func join(t *testing.T, h *Hub, userID, conv string) *Subscriber {
+	t.Helper()
+	sub := h.Connect(userID)
+	if err := h.Join(context.Background(), sub, conv); err != nil {
+		t.Fatalf("%s joins %s: %v", userID, conv, err)
+	}
+	return sub
+}
+
+// последнее событие о userID среди уже пришедших
+func lastEvent(t *testing.T, sub *Subscriber, userID string) Event {
+	t.Helper()
+	var last *Event
+	for {
+		select {
+		case ev := <-sub.C:
+			if ev.UserID == userID {
+				last = &ev
+			}
+		default:
+			if last == nil {
+				t.Fatalf("%s got no events about %s", sub.UserID, userID)
+			}
+			return *last
+		}
+	}
+}
+
+func TestJoinOnlyParticipants(t *testing.T) {
+	h := NewHub(newTestStore(t))
+	ctx := context.Background()
+	for _, tc := range []struct {
+		user, conv string
+		ok         bool
+	}{
+		{"alice", dm, true},
+		{"bob", dm, true},
+		{"carol", dm, false},
+		{"carol", "g1", true},
+		{"bob", "g1", false},
+		{"bob", "dm:bob:alice", false},
+		{"alice", "other", false},
+	} {
+		err := h.Join(ctx, h.Connect(tc.user), tc.conv)
+		if (err == nil) != tc.ok {
+			t.Errorf("%s joins %s: %v", tc.user, tc.conv, err)
+		}
+	}
+}
+
+func TestLastSeenVisibility(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	h := NewHub(s)
+	// alice была в сети и ушла
+	h.Disconnect(h.Connect("alice"))
+
+	cases := []struct {
+		vis        Visibility
+		bob, carol bool
+	}{
+		{VisibleEveryone, true, true},
+		{VisibleContacts, false, true},
+		{VisibleNobody, false, false},
+	}
+	for _, tc := range cases {
+		if err := s.SetVisibility(ctx, "alice", tc.vis); err != nil {
+			t.Fatal(err)
+		}
+		// снимок при подписке
+		bob := join(t, h, "bob", dm)
+		carol := join(t, h, "carol", "g1")
+		if ev := lastEvent(t, bob, "alice"); ev.Kind != KindOffline || ev.LastSeen.IsZero() == tc.bob {
+			t.Errorf("visibility %d: bob's snapshot %+v", tc.vis, ev)
+		}
+		if ev := lastEvent(t, carol, "alice"); ev.Kind != KindOffline || ev.LastSeen.IsZero() == tc.carol {
+			t.Errorf("visibility %d: carol's snapshot %+v", tc.vis, ev)
+		}
+		// рассылка при уходе из сети фильтруется так же. статус уходит в беседы, на которые
+		// alice подписана
+		alice := join(t, h, "alice", dm)
+		if err := h.Join(ctx, alice, "g1"); err != nil {
+			t.Fatal(err)
+		}
+		h.Disconnect(alice)
+		if ev := lastEvent(t, bob, "alice"); ev.Kind != KindOffline || ev.LastSeen.IsZero() == tc.bob {
+			t.Errorf("visibility %d: bob got %+v", tc.vis, ev)
+		}
+		if ev := lastEvent(t, carol, "alice"); ev.Kind != KindOffline || ev.LastSeen.IsZero() == tc.carol {
+			t.Errorf("visibility %d: carol got %+v", tc.vis, ev)
+		}
+		h.Disconnect(bob)
+		h.Disconnect(carol)
+	}
+}
+
+func TestLastSeenSurvivesRestart(t *testing.T) {
+	s := newTestStore(t)
+	h := NewHub(s)
+	h.Disconnect(h.Connect("alice"))
+	if at, err := s.LastSeen(context.Background(), "alice"); err != nil || at.IsZero() {
+		t.Fatalf("last seen stored: %v %v", at, err)
+	}
+
+	// новый хаб ничего не знает о статусах, last seen берется из бд
+	h = NewHub(s)
+	bob := join(t, h, "bob", dm)
+	if ev := lastEvent(t, bob, "alice"); ev.Kind != KindOffline || ev.LastSeen.IsZero() || time.Since(ev.LastSeen) > time.Minute {
+		t.Fatalf("snapshot after restart: %+v", ev)
+	}
+}
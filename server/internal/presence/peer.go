@@ -0,0 +1,49 @@
+package presence
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// контекст подписи привязки, меняется вместе с форматом
+const peerBindingContext = "heroin presence peer v1"
+
+// допустимое расхождение at_unix подписи с часами сервера
+const peerBindingSkew = 5 * time.Minute
+
+var ErrPeerSignature = errors.New("peer id is not signed by its key")
+
+// что клиент подписывает ключом своего libp2p peer: контекст, id пользователя и время
+func PeerBindingPayload(userID string, atUnix int64) []byte {
+	b := make([]byte, 0, len(peerBindingContext)+len(userID)+8)
+	b = append(b, peerBindingContext...)
+	b = append(b, userID...)
+	return binary.BigEndian.AppendUint64(b, uint64(atUnix))
+}
+
+// peer id из стрима присутствия ничем не подтвержден, пока клиент не подписал привязку
+// ключом, из которого этот id выведен. годятся id со встроенным ключом (ed25519, secp256k1)
+func verifyPeerBinding(peerID, userID string, atUnix int64, sig []byte, now time.Time) error {
+	if len(sig) == 0 {
+		return ErrPeerSignature
+	}
+	if d := now.Sub(time.Unix(atUnix, 0)); d > peerBindingSkew || d < -peerBindingSkew {
+		return ErrPeerSignature
+	}
+	id, err := peer.Decode(peerID)
+	if err != nil {
+		return ErrPeerSignature
+	}
+	pub, err := id.ExtractPublicKey()
+	if err != nil {
+		return ErrPeerSignature
+	}
+	ok, err := pub.Verify(PeerBindingPayload(userID, atUnix), sig)
+	if err != nil || !ok {
+		return ErrPeerSignature
+	}
+	return nil
+}
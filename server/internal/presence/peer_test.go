@@ -0,0 +1,104 @@
+package presence
+
+import (
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+func newTestPeer(t *testing.T, typ int, bits int) (crypto.PrivKey, string) {
+	t.Helper()
+	priv, pub, err := crypto.GenerateKeyPairWithReader(typ, bits, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := peer.IDFromPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv, id.String()
+}
+
+func sign(t *testing.T, priv crypto.PrivKey, userID string, at int64) []byte {
+	t.Helper()
+	sig, err := priv.Sign(PeerBindingPayload(userID, at))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+func TestVerifyPeerBinding(t *testing.T) {
+	now := time.Now()
+	at := now.Unix()
+	priv, id := newTestPeer(t, crypto.Ed25519, -1)
+	other, _ := newTestPeer(t, crypto.Ed25519, -1)
+	// ключ rsa в peer id не встроен, подпись проверить нечем
+	rsaPriv, rsaID := newTestPeer(t, crypto.RSA, 2048)
+
+	if err := verifyPeerBinding(id, "alice", at, sign(t, priv, "alice", at), now); err != nil {
+		t.Fatalf("valid binding: %v", err)
+	}
+	cases := []struct {
+		name   string
+		peerID string
+		userID string
+		at     int64
+		sig    []byte
+	}{
+		{"no signature", id, "alice", at, nil},
+		{"signed for another user", id, "bob", at, sign(t, priv, "alice", at)},
+		{"signed by another key", id, "alice", at, sign(t, other, "alice", at)},
+		{"signed at another time", id, "alice", at + 1, sign(t, priv, "alice", at)},
+		{"stale", id, "alice", at - 600, sign(t, priv, "alice", at-600)},
+		{"from the future", id, "alice", at + 600, sign(t, priv, "alice", at+600)},
+		{"bad peer id", "not-a-peer", "alice", at, sign(t, priv, "alice", at)},
+		{"key not in peer id", rsaID, "alice", at, sign(t, rsaPriv, "alice", at)},
+	}
+	for _, tc := range cases {
+		if err := verifyPeerBinding(tc.peerID, tc.userID, tc.at, tc.sig, now); !errors.Is(err, ErrPeerSignature) {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+	}
+}
+
+func TestPeerLiveness(t *testing.T) {
+	h := NewHub(newTestStore(t))
+	priv, id := newTestPeer(t, crypto.Ed25519, -1)
+	at := time.Now().Unix()
+
+	// живость непривязанного пира статус не меняет
+	h.PeerLive(id, true)
+	if h.IsOnline("alice") {
+		t.Fatal("unbound peer made alice online")
+	}
+	if err := h.BindPeer(id, "alice", at, sign(t, priv, "bob", at)); !errors.Is(err, ErrPeerSignature) {
+		t.Fatalf("binding signed for bob: %v", err)
+	}
+	if err := h.BindPeer(id, "alice", at, sign(t, priv, "alice", at)); err != nil {
+		t.Fatal(err)
+	}
+	h.PeerLive(id, true)
+	if !h.IsOnline("alice") {
+		t.Fatal("live peer did not make alice online")
+	}
+
+	// в снимке для подписчика alice в сети, хотя стрима присутствия у нее нет
+	bob := join(t, h, "bob", dm)
+	if ev := lastEvent(t, bob, "alice"); ev.Kind != KindOnline {
+		t.Fatalf("bob's snapshot %+v", ev)
+	}
+	// стрим закрылся, пока жив пир: alice остается в сети
+	h.Disconnect(h.Connect("alice"))
+	if !h.IsOnline("alice") || h.status["alice"] != KindOnline {
+		t.Fatal("alice offline while her peer is alive")
+	}
+	h.PeerLive(id, false)
+	if h.IsOnline("alice") || h.status["alice"] != KindOffline {
+		t.Fatal("alice online after her peer went away")
+	}
+}
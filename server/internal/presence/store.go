@@ -0,0 +1,124 @@
+package presence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"dev.c0rex64.heroin/internal/messaging"
+)
+
+// кто видит last seen пользователя, совпадает с LastSeenVisibility в messaging.proto
+type Visibility int32
+
+const (
+	VisibleEveryone Visibility = iota
+	VisibleContacts
+	VisibleNobody
+)
+
+// хранилище настроек приватности, last seen и проверок участия в беседах
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) SetVisibility(ctx context.Context, userID string, v Visibility) error {
+	if v < VisibleEveryone || v > VisibleNobody {
+		return errors.New("unknown visibility")
+	}
+	_, err := s.db.ExecContext(ctx, `INSERT INTO presence_settings (user_id, last_seen_visibility, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET last_seen_visibility = excluded.last_seen_visibility, updated_at = excluded.updated_at`,
+		userID, int32(v), time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("set visibility: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) Visibility(ctx context.Context, userID string) (Visibility, error) {
+	var v int32
+	err := s.db.QueryRowContext(ctx, `SELECT last_seen_visibility FROM presence_settings WHERE user_id = ?`, userID).Scan(&v)
+	if errors.Is(err, sql.ErrNoRows) {
+		return VisibleEveryone, nil
+	}
+	if err != nil {
+		return VisibleEveryone, fmt.Errorf("get visibility: %w", err)
+	}
+	return Visibility(v), nil
+}
+
+// контакты: пользователи, у которых есть хотя бы одна общая группа
+func (s *Store) AreContacts(ctx context.Context, userID, otherID string) (bool, error) {
+	var ok bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS(
+		SELECT 1 FROM group_members a JOIN group_members b ON a.group_id = b.group_id
+		WHERE a.user_id = ? AND b.user_id = ?)`, userID, otherID).Scan(&ok)
+	if err != nil {
+		return false, fmt.Errorf("check contacts: %w", err)
+	}
+	return ok, nil
+}
+
+// в групповую беседу входят члены группы, в личную - два ее участника. беседы другого вида
+// сервер не знает, в них не пускаем никого
+func (s *Store) CanJoin(ctx context.Context, conversationID, userID string) (bool, error) {
+	if a, b, ok := messaging.DirectParticipants(conversationID); ok {
+		return userID == a || userID == b, nil
+	}
+	var isMember bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM group_members WHERE group_id = ? AND user_id = ?)`,
+		conversationID, userID).Scan(&isMember)
+	if err != nil {
+		return false, fmt.Errorf("check membership: %w", err)
+	}
+	return isMember, nil
+}
+
+// все участники беседы, для снимка статусов при подписке
+func (s *Store) Participants(ctx context.Context, conversationID string) ([]string, error) {
+	if a, b, ok := messaging.DirectParticipants(conversationID); ok {
+		return []string{a, b}, nil
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT user_id FROM group_members WHERE group_id = ?`, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("list participants: %w", err)
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (s *Store) SetLastSeen(ctx context.Context, userID string, at time.Time) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO presence_last_seen (user_id, last_seen_at) VALUES (?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET last_seen_at = excluded.last_seen_at`, userID, at.Unix())
+	if err != nil {
+		return fmt.Errorf("set last seen: %w", err)
+	}
+	return nil
+}
+
+// нулевое время, если пользователь еще ни разу не уходил из сети
+func (s *Store) LastSeen(ctx context.Context, userID string) (time.Time, error) {
+	var at int64
+	err := s.db.QueryRowContext(ctx, `SELECT last_seen_at FROM presence_last_seen WHERE user_id = ?`, userID).Scan(&at)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("get last seen: %w", err)
+	}
+	return time.Unix(at, 0), nil
+}
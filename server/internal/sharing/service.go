@@ -0,0 +1,446 @@
+package sharing
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/argon2"
+)
+
+type GranteeKind string
+
+const (
+	GranteeUser  GranteeKind = "user"
+	GranteeGroup GranteeKind = "group"
+)
+
+const (
+	tokenBytes      = 32
+	maxWrappedKey   = 4096
+	maxLinkPassword = 1024
+)
+
+// argon2id для паролей ссылок, параметры как у минимальной рекомендации owasp
+const (
+	passwordTime    = 2
+	passwordMemory  = 19 * 1024
+	passwordThreads = 1
+	passwordKeyLen  = 32
+	passwordSaltLen = 16
+)
+
+var (
+	ErrNotFound      = errors.New("share not found")
+	ErrTargetMissing = errors.New("shared file or folder not found")
+	ErrBadTarget     = errors.New("exactly one of file and folder must be set")
+	ErrBadGrantee    = errors.New("invalid grantee")
+	ErrBadKey        = errors.New("invalid wrapped key")
+	ErrExists        = errors.New("already shared with grantee")
+	ErrLinkInvalid   = errors.New("link not found or expired")
+	ErrLinkExhausted = errors.New("link download limit reached")
+	ErrPassword      = errors.New("link password required or incorrect")
+	ErrBadPassword   = errors.New("link password too long")
+	ErrOutsideLink   = errors.New("not covered by link")
+)
+
+// цель доступа: ровно одно из FileID и NodeID
+type Target struct {
+	FileID string
+	NodeID string
+}
+
+type Grant struct {
+	ID          string
+	OwnerID     string
+	Target      Target
+	GranteeKind GranteeKind
+	GranteeID   string
+	WrappedKey  []byte
+	CreatedAt   time.Time
+}
+
+type Link struct {
+	ID           string
+	OwnerID      string
+	Target       Target
+	HasPassword  bool
+	ExpiresAt    time.Time
+	MaxDownloads int
+	Downloads    int
+	CreatedAt    time.Time
+	RevokedAt    time.Time
+}
+
+// общий доступ к файлам и каталогам. сервер хранит только обернутые клиентом ключи,
+// а для ссылок - хеш токена: сам ключ расшифровки живет во фрагменте url и на сервер не приходит
+type Service struct {
+	db *sql.DB
+}
+
+func NewService(db *sql.DB) *Service {
+	return &Service{db: db}
+}
+
+// выдать доступ пользователю или группе. группе может выдать только ее участник
+func (s *Service) Grant(ctx context.Context, ownerID string, t Target, kind GranteeKind, granteeID string, wrappedKey []byte) (*Grant, error) {
+	if err := s.checkTarget(ctx, ownerID, t); err != nil {
+		return nil, err
+	}
+	if len(wrappedKey) == 0 || len(wrappedKey) > maxWrappedKey {
+		return nil, ErrBadKey
+	}
+	var n int
+	var err error
+	switch kind {
+	case GranteeUser:
+		if granteeID == ownerID {
+			return nil, ErrBadGrantee
+		}
+		err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE id = ?`, granteeID).Scan(&n)
+	case GranteeGroup:
+		err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM group_members WHERE group_id = ? AND user_id = ?`, granteeID, ownerID).Scan(&n)
+	default:
+		return nil, ErrBadGrantee
+	}
+	if err != nil {
+		return nil, fmt.Errorf("check grantee: %w", err)
+	}
+	if n == 0 {
+		return nil, ErrBadGrantee
+	}
+	g := &Grant{
+		ID:          uuid.NewString(),
+		OwnerID:     ownerID,
+		Target:      t,
+		GranteeKind: kind,
+		GranteeID:   granteeID,
+		WrappedKey:  wrappedKey,
+		CreatedAt:   time.Unix(time.Now().Unix(), 0),
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO share_grants (id, owner_id, file_id, node_id, grantee_kind, grantee_id, wrapped_key, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		g.ID, ownerID, nullable(t.FileID), nullable(t.NodeID), string(kind), granteeID, wrappedKey, g.CreatedAt.Unix())
+	if err != nil {
+		var n int
+		if cerr := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM share_grants WHERE COALESCE(file_id, '') = ? AND COALESCE(node_id, '') = ? AND grantee_kind = ? AND grantee_id = ?`,
+			t.FileID, t.NodeID, string(kind), granteeID).Scan(&n); cerr == nil && n > 0 {
+			return nil, ErrExists
+		}
+		return nil, fmt.Errorf("insert grant: %w", err)
+	}
+	return g, nil
+}
+
+// отозвать доступ может владелец, а получатель-пользователь - отказаться от него
+func (s *Service) Revoke(ctx context.Context, userID, grantID string) error {
+	r, err := s.db.ExecContext(ctx, `DELETE FROM share_grants WHERE id = ? AND (owner_id = ? OR (grantee_kind = 'user' AND grantee_id = ?))`, grantID, userID, userID)
+	if err != nil {
+		return fmt.Errorf("revoke grant: %w", err)
+	}
+	if n, _ := r.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// доступы, выданные владельцем. пустая цель выводит все
+func (s *Service) ListGrants(ctx context.Context, ownerID string, t Target) ([]Grant, error) {
+	q := `SELECT ` + grantColumns + ` FROM share_grants WHERE owner_id = ?`
+	args := []any{ownerID}
+	if t.FileID != "" {
+		q += ` AND file_id = ?`
+		args = append(args, t.FileID)
+	}
+	if t.NodeID != "" {
+		q += ` AND node_id = ?`
+		args = append(args, t.NodeID)
+	}
+	return s.queryGrants(ctx, q+` ORDER BY created_at DESC, id`, args...)
+}
+
+// доступы, выданные пользователю напрямую и через его группы
+func (s *Service) SharedWith(ctx context.Context, userID string) ([]Grant, error) {
+	return s.queryGrants(ctx, `SELECT `+grantColumns+` FROM share_grants WHERE `+granteeMatch+` ORDER BY created_at DESC, id`, userID, userID)
+}
+
+func (s *Service) queryGrants(ctx context.Context, q string, args ...any) ([]Grant, error) {
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list grants: %w", err)
+	}
+	defer rows.Close()
+	var out []Grant
+	for rows.Next() {
+		var g Grant
+		var created int64
+		if err := rows.Scan(&g.ID, &g.OwnerID, &g.Target.FileID, &g.Target.NodeID, &g.GranteeKind, &g.GranteeID, &g.WrappedKey, &created); err != nil {
+			return nil, err
+		}
+		g.CreatedAt = time.Unix(created, 0)
+		out = append(out, g)
+	}
+	return out, rows.Err()
+}
+
+// может ли пользователь читать cid: свой файл, прямой доступ к файлу с этим cid
+// или доступ к каталогу, в котором лежит такой файл. превью файла доступно тем же,
+// кому сам файл. узлы в корзине доступа не дают
+func (s *Service) CanRead(ctx context.Context, userID, cid string) (bool, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM files f WHERE `+fileHasCID+` AND f.user_id = ?)
+		OR EXISTS (SELECT 1 FROM share_grants JOIN files f ON f.id = share_grants.file_id WHERE `+fileHasCID+` AND `+granteeMatch+`)
+		OR EXISTS (`+ancestorsOf+` SELECT 1 FROM share_grants JOIN up ON share_grants.node_id = up.id WHERE `+granteeMatch+`)`,
+		cid, cid, userID, cid, cid, userID, userID, cid, cid, userID, userID).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("check access: %w", err)
+	}
+	return n > 0, nil
+}
+
+// создать ссылку. токен возвращается один раз, в базе остается только его хеш
+func (s *Service) CreateLink(ctx context.Context, ownerID string, t Target, password string, expiresAt time.Time, maxDownloads int) (*Link, string, error) {
+	if err := s.checkTarget(ctx, ownerID, t); err != nil {
+		return nil, "", err
+	}
+	if len(password) > maxLinkPassword {
+		return nil, "", ErrBadPassword
+	}
+	raw := make([]byte, tokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	hash := sha256.Sum256([]byte(token))
+	var salt, pwHash []byte
+	if password != "" {
+		salt = make([]byte, passwordSaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, "", err
+		}
+		pwHash = hashPassword(password, salt)
+	}
+	l := &Link{
+		ID:           uuid.NewString(),
+		OwnerID:      ownerID,
+		Target:       t,
+		HasPassword:  password != "",
+		MaxDownloads: max(maxDownloads, 0),
+		CreatedAt:    time.Unix(time.Now().Unix(), 0),
+	}
+	var expires any
+	if !expiresAt.IsZero() {
+		l.ExpiresAt = time.Unix(expiresAt.Unix(), 0)
+		expires = expiresAt.Unix()
+	}
+	var limit any
+	if l.MaxDownloads > 0 {
+		limit = l.MaxDownloads
+	}
+	_, err := s.db.ExecContext(ctx, `INSERT INTO share_links (id, token_hash, owner_id, file_id, node_id, password_salt, password_hash, expires_at, max_downloads, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		l.ID, hash[:], ownerID, nullable(t.FileID), nullable(t.NodeID), salt, pwHash, expires, limit, l.CreatedAt.Unix())
+	if err != nil {
+		return nil, "", fmt.Errorf("insert link: %w", err)
+	}
+	return l, token, nil
+}
+
+// ссылка перестает работать сразу, строка остается для истории
+func (s *Service) RevokeLink(ctx context.Context, ownerID, linkID string) error {
+	r, err := s.db.ExecContext(ctx, `UPDATE share_links SET revoked_at = ? WHERE id = ? AND owner_id = ? AND revoked_at IS NULL`, time.Now().Unix(), linkID, ownerID)
+	if err != nil {
+		return fmt.Errorf("revoke link: %w", err)
+	}
+	if n, _ := r.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *Service) ListLinks(ctx context.Context, ownerID string) ([]Link, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+linkColumns+` FROM share_links WHERE owner_id = ? AND revoked_at IS NULL ORDER BY created_at DESC, id`, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("list links: %w", err)
+	}
+	defer rows.Close()
+	var out []Link
+	for rows.Next() {
+		l, _, _, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *l)
+	}
+	return out, rows.Err()
+}
+
+// открыть ссылку по токену и паролю. отозванные, просроченные и исчерпанные ссылки
+// неотличимы от несуществующих, кроме исчерпанных - о них клиенту стоит знать
+func (s *Service) OpenLink(ctx context.Context, token, password string) (*Link, error) {
+	if token == "" {
+		return nil, ErrLinkInvalid
+	}
+	if len(password) > maxLinkPassword {
+		return nil, ErrPassword
+	}
+	hash := sha256.Sum256([]byte(token))
+	l, salt, pwHash, err := scanLink(s.db.QueryRowContext(ctx, `SELECT `+linkColumns+` FROM share_links WHERE token_hash = ?`, hash[:]))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrLinkInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load link: %w", err)
+	}
+	if !l.RevokedAt.IsZero() || (!l.ExpiresAt.IsZero() && !time.Now().Before(l.ExpiresAt)) {
+		return nil, ErrLinkInvalid
+	}
+	if l.HasPassword && subtle.ConstantTimeCompare(hashPassword(password, salt), pwHash) != 1 {
+		return nil, ErrPassword
+	}
+	if l.MaxDownloads > 0 && l.Downloads >= l.MaxDownloads {
+		return nil, ErrLinkExhausted
+	}
+	return l, nil
+}
+
+// входит ли cid в ссылку: файл ссылки или файл внутри ее каталога, либо их превью
+func (s *Service) LinkCovers(ctx context.Context, l *Link, cid string) (bool, error) {
+	var n int
+	var err error
+	if l.Target.FileID != "" {
+		err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM files f WHERE f.id = ? AND `+fileHasCID, l.Target.FileID, cid, cid).Scan(&n)
+	} else {
+		err = s.db.QueryRowContext(ctx, ancestorsOf+` SELECT COUNT(*) FROM up WHERE id = ?`, cid, cid, l.Target.NodeID).Scan(&n)
+	}
+	if err != nil {
+		return false, fmt.Errorf("check link: %w", err)
+	}
+	return n > 0, nil
+}
+
+// проверить, что каталог parentID лежит внутри каталога ссылки. пустой parentID - сам каталог
+func (s *Service) LinkDir(ctx context.Context, l *Link, parentID string) (string, error) {
+	if l.Target.NodeID == "" {
+		return "", ErrOutsideLink
+	}
+	if parentID == "" || parentID == l.Target.NodeID {
+		return l.Target.NodeID, nil
+	}
+	var n int
+	err := s.db.QueryRowContext(ctx, `WITH RECURSIVE up(id, parent_id) AS (
+			SELECT id, parent_id FROM vfs_nodes WHERE id = ? AND user_id = ? AND kind = 'dir' AND trashed_at IS NULL
+			UNION
+			SELECT p.id, p.parent_id FROM vfs_nodes p JOIN up ON p.id = up.parent_id WHERE p.trashed_at IS NULL
+		) SELECT COUNT(*) FROM up WHERE id = ?`, parentID, l.OwnerID, l.Target.NodeID).Scan(&n)
+	if err != nil {
+		return "", fmt.Errorf("check link folder: %w", err)
+	}
+	if n == 0 {
+		return "", ErrOutsideLink
+	}
+	return parentID, nil
+}
+
+// засчитать скачивание. лимит проверяется в том же запросе, так что параллельные
+// скачивания не превысят его
+func (s *Service) ConsumeDownload(ctx context.Context, l *Link) error {
+	r, err := s.db.ExecContext(ctx, `UPDATE share_links SET downloads = downloads + 1
+		WHERE id = ? AND revoked_at IS NULL AND (max_downloads IS NULL OR downloads < max_downloads)`, l.ID)
+	if err != nil {
+		return fmt.Errorf("count download: %w", err)
+	}
+	if n, _ := r.RowsAffected(); n == 0 {
+		return ErrLinkExhausted
+	}
+	l.Downloads++
+	return nil
+}
+
+// cid только превью, а не содержимое файла: галерея по ссылке не тратит лимит скачиваний
+func (s *Service) IsPreview(ctx context.Context, cid string) (bool, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM file_previews WHERE cid = ?) AND NOT EXISTS (SELECT 1 FROM file_versions WHERE cid = ?)`, cid, cid).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("check preview: %w", err)
+	}
+	return n > 0, nil
+}
+
+// цель должна принадлежать владельцу и не лежать в корзине
+func (s *Service) checkTarget(ctx context.Context, ownerID string, t Target) error {
+	if (t.FileID == "") == (t.NodeID == "") {
+		return ErrBadTarget
+	}
+	var n int
+	var err error
+	if t.FileID != "" {
+		err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM files WHERE id = ? AND user_id = ?`, t.FileID, ownerID).Scan(&n)
+	} else {
+		err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM vfs_nodes WHERE id = ? AND user_id = ? AND trashed_at IS NULL`, t.NodeID, ownerID).Scan(&n)
+	}
+	if err != nil {
+		return fmt.Errorf("check share target: %w", err)
+	}
+	if n == 0 {
+		return ErrTargetMissing
+	}
+	return nil
+}
+
+// получатель - сам пользователь или группа, где он состоит. два параметра: userID дважды
+const granteeMatch = `((share_grants.grantee_kind = 'user' AND share_grants.grantee_id = ?)
+	OR (share_grants.grantee_kind = 'group' AND share_grants.grantee_id IN (SELECT group_id FROM group_members WHERE user_id = ?)))`
+
+// файл f с содержимым cid в любой из версий или с превью cid. текущая версия тоже
+// есть в file_versions. два параметра: cid дважды
+const fileHasCID = `f.id IN (SELECT file_id FROM file_versions WHERE cid = ? UNION ALL SELECT file_id FROM file_previews WHERE cid = ?)`
+
+// up - живые файловые узлы с данным cid и все их живые предки. два параметра: cid дважды
+const ancestorsOf = `WITH RECURSIVE up(id, parent_id) AS (
+	SELECT n.id, n.parent_id FROM vfs_nodes n JOIN files f ON f.id = n.file_id WHERE ` + fileHasCID + ` AND n.user_id = f.user_id AND n.trashed_at IS NULL
+	UNION
+	SELECT p.id, p.parent_id FROM vfs_nodes p JOIN up ON p.id = up.parent_id WHERE p.trashed_at IS NULL
+)`
+
+const grantColumns = `id, owner_id, COALESCE(file_id, ''), COALESCE(node_id, ''), grantee_kind, grantee_id, wrapped_key, created_at`
+
+const linkColumns = `id, owner_id, COALESCE(file_id, ''), COALESCE(node_id, ''), password_salt, password_hash, COALESCE(expires_at, 0), COALESCE(max_downloads, 0), downloads, created_at, COALESCE(revoked_at, 0)`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanLink(r rowScanner) (*Link, []byte, []byte, error) {
+	var l Link
+	var salt, pwHash []byte
+	var expires, created, revoked int64
+	if err := r.Scan(&l.ID, &l.OwnerID, &l.Target.FileID, &l.Target.NodeID, &salt, &pwHash, &expires, &l.MaxDownloads, &l.Downloads, &created, &revoked); err != nil {
+		return nil, nil, nil, err
+	}
+	l.HasPassword = len(pwHash) > 0
+	if expires > 0 {
+		l.ExpiresAt = time.Unix(expires, 0)
+	}
+	if revoked > 0 {
+		l.RevokedAt = time.Unix(revoked, 0)
+	}
+	l.CreatedAt = time.Unix(created, 0)
+	return &l, salt, pwHash, nil
+}
+
+func hashPassword(password string, salt []byte) []byte {
+	return argon2.IDKey([]byte(password), salt, passwordTime, passwordMemory, passwordThreads, passwordKeyLen)
+}
+
+func nullable(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
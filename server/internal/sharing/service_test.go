@@ -0,0 +1,340 @@
+package sharing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"dev.c0rex64.heroin/internal/store"
+)
+
+// alice владеет деревом
+//
+//	d1/n1 -> f1 (c1, превью p1)
+//	d1/d3/n3 -> f3 (c3)
+//	d1/n4 -> f4 (c4, в корзине)
+//	d2/n2 -> f2 (c2)
+//
+// bob и alice в группе g1, carol ни с кем не связана
+func newTestService(t *testing.T) (*Service, *store.DB) {
+	t.Helper()
+	db, err := store.Open(context.Background(), "file:"+t.TempDir()+"/sharing.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	for _, q := range []string{
+		`INSERT INTO users (id, username, server_salt, password_hash, second_factor_secret, created_at) VALUES
+			('alice', 'alice', x'00', x'00', x'00', 0), ('bob', 'bob', x'00', x'00', x'00', 0), ('carol', 'carol', x'00', x'00', x'00', 0)`,
+		`INSERT INTO groups (id, name, creator_id, created_at, group_key) VALUES ('g1', 'g1', 'alice', 0, x'00')`,
+		`INSERT INTO group_members (group_id, user_id, joined_at, role, encrypted_key, key_version) VALUES
+			('g1', 'alice', 0, 'admin', x'00', 1), ('g1', 'bob', 0, 'member', x'00', 1)`,
+		`INSERT INTO files (id, user_id, cid, size_bytes, created_at) VALUES
+			('f1', 'alice', 'c1', 1, 0), ('f2', 'alice', 'c2', 1, 0), ('f3', 'alice', 'c3', 1, 0), ('f4', 'alice', 'c4', 1, 0)`,
+		`INSERT INTO file_previews (file_id, kind, cid, mime, size_bytes, width, height, wrapped_key, created_at) VALUES
+			('f1', 'thumbnail', 'p1', 'image/jpeg', 1, 1, 1, x'00', 0)`,
+		`INSERT INTO vfs_nodes (id, user_id, parent_id, kind, file_id, name_blob, name_hash, trashed_at, created_at, updated_at) VALUES
+			('d1', 'alice', NULL, 'dir', NULL, x'01', x'01', NULL, 0, 0),
+			('d2', 'alice', NULL, 'dir', NULL, x'02', x'02', NULL, 0, 0),
+			('d3', 'alice', 'd1', 'dir', NULL, x'03', x'03', NULL, 0, 0),
+			('n1', 'alice', 'd1', 'file', 'f1', x'04', x'04', NULL, 0, 0),
+			('n2', 'alice', 'd2', 'file', 'f2', x'05', x'05', NULL, 0, 0),
+			('n3', 'alice', 'd3', 'file', 'f3', x'06', x'06', NULL, 0, 0),
+			('n4', 'alice', 'd1', 'file', 'f4', x'07', x'07', 1, 0, 0)`,
+	} {
+		if _, err := db.SQL.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewService(db.SQL), db
+}
+
+func grant(t *testing.T, s *Service, target Target, kind GranteeKind, grantee string) *Grant {
+	t.Helper()
+	g, err := s.Grant(context.Background(), "alice", target, kind, grantee, []byte("wrapped"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func link(t *testing.T, s *Service, target Target, password string, expires time.Time, maxDownloads int) string {
+	t.Helper()
+	_, token, err := s.CreateLink(context.Background(), "alice", target, password, expires, maxDownloads)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestCanRead(t *testing.T) {
+	s, db := newTestService(t)
+	ctx := context.Background()
+	direct := grant(t, s, Target{FileID: "f2"}, GranteeUser, "carol")
+	grant(t, s, Target{NodeID: "d1"}, GranteeGroup, "g1")
+	revoked := grant(t, s, Target{FileID: "f2"}, GranteeUser, "bob")
+	if err := s.Revoke(ctx, "alice", revoked.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(when string, cases []struct {
+		user, cid string
+		want      bool
+	}) {
+		t.Helper()
+		for _, tc := range cases {
+			got, err := s.CanRead(ctx, tc.user, tc.cid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("%s: %s reads %s = %v, want %v", when, tc.user, tc.cid, got, tc.want)
+			}
+		}
+	}
+	check("granted", []struct {
+		user, cid string
+		want      bool
+	}{
+		{"alice", "c2", true},
+		{"alice", "c4", true},
+		{"carol", "c2", true},
+		{"carol", "c1", false},
+		// доступ к каталогу через группу: файлы внутри, вложенные и превью
+		{"bob", "c1", true},
+		{"bob", "c3", true},
+		{"bob", "p1", true},
+		// файл в корзине внутри каталога не входит
+		{"bob", "c4", false},
+		// соседний каталог, прямой доступ к его файлу отозван
+		{"bob", "c2", false},
+		{"carol", "unknown", false},
+	})
+
+	// отказ получателя от доступа и выход из группы закрывают чтение
+	if err := s.Revoke(ctx, "carol", direct.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.SQL.Exec(`DELETE FROM group_members WHERE group_id = 'g1' AND user_id = 'bob'`); err != nil {
+		t.Fatal(err)
+	}
+	check("after revoke and leaving the group", []struct {
+		user, cid string
+		want      bool
+	}{
+		{"carol", "c2", false},
+		{"bob", "c1", false},
+		{"bob", "c3", false},
+		{"bob", "p1", false},
+		{"alice", "c1", true},
+	})
+}
+
+func TestOpenLink(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	file := Target{FileID: "f1"}
+	plain := link(t, s, file, "", time.Time{}, 0)
+	future := link(t, s, file, "", time.Now().Add(time.Hour), 0)
+	expired := link(t, s, file, "", time.Now().Add(-time.Second), 0)
+	protected := link(t, s, file, "secret", time.Time{}, 0)
+	exhausted := link(t, s, file, "", time.Time{}, 1)
+	l, err := s.OpenLink(ctx, exhausted, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ConsumeDownload(ctx, l); err != nil {
+		t.Fatal(err)
+	}
+	revoked := link(t, s, file, "", time.Time{}, 0)
+	rl, err := s.OpenLink(ctx, revoked, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RevokeLink(ctx, "alice", rl.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name, token, password string
+		want                  error
+	}{
+		{"no password", plain, "", nil},
+		{"password ignored without one", plain, "anything", nil},
+		{"not expired yet", future, "", nil},
+		{"right password", protected, "secret", nil},
+		{"expired", expired, "", ErrLinkInvalid},
+		{"revoked", revoked, "", ErrLinkInvalid},
+		{"unknown token", "nope", "", ErrLinkInvalid},
+		{"empty token", "", "", ErrLinkInvalid},
+		{"wrong password", protected, "Secret", ErrPassword},
+		{"missing password", protected, "", ErrPassword},
+		{"exhausted", exhausted, "", ErrLinkExhausted},
+	}
+	for _, tc := range cases {
+		l, err := s.OpenLink(ctx, tc.token, tc.password)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: %v, want %v", tc.name, err, tc.want)
+			continue
+		}
+		if err == nil && (l.Target != file || l.OwnerID != "alice") {
+			t.Errorf("%s: link %+v", tc.name, l)
+		}
+	}
+}
+
+func TestConsumeDownload(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	l, err := s.OpenLink(ctx, link(t, s, Target{FileID: "f1"}, "", time.Time{}, 2), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.ConsumeDownload(ctx, l); err != nil {
+			t.Fatalf("download %d: %v", i+1, err)
+		}
+	}
+	if err := s.ConsumeDownload(ctx, l); !errors.Is(err, ErrLinkExhausted) {
+		t.Fatalf("download past the limit: %v", err)
+	}
+	if l.Downloads != 2 {
+		t.Fatalf("%d downloads counted", l.Downloads)
+	}
+
+	unlimited, err := s.OpenLink(ctx, link(t, s, Target{FileID: "f1"}, "", time.Time{}, 0), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := s.ConsumeDownload(ctx, unlimited); err != nil {
+			t.Fatalf("unlimited download %d: %v", i+1, err)
+		}
+	}
+	// ссылку отозвали, пока она была открыта
+	if err := s.RevokeLink(ctx, "alice", unlimited.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ConsumeDownload(ctx, unlimited); !errors.Is(err, ErrLinkExhausted) {
+		t.Fatalf("download from revoked link: %v", err)
+	}
+}
+
+func TestLinkCovers(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	fileLink, err := s.OpenLink(ctx, link(t, s, Target{FileID: "f1"}, "", time.Time{}, 0), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirLink, err := s.OpenLink(ctx, link(t, s, Target{NodeID: "d1"}, "", time.Time{}, 0), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name string
+		l    *Link
+		cid  string
+		want bool
+	}{
+		{"file itself", fileLink, "c1", true},
+		{"file preview", fileLink, "p1", true},
+		{"other file", fileLink, "c3", false},
+		{"child of folder", dirLink, "c1", true},
+		{"nested child", dirLink, "c3", true},
+		{"preview inside folder", dirLink, "p1", true},
+		{"sibling folder", dirLink, "c2", false},
+		{"trashed child", dirLink, "c4", false},
+	}
+	for _, tc := range cases {
+		got, err := s.LinkCovers(ctx, tc.l, tc.cid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: covers %s = %v, want %v", tc.name, tc.cid, got, tc.want)
+		}
+	}
+}
+
+func TestLinkDir(t *testing.T) {
+	s, db := newTestService(t)
+	ctx := context.Background()
+	dirLink, err := s.OpenLink(ctx, link(t, s, Target{NodeID: "d1"}, "", time.Time{}, 0), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileLink, err := s.OpenLink(ctx, link(t, s, Target{FileID: "f1"}, "", time.Time{}, 0), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name   string
+		l      *Link
+		parent string
+		want   string
+		err    error
+	}{
+		{"link root", dirLink, "", "d1", nil},
+		{"link root by id", dirLink, "d1", "d1", nil},
+		{"nested folder", dirLink, "d3", "d3", nil},
+		{"sibling folder", dirLink, "d2", "", ErrOutsideLink},
+		{"file instead of folder", dirLink, "n1", "", ErrOutsideLink},
+		{"unknown folder", dirLink, "nope", "", ErrOutsideLink},
+		{"file link", fileLink, "", "", ErrOutsideLink},
+	}
+	for _, tc := range cases {
+		got, err := s.LinkDir(ctx, tc.l, tc.parent)
+		if !errors.Is(err, tc.err) || got != tc.want {
+			t.Errorf("%s: %q %v, want %q %v", tc.name, got, err, tc.want, tc.err)
+		}
+	}
+
+	// вложенный каталог ушел в корзину
+	if _, err := db.SQL.Exec(`UPDATE vfs_nodes SET trashed_at = 1 WHERE id = 'd3'`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.LinkDir(ctx, dirLink, "d3"); !errors.Is(err, ErrOutsideLink) {
+		t.Fatalf("trashed folder: %v", err)
+	}
+}
+
+func TestIsPreview(t *testing.T) {
+	s, db := newTestService(t)
+	ctx := context.Background()
+	// то же содержимое, что у превью, загружено и как файл, потом файл удален
+	for _, q := range []string{
+		`INSERT INTO files (id, user_id, cid, size_bytes, created_at) VALUES ('f5', 'bob', 'p5', 1, 0)`,
+		`INSERT INTO file_previews (file_id, kind, cid, mime, size_bytes, width, height, wrapped_key, created_at) VALUES
+			('f2', 'thumbnail', 'p5', 'image/jpeg', 1, 1, 1, x'00', 0)`,
+	} {
+		if _, err := db.SQL.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ok, err := s.IsPreview(ctx, "p5"); err != nil || ok {
+		t.Fatalf("preview that is also a file: %v %v", ok, err)
+	}
+	if _, err := db.SQL.Exec(`DELETE FROM files WHERE id = 'f5'`); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		cid  string
+		want bool
+	}{
+		{"p1", true},
+		{"c1", false},
+		{"p5", true},
+		{"unknown", false},
+	}
+	for _, tc := range cases {
+		got, err := s.IsPreview(ctx, tc.cid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("IsPreview(%s) = %v, want %v", tc.cid, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,412 @@
+package storage
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"lukechampine.com/blake3"
+)
+
+// заполнение идет без отмены вызывающим, чтобы обрыв одного клиента не ронял
+// загрузку остальным; поток экспорта сам по себе ничем не ограничен
+const cacheFillTimeout = time.Hour
+
+// запомненных car больше лимита записи, чтобы не тянуть их в кэш повторно
+const maxSkipped = 10000
+
+var (
+	ErrCacheCorrupt = errors.New("cached car failed verification")
+	errCacheTooBig  = errors.New("car exceeds cache entry limit")
+	errCacheWrite   = errors.New("cache write failed")
+)
+
+// горячий кэш экспортов car на диске, ключ - cid. вытесняются давно не читанные записи,
+// когда сумма размеров превышает бюджет. запись сверяется с BLAKE3 по мере отдачи:
+// у битой поток обрывается ErrCacheCorrupt, а сама она удаляется, следующее чтение
+// берет car из хранилища. одновременные промахи по одному cid ждут одного экспорта
+type BlobCache struct {
+	dir      string
+	maxBytes int64
+	maxEntry int64
+
+	mu       sync.Mutex
+	lru      *list.List
+	entries  map[string]*list.Element
+	size     int64
+	flights  map[string]*cacheFlight
+	skipped  map[string]bool
+	onLookup func(result string, bytes int64, entries int)
+}
+
+type cacheEntry struct {
+	cid  string
+	sum  [32]byte
+	size int64
+}
+
+type cacheFlight struct {
+	done chan struct{}
+	err  error
+	// Remove пришел во время заполнения: экспорт мог начаться до открепления,
+	// такая запись в кэш не попадает. под c.mu
+	removed bool
+}
+
+// maxEntry больше maxBytes урезается до него. записи, оставшиеся в dir с прошлого
+// запуска, подхватываются в порядке времени последнего чтения
+func NewBlobCache(dir string, maxBytes, maxEntry int64) (*BlobCache, error) {
+	if maxBytes <= 0 {
+		return nil, errors.New("blob cache size must be positive")
+	}
+	if maxEntry <= 0 || maxEntry > maxBytes {
+		maxEntry = maxBytes
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create blob cache dir: %w", err)
+	}
+	c := &BlobCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		maxEntry: maxEntry,
+		lru:      list.New(),
+		entries:  map[string]*list.Element{},
+		flights:  map[string]*cacheFlight{},
+		skipped:  map[string]bool{},
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// f вызывается на каждое обращение: hit или miss, и еще corrupt, когда отданная запись
+// не сошлась с BLAKE3 и удалена, с размером кэша после него
+func (c *BlobCache) OnLookup(f func(result string, bytes int64, entries int)) {
+	c.onLookup = f
+}
+
+// имя записи - cid и BLAKE3 содержимого
+func (c *BlobCache) path(e *cacheEntry) string {
+	return filepath.Join(c.dir, e.cid+"."+hex.EncodeToString(e.sum[:])+".car")
+}
+
+func (c *BlobCache) load() error {
+	des, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	type found struct {
+		e   *cacheEntry
+		mod time.Time
+	}
+	var all []found
+	for _, de := range des {
+		name := de.Name()
+		if strings.HasPrefix(name, "fill-") {
+			// недописанное заполнение
+			os.Remove(filepath.Join(c.dir, name))
+			continue
+		}
+		parts := strings.Split(name, ".")
+		if len(parts) != 3 || parts[2] != "car" {
+			continue
+		}
+		sum, err := hex.DecodeString(parts[1])
+		if err != nil || len(sum) != 32 {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		e := &cacheEntry{cid: parts[0], size: info.Size()}
+		copy(e.sum[:], sum)
+		all = append(all, found{e, info.ModTime()})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].mod.Before(all[j].mod) })
+	for _, f := range all {
+		if old, ok := c.entries[f.e.cid]; ok {
+			os.Remove(c.path(old.Value.(*cacheEntry)))
+			c.drop(old)
+		}
+		c.entries[f.e.cid] = c.lru.PushFront(f.e)
+		c.size += f.e.size
+	}
+	for _, p := range c.evict() {
+		os.Remove(p)
+	}
+	return nil
+}
+
+// car из кэша или через fetch с записью в кэш. cid не в каноническом виде, car больше
+// лимита записи и ошибки диска кэша обходят кэш
+func (c *BlobCache) Get(ctx context.Context, cid string, fetch func(context.Context) (io.ReadCloser, error)) (io.ReadCloser, error) {
+	key, err := blobKey(cid)
+	if err != nil || key != cid {
+		return fetch(ctx)
+	}
+	result := "hit"
+	for round := 0; ; round++ {
+		if f := c.open(key); f != nil {
+			c.report(result)
+			return f, nil
+		}
+		result = "miss"
+		// после двух заполнений подряд без годной записи диск кэша не в порядке
+		if round == 2 {
+			c.report(result)
+			return fetch(ctx)
+		}
+		fl, skip := c.flight(key, fetch)
+		if skip {
+			c.report(result)
+			return fetch(ctx)
+		}
+		select {
+		case <-fl.done:
+		case <-ctx.Done():
+			c.report(result)
+			return nil, ctx.Err()
+		}
+		switch {
+		case fl.err == nil:
+			// запись могли вытеснить сразу после заполнения, тогда еще круг
+		case errors.Is(fl.err, errCacheTooBig), errors.Is(fl.err, errCacheWrite):
+			c.report(result)
+			return fetch(ctx)
+		default:
+			c.report(result)
+			return nil, fl.err
+		}
+	}
+}
+
+func (c *BlobCache) report(result string) {
+	if c.onLookup == nil {
+		return
+	}
+	c.mu.Lock()
+	size, n := c.size, c.lru.Len()
+	c.mu.Unlock()
+	c.onLookup(result, size, n)
+}
+
+// открыть запись. проверка идет при чтении, а не отдельным проходом по файлу
+func (c *BlobCache) open(key string) io.ReadCloser {
+	c.mu.Lock()
+	el, ok := c.entries[key]
+	if !ok {
+		c.mu.Unlock()
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	e := el.Value.(*cacheEntry)
+	c.mu.Unlock()
+	p := c.path(e)
+	f, err := os.Open(p)
+	if err != nil {
+		c.remove(e)
+		return nil
+	}
+	// время изменения задает порядок вытеснения после рестарта
+	now := time.Now()
+	os.Chtimes(p, now, now)
+	return &verifiedEntry{c: c, e: e, f: f, h: blake3.New(32, nil)}
+}
+
+// запись, которая считает BLAKE3 того, что отдала. байты до конца файла уходят
+// непроверенными, поэтому расхождение обрывает поток ошибкой вместо io.EOF: клиент
+// не примет битый car за целый. диапазон, дочитанный не до конца, не проверяется
+type verifiedEntry struct {
+	c   *BlobCache
+	e   *cacheEntry
+	f   *os.File
+	h   *blake3.Hasher
+	n   int64
+	err error
+}
+
+func (v *verifiedEntry) Read(p []byte) (int, error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+	n, err := v.f.Read(p)
+	v.h.Write(p[:n])
+	v.n += int64(n)
+	if err == io.EOF && (v.n != v.e.size || !bytes.Equal(v.h.Sum(nil), v.e.sum[:])) {
+		slog.Warn("blob cache: entry failed verification", "cid", v.e.cid, "size", v.n, "want", v.e.size)
+		v.c.remove(v.e)
+		v.c.report("corrupt")
+		err = ErrCacheCorrupt
+	}
+	if err != nil {
+		v.err = err
+	}
+	return n, err
+}
+
+func (v *verifiedEntry) Close() error {
+	return v.f.Close()
+}
+
+// присоединиться к заполнению cid или начать его. skip - car больше лимита записи
+func (c *BlobCache) flight(key string, fetch func(context.Context) (io.ReadCloser, error)) (*cacheFlight, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.skipped[key] {
+		return nil, true
+	}
+	if fl, ok := c.flights[key]; ok {
+		return fl, false
+	}
+	fl := &cacheFlight{done: make(chan struct{})}
+	c.flights[key] = fl
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cacheFillTimeout)
+		defer cancel()
+		fl.err = c.fill(ctx, fl, key, fetch)
+		c.mu.Lock()
+		delete(c.flights, key)
+		if errors.Is(fl.err, errCacheTooBig) {
+			if len(c.skipped) >= maxSkipped {
+				clear(c.skipped)
+			}
+			c.skipped[key] = true
+		}
+		c.mu.Unlock()
+		close(fl.done)
+	}()
+	return fl, false
+}
+
+// экспорт во временный файл с BLAKE3 на лету, затем переименование в запись.
+// ошибки хранилища возвращаются как есть, ошибки диска кэша - с errCacheWrite
+func (c *BlobCache) fill(ctx context.Context, fl *cacheFlight, key string, fetch func(context.Context) (io.ReadCloser, error)) error {
+	rc, err := fetch(ctx)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	tmp, err := os.CreateTemp(c.dir, "fill-*")
+	if err != nil {
+		slog.Warn("blob cache: create entry", "cid", key, "error", err)
+		return errCacheWrite
+	}
+	defer os.Remove(tmp.Name())
+	h := blake3.New(32, nil)
+	src := &sourceReader{r: io.LimitReader(rc, c.maxEntry+1)}
+	n, err := io.Copy(io.MultiWriter(tmp, h), src)
+	if src.err != nil {
+		tmp.Close()
+		return src.err
+	}
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err != nil {
+		slog.Warn("blob cache: write entry", "cid", key, "error", err)
+		return errCacheWrite
+	}
+	if n > c.maxEntry {
+		return errCacheTooBig
+	}
+	e := &cacheEntry{cid: key, size: n}
+	copy(e.sum[:], h.Sum(nil))
+	if err := os.Rename(tmp.Name(), c.path(e)); err != nil {
+		slog.Warn("blob cache: store entry", "cid", key, "error", err)
+		return errCacheWrite
+	}
+	c.mu.Lock()
+	if fl.removed {
+		c.mu.Unlock()
+		os.Remove(c.path(e))
+		return nil
+	}
+	if old, ok := c.entries[key]; ok {
+		// тот же cid с другими байтами экспорта: остается свежий
+		if old.Value.(*cacheEntry).sum != e.sum {
+			defer os.Remove(c.path(old.Value.(*cacheEntry)))
+		}
+		c.drop(old)
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	c.size += n
+	evicted := c.evict()
+	c.mu.Unlock()
+	for _, p := range evicted {
+		os.Remove(p)
+	}
+	return nil
+}
+
+// вытеснить старые записи сверх бюджета, самая свежая остается всегда. файлы удаляет
+// вызывающий вне блокировки, открытые на чтение дочитываются и после удаления
+func (c *BlobCache) evict() []string {
+	var paths []string
+	for c.size > c.maxBytes && c.lru.Len() > 1 {
+		el := c.lru.Back()
+		paths = append(paths, c.path(el.Value.(*cacheEntry)))
+		c.drop(el)
+	}
+	return paths
+}
+
+func (c *BlobCache) drop(el *list.Element) {
+	e := el.Value.(*cacheEntry)
+	c.lru.Remove(el)
+	delete(c.entries, e.cid)
+	c.size -= e.size
+}
+
+// удалить запись e, если она еще в кэше
+func (c *BlobCache) remove(e *cacheEntry) {
+	c.mu.Lock()
+	el, ok := c.entries[e.cid]
+	ok = ok && el.Value == e
+	if ok {
+		c.drop(el)
+	}
+	c.mu.Unlock()
+	// вытесненную запись уже удалили, а путь могла занять новая с той же суммой
+	if ok {
+		os.Remove(c.path(e))
+	}
+}
+
+// забыть cid, например после открепления. идущее заполнение cid запись не оставит
+func (c *BlobCache) Remove(cid string) {
+	c.mu.Lock()
+	delete(c.skipped, cid)
+	if fl, ok := c.flights[cid]; ok {
+		fl.removed = true
+	}
+	el, ok := c.entries[cid]
+	if ok {
+		c.drop(el)
+	}
+	c.mu.Unlock()
+	if ok {
+		os.Remove(c.path(el.Value.(*cacheEntry)))
+	}
+}
+
+// размер записей в байтах и их число
+func (c *BlobCache) Size() (int64, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size, c.lru.Len()
+}
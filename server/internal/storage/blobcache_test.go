@@ -0,0 +1,285 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// источник car для кэша со счетчиком экспортов. пока gate не закрыт, экспорт ждет
+type countingFetch struct {
+	data  []byte
+	calls atomic.Int32
+	gate  chan struct{}
+}
+
+func (f *countingFetch) fetch(ctx context.Context) (io.ReadCloser, error) {
+	f.calls.Add(1)
+	if f.gate != nil {
+		select {
+		case <-f.gate:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return io.NopCloser(bytes.NewReader(f.data)), nil
+}
+
+func newTestCache(t *testing.T, maxBytes, maxEntry int64) (*BlobCache, map[string]int) {
+	t.Helper()
+	c, err := NewBlobCache(t.TempDir(), maxBytes, maxEntry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	results := map[string]int{}
+	c.OnLookup(func(result string, _ int64, _ int) {
+		mu.Lock()
+		results[result]++
+		mu.Unlock()
+	})
+	return c, results
+}
+
+func cacheGet(t *testing.T, c *BlobCache, cid string, f *countingFetch) []byte {
+	t.Helper()
+	rc, err := c.Get(context.Background(), cid, f.fetch)
+	return readAll(t, rc, err)
+}
+
+func cacheFiles(t *testing.T, c *BlobCache) []string {
+	t.Helper()
+	names, err := filepath.Glob(filepath.Join(c.dir, "*.car"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestBlobCacheSingleFlight(t *testing.T) {
+	c, results := newTestCache(t, 1<<20, 0)
+	cid := rawCID(t, "shared")
+	f := &countingFetch{data: bytes.Repeat([]byte("car"), 1000), gate: make(chan struct{})}
+
+	const readers = 8
+	var wg sync.WaitGroup
+	got := make([][]byte, readers)
+	errs := make([]error, readers)
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rc, err := c.Get(context.Background(), cid, f.fetch)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer rc.Close()
+			got[i], errs[i] = io.ReadAll(rc)
+		}(i)
+	}
+	// все читатели либо ждут заполнения, либо еще не дошли до Get: экспорт один
+	for f.calls.Load() == 0 {
+		runtime.Gosched()
+	}
+	close(f.gate)
+	wg.Wait()
+
+	for i := range got {
+		if errs[i] != nil || !bytes.Equal(got[i], f.data) {
+			t.Fatalf("reader %d: %v, %d bytes", i, errs[i], len(got[i]))
+		}
+	}
+	if n := f.calls.Load(); n != 1 {
+		t.Fatalf("%d exports for one cid", n)
+	}
+	if results["miss"]+results["hit"] != readers {
+		t.Fatalf("lookups %v", results)
+	}
+	if got := cacheGet(t, c, cid, f); !bytes.Equal(got, f.data) || f.calls.Load() != 1 {
+		t.Fatalf("cached read went to the store: %d exports", f.calls.Load())
+	}
+}
+
+func TestBlobCacheFillSurvivesCanceledReader(t *testing.T) {
+	c, _ := newTestCache(t, 1<<20, 0)
+	cid := rawCID(t, "canceled")
+	f := &countingFetch{data: []byte("car bytes"), gate: make(chan struct{})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Get(ctx, cid, f.fetch)
+		done <- err
+	}()
+	for f.calls.Load() == 0 {
+		runtime.Gosched()
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled reader: %v", err)
+	}
+	close(f.gate)
+
+	if got := cacheGet(t, c, cid, f); !bytes.Equal(got, f.data) {
+		t.Fatalf("read after cancel: %q", got)
+	}
+	if n := f.calls.Load(); n != 1 {
+		t.Fatalf("fill restarted after the first reader left: %d exports", n)
+	}
+}
+
+func TestBlobCacheRemoveDuringFill(t *testing.T) {
+	c, _ := newTestCache(t, 1<<20, 0)
+	cid := rawCID(t, "unpinned while filling")
+	f := &countingFetch{data: []byte("car bytes"), gate: make(chan struct{})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Get(ctx, cid, f.fetch)
+		done <- err
+	}()
+	for f.calls.Load() == 0 {
+		runtime.Gosched()
+	}
+	cancel()
+	<-done
+	// открепление приходит, пока экспорт еще идет
+	c.Remove(cid)
+	close(f.gate)
+	for {
+		c.mu.Lock()
+		n := len(c.flights)
+		c.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		runtime.Gosched()
+	}
+
+	if _, n := c.Size(); n != 0 || len(cacheFiles(t, c)) != 0 {
+		t.Fatalf("fill started before Remove cached the car: %d entries", n)
+	}
+	cacheGet(t, c, cid, f)
+	if n := f.calls.Load(); n != 2 {
+		t.Fatalf("read after Remove served from cache: %d exports", n)
+	}
+}
+
+func TestBlobCacheEvictsLeastRecentlyRead(t *testing.T) {
+	c, _ := newTestCache(t, 250, 0)
+	a, b, d := rawCID(t, "a"), rawCID(t, "b"), rawCID(t, "d")
+	fa := &countingFetch{data: bytes.Repeat([]byte("a"), 100)}
+	fb := &countingFetch{data: bytes.Repeat([]byte("b"), 100)}
+	fd := &countingFetch{data: bytes.Repeat([]byte("d"), 100)}
+
+	cacheGet(t, c, a, fa)
+	cacheGet(t, c, b, fb)
+	// a прочитан позже b, вытесняется b
+	cacheGet(t, c, a, fa)
+	cacheGet(t, c, d, fd)
+
+	if size, n := c.Size(); size != 200 || n != 2 {
+		t.Fatalf("cache size %d in %d entries", size, n)
+	}
+	if files := cacheFiles(t, c); len(files) != 2 {
+		t.Fatalf("files on disk %v", files)
+	}
+	cacheGet(t, c, a, fa)
+	cacheGet(t, c, d, fd)
+	if fa.calls.Load() != 1 || fd.calls.Load() != 1 {
+		t.Fatalf("recent entries evicted: a %d, d %d exports", fa.calls.Load(), fd.calls.Load())
+	}
+	cacheGet(t, c, b, fb)
+	if fb.calls.Load() != 2 {
+		t.Fatalf("least recently read entry kept: %d exports", fb.calls.Load())
+	}
+
+	// после рестарта порядок восстанавливается по времени чтения
+	c2, err := NewBlobCache(c.dir, 250, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size, n := c2.Size(); size != 200 || n != 2 {
+		t.Fatalf("reloaded cache size %d in %d entries", size, n)
+	}
+}
+
+func TestBlobCacheBypassesOversizedCAR(t *testing.T) {
+	c, _ := newTestCache(t, 1000, 10)
+	cid := rawCID(t, "big")
+	f := &countingFetch{data: bytes.Repeat([]byte("x"), 11)}
+	for i := 0; i < 2; i++ {
+		if got := cacheGet(t, c, cid, f); !bytes.Equal(got, f.data) {
+			t.Fatalf("read %d: %d bytes", i, len(got))
+		}
+	}
+	if _, n := c.Size(); n != 0 {
+		t.Fatalf("oversized car cached: %d entries", n)
+	}
+	// первый раз экспорт для заполнения и повтор в обход, дальше только в обход
+	if n := f.calls.Load(); n != 3 {
+		t.Fatalf("%d exports", n)
+	}
+}
+
+func TestBlobCacheCorruptEntryFailsStream(t *testing.T) {
+	c, results := newTestCache(t, 1<<20, 0)
+	cid := rawCID(t, "corrupt")
+	f := &countingFetch{data: bytes.Repeat([]byte("0123456789"), 1000)}
+	cacheGet(t, c, cid, f)
+
+	files := cacheFiles(t, c)
+	if len(files) != 1 {
+		t.Fatalf("files on disk %v", files)
+	}
+	bad := bytes.Clone(f.data)
+	bad[len(bad)/2] ^= 1
+	if err := os.WriteFile(files[0], bad, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	rc, err := c.Get(context.Background(), cid, f.fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.ReadAll(rc)
+	rc.Close()
+	if !errors.Is(err, ErrCacheCorrupt) {
+		t.Fatalf("corrupt entry read: %v", err)
+	}
+	if _, n := c.Size(); n != 0 || len(cacheFiles(t, c)) != 0 || results["corrupt"] != 1 {
+		t.Fatalf("corrupt entry kept: %d entries, lookups %v", n, results)
+	}
+
+	if got := cacheGet(t, c, cid, f); !bytes.Equal(got, f.data) || f.calls.Load() != 2 {
+		t.Fatalf("refill after corruption: %d exports", f.calls.Load())
+	}
+}
+
+func TestBlobCacheTruncatedEntryFailsStream(t *testing.T) {
+	c, _ := newTestCache(t, 1<<20, 0)
+	cid := rawCID(t, "truncated")
+	f := &countingFetch{data: []byte(strings.Repeat("car", 100))}
+	cacheGet(t, c, cid, f)
+	if err := os.Truncate(cacheFiles(t, c)[0], 10); err != nil {
+		t.Fatal(err)
+	}
+	rc, err := c.Get(context.Background(), cid, f.fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if _, err := io.ReadAll(rc); !errors.Is(err, ErrCacheCorrupt) {
+		t.Fatalf("truncated entry read: %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ipfs/go-cid"
+)
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+// где лежат car файлы. Put принимает car потоком и возвращает корень, после Put
+// содержимое сохранено надолго (в kubo - закреплено), Delete его отпускает.
+// Get отдает car целиком, для kubo это dag/export, байты могут отличаться от
+// загруженных порядком блоков
+type BlobStore interface {
+	Put(ctx context.Context, car io.Reader) (string, error)
+	Get(ctx context.Context, cid string) (io.ReadCloser, error)
+	Stat(ctx context.Context, cid string) (BlobInfo, error)
+	Delete(ctx context.Context, cid string) error
+	List(ctx context.Context) ([]string, error)
+}
+
+// Size у kubo - объем блоков dag, у остальных - размер car
+type BlobInfo struct {
+	CID  string
+	Size int64
+}
+
+// хранилище, которое умеет отдавать отдельные блоки без чтения всего car
+type blockSource interface {
+	BlockCIDs(ctx context.Context, cid string) ([]string, error)
+	Block(ctx context.Context, cid string) ([]byte, error)
+}
+
+// хранилище, где блоки живут отдельно от car и остаются после Delete до своего gc.
+// блоки, нужные другим пинам, хранилище удалять не должно
+type blockRemover interface {
+	DeleteBlocks(ctx context.Context, cids []string) (int, error)
+}
+
+// хранилище, которое может вернуть потерянное содержимое (kubo найдет его в сети)
+type pinner interface {
+	Pin(ctx context.Context, cid string) error
+}
+
+// cid из пути пользователя в каноническом виде, заодно защита от путей вида ../
+func blobKey(s string) (string, error) {
+	c, err := cid.Decode(s)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrBlobNotFound, err)
+	}
+	return c.String(), nil
+}
+
+// принятый во временный файл car, файл открыт и перемотан в начало
+type spooledCAR struct {
+	file   *os.File
+	root   string
+	size   int64
+	sha256 []byte
+}
+
+func (s *spooledCAR) Close() {
+	s.file.Close()
+	os.Remove(s.file.Name())
+}
+
+// принять car во временный файл в dir с проверкой всех блоков. ошибка чтения
+// источника (лимит, обрыв стрима) важнее ошибки разбора, которую она вызвала
+func spoolCAR(dir string, r io.Reader) (*spooledCAR, error) {
+	f, err := os.CreateTemp(dir, "put-*.car")
+	if err != nil {
+		return nil, err
+	}
+	sp := &spooledCAR{file: f}
+	h := sha256.New()
+	cw := &countWriter{}
+	src := &sourceReader{r: r}
+	root, err := checkCAR(io.TeeReader(src, io.MultiWriter(f, h, cw)))
+	if err != nil && src.err != nil {
+		err = src.err
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		sp.Close()
+		return nil, err
+	}
+	sp.root, sp.size, sp.sha256 = root, cw.n, h.Sum(nil)
+	return sp, nil
+}
+
+type sourceReader struct {
+	r   io.Reader
+	err error
+}
+
+func (s *sourceReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if err != nil && err != io.EOF && s.err == nil {
+		s.err = err
+	}
+	return n, err
+}
+
+type countWriter struct {
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// cid всех блоков car, корень первым, для хранилищ без отдельного доступа к блокам
+func carBlockCIDs(r io.Reader, root string) ([]string, error) {
+	cr, err := newCARBlockReader(r)
+	if err != nil {
+		return nil, err
+	}
+	out := []string{root}
+	seen := map[string]bool{}
+	if c, err := cid.Decode(root); err == nil {
+		seen[c.KeyString()] = true
+	}
+	for {
+		id, _, err := cr.Next()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !seen[id.KeyString()] {
+			seen[id.KeyString()] = true
+			out = append(out, id.String())
+		}
+	}
+}
+
+// данные блока want из car
+func carBlock(r io.Reader, want string) ([]byte, error) {
+	w, err := cid.Decode(want)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBlobNotFound, err)
+	}
+	cr, err := newCARBlockReader(r)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		id, data, err := cr.Next()
+		if err == io.EOF {
+			return nil, ErrBlobNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if id.Equals(w) {
+			return data, nil
+		}
+	}
+}
+
+// отдать reader чанками, как StreamCAR у kubo
+func streamReader(ctx context.Context, open func() (io.ReadCloser, error), chunkSize int) (<-chan []byte, <-chan error) {
+	chunks := make(chan []byte, 2)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(chunks)
+		defer close(errs)
+		rc, err := open()
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer rc.Close()
+		buf := make([]byte, chunkSize)
+		for {
+			n, err := rc.Read(buf)
+			if n > 0 {
+				chunk := make([]byte, n)
+				copy(chunk, buf[:n])
+				select {
+				case chunks <- chunk:
+				case <-ctx.Done():
+					errs <- ctx.Err()
+					return
+				}
+			}
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	return chunks, errs
+}
@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+)
+
+// сколько блоков без ссылок уходит в хранилище одним запросом
+const blockGCBatch = 500
+
+var ErrBlockGCRunning = errors.New("block gc already running")
+
+// итог прохода gc блоков со сводкой дедупликации. LogicalBytes - блоки всех
+// содержимых, как если бы общие блоки хранились отдельно у каждого, UniqueBytes -
+// блоки, на которые есть ссылки, по одному разу
+type BlockReport struct {
+	RunAt        time.Time
+	Collected    int
+	Removed      int
+	LogicalBytes int64
+	UniqueBytes  int64
+	UniqueBlocks int64
+	Err          string
+}
+
+// во сколько раз общие блоки уменьшили объем, 1 - общих блоков нет
+func (r BlockReport) DedupRatio() float64 {
+	if r.UniqueBytes == 0 {
+		return 1
+	}
+	return float64(r.LogicalBytes) / float64(r.UniqueBytes)
+}
+
+// индекс блоков загруженных car со счетчиком ссылок из содержимых. блоки, на которые
+// не осталось ссылок, удаляются из хранилища, если оно держит блоки отдельно (kubo);
+// у local и s3 блоки уходят вместе с car, и gc только чистит индекс.
+// содержимое, загруженное до индекса, в нем не учитывается
+type BlockIndex struct {
+	svc      *Service
+	db       *sql.DB
+	kick     chan struct{}
+	running  sync.Mutex
+	onReport func(BlockReport)
+}
+
+func NewBlockIndex(svc *Service, db *sql.DB) *BlockIndex {
+	return &BlockIndex{svc: svc, db: db, kick: make(chan struct{}, 1)}
+}
+
+// f вызывается после каждого прохода, например для метрик
+func (b *BlockIndex) OnReport(f func(BlockReport)) {
+	b.onReport = f
+}
+
+// проход раз в interval и после каждого освобожденного содержимого
+func (b *BlockIndex) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			if _, err := b.Run(ctx); err != nil && ctx.Err() == nil && !errors.Is(err, ErrBlockGCRunning) {
+				slog.Warn("blocks: gc", "error", err)
+			}
+			select {
+			case <-t.C:
+			case <-b.kick:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// разбудить gc, не дожидаясь таймера
+func (b *BlockIndex) Kick() {
+	select {
+	case b.kick <- struct{}{}:
+	default:
+	}
+}
+
+// ключ блока в индексе: raw cidv1 от multihash, так блок под cidv0 и cidv1 один
+func blockIndexKey(c cid.Cid) string {
+	return cid.NewCidV1(cid.Raw, c.Hash()).String()
+}
+
+// записать блоки содержимого content. повторная загрузка того же содержимого
+// ничего не меняет: строки уже есть, триггер не срабатывает
+func (b *BlockIndex) add(ctx context.Context, content string, blocks map[string]int) error {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, `INSERT OR IGNORE INTO content_blocks (content_cid, block_cid, size_bytes) VALUES (?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for key, size := range blocks {
+		if _, err := stmt.ExecContext(ctx, content, key, size); err != nil {
+			return fmt.Errorf("index blocks: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// один проход: удалить блоки без ссылок и пересчитать сводку
+func (b *BlockIndex) Run(ctx context.Context) (BlockReport, error) {
+	if !b.running.TryLock() {
+		return BlockReport{}, ErrBlockGCRunning
+	}
+	defer b.running.Unlock()
+	rep := BlockReport{RunAt: time.Now()}
+	err := b.collect(ctx, &rep)
+	if err == nil {
+		err = b.stats(ctx, &rep)
+	}
+	if err != nil {
+		rep.Err = err.Error()
+	}
+	if b.onReport != nil {
+		b.onReport(rep)
+	}
+	return rep, err
+}
+
+func (b *BlockIndex) collect(ctx context.Context, rep *BlockReport) error {
+	remover, _ := b.svc.blobs.(blockRemover)
+	for {
+		rows, err := b.db.QueryContext(ctx, `SELECT cid FROM blocks WHERE refcount <= 0 LIMIT ?`, blockGCBatch)
+		if err != nil {
+			return fmt.Errorf("unreferenced blocks: %w", err)
+		}
+		var batch []string
+		for rows.Next() {
+			var c string
+			if err := rows.Scan(&c); err != nil {
+				rows.Close()
+				return err
+			}
+			batch = append(batch, c)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		// при ошибке строки остаются до следующего прохода. блок, который kubo оставил
+		// из-за чужого пина, из индекса уходит: ссылок из содержимых на него нет
+		if remover != nil {
+			n, err := remover.DeleteBlocks(ctx, batch)
+			rep.Removed += n
+			if err != nil {
+				return fmt.Errorf("delete blocks: %w", err)
+			}
+		}
+		// ссылка могла появиться, пока блоки удалялись: такие строки остаются
+		args := make([]any, len(batch))
+		for i, c := range batch {
+			args[i] = c
+		}
+		res, err := b.db.ExecContext(ctx, `DELETE FROM blocks WHERE refcount <= 0 AND cid IN (?`+strings.Repeat(", ?", len(batch)-1)+`)`, args...)
+		if err != nil {
+			return fmt.Errorf("drop collected blocks: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		rep.Collected += int(n)
+		if len(batch) < blockGCBatch {
+			return nil
+		}
+	}
+}
+
+func (b *BlockIndex) stats(ctx context.Context, rep *BlockReport) error {
+	err := b.db.QueryRowContext(ctx, `SELECT COALESCE(SUM(size_bytes), 0) FROM content_blocks`).Scan(&rep.LogicalBytes)
+	if err != nil {
+		return fmt.Errorf("block stats: %w", err)
+	}
+	err = b.db.QueryRowContext(ctx, `SELECT COUNT(*), COALESCE(SUM(size_bytes), 0) FROM blocks WHERE refcount > 0`).Scan(&rep.UniqueBlocks, &rep.UniqueBytes)
+	if err != nil {
+		return fmt.Errorf("block stats: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/go-cid"
+)
+
+var (
+	ErrBadCARBlock      = errors.New("invalid car block")
+	ErrCARCodec         = errors.New("unsupported block codec")
+	ErrCARBlockTooLarge = errors.New("car block too large")
+)
+
+// секция больше этого не пропускается: kubo сам не принимает блоки крупнее 2 мб
+const maxCARSectionSize = 8 << 20
+
+// cid длиннее не бывает: версия, кодек и sha2-512 с запасом
+const maxCIDBytes = 128
+
+// кодеки, которые понимает dag/import kubo, по именам из multicodec
+var carCodecs = map[string]uint64{
+	"raw":      cid.Raw,
+	"dag-pb":   cid.DagProtobuf,
+	"dag-cbor": cid.DagCBOR,
+	"dag-json": cid.DagJSON,
+}
+
+// ограничения на блоки принимаемых car. нулевое значение пропускает любые кодеки
+// и блоки до maxCARSectionSize
+type CARPolicy struct {
+	MaxBlockBytes int
+	Codecs        map[uint64]bool
+}
+
+// codecs - имена кодеков, пустой список не ограничивает
+func NewCARPolicy(maxBlockBytes int, codecs []string) (CARPolicy, error) {
+	p := CARPolicy{MaxBlockBytes: maxBlockBytes}
+	if maxBlockBytes < 0 || maxBlockBytes > maxCARSectionSize-maxCIDBytes {
+		return p, fmt.Errorf("max block size %d out of range", maxBlockBytes)
+	}
+	for _, name := range codecs {
+		code, ok := carCodecs[name]
+		if !ok {
+			return p, fmt.Errorf("%w: %q", ErrCARCodec, name)
+		}
+		if p.Codecs == nil {
+			p.Codecs = map[uint64]bool{}
+		}
+		p.Codecs[code] = true
+	}
+	return p, nil
+}
+
+// что известно о car после полной проверки
+type CARStats struct {
+	Version  uint64
+	Root     string
+	Blocks   int64
+	MaxBlock int
+}
+
+// блоки car по порядку. каждый блок сверяется с хешем из своего cid,
+// так что cid считаются здесь же, без kubo
+type carBlockReader struct {
+	br        *bufio.Reader
+	header    *carHeader
+	remaining int64
+	policy    CARPolicy
+	// последняя секция как она была в потоке, с префиксом длины
+	raw []byte
+}
+
+func newCARBlockReader(r io.Reader) (*carBlockReader, error) {
+	return newCARBlockReaderPolicy(r, CARPolicy{})
+}
+
+func newCARBlockReaderPolicy(r io.Reader, p CARPolicy) (*carBlockReader, error) {
+	br := bufio.NewReader(r)
+	h, err := parseCARHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	return &carBlockReader{br: br, header: h, remaining: h.sections, policy: p}, nil
+}
+
+func (c *carBlockReader) Roots() []string {
+	return c.header.roots
+}
+
+// следующий блок, io.EOF после последнего. у carv2 индекс после блоков не читается
+func (c *carBlockReader) Next() (cid.Cid, []byte, error) {
+	if c.remaining == 0 {
+		return cid.Undef, nil, io.EOF
+	}
+	peek, perr := c.br.Peek(binary.MaxVarintLen64)
+	if len(peek) == 0 && perr == io.EOF && c.remaining < 0 {
+		return cid.Undef, nil, io.EOF
+	}
+	size, n := binary.Uvarint(peek)
+	if n <= 0 {
+		if perr != nil {
+			return cid.Undef, nil, fmt.Errorf("%w: section length: %v", ErrBadCARBlock, perr)
+		}
+		return cid.Undef, nil, fmt.Errorf("%w: bad section length", ErrBadCARBlock)
+	}
+	// длина в неминимальной записи дала бы два разных потока для одного car
+	if n != uvarintSize(size) {
+		return cid.Undef, nil, fmt.Errorf("%w: non-minimal section length", ErrBadCARBlock)
+	}
+	limit := uint64(maxCARSectionSize)
+	if c.policy.MaxBlockBytes > 0 {
+		limit = uint64(c.policy.MaxBlockBytes + maxCIDBytes)
+	}
+	if size == 0 {
+		return cid.Undef, nil, fmt.Errorf("%w: empty section", ErrBadCARBlock)
+	}
+	if size > limit {
+		return cid.Undef, nil, fmt.Errorf("%w: section of %d bytes", ErrCARBlockTooLarge, size)
+	}
+	if c.remaining > 0 {
+		c.remaining -= int64(n) + int64(size)
+		if c.remaining < 0 {
+			return cid.Undef, nil, fmt.Errorf("%w: section past data end", ErrBadCARBlock)
+		}
+	}
+	buf := make([]byte, n+int(size))
+	if _, err := io.ReadFull(c.br, buf); err != nil {
+		return cid.Undef, nil, fmt.Errorf("%w: %v", ErrBadCARBlock, err)
+	}
+	c.raw = buf
+	cn, id, err := cid.CidFromBytes(buf[n:])
+	if err != nil {
+		return cid.Undef, nil, fmt.Errorf("%w: %v", ErrBadCARBlock, err)
+	}
+	data := buf[n+cn:]
+	if c.policy.MaxBlockBytes > 0 && len(data) > c.policy.MaxBlockBytes {
+		return cid.Undef, nil, fmt.Errorf("%w: %s has %d bytes, limit %d", ErrCARBlockTooLarge, id, len(data), c.policy.MaxBlockBytes)
+	}
+	if c.policy.Codecs != nil && !c.policy.Codecs[id.Type()] {
+		return cid.Undef, nil, fmt.Errorf("%w: %s uses codec 0x%x", ErrCARCodec, id, id.Type())
+	}
+	sum, err := id.Prefix().Sum(data)
+	if err != nil {
+		return cid.Undef, nil, fmt.Errorf("%w: %s: %v", ErrBadCARBlock, id, err)
+	}
+	if !sum.Equals(id) {
+		return cid.Undef, nil, fmt.Errorf("%w: %s: hash mismatch", ErrBadCARBlock, id)
+	}
+	return id, data, nil
+}
+
+func uvarintSize(v uint64) int {
+	var b [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(b[:], v)
+}
+
+// дочитать car до конца с проверкой блоков. возвращает первый корень, он должен
+// быть среди блоков, иначе из такого car dag не собрать
+func checkCAR(r io.Reader) (string, error) {
+	v := newCARValidator(r, CARPolicy{})
+	if _, err := io.Copy(io.Discard, v); err != nil {
+		return "", err
+	}
+	return v.Stats().Root, nil
+}
+
+// проверка car на лету: читает из src секцию целиком, проверяет и только потом
+// отдает дальше, так что битый или запрещенный блок не доходит до хранилища.
+// ошибка чтения src (лимит, обрыв) возвращается как есть
+type carValidator struct {
+	src     *sourceReader
+	policy  CARPolicy
+	cr      *carBlockReader
+	root    cid.Cid
+	found   bool
+	pending []byte
+	stats   CARStats
+	tail    bool
+	err     error
+	// размеры блоков по ключу blockIndexKey, повторы в car считаются один раз
+	blocks map[string]int
+	// ссылки на блоки, которых в car пока не было: ключ и ссылающийся блок
+	missing map[string]cid.Cid
+}
+
+func newCARValidator(r io.Reader, p CARPolicy) *carValidator {
+	return &carValidator{src: &sourceReader{r: r}, policy: p, blocks: map[string]int{}, missing: map[string]cid.Cid{}}
+}
+
+func (v *carValidator) Read(p []byte) (int, error) {
+	for len(v.pending) == 0 {
+		if v.err != nil {
+			return 0, v.err
+		}
+		if v.tail {
+			// индекс carv2 за блоками отдается как есть, kubo его пропускает
+			n, err := v.cr.br.Read(p)
+			if err != nil {
+				v.fail(err)
+			}
+			return n, v.pickErr(err)
+		}
+		v.fail(v.step())
+	}
+	n := copy(p, v.pending)
+	v.pending = v.pending[n:]
+	return n, nil
+}
+
+func (v *carValidator) step() error {
+	if v.cr == nil {
+		cr, err := newCARBlockReaderPolicy(v.src, v.policy)
+		if err != nil {
+			return err
+		}
+		root, err := cid.Decode(cr.Roots()[0])
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrBadCARHeader, err)
+		}
+		v.cr, v.root = cr, root
+		v.stats.Version, v.stats.Root = cr.header.version, root.String()
+		v.pending = cr.header.raw
+		return nil
+	}
+	id, data, err := v.cr.Next()
+	if err == io.EOF {
+		if !v.found {
+			return fmt.Errorf("%w: root block %s missing", ErrBadCARBlock, v.root)
+		}
+		// неполный dag kubo дособрал бы из чужих локальных блоков
+		for key, from := range v.missing {
+			return fmt.Errorf("%w: %s links to %s missing from car", ErrBadCARBlock, from, key)
+		}
+		v.tail = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if id.Equals(v.root) {
+		v.found = true
+	}
+	links, err := blockLinks(id, data)
+	if err != nil {
+		if errors.Is(err, ErrCARCodec) {
+			return err
+		}
+		return fmt.Errorf("%w: %s: links: %v", ErrBadCARBlock, id, err)
+	}
+	for _, l := range links {
+		if l.Prefix().MhType == mhIdentity {
+			continue
+		}
+		key := blockIndexKey(l)
+		if _, seen := v.blocks[key]; seen {
+			continue
+		}
+		if _, ok := v.missing[key]; !ok {
+			v.missing[key] = id
+		}
+	}
+	key := blockIndexKey(id)
+	delete(v.missing, key)
+	v.stats.Blocks++
+	v.blocks[key] = len(data)
+	v.stats.MaxBlock = max(v.stats.MaxBlock, len(data))
+	v.pending = v.cr.raw
+	return nil
+}
+
+func (v *carValidator) fail(err error) {
+	if err != nil && v.err == nil {
+		v.err = v.pickErr(err)
+	}
+}
+
+func (v *carValidator) pickErr(err error) error {
+	if err != nil && err != io.EOF && v.src.err != nil {
+		return v.src.err
+	}
+	return err
+}
+
+// корень, который вернуло хранилище, должен совпасть с корнем из заголовка
+func sameCID(a, b string) bool {
+	ca, err := cid.Decode(a)
+	if err != nil {
+		return false
+	}
+	cb, err := cid.Decode(b)
+	return err == nil && ca.Equals(cb)
+}
+
+// итог проверки, полон после того, как Read вернул io.EOF
+func (v *carValidator) Stats() CARStats {
+	return v.stats
+}
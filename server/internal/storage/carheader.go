@@ -0,0 +1,259 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/go-cid"
+)
+
+var ErrBadCARHeader = errors.New("invalid car header")
+
+// заголовок больше этого не бывает у нормальных car файлов
+const maxCARHeaderSize = 1 << 20
+
+// carv2: прагма 11 байт + заголовок 40 байт, внутри лежит обычный carv1
+const (
+	carV2PragmaSize = 11
+	carV2HeaderSize = 40
+)
+
+// прочитать заголовок car из br: корни и все прочитанные байты, которые
+// вызывающий должен отправить дальше перед остатком потока
+func readCARHeader(br *bufio.Reader) ([]string, []byte, error) {
+	h, err := parseCARHeader(br)
+	if err != nil {
+		return nil, nil, err
+	}
+	return h.roots, h.raw, nil
+}
+
+// разобранный заголовок. для carv2 sections - сколько байт секций с блоками идет
+// после внутреннего заголовка до индекса, для carv1 -1 (до конца потока)
+type carHeader struct {
+	roots    []string
+	raw      []byte
+	version  uint64
+	sections int64
+}
+
+func parseCARHeader(br *bufio.Reader) (*carHeader, error) {
+	roots, version, raw, err := readCARv1Header(br, nil)
+	if err != nil {
+		return nil, err
+	}
+	h := &carHeader{version: version, sections: -1}
+	switch version {
+	case 1:
+	case 2:
+		// прагма уже прочитана, дальше заголовок v2 с отступом до данных
+		hdr := make([]byte, carV2HeaderSize)
+		if _, err := io.ReadFull(br, hdr); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBadCARHeader, err)
+		}
+		raw = append(raw, hdr...)
+		dataOffset := binary.LittleEndian.Uint64(hdr[16:24])
+		dataSize := binary.LittleEndian.Uint64(hdr[24:32])
+		consumed := uint64(carV2PragmaSize + carV2HeaderSize)
+		if dataOffset < consumed || dataOffset-consumed > maxCARHeaderSize {
+			return nil, fmt.Errorf("%w: bad data offset", ErrBadCARHeader)
+		}
+		pad := make([]byte, dataOffset-consumed)
+		if _, err := io.ReadFull(br, pad); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBadCARHeader, err)
+		}
+		raw = append(raw, pad...)
+		before := len(raw)
+		roots, version, raw, err = readCARv1Header(br, raw)
+		if err != nil {
+			return nil, err
+		}
+		if version != 1 {
+			return nil, fmt.Errorf("%w: inner version %d", ErrBadCARHeader, version)
+		}
+		inner := uint64(len(raw) - before)
+		if dataSize < inner || dataSize-inner > 1<<62 {
+			return nil, fmt.Errorf("%w: bad data size", ErrBadCARHeader)
+		}
+		h.sections = int64(dataSize - inner)
+	default:
+		return nil, fmt.Errorf("%w: version %d", ErrBadCARHeader, version)
+	}
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("%w: no roots", ErrBadCARHeader)
+	}
+	h.roots, h.raw = roots, raw
+	return h, nil
+}
+
+func readCARv1Header(br *bufio.Reader, raw []byte) ([]string, uint64, []byte, error) {
+	peek, _ := br.Peek(binary.MaxVarintLen64)
+	size, n := binary.Uvarint(peek)
+	if n <= 0 || size == 0 || size > maxCARHeaderSize {
+		return nil, 0, nil, fmt.Errorf("%w: bad length", ErrBadCARHeader)
+	}
+	buf := make([]byte, n+int(size))
+	if _, err := io.ReadFull(br, buf); err != nil {
+		return nil, 0, nil, fmt.Errorf("%w: %v", ErrBadCARHeader, err)
+	}
+	roots, version, err := decodeCARHeader(buf[n:])
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	return roots, version, append(raw, buf...), nil
+}
+
+// заголовок это dag-cbor map {roots: [cid...], version: n}
+func decodeCARHeader(b []byte) ([]string, uint64, error) {
+	r := &cborReader{b: b}
+	major, pairs, err := r.head()
+	if err != nil || major != cborMap {
+		return nil, 0, fmt.Errorf("%w: expected map", ErrBadCARHeader)
+	}
+	var roots []string
+	var version uint64
+	for i := uint64(0); i < pairs; i++ {
+		major, klen, err := r.head()
+		if err != nil || major != cborText {
+			return nil, 0, fmt.Errorf("%w: expected text key", ErrBadCARHeader)
+		}
+		key, err := r.take(klen)
+		if err != nil {
+			return nil, 0, err
+		}
+		switch string(key) {
+		case "version":
+			major, v, err := r.head()
+			if err != nil || major != cborUint {
+				return nil, 0, fmt.Errorf("%w: bad version", ErrBadCARHeader)
+			}
+			version = v
+		case "roots":
+			major, count, err := r.head()
+			if err != nil || major != cborArray {
+				return nil, 0, fmt.Errorf("%w: bad roots", ErrBadCARHeader)
+			}
+			for j := uint64(0); j < count; j++ {
+				c, err := r.cidLink()
+				if err != nil {
+					return nil, 0, err
+				}
+				roots = append(roots, c)
+			}
+		default:
+			if err := r.skip(); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+	return roots, version, nil
+}
+
+const (
+	cborUint   = 0
+	cborNegInt = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+	cborSimple = 7
+
+	cborTagCID = 42
+)
+
+// минимальный cbor для заголовков, неопределенные длины dag-cbor запрещает
+type cborReader struct {
+	b   []byte
+	off int
+}
+
+func (r *cborReader) head() (byte, uint64, error) {
+	if r.off >= len(r.b) {
+		return 0, 0, ErrBadCARHeader
+	}
+	ib := r.b[r.off]
+	r.off++
+	major, info := ib>>5, ib&0x1f
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info <= 27:
+		n := 1 << (info - 24)
+		v, err := r.take(uint64(n))
+		if err != nil {
+			return 0, 0, err
+		}
+		var arg uint64
+		for _, c := range v {
+			arg = arg<<8 | uint64(c)
+		}
+		return major, arg, nil
+	}
+	return 0, 0, fmt.Errorf("%w: unsupported cbor encoding", ErrBadCARHeader)
+}
+
+func (r *cborReader) take(n uint64) ([]byte, error) {
+	if n > uint64(len(r.b)-r.off) {
+		return nil, fmt.Errorf("%w: truncated", ErrBadCARHeader)
+	}
+	v := r.b[r.off : r.off+int(n)]
+	r.off += int(n)
+	return v, nil
+}
+
+// ссылка на cid: тег 42 над байтами с ведущим нулем (multibase identity)
+func (r *cborReader) cidLink() (string, error) {
+	major, tag, err := r.head()
+	if err != nil || major != cborTag || tag != cborTagCID {
+		return "", fmt.Errorf("%w: expected cid link", ErrBadCARHeader)
+	}
+	major, n, err := r.head()
+	if err != nil || major != cborBytes {
+		return "", fmt.Errorf("%w: expected cid bytes", ErrBadCARHeader)
+	}
+	b, err := r.take(n)
+	if err != nil {
+		return "", err
+	}
+	if len(b) < 2 || b[0] != 0 {
+		return "", fmt.Errorf("%w: bad cid prefix", ErrBadCARHeader)
+	}
+	c, err := cid.Cast(b[1:])
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrBadCARHeader, err)
+	}
+	return c.String(), nil
+}
+
+func (r *cborReader) skip() error {
+	major, arg, err := r.head()
+	if err != nil {
+		return err
+	}
+	switch major {
+	case cborUint, cborNegInt, cborSimple:
+		return nil
+	case cborBytes, cborText:
+		_, err := r.take(arg)
+		return err
+	case cborArray:
+		for i := uint64(0); i < arg; i++ {
+			if err := r.skip(); err != nil {
+				return err
+			}
+		}
+	case cborMap:
+		for i := uint64(0); i < 2*arg; i++ {
+			if err := r.skip(); err != nil {
+				return err
+			}
+		}
+	case cborTag:
+		return r.skip()
+	}
+	return nil
+}
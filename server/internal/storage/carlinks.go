@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// вложенность cbor глубже этого в нормальных блоках не встречается
+const maxCBORDepth = 256
+
+// multihash identity: данные лежат в самом cid, блок для него не нужен
+const mhIdentity = 0x00
+
+// ссылки блока на другие блоки. по ним kubo собирает dag при закреплении,
+// поэтому блок с кодеком, ссылки которого не разобрать, не принимается
+func blockLinks(id cid.Cid, data []byte) ([]cid.Cid, error) {
+	switch id.Type() {
+	case cid.Raw:
+		return nil, nil
+	case cid.DagProtobuf:
+		return pbLinks(data)
+	case cid.DagCBOR:
+		var links []cid.Cid
+		r := &cborReader{b: data}
+		if err := r.links(0, &links); err != nil {
+			return nil, err
+		}
+		if r.off != len(data) {
+			return nil, errors.New("trailing bytes")
+		}
+		return links, nil
+	case cid.DagJSON:
+		var v any
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		var links []cid.Cid
+		return links, jsonLinks(v, &links)
+	}
+	return nil, fmt.Errorf("%w: %s uses codec 0x%x", ErrCARCodec, id, id.Type())
+}
+
+// dag-pb: PBNode.Links (поле 2), в каждой PBLink.Hash (поле 1)
+func pbLinks(data []byte) ([]cid.Cid, error) {
+	var links []cid.Cid
+	err := pbFields(data, func(num protowire.Number, v []byte) error {
+		if num != 2 {
+			return nil
+		}
+		return pbFields(v, func(num protowire.Number, v []byte) error {
+			if num != 1 {
+				return nil
+			}
+			c, err := cid.Cast(v)
+			if err != nil {
+				return err
+			}
+			links = append(links, c)
+			return nil
+		})
+	})
+	return links, err
+}
+
+// поля сообщения protobuf, fn получает только поля с длиной
+func pbFields(b []byte, fn func(protowire.Number, []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		if err := fn(num, v); err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// все теги 42 в значении, остальное пропускается
+func (r *cborReader) links(depth int, out *[]cid.Cid) error {
+	if depth > maxCBORDepth {
+		return errors.New("cbor nested too deep")
+	}
+	major, arg, err := r.head()
+	if err != nil {
+		return err
+	}
+	switch major {
+	case cborBytes, cborText:
+		_, err := r.take(arg)
+		return err
+	case cborArray, cborMap:
+		if major == cborMap {
+			arg *= 2
+		}
+		for i := uint64(0); i < arg; i++ {
+			if err := r.links(depth+1, out); err != nil {
+				return err
+			}
+		}
+	case cborTag:
+		if arg != cborTagCID {
+			return fmt.Errorf("unexpected cbor tag %d", arg)
+		}
+		major, n, err := r.head()
+		if err != nil || major != cborBytes {
+			return errors.New("expected cid bytes")
+		}
+		b, err := r.take(n)
+		if err != nil {
+			return err
+		}
+		if len(b) < 2 || b[0] != 0 {
+			return errors.New("bad cid prefix")
+		}
+		c, err := cid.Cast(b[1:])
+		if err != nil {
+			return err
+		}
+		*out = append(*out, c)
+	}
+	return nil
+}
+
+// dag-json: ссылка это объект с единственным ключом "/" и строкой cid
+func jsonLinks(v any, out *[]cid.Cid) error {
+	switch v := v.(type) {
+	case []any:
+		for _, e := range v {
+			if err := jsonLinks(e, out); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		if s, ok := v["/"].(string); ok && len(v) == 1 {
+			c, err := cid.Decode(s)
+			if err != nil {
+				return err
+			}
+			*out = append(*out, c)
+			return nil
+		}
+		for _, e := range v {
+			if err := jsonLinks(e, out); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
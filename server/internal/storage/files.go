@@ -0,0 +1,286 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrFileNotFound = errors.New("file not found")
+	ErrBadFileName  = errors.New("invalid file name")
+	ErrBadPageToken = errors.New("invalid page token")
+)
+
+const (
+	defaultFilesPage = 100
+	maxFilesPage     = 500
+	maxFileNameBytes = 255
+)
+
+type File struct {
+	ID        string
+	UserID    string
+	CID       string
+	Name      string
+	Mime      string
+	Size      int64
+	CreatedAt time.Time
+	// блоков в car по разбору при загрузке, 0 - неизвестно
+	Blocks int64
+	// в StatFile всегда, в ListFiles по FileFilter.WithPreviews
+	Previews []Preview
+	// номер текущей версии
+	Version int64
+}
+
+// фильтр списка файлов, нулевые поля не ограничивают
+type FileFilter struct {
+	MimePrefix    string
+	CreatedAfter  int64
+	CreatedBefore int64
+	// не фильтр: подгрузить превью файлов страницы
+	WithPreviews bool
+}
+
+// страница файлов пользователя, новые сначала. токен следующей страницы пустой на последней
+func (s *Service) ListFiles(ctx context.Context, userID string, f FileFilter, pageToken string, limit int) ([]File, string, error) {
+	if s.db == nil {
+		return nil, "", errors.New("storage database not configured")
+	}
+	if limit <= 0 {
+		limit = defaultFilesPage
+	}
+	if limit > maxFilesPage {
+		limit = maxFilesPage
+	}
+	q := `SELECT id, user_id, cid, COALESCE(name, ''), COALESCE(mime, ''), COALESCE(size_bytes, 0), created_at, (SELECT blocks FROM car_info WHERE car_info.cid = files.cid), (SELECT MAX(version) FROM file_versions WHERE file_id = files.id) FROM files WHERE user_id = ?`
+	args := []any{userID}
+	if f.MimePrefix != "" {
+		q += ` AND mime LIKE ? ESCAPE '\'`
+		args = append(args, likePrefix(f.MimePrefix))
+	}
+	if f.CreatedAfter > 0 {
+		q += ` AND created_at >= ?`
+		args = append(args, f.CreatedAfter)
+	}
+	if f.CreatedBefore > 0 {
+		q += ` AND created_at <= ?`
+		args = append(args, f.CreatedBefore)
+	}
+	if pageToken != "" {
+		at, id, err := decodePageToken(pageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		q += ` AND (created_at < ? OR (created_at = ? AND id < ?))`
+		args = append(args, at, at, id)
+	}
+	q += ` ORDER BY created_at DESC, id DESC LIMIT ?`
+	args = append(args, limit+1)
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("list files: %w", err)
+	}
+	defer rows.Close()
+	var files []File
+	for rows.Next() {
+		file, err := scanFile(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		files = append(files, *file)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	next := ""
+	if len(files) > limit {
+		files = files[:limit]
+		last := files[limit-1]
+		next = encodePageToken(last.CreatedAt.Unix(), last.ID)
+	}
+	if f.WithPreviews {
+		if err := s.attachPreviews(ctx, files); err != nil {
+			return nil, "", err
+		}
+	}
+	return files, next, nil
+}
+
+func (s *Service) StatFile(ctx context.Context, userID, fileID string) (*File, error) {
+	if s.db == nil {
+		return nil, errors.New("storage database not configured")
+	}
+	row := s.db.QueryRowContext(ctx, `SELECT id, user_id, cid, COALESCE(name, ''), COALESCE(mime, ''), COALESCE(size_bytes, 0), created_at, (SELECT blocks FROM car_info WHERE car_info.cid = files.cid), (SELECT MAX(version) FROM file_versions WHERE file_id = files.id) FROM files WHERE id = ? AND user_id = ?`, fileID, userID)
+	f, err := scanFile(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	byFile, err := s.previews(ctx, []string{f.ID})
+	if err != nil {
+		return nil, err
+	}
+	f.Previews = byFile[f.ID]
+	return f, nil
+}
+
+func (s *Service) RenameFile(ctx context.Context, userID, fileID, name string) (*File, error) {
+	if s.db == nil {
+		return nil, errors.New("storage database not configured")
+	}
+	if name == "" || len(name) > maxFileNameBytes || strings.ContainsAny(name, "/\x00") {
+		return nil, ErrBadFileName
+	}
+	r, err := s.db.ExecContext(ctx, `UPDATE files SET name = ? WHERE id = ? AND user_id = ?`, name, fileID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("rename file: %w", err)
+	}
+	if n, _ := r.RowsAffected(); n == 0 {
+		return nil, ErrFileNotFound
+	}
+	return s.StatFile(ctx, userID, fileID)
+}
+
+// удалить файл пользователя вместе с превью и версиями. содержимое открепляется,
+// только когда на cid не осталось файлов, версий и превью
+func (s *Service) DeleteFile(ctx context.Context, userID, fileID string) error {
+	if s.db == nil {
+		return errors.New("storage database not configured")
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var cid string
+	if err := tx.QueryRowContext(ctx, `SELECT cid FROM files WHERE id = ? AND user_id = ?`, fileID, userID).Scan(&cid); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrFileNotFound
+		}
+		return fmt.Errorf("delete file: %w", err)
+	}
+	cids := []string{cid}
+	rows, err := tx.QueryContext(ctx, `SELECT cid FROM file_previews WHERE file_id = ? UNION SELECT cid FROM file_versions WHERE file_id = ?`, fileID, fileID)
+	if err != nil {
+		return fmt.Errorf("delete file: %w", err)
+	}
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			rows.Close()
+			return fmt.Errorf("delete file: %w", err)
+		}
+		cids = append(cids, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("delete file: %w", err)
+	}
+	// счетчики в contents уменьшают триггеры, превью и версии уходят каскадом
+	if _, err := tx.ExecContext(ctx, `DELETE FROM files WHERE id = ?`, fileID); err != nil {
+		return fmt.Errorf("delete file: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("delete file: %w", err)
+	}
+	if err := s.ReleaseUnreferenced(ctx, cids...); err != nil {
+		slog.Warn("storage: release unreferenced content", "error", err)
+	}
+	return nil
+}
+
+// сколько отметка загрузки защищает cid от освобождения и через сколько брошенное
+// освобождение может подхватить другой вызов
+const contentClaimTTL = time.Hour
+
+// освободить cid без ссылок. заявка ставится одной командой с проверкой refcount, и
+// только после нее car уходит из хранилища: cid с файлами, недавно принятый загрузкой
+// или уже освобождаемый не трогается, тогда released false. если удалить не вышло,
+// заявка снимается, и cid подбирает следующая попытка или сверка пинов
+func (s *Service) release(ctx context.Context, cid string) (bool, error) {
+	now := time.Now()
+	at, cutoff := now.UnixNano(), now.Add(-contentClaimTTL).UnixNano()
+	res, err := s.db.ExecContext(ctx, `INSERT INTO content_claims (cid, claimed_at, releasing_at)
+		SELECT cid, 0, ? FROM contents WHERE cid = ? AND refcount <= 0
+		ON CONFLICT(cid) DO UPDATE SET releasing_at = excluded.releasing_at WHERE claimed_at <= ? AND releasing_at <= ?`,
+		at, cid, cutoff, cutoff)
+	if err != nil {
+		return false, fmt.Errorf("claim release: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if err := s.PinRm(ctx, cid); err != nil {
+		if _, err := s.db.ExecContext(ctx, `UPDATE content_claims SET releasing_at = 0 WHERE cid = ? AND releasing_at = ?`, cid, at); err != nil {
+			slog.Warn("storage: drop release claim", "cid", cid, "error", err)
+		}
+		return false, err
+	}
+	if s.outboards != nil {
+		if err := s.outboards.Remove(ctx, cid); err != nil {
+			slog.Warn("storage: remove outboard", "cid", cid, "error", err)
+		}
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM contents WHERE cid = ? AND refcount <= 0`, cid); err != nil {
+		slog.Warn("storage: delete content", "cid", cid, "error", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM content_claims WHERE cid = ? AND releasing_at = ?`, cid, at); err != nil {
+		slog.Warn("storage: drop release claim", "cid", cid, "error", err)
+	}
+	if s.blockIdx != nil {
+		s.blockIdx.Kick()
+	}
+	return true, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanFile(r rowScanner) (*File, error) {
+	var f File
+	var created int64
+	var blocks, version sql.NullInt64
+	if err := r.Scan(&f.ID, &f.UserID, &f.CID, &f.Name, &f.Mime, &f.Size, &created, &blocks, &version); err != nil {
+		return nil, err
+	}
+	f.Blocks, f.Version = blocks.Int64, version.Int64
+	f.CreatedAt = time.Unix(created, 0)
+	return &f, nil
+}
+
+func likePrefix(p string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(p) + "%"
+}
+
+// токен страницы: created_at и id последнего файла
+func encodePageToken(createdAt int64, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(createdAt, 10) + ":" + id))
+}
+
+func decodePageToken(tok string) (int64, string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(tok)
+	if err != nil {
+		return 0, "", ErrBadPageToken
+	}
+	at, id, ok := strings.Cut(string(b), ":")
+	if !ok || id == "" {
+		return 0, "", ErrBadPageToken
+	}
+	n, err := strconv.ParseInt(at, 10, 64)
+	if err != nil {
+		return 0, "", ErrBadPageToken
+	}
+	return n, id, nil
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"io"
+
+	"dev.c0rex64.heroin/internal/ipfs"
+)
+
+// один узел kubo. с pin=false загруженное не закрепляется и уйдет при gc ipfs
+type KuboStore struct {
+	client   *ipfs.Client
+	streamer *CARStreamer
+	pin      bool
+}
+
+func NewKuboStore(client *ipfs.Client, streamer *CARStreamer, pin bool) *KuboStore {
+	return &KuboStore{client: client, streamer: streamer, pin: pin}
+}
+
+// корни закрепляются тем же dag/import
+func (k *KuboStore) Put(ctx context.Context, car io.Reader) (string, error) {
+	return k.streamer.ImportCAR(ctx, car, k.pin)
+}
+
+func (k *KuboStore) Get(ctx context.Context, cid string) (io.ReadCloser, error) {
+	return k.streamer.Export(ctx, cid)
+}
+
+func (k *KuboStore) Stat(ctx context.Context, cid string) (BlobInfo, error) {
+	size, _, err := k.client.DagStat(ctx, cid)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	return BlobInfo{CID: cid, Size: size}, nil
+}
+
+func (k *KuboStore) Delete(ctx context.Context, cid string) error {
+	return k.client.PinRm(ctx, cid)
+}
+
+func (k *KuboStore) List(ctx context.Context) ([]string, error) {
+	return k.client.PinLs(ctx)
+}
+
+func (k *KuboStore) Pin(ctx context.Context, cid string) error {
+	return k.client.PinAdd(ctx, cid)
+}
+
+func (k *KuboStore) BlockCIDs(ctx context.Context, cid string) ([]string, error) {
+	refs, err := k.client.Refs(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{cid}, refs...), nil
+}
+
+func (k *KuboStore) Block(ctx context.Context, cid string) ([]byte, error) {
+	return k.client.BlockGet(ctx, cid)
+}
+
+func (k *KuboStore) DeleteBlocks(ctx context.Context, cids []string) (int, error) {
+	return k.client.BlockRm(ctx, cids)
+}
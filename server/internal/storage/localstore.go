@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// car файлы в каталоге по cid корня, для разработки и тестов без kubo.
+// блоки проверяются при приеме, файл появляется под своим именем только целиком
+type LocalStore struct {
+	dir string
+	tmp string
+}
+
+func NewLocalStore(dir string) (*LocalStore, error) {
+	tmp := filepath.Join(dir, "tmp")
+	if err := os.MkdirAll(tmp, 0o700); err != nil {
+		return nil, fmt.Errorf("create blob dir: %w", err)
+	}
+	// недописанное при прошлом запуске
+	old, _ := filepath.Glob(filepath.Join(tmp, "put-*.car"))
+	for _, p := range old {
+		os.Remove(p)
+	}
+	return &LocalStore{dir: dir, tmp: tmp}, nil
+}
+
+// два последних символа cid как подкаталог: начало у всех cid одинаковое
+func (l *LocalStore) path(key string) string {
+	return filepath.Join(l.dir, key[len(key)-2:], key+".car")
+}
+
+func (l *LocalStore) Put(ctx context.Context, car io.Reader) (string, error) {
+	sp, err := spoolCAR(l.tmp, car)
+	if err != nil {
+		return "", err
+	}
+	defer sp.Close()
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	p := l.path(sp.root)
+	if intactBlob(p, sp) {
+		return sp.root, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return "", err
+	}
+	if err := sp.file.Sync(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(sp.file.Name(), p); err != nil {
+		return "", fmt.Errorf("store blob: %w", err)
+	}
+	return sp.root, nil
+}
+
+// файл под именем cid оставляется, только если он целый: совпадает с принятым
+// или сам проходит проверку car с тем же корнем. обрезанный или битый после
+// сбоя файл заменяется новым, иначе все следующие загрузки "удавались" бы поверх него
+func intactBlob(p string, sp *spooledCAR) bool {
+	f, err := os.Open(p)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	if fi, err := f.Stat(); err == nil && fi.Size() == sp.size {
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err == nil && bytes.Equal(h.Sum(nil), sp.sha256) {
+			return true
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return false
+		}
+	}
+	root, err := checkCAR(f)
+	return err == nil && root == sp.root
+}
+
+func (l *LocalStore) Get(ctx context.Context, cid string) (io.ReadCloser, error) {
+	key, err := blobKey(cid)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(l.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrBlobNotFound, cid)
+	}
+	return f, err
+}
+
+func (l *LocalStore) Stat(ctx context.Context, cid string) (BlobInfo, error) {
+	key, err := blobKey(cid)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	fi, err := os.Stat(l.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return BlobInfo{}, fmt.Errorf("%w: %s", ErrBlobNotFound, cid)
+	}
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	return BlobInfo{CID: cid, Size: fi.Size()}, nil
+}
+
+func (l *LocalStore) Delete(ctx context.Context, cid string) error {
+	key, err := blobKey(cid)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(l.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (l *LocalStore) List(ctx context.Context) ([]string, error) {
+	var out []string
+	err := filepath.WalkDir(l.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p == l.tmp {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if name, ok := strings.CutSuffix(d.Name(), ".car"); ok {
+			out = append(out, name)
+		}
+		return ctx.Err()
+	})
+	return out, err
+}
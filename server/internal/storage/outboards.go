@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"dev.c0rex64.heroin/pkg/verify"
+)
+
+var ErrOutboardNotReady = errors.New("outboard not ready")
+
+// outboard открывается на чтение по смещениям, см. verify.Proof
+type OutboardReader interface {
+	io.ReaderAt
+	io.Closer
+}
+
+// bao outboard над экспортом kubo. строится в фоне после загрузки: клиент получает
+// ровно байты dag/export, а они могут отличаться от загруженного car порядком блоков
+type Outboards struct {
+	svc   *Service
+	db    *sql.DB
+	dir   string
+	queue chan string
+}
+
+func NewOutboards(svc *Service, db *sql.DB, dir string) (*Outboards, error) {
+	if svc.blobs == nil {
+		return nil, errors.New("blob store not configured")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create outboard dir: %w", err)
+	}
+	return &Outboards{svc: svc, db: db, dir: dir, queue: make(chan string, 256)}, nil
+}
+
+func (o *Outboards) path(cid string) string {
+	return filepath.Join(o.dir, cid+".obao")
+}
+
+// поставить cid в очередь на построение, при переполненной очереди outboard
+// построится по первому запросу
+func (o *Outboards) Schedule(cid string) {
+	select {
+	case o.queue <- cid:
+	default:
+	}
+}
+
+// обработчик очереди, по одному cid за раз, чтобы не грузить kubo экспортами
+func (o *Outboards) Start(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case cid := <-o.queue:
+				if err := o.Build(ctx, cid); err != nil && ctx.Err() == nil {
+					slog.Warn("outboards: build", "cid", cid, "error", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// открыть outboard и корень. если его еще нет, построение ставится в очередь
+func (o *Outboards) Open(ctx context.Context, cid string) (OutboardReader, [32]byte, error) {
+	var root [32]byte
+	var sum []byte
+	err := o.db.QueryRowContext(ctx, `SELECT blake3 FROM outboards WHERE cid = ?`, cid).Scan(&sum)
+	if errors.Is(err, sql.ErrNoRows) {
+		o.Schedule(cid)
+		return nil, root, ErrOutboardNotReady
+	}
+	if err != nil {
+		return nil, root, fmt.Errorf("load outboard: %w", err)
+	}
+	copy(root[:], sum)
+	f, err := os.Open(o.path(cid))
+	if errors.Is(err, os.ErrNotExist) {
+		// файл потерян, строка устарела
+		o.db.ExecContext(ctx, `DELETE FROM outboards WHERE cid = ?`, cid)
+		o.Schedule(cid)
+		return nil, root, ErrOutboardNotReady
+	}
+	if err != nil {
+		return nil, root, fmt.Errorf("open outboard: %w", err)
+	}
+	return f, root, nil
+}
+
+// построить outboard за два прохода экспорта: bao нужен размер заранее, а kubo его не сообщает
+func (o *Outboards) Build(ctx context.Context, cid string) error {
+	var exists int
+	if err := o.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM outboards WHERE cid = ?`, cid).Scan(&exists); err != nil {
+		return err
+	}
+	if exists > 0 {
+		if _, err := os.Stat(o.path(cid)); err == nil {
+			return nil
+		}
+	}
+	r := o.export(ctx, cid)
+	size, err := io.Copy(io.Discard, r)
+	r.Close()
+	if err != nil {
+		return fmt.Errorf("measure export: %w", err)
+	}
+	tmp, err := os.CreateTemp(o.dir, "*.obao.tmp")
+	if err != nil {
+		return fmt.Errorf("create outboard: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	r = o.export(ctx, cid)
+	root, err := verify.Encode(tmp, r, size)
+	if err == nil {
+		// экспорт между проходами поменяться не должен, но лишние байты проверим
+		var b [1]byte
+		if n, _ := r.Read(b[:]); n > 0 {
+			err = errors.New("export changed between passes")
+		}
+	}
+	r.Close()
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("encode outboard: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), o.path(cid)); err != nil {
+		return fmt.Errorf("store outboard: %w", err)
+	}
+	_, err = o.db.ExecContext(ctx, `INSERT OR REPLACE INTO outboards (cid, blake3, size_bytes, created_at) VALUES (?, ?, ?, ?)`,
+		cid, root[:], size, time.Now().Unix())
+	return err
+}
+
+// удалить outboard, например когда cid откреплен
+func (o *Outboards) Remove(ctx context.Context, cid string) error {
+	if _, err := o.db.ExecContext(ctx, `DELETE FROM outboards WHERE cid = ?`, cid); err != nil {
+		return err
+	}
+	if err := os.Remove(o.path(cid)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// экспорт car как io.Reader, Close обрывает запрос к kubo
+func (o *Outboards) export(ctx context.Context, cid string) io.ReadCloser {
+	ctx, cancel := context.WithCancel(ctx)
+	chunks, errs := o.svc.StreamCAR(ctx, cid)
+	pr, pw := io.Pipe()
+	go func() {
+		var werr error
+		for ch := range chunks {
+			if werr == nil {
+				_, werr = pw.Write(ch)
+			}
+		}
+		pw.CloseWithError(<-errs)
+	}()
+	return &exportReader{PipeReader: pr, cancel: cancel}
+}
+
+type exportReader struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+func (r *exportReader) Close() error {
+	r.cancel()
+	return r.PipeReader.Close()
+}
@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+var (
+	ErrBadPreview      = errors.New("invalid preview")
+	ErrPreviewNotFound = errors.New("preview not found")
+)
+
+// превью строит клиент, сервер только хранит: маленький car со своим ключом
+const (
+	maxPreviewBytes      = 2 << 20
+	maxPreviewKeyBytes   = 1024
+	maxPreviewDimension  = 1 << 16
+	maxPreviewMimeLength = 255
+)
+
+const (
+	PreviewThumbnail = "thumbnail"
+	PreviewLowRes    = "preview"
+	PreviewPoster    = "poster"
+)
+
+// производный от файла блоб: миниатюра, превью низкого разрешения или постер видео.
+// WrappedKey завернут клиентом так же, как ключ самого файла, сервер его не читает
+type Preview struct {
+	FileID     string
+	Kind       string
+	CID        string
+	Mime       string
+	Size       int64
+	Width      int
+	Height     int
+	WrappedKey []byte
+	CreatedAt  time.Time
+}
+
+func validPreviewKind(kind string) bool {
+	switch kind {
+	case PreviewThumbnail, PreviewLowRes, PreviewPoster:
+		return true
+	}
+	return false
+}
+
+// загрузить превью файла пользователя. превью того же вида заменяется, его содержимое
+// отпускается, если на него больше никто не ссылается
+func (s *Service) PutPreview(ctx context.Context, userID string, p Preview, car io.Reader) (*Preview, error) {
+	if s.db == nil {
+		return nil, errors.New("storage database not configured")
+	}
+	if !validPreviewKind(p.Kind) || p.Mime == "" || len(p.Mime) > maxPreviewMimeLength ||
+		len(p.WrappedKey) == 0 || len(p.WrappedKey) > maxPreviewKeyBytes ||
+		p.Width < 0 || p.Height < 0 || p.Width > maxPreviewDimension || p.Height > maxPreviewDimension {
+		return nil, ErrBadPreview
+	}
+	if err := s.ownsFile(ctx, userID, p.FileID); err != nil {
+		return nil, err
+	}
+	imp, err := s.importCAR(ctx, userID, "", p.Size, NewCARReader(io.NopCloser(car), maxPreviewBytes), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer imp.release(ctx)
+	p.CID, p.Size = imp.cid, imp.received
+	p.CreatedAt = time.Unix(time.Now().Unix(), 0)
+	old, err := s.replacePreview(ctx, &p)
+	if err != nil {
+		s.abandon(ctx, imp)
+		return nil, err
+	}
+	s.recordCAR(ctx, imp, p.CreatedAt.Unix())
+	if old != "" && old != p.CID {
+		if err := s.ReleaseUnreferenced(ctx, old); err != nil {
+			slog.Warn("storage: release unreferenced content", "error", err)
+		}
+	}
+	return &p, nil
+}
+
+// записать превью вместо прежнего того же вида, вернуть cid прежнего. замена идет
+// удалением и вставкой: REPLACE не вызывает триггер удаления, и счетчик contents разошелся бы
+func (s *Service) replacePreview(ctx context.Context, p *Preview) (string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	var old string
+	err = tx.QueryRowContext(ctx, `SELECT cid FROM file_previews WHERE file_id = ? AND kind = ?`, p.FileID, p.Kind).Scan(&old)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("store preview: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM file_previews WHERE file_id = ? AND kind = ?`, p.FileID, p.Kind); err != nil {
+		return "", fmt.Errorf("store preview: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO file_previews (file_id, kind, cid, mime, size_bytes, width, height, wrapped_key, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.FileID, p.Kind, p.CID, p.Mime, p.Size, p.Width, p.Height, p.WrappedKey, p.CreatedAt.Unix())
+	if err != nil {
+		return "", fmt.Errorf("store preview: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("store preview: %w", err)
+	}
+	return old, nil
+}
+
+func (s *Service) DeletePreview(ctx context.Context, userID, fileID, kind string) error {
+	if s.db == nil {
+		return errors.New("storage database not configured")
+	}
+	if err := s.ownsFile(ctx, userID, fileID); err != nil {
+		return err
+	}
+	var cid string
+	err := s.db.QueryRowContext(ctx, `DELETE FROM file_previews WHERE file_id = ? AND kind = ? RETURNING cid`, fileID, kind).Scan(&cid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrPreviewNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("delete preview: %w", err)
+	}
+	if err := s.ReleaseUnreferenced(ctx, cid); err != nil {
+		slog.Warn("storage: release unreferenced content", "error", err)
+	}
+	return nil
+}
+
+func (s *Service) ownsFile(ctx context.Context, userID, fileID string) error {
+	var n int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM files WHERE id = ? AND user_id = ?`, fileID, userID).Scan(&n); err != nil {
+		return fmt.Errorf("check file: %w", err)
+	}
+	if n == 0 {
+		return ErrFileNotFound
+	}
+	return nil
+}
+
+// превью файлов одним запросом, по file_id
+func (s *Service) previews(ctx context.Context, fileIDs []string) (map[string][]Preview, error) {
+	out := map[string][]Preview{}
+	if len(fileIDs) == 0 {
+		return out, nil
+	}
+	args := make([]any, len(fileIDs))
+	for i, id := range fileIDs {
+		args[i] = id
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT file_id, kind, cid, mime, size_bytes, width, height, wrapped_key, created_at FROM file_previews
+		WHERE file_id IN (?`+strings.Repeat(", ?", len(fileIDs)-1)+`) ORDER BY file_id, kind`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list previews: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p Preview
+		var created int64
+		if err := rows.Scan(&p.FileID, &p.Kind, &p.CID, &p.Mime, &p.Size, &p.Width, &p.Height, &p.WrappedKey, &created); err != nil {
+			return nil, err
+		}
+		p.CreatedAt = time.Unix(created, 0)
+		out[p.FileID] = append(out[p.FileID], p)
+	}
+	return out, rows.Err()
+}
+
+func (s *Service) attachPreviews(ctx context.Context, files []File) error {
+	ids := make([]string, len(files))
+	for i := range files {
+		ids[i] = files[i].ID
+	}
+	byFile, err := s.previews(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range files {
+		files[i].Previews = byFile[files[i].ID]
+	}
+	return nil
+}
+
+// отпустить содержимое, на которое не осталось ни файлов, ни превью, ни версий. cid,
+// который недавно приняла загрузка, пропускается и достается сверке пинов
+func (s *Service) ReleaseUnreferenced(ctx context.Context, cids ...string) error {
+	var errs []error
+	for _, cid := range cids {
+		if _, err := s.release(ctx, cid); err != nil {
+			errs = append(errs, fmt.Errorf("release %s: %w", cid, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,329 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnknownPlan  = errors.New("unknown quota plan")
+	ErrSizeMismatch = errors.New("received bytes differ from declared size")
+)
+
+type QuotaSubject string
+
+const (
+	QuotaUser  QuotaSubject = "user"
+	QuotaGroup QuotaSubject = "group"
+)
+
+// загрузка не помещается в квоту. Requested 0, если размер заранее не известен
+type QuotaError struct {
+	Limit     int64
+	Used      int64
+	Requested int64
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("storage quota exceeded: %d of %d bytes used, %d requested", e.Used, e.Limit, e.Requested)
+}
+
+// использование квоты пользователем. LimitBytes 0 значит без лимита,
+// ReservedBytes - объявленные размеры незавершенных возобновляемых загрузок и импорты,
+// которые еще идут
+type Usage struct {
+	Plan          string
+	LimitBytes    int64
+	UsedBytes     int64
+	ReservedBytes int64
+	Files         int64
+	Contents      int64
+}
+
+// сводка по тарифу для метрик
+type PlanUsage struct {
+	Plan      string
+	Users     int
+	UsedBytes int64
+}
+
+// квоты считаются по реально принятым байтам car. одинаковое содержимое (один cid)
+// оплачивается пользователем один раз, сколько бы файлов на него ни ссылалось,
+// и не зависит от того, загрузил ли его кто-то еще
+type Quotas struct {
+	db          *sql.DB
+	plans       map[string]int64
+	defaultPlan string
+}
+
+// plans - лимиты в байтах по именам тарифов, 0 снимает лимит. пустой defaultPlan
+// оставляет пользователей без назначенного тарифа без лимита. резервы прошлого
+// запуска снимаются: импорты, под которые они взяты, оборвались вместе с ним
+func NewQuotas(db *sql.DB, plans map[string]int64, defaultPlan string) (*Quotas, error) {
+	for name, limit := range plans {
+		if limit < 0 {
+			return nil, fmt.Errorf("quota plan %q: negative limit", name)
+		}
+	}
+	if _, ok := plans[defaultPlan]; defaultPlan != "" && !ok {
+		return nil, fmt.Errorf("default quota plan %q: %w", defaultPlan, ErrUnknownPlan)
+	}
+	if _, err := db.Exec(`DELETE FROM quota_reservations`); err != nil {
+		return nil, fmt.Errorf("drop stale quota reservations: %w", err)
+	}
+	return &Quotas{db: db, plans: plans, defaultPlan: defaultPlan}, nil
+}
+
+// *sql.DB или *sql.Tx
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// назначить тариф пользователю или группе, пустой plan снимает назначение. тариф
+// группы не делится между участниками: каждый получает его лимит на себя
+func (q *Quotas) Assign(ctx context.Context, kind QuotaSubject, subjectID, plan string) error {
+	if kind != QuotaUser && kind != QuotaGroup {
+		return fmt.Errorf("quota subject %q: invalid kind", kind)
+	}
+	if plan == "" {
+		_, err := q.db.ExecContext(ctx, `DELETE FROM quota_assignments WHERE subject_kind = ? AND subject_id = ?`, string(kind), subjectID)
+		return err
+	}
+	if _, ok := q.plans[plan]; !ok {
+		return ErrUnknownPlan
+	}
+	_, err := q.db.ExecContext(ctx, `INSERT INTO quota_assignments (subject_kind, subject_id, plan, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(subject_kind, subject_id) DO UPDATE SET plan = excluded.plan, updated_at = excluded.updated_at`,
+		string(kind), subjectID, plan, time.Now().Unix())
+	return err
+}
+
+// действующий тариф: свой, иначе наибольший из тарифов групп, иначе по умолчанию.
+// лимит всегда считается по использованию одного пользователя. назначения на
+// тарифы, убранные из конфига, пропускаются
+func (q *Quotas) plan(ctx context.Context, db querier, userID string) (string, int64, error) {
+	rows, err := db.QueryContext(ctx, `SELECT subject_kind, plan FROM quota_assignments WHERE (subject_kind = 'user' AND subject_id = ?)
+		OR (subject_kind = 'group' AND subject_id IN (SELECT group_id FROM group_members WHERE user_id = ?))`, userID, userID)
+	if err != nil {
+		return "", 0, fmt.Errorf("load quota plan: %w", err)
+	}
+	defer rows.Close()
+	var own, group string
+	for rows.Next() {
+		var kind, plan string
+		if err := rows.Scan(&kind, &plan); err != nil {
+			return "", 0, err
+		}
+		if _, ok := q.plans[plan]; !ok {
+			continue
+		}
+		if kind == string(QuotaUser) {
+			own = plan
+		} else if group == "" || q.larger(plan, group) {
+			group = plan
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", 0, err
+	}
+	name := q.defaultPlan
+	if own != "" {
+		name = own
+	} else if group != "" {
+		name = group
+	}
+	return name, q.plans[name], nil
+}
+
+// лимит 0 означает безлимит, он больше любого
+func (q *Quotas) larger(a, b string) bool {
+	la, lb := q.plans[a], q.plans[b]
+	if la == 0 || lb == 0 {
+		return la == 0 && lb != 0
+	}
+	return la > lb
+}
+
+// содержимое пользователя для квоты: файлы, их превью и старые версии. файлами
+// считаются только строки files
+const userContent = `SELECT user_id, cid, COALESCE(size_bytes, 0) AS size_bytes, 1 AS is_file FROM files
+	UNION ALL SELECT f.user_id, p.cid, p.size_bytes, 0 FROM file_previews p JOIN files f ON f.id = p.file_id
+	UNION ALL SELECT f.user_id, v.cid, v.size_bytes, 0 FROM file_versions v JOIN files f ON f.id = v.file_id`
+
+func (q *Quotas) Usage(ctx context.Context, userID string) (*Usage, error) {
+	return q.usage(ctx, q.db, userID, "")
+}
+
+// exceptUpload исключает из резерва загрузку, которая сейчас фиксируется
+func (q *Quotas) usage(ctx context.Context, db querier, userID, exceptUpload string) (*Usage, error) {
+	plan, limit, err := q.plan(ctx, db, userID)
+	if err != nil {
+		return nil, err
+	}
+	u := &Usage{Plan: plan, LimitBytes: limit}
+	err = db.QueryRowContext(ctx, `SELECT COALESCE(SUM(size_bytes), 0), COUNT(*), COALESCE(SUM(files), 0)
+		FROM (SELECT MAX(size_bytes) AS size_bytes, SUM(is_file) AS files FROM (`+userContent+`) WHERE user_id = ? GROUP BY cid)`, userID).
+		Scan(&u.UsedBytes, &u.Contents, &u.Files)
+	if err != nil {
+		return nil, fmt.Errorf("compute usage: %w", err)
+	}
+	err = db.QueryRowContext(ctx, `SELECT
+		(SELECT COALESCE(SUM(size_bytes), 0) FROM uploads WHERE user_id = ? AND id <> ? AND expires_at > ?
+			AND id NOT IN (SELECT upload_id FROM upload_commits WHERE state = 'committed')) +
+		(SELECT COALESCE(SUM(size_bytes), 0) FROM quota_reservations WHERE user_id = ?)`,
+		userID, exceptUpload, time.Now().Unix(), userID).Scan(&u.ReservedBytes)
+	if err != nil {
+		return nil, fmt.Errorf("compute reserved: %w", err)
+	}
+	return u, nil
+}
+
+// проверить квоту в транзакции, которая уже записала резерв size байт. транзакция
+// должна начинаться с этой записи: sqlite держит блокировку записи до ее конца, и
+// параллельные проверки того же пользователя не делят одно и то же свободное место.
+// возвращает, сколько байт можно принять, -1 без лимита, и использование без этого
+// резерва. size 0 - размер не известен
+func (q *Quotas) admit(ctx context.Context, tx *sql.Tx, userID, exceptUpload string, size int64) (int64, *Usage, error) {
+	u, err := q.usage(ctx, tx, userID, exceptUpload)
+	if err != nil {
+		return 0, nil, err
+	}
+	u.ReservedBytes -= size
+	if u.LimitBytes == 0 {
+		return -1, u, nil
+	}
+	used := u.UsedBytes + u.ReservedBytes
+	left := u.LimitBytes - used
+	if left <= 0 || size > left {
+		return 0, nil, &QuotaError{Limit: u.LimitBytes, Used: used, Requested: size}
+	}
+	return left, u, nil
+}
+
+// резерв квоты под импорт, который еще идет. limit, used и left - как их увидела
+// проверка при резерве, left -1 без лимита
+type reservation struct {
+	q     *Quotas
+	id    string
+	limit int64
+	used  int64
+	left  int64
+}
+
+// зарезервировать size байт под импорт, при неизвестном размере - весь остаток.
+// не помещается - *QuotaError
+func (q *Quotas) reserve(ctx context.Context, userID, exceptUpload string, size int64) (*reservation, error) {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	r := &reservation{q: q, id: uuid.NewString()}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO quota_reservations (id, user_id, size_bytes, created_at) VALUES (?, ?, ?, ?)`,
+		r.id, userID, size, time.Now().Unix()); err != nil {
+		return nil, fmt.Errorf("reserve quota: %w", err)
+	}
+	left, u, err := q.admit(ctx, tx, userID, exceptUpload, size)
+	if err != nil {
+		return nil, err
+	}
+	r.limit, r.used, r.left = u.LimitBytes, u.UsedBytes+u.ReservedBytes, left
+	if size == 0 && left > 0 {
+		if _, err := tx.ExecContext(ctx, `UPDATE quota_reservations SET size_bytes = ? WHERE id = ?`, left, r.id); err != nil {
+			return nil, fmt.Errorf("reserve quota: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("reserve quota: %w", err)
+	}
+	return r, nil
+}
+
+// поток импорта под резервом: чтение сверх остатка квоты обрывается *QuotaError,
+// requested - объявленный размер, 0 если не известен. без лимита поток идет как есть
+func (r *reservation) stream(src io.Reader, requested int64) io.Reader {
+	if r == nil || r.left < 0 {
+		return src
+	}
+	return &quotaReader{r: r, cr: NewCARReader(io.NopCloser(src), r.left), requested: requested}
+}
+
+type quotaReader struct {
+	r         *reservation
+	cr        *CARReader
+	requested int64
+}
+
+func (q *quotaReader) Read(p []byte) (int, error) {
+	n, err := q.cr.Read(p)
+	var le *LimitError
+	if errors.As(err, &le) {
+		err = &QuotaError{Limit: q.r.limit, Used: q.r.used, Requested: max(q.requested, q.cr.BytesRead()+1)}
+	}
+	return n, err
+}
+
+// снять резерв. вызывается после записи строк, которые учитывают те же байты,
+// так что на время между ними они считаются дважды, а не ни разу
+func (r *reservation) release(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	if _, err := r.q.db.ExecContext(context.WithoutCancel(ctx), `DELETE FROM quota_reservations WHERE id = ?`, r.id); err != nil {
+		slog.Warn("storage: release quota reservation", "error", err)
+	}
+}
+
+// использование по тарифам и физический объем уникального содержимого
+func (q *Quotas) Report(ctx context.Context) ([]PlanUsage, int64, error) {
+	rows, err := q.db.QueryContext(ctx, `SELECT user_id, SUM(size_bytes) FROM
+		(SELECT user_id, MAX(size_bytes) AS size_bytes FROM (`+userContent+`) GROUP BY user_id, cid) GROUP BY user_id`)
+	if err != nil {
+		return nil, 0, fmt.Errorf("usage report: %w", err)
+	}
+	used := map[string]int64{}
+	for rows.Next() {
+		var id string
+		var n int64
+		if err := rows.Scan(&id, &n); err != nil {
+			rows.Close()
+			return nil, 0, err
+		}
+		used[id] = n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	byPlan := map[string]*PlanUsage{}
+	for id, n := range used {
+		plan, _, err := q.plan(ctx, q.db, id)
+		if err != nil {
+			return nil, 0, err
+		}
+		p := byPlan[plan]
+		if p == nil {
+			p = &PlanUsage{Plan: plan}
+			byPlan[plan] = p
+		}
+		p.Users++
+		p.UsedBytes += n
+	}
+	out := make([]PlanUsage, 0, len(byPlan))
+	for _, p := range byPlan {
+		out = append(out, *p)
+	}
+	var stored int64
+	if err := q.db.QueryRowContext(ctx, `SELECT COALESCE(SUM(size_bytes), 0) FROM contents WHERE refcount > 0`).Scan(&stored); err != nil {
+		return nil, 0, fmt.Errorf("usage report: %w", err)
+	}
+	return out, stored, nil
+}
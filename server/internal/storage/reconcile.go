@@ -0,0 +1,309 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+)
+
+const (
+	DriftMissing = "missing"
+	DriftOrphan  = "orphan"
+)
+
+// сколько сирот открепляется за один проход: если files и kubo разошлись массово
+// (не та база, не тот узел), лучше заметить это по метрикам, чем потерять все пины разом
+const maxUnpinPerRun = 1000
+
+// повторный пин может тянуть данные из сети, ограничиваем каждый отдельно
+const repinTimeout = 5 * time.Minute
+
+var ErrReconcileRunning = errors.New("pin reconciliation already running")
+
+// итог прохода сверки. Missing и Orphaned - найденные расхождения до исправления
+type PinReport struct {
+	RunAt      time.Time
+	Duration   time.Duration
+	Pinned     int
+	Referenced int
+	Missing    int
+	Orphaned   int
+	Repinned   int
+	Unpinned   int
+	Err        string
+}
+
+type PinDrift struct {
+	CID       string
+	Kind      string
+	FirstSeen time.Time
+	LastSeen  time.Time
+	Attempts  int
+	LastError string
+}
+
+// сверка рекурсивных пинов kubo с cid из files: недостающие пины возвращаются сразу,
+// пины содержимого, которое записал сервер и на которое больше не ссылаются, снимаются
+// только после грейс периода тем же освобождением, что и при удалении файла
+type Reconciler struct {
+	svc      *Service
+	db       *sql.DB
+	grace    time.Duration
+	running  sync.Mutex
+	mu       sync.Mutex
+	last     PinReport
+	onReport func(PinReport)
+}
+
+func NewReconciler(svc *Service, db *sql.DB, grace time.Duration) *Reconciler {
+	return &Reconciler{svc: svc, db: db, grace: grace}
+}
+
+// f вызывается после каждого прохода, например для метрик
+func (r *Reconciler) OnReport(f func(PinReport)) {
+	r.onReport = f
+}
+
+func (r *Reconciler) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				if _, err := r.Run(ctx); err != nil && ctx.Err() == nil && !errors.Is(err, ErrReconcileRunning) {
+					slog.Warn("pins: reconcile", "error", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (r *Reconciler) Last() PinReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.last
+}
+
+// один проход сверки. параллельный запуск (по таймеру и из админского rpc) отклоняется
+func (r *Reconciler) Run(ctx context.Context) (PinReport, error) {
+	if !r.running.TryLock() {
+		return PinReport{}, ErrReconcileRunning
+	}
+	defer r.running.Unlock()
+	rep := PinReport{RunAt: time.Now()}
+	err := r.run(ctx, &rep)
+	rep.Duration = time.Since(rep.RunAt)
+	if err != nil {
+		rep.Err = err.Error()
+	}
+	r.mu.Lock()
+	r.last = rep
+	r.mu.Unlock()
+	if r.onReport != nil {
+		r.onReport(rep)
+	}
+	return rep, err
+}
+
+func (r *Reconciler) run(ctx context.Context, rep *PinReport) error {
+	// пины читаются раньше files: загрузка между двумя чтениями даст ложный missing,
+	// а повторный пин безвреден. в обратном порядке она дала бы ложную сироту
+	pinList, err := r.svc.blobs.List(ctx)
+	if err != nil {
+		return fmt.Errorf("list pins: %w", err)
+	}
+	pins := make(map[string]string, len(pinList))
+	for _, c := range pinList {
+		pins[normalizeCID(c)] = c
+	}
+	refs, err := r.contents(ctx, `SELECT cid FROM contents WHERE refcount > 0`)
+	if err != nil {
+		return err
+	}
+	// сиротой считается только то, что записал сам сервер: пины узла, которых нет в
+	// contents, ставил кто-то другой
+	unrefs, err := r.contents(ctx, `SELECT cid FROM contents WHERE refcount <= 0`)
+	if err != nil {
+		return err
+	}
+	rep.Pinned, rep.Referenced = len(pins), len(refs)
+	now := time.Now()
+	found := map[string]string{}
+	for k, c := range refs {
+		if _, ok := pins[k]; !ok {
+			found[c] = DriftMissing
+		}
+	}
+	for k := range unrefs {
+		if c, ok := pins[k]; ok {
+			if _, ok := refs[k]; !ok {
+				found[c] = DriftOrphan
+			}
+		}
+	}
+	rep.Missing = countKind(found, DriftMissing)
+	rep.Orphaned = countKind(found, DriftOrphan)
+	drift, err := r.record(ctx, found, now)
+	if err != nil {
+		return err
+	}
+	unpinned := 0
+	for _, d := range drift {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		switch d.Kind {
+		case DriftMissing:
+			pctx, cancel := context.WithTimeout(ctx, repinTimeout)
+			err := r.svc.PinAdd(pctx, d.CID)
+			cancel()
+			if r.settle(ctx, d.CID, err) {
+				rep.Repinned++
+			}
+		case DriftOrphan:
+			if now.Sub(d.FirstSeen) < r.grace || unpinned >= maxUnpinPerRun {
+				continue
+			}
+			unpinned++
+			// cid, на который за это время сослались или который снова приняла загрузка,
+			// освобождение пропускает, и следующий проход его уже не увидит сиротой
+			released, err := r.svc.release(ctx, d.CID)
+			if r.settle(ctx, d.CID, err) && released {
+				rep.Unpinned++
+			}
+		}
+	}
+	return nil
+}
+
+// записать итог исправления: успех убирает строку, ошибка копится в попытках
+func (r *Reconciler) settle(ctx context.Context, c string, err error) bool {
+	if err == nil {
+		if _, err := r.db.ExecContext(ctx, `DELETE FROM pin_drift WHERE cid = ?`, c); err != nil {
+			slog.Warn("pins: clear drift", "cid", c, "error", err)
+		}
+		return true
+	}
+	slog.Warn("pins: repair", "cid", c, "error", err)
+	if _, err := r.db.ExecContext(ctx, `UPDATE pin_drift SET attempts = attempts + 1, last_error = ? WHERE cid = ?`, err.Error(), c); err != nil {
+		slog.Warn("pins: record drift error", "cid", c, "error", err)
+	}
+	return false
+}
+
+// cid из contents по запросу query, по нормализованному ключу
+func (r *Reconciler) contents(ctx context.Context, query string) (map[string]string, error) {
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("list contents: %w", err)
+	}
+	defer rows.Close()
+	refs := map[string]string{}
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			return nil, err
+		}
+		refs[normalizeCID(c)] = c
+	}
+	return refs, rows.Err()
+}
+
+// обновить pin_drift по найденному: исчезнувшие расхождения удаляются, новые получают
+// first_seen сейчас, у прежних он сохраняется. смена вида расхождения начинает отсчет заново
+func (r *Reconciler) record(ctx context.Context, found map[string]string, now time.Time) ([]PinDrift, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	rows, err := tx.QueryContext(ctx, `SELECT cid, kind FROM pin_drift`)
+	if err != nil {
+		return nil, fmt.Errorf("load drift: %w", err)
+	}
+	var stale []string
+	for rows.Next() {
+		var c, kind string
+		if err := rows.Scan(&c, &kind); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if found[c] != kind {
+			stale = append(stale, c)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, c := range stale {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM pin_drift WHERE cid = ?`, c); err != nil {
+			return nil, fmt.Errorf("clear drift: %w", err)
+		}
+	}
+	for c, kind := range found {
+		_, err := tx.ExecContext(ctx, `INSERT INTO pin_drift (cid, kind, first_seen, last_seen) VALUES (?, ?, ?, ?)
+			ON CONFLICT(cid) DO UPDATE SET last_seen = excluded.last_seen`, c, kind, now.Unix(), now.Unix())
+		if err != nil {
+			return nil, fmt.Errorf("record drift: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.Drift(ctx, 0)
+}
+
+// текущие расхождения, старые сначала. limit <= 0 без ограничения
+func (r *Reconciler) Drift(ctx context.Context, limit int) ([]PinDrift, error) {
+	q := `SELECT cid, kind, first_seen, last_seen, attempts, COALESCE(last_error, '') FROM pin_drift ORDER BY first_seen, cid`
+	var args []any
+	if limit > 0 {
+		q += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list drift: %w", err)
+	}
+	defer rows.Close()
+	var out []PinDrift
+	for rows.Next() {
+		var d PinDrift
+		var first, last int64
+		if err := rows.Scan(&d.CID, &d.Kind, &first, &last, &d.Attempts, &d.LastError); err != nil {
+			return nil, err
+		}
+		d.FirstSeen, d.LastSeen = time.Unix(first, 0), time.Unix(last, 0)
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+func countKind(found map[string]string, kind string) int {
+	n := 0
+	for _, k := range found {
+		if k == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// kubo может вернуть cid в другом виде (v0 и v1 одного dag-pb), сравниваем по v1
+func normalizeCID(s string) string {
+	c, err := cid.Decode(s)
+	if err != nil {
+		return s
+	}
+	return cid.NewCidV1(c.Type(), c.Hash()).String()
+}
@@ -0,0 +1,384 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"dev.c0rex64.heroin/internal/ipfs"
+	"dev.c0rex64.heroin/internal/store"
+	"github.com/ipfs/go-cid"
+)
+
+// kubo с рекурсивными пинами (pin/ls, pin/add, pin/rm) и car по корню (dag/import,
+// dag/export, dag/stat, refs, block/get). cid из failAdd не закрепляются, сломанный
+// узел отвечает ошибкой на все, кроме id, failImport - на dag/import
+type fakeKubo struct {
+	mu         sync.Mutex
+	pins       map[string]bool
+	cars       map[string][]byte
+	failAdd    map[string]bool
+	failImport bool
+	broken     bool
+	adds       int
+}
+
+func newFakeKubo(t *testing.T, pins ...string) (*fakeKubo, *ipfs.Client) {
+	t.Helper()
+	k := &fakeKubo{pins: map[string]bool{}, cars: map[string][]byte{}, failAdd: map[string]bool{}}
+	for _, c := range pins {
+		k.pins[c] = true
+	}
+	srv := httptest.NewServer(http.HandlerFunc(k.serve))
+	t.Cleanup(srv.Close)
+	return k, ipfs.NewWithOptions(srv.URL, ipfs.Options{Retries: -1})
+}
+
+func (k *fakeKubo) serve(w http.ResponseWriter, r *http.Request) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	c := r.URL.Query().Get("arg")
+	if r.URL.Path == "/api/v0/id" {
+		json.NewEncoder(w).Encode(map[string]string{"ID": "12D3KooWFake", "AgentVersion": "kubo/fake"})
+		return
+	}
+	if k.broken {
+		http.Error(w, `{"Message":"node broken","Code":0,"Type":"error"}`, http.StatusInternalServerError)
+		return
+	}
+	car, stored := k.cars[c]
+	switch r.URL.Path {
+	case "/api/v0/dag/import":
+		if k.failImport {
+			http.Error(w, `{"Message":"import failed","Code":0,"Type":"error"}`, http.StatusInternalServerError)
+			return
+		}
+		mr, err := r.MultipartReader()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		part, err := mr.NextPart()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		car, err := io.ReadAll(part)
+		if err != nil {
+			return
+		}
+		root, err := checkCAR(bytes.NewReader(car))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		k.cars[root] = car
+		if r.URL.Query().Get("pin-roots") == "true" {
+			k.pins[root] = true
+			json.NewEncoder(w).Encode(map[string]any{"Root": map[string]any{"Cid": map[string]string{"/": root}, "PinErrorMsg": ""}})
+		}
+	case "/api/v0/dag/export", "/api/v0/dag/stat", "/api/v0/refs":
+		if !stored {
+			http.Error(w, `{"Message":"block was not found locally (offline)","Code":0,"Type":"error"}`, http.StatusInternalServerError)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v0/dag/export":
+			w.Write(car)
+		case "/api/v0/dag/stat":
+			json.NewEncoder(w).Encode(map[string]int{"Size": len(car), "NumBlocks": 1})
+		case "/api/v0/refs":
+			cids, _ := carBlockCIDs(bytes.NewReader(car), c)
+			for _, ref := range cids[1:] {
+				json.NewEncoder(w).Encode(map[string]string{"Ref": ref})
+			}
+		}
+	case "/api/v0/block/get":
+		for _, car := range k.cars {
+			if b, err := carBlock(bytes.NewReader(car), c); err == nil {
+				w.Write(b)
+				return
+			}
+		}
+		http.Error(w, `{"Message":"block was not found locally (offline)","Code":0,"Type":"error"}`, http.StatusInternalServerError)
+	case "/api/v0/pin/ls":
+		enc := json.NewEncoder(w)
+		for p := range k.pins {
+			enc.Encode(map[string]string{"Cid": p, "Type": "recursive"})
+		}
+	case "/api/v0/pin/add":
+		k.adds++
+		if k.failAdd[c] {
+			http.Error(w, `{"Message":"block not found","Code":0,"Type":"error"}`, http.StatusInternalServerError)
+			return
+		}
+		k.pins[c] = true
+		json.NewEncoder(w).Encode(map[string][]string{"Pins": {c}})
+	case "/api/v0/pin/rm":
+		if !k.pins[c] {
+			http.Error(w, `{"Message":"not pinned or pinned indirectly","Code":0,"Type":"error"}`, http.StatusInternalServerError)
+			return
+		}
+		delete(k.pins, c)
+		json.NewEncoder(w).Encode(map[string][]string{"Pins": {c}})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (k *fakeKubo) pinned(c string) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.pins[c]
+}
+
+func newTestReconciler(t *testing.T, grace time.Duration, pins ...string) (*Reconciler, *fakeKubo, *store.DB) {
+	t.Helper()
+	db := newTestDB(t)
+	k, client := newFakeKubo(t, pins...)
+	svc := NewWithDB(client, true, 0, db.SQL)
+	svc.SetBlobStore(NewKuboStore(client, nil, true))
+	return NewReconciler(svc, db.SQL, grace), k, db
+}
+
+func rawCID(t *testing.T, data string) string {
+	t.Helper()
+	c, err := cid.Prefix{Version: 1, Codec: cid.Raw, MhType: 0x12, MhLength: -1}.Sum([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.String()
+}
+
+func reference(t *testing.T, db *store.DB, c string) {
+	t.Helper()
+	if _, err := db.SQL.Exec(`INSERT INTO contents (cid, size_bytes, refcount, created_at) VALUES (?, 1, 1, 0)
+		ON CONFLICT(cid) DO UPDATE SET refcount = refcount + 1`, c); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// содержимое, которое записал сервер и на которое больше нет ссылок
+func unreferenced(t *testing.T, db *store.DB, c string) {
+	t.Helper()
+	if _, err := db.SQL.Exec(`INSERT INTO contents (cid, size_bytes, refcount, created_at) VALUES (?, 1, 0, 0)`, c); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expireGrace(t *testing.T, db *store.DB, c string) {
+	t.Helper()
+	if _, err := db.SQL.Exec(`UPDATE pin_drift SET first_seen = ? WHERE cid = ?`, time.Now().Add(-2*time.Hour).Unix(), c); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func drift(t *testing.T, r *Reconciler) map[string]PinDrift {
+	t.Helper()
+	ds, err := r.Drift(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := map[string]PinDrift{}
+	for _, d := range ds {
+		out[d.CID] = d
+	}
+	return out
+}
+
+func TestReconcileRepinsMissing(t *testing.T) {
+	r, k, db := newTestReconciler(t, time.Hour)
+	missing := rawCID(t, "missing")
+	reference(t, db, missing)
+
+	rep, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Referenced != 1 || rep.Missing != 1 || rep.Repinned != 1 {
+		t.Fatalf("report %+v", rep)
+	}
+	if !k.pinned(missing) {
+		t.Fatal("referenced cid not pinned back")
+	}
+	if d := drift(t, r); len(d) != 0 {
+		t.Fatalf("drift left after repair: %+v", d)
+	}
+}
+
+func TestReconcileUnpinsOrphanAfterGrace(t *testing.T) {
+	orphan := rawCID(t, "orphan")
+	r, k, db := newTestReconciler(t, time.Hour, orphan)
+	unreferenced(t, db, orphan)
+	ctx := context.Background()
+
+	rep, err := r.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Orphaned != 1 || rep.Unpinned != 0 || !k.pinned(orphan) {
+		t.Fatalf("orphan unpinned inside the grace period: %+v", rep)
+	}
+	first := drift(t, r)[orphan]
+	if first.Kind != DriftOrphan {
+		t.Fatalf("drift %+v", first)
+	}
+
+	// второй проход в пределах грейса не сдвигает first_seen
+	if _, err := r.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if d := drift(t, r)[orphan]; !d.FirstSeen.Equal(first.FirstSeen) || !k.pinned(orphan) {
+		t.Fatalf("orphan after second run: %+v pinned=%v", d, k.pinned(orphan))
+	}
+
+	expireGrace(t, db, orphan)
+	rep, err = r.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Unpinned != 1 || k.pinned(orphan) {
+		t.Fatalf("orphan kept after the grace period: %+v", rep)
+	}
+	if d := drift(t, r); len(d) != 0 {
+		t.Fatalf("drift left after unpin: %+v", d)
+	}
+	var n int
+	if err := db.SQL.QueryRow(`SELECT (SELECT COUNT(*) FROM contents) + (SELECT COUNT(*) FROM content_claims)`).Scan(&n); err != nil || n != 0 {
+		t.Fatalf("released content rows left: %d, %v", n, err)
+	}
+}
+
+func TestReconcileIgnoresForeignPins(t *testing.T) {
+	foreign := rawCID(t, "pinned by someone else")
+	r, k, _ := newTestReconciler(t, 0, foreign)
+	for i := 0; i < 2; i++ {
+		rep, err := r.Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rep.Orphaned != 0 || rep.Unpinned != 0 || !k.pinned(foreign) {
+			t.Fatalf("pin the server never recorded treated as orphan: %+v", rep)
+		}
+	}
+}
+
+func TestReconcileKeepsFreshlyClaimedOrphan(t *testing.T) {
+	orphan := rawCID(t, "uploading again")
+	r, k, db := newTestReconciler(t, time.Hour, orphan)
+	unreferenced(t, db, orphan)
+	ctx := context.Background()
+	if _, err := r.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expireGrace(t, db, orphan)
+	// загрузка того же cid только что записала car и еще не создала файл
+	if _, err := db.SQL.Exec(`INSERT INTO content_claims (cid, claimed_at) VALUES (?, ?)`, orphan, time.Now().UnixNano()); err != nil {
+		t.Fatal(err)
+	}
+	rep, err := r.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Unpinned != 0 || !k.pinned(orphan) {
+		t.Fatalf("orphan claimed by an upload unpinned: %+v", rep)
+	}
+}
+
+func TestReleaseSkipsReferencedContent(t *testing.T) {
+	c := rawCID(t, "referenced")
+	r, k, db := newTestReconciler(t, 0, c)
+	reference(t, db, c)
+	released, err := r.svc.release(context.Background(), c)
+	if err != nil || released || !k.pinned(c) {
+		t.Fatalf("referenced content released: %v, %v", released, err)
+	}
+	var n int
+	if err := db.SQL.QueryRow(`SELECT COUNT(*) FROM content_claims`).Scan(&n); err != nil || n != 0 {
+		t.Fatalf("claim left for referenced content: %d, %v", n, err)
+	}
+}
+
+func TestReconcileOrphanReferencedAgainIsNotUnpinned(t *testing.T) {
+	orphan := rawCID(t, "late upload")
+	r, k, db := newTestReconciler(t, time.Hour, orphan)
+	unreferenced(t, db, orphan)
+	ctx := context.Background()
+	if _, err := r.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expireGrace(t, db, orphan)
+	// загрузка дописала файл после закрепления
+	reference(t, db, orphan)
+
+	rep, err := r.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Orphaned != 0 || rep.Unpinned != 0 || !k.pinned(orphan) {
+		t.Fatalf("referenced cid unpinned: %+v", rep)
+	}
+	if d := drift(t, r); len(d) != 0 {
+		t.Fatalf("stale drift kept: %+v", d)
+	}
+}
+
+func TestReconcileRecordsFailedRepair(t *testing.T) {
+	r, k, db := newTestReconciler(t, time.Hour)
+	ctx := context.Background()
+	lost := rawCID(t, "lost")
+	reference(t, db, lost)
+	k.failAdd[lost] = true
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		rep, err := r.Run(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rep.Missing != 1 || rep.Repinned != 0 {
+			t.Fatalf("run %d: %+v", attempt, rep)
+		}
+		d := drift(t, r)[lost]
+		if d.Kind != DriftMissing || d.Attempts != attempt || d.LastError == "" {
+			t.Fatalf("run %d: drift %+v", attempt, d)
+		}
+	}
+
+	k.mu.Lock()
+	delete(k.failAdd, lost)
+	k.mu.Unlock()
+	rep, err := r.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Repinned != 1 || !k.pinned(lost) {
+		t.Fatalf("repair after recovery: %+v", rep)
+	}
+	if d := drift(t, r); len(d) != 0 {
+		t.Fatalf("drift left after recovery: %+v", d)
+	}
+}
+
+func TestReconcileMatchesCIDv0Pins(t *testing.T) {
+	v1, err := cid.Prefix{Version: 1, Codec: cid.DagProtobuf, MhType: 0x12, MhLength: -1}.Sum([]byte("dag-pb node"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v0 := cid.NewCidV0(v1.Hash()).String()
+	r, k, db := newTestReconciler(t, 0, v0)
+	reference(t, db, v1.String())
+
+	rep, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Missing != 0 || rep.Orphaned != 0 || k.adds != 0 || !k.pinned(v0) {
+		t.Fatalf("v0 pin of a v1 reference reported as drift: %+v", rep)
+	}
+}
@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"dev.c0rex64.heroin/internal/store"
+)
+
+// хранилище, которое после записи car дает тесту вклиниться до отметки загрузки
+type hookedStore struct {
+	BlobStore
+	afterPut func(cid string)
+}
+
+func (h *hookedStore) Put(ctx context.Context, car io.Reader) (string, error) {
+	c, err := h.BlobStore.Put(ctx, car)
+	if err == nil && h.afterPut != nil {
+		h.afterPut(c)
+	}
+	return c, err
+}
+
+func newReleaseService(t *testing.T) (*Service, *hookedStore, *store.DB) {
+	t.Helper()
+	db := newTestDB(t)
+	if _, err := db.SQL.Exec(`INSERT INTO users (id, username, server_salt, password_hash, second_factor_secret, created_at) VALUES ('u2', 'u2', x'00', x'00', x'00', 0)`); err != nil {
+		t.Fatal(err)
+	}
+	local, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &hookedStore{BlobStore: local}
+	svc := NewWithDB(nil, false, 0, db.SQL)
+	svc.SetBlobStore(h)
+	return svc, h, db
+}
+
+func put(t *testing.T, svc *Service, userID string, car []byte) string {
+	t.Helper()
+	fileID, _, err := svc.PutCAR(context.Background(), userID, "d1", "f", "application/octet-stream", int64(len(car)), bytes.NewReader(car), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fileID
+}
+
+func blobStored(t *testing.T, h *hookedStore, c string) bool {
+	t.Helper()
+	_, err := h.Stat(context.Background(), c)
+	if err != nil && !errors.Is(err, ErrBlobNotFound) {
+		t.Fatal(err)
+	}
+	return err == nil
+}
+
+func TestDeleteFileKeepsSharedContent(t *testing.T) {
+	svc, h, _ := newReleaseService(t)
+	ctx := context.Background()
+	car, c := testCAR(t, []byte("same bytes"))
+	f1 := put(t, svc, "u1", car)
+	f2 := put(t, svc, "u2", car)
+
+	if err := svc.DeleteFile(ctx, "u1", f1); err != nil {
+		t.Fatal(err)
+	}
+	if !blobStored(t, h, c) {
+		t.Fatal("content still referenced by another user deleted")
+	}
+	if err := svc.DeleteFile(ctx, "u2", f2); err != nil {
+		t.Fatal(err)
+	}
+	if blobStored(t, h, c) {
+		t.Fatal("unreferenced content kept")
+	}
+}
+
+func TestReleaseSkipsContentClaimedByUpload(t *testing.T) {
+	svc, h, db := newReleaseService(t)
+	ctx := context.Background()
+	car, c := testCAR(t, []byte("deleted while uploaded again"))
+	f1 := put(t, svc, "u1", car)
+
+	// удаление первого файла приходится между записью car второй загрузкой и ее файлом
+	imp, err := svc.importCAR(ctx, "u2", "", int64(len(car)), bytes.NewReader(car), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.DeleteFile(ctx, "u1", f1); err != nil {
+		t.Fatal(err)
+	}
+	if !blobStored(t, h, c) {
+		t.Fatal("content claimed by an upload released")
+	}
+	if err := svc.insertFile(ctx, "f2", "u2", "d1", "", "f", "application/octet-stream", imp, time.Now().Unix()); err != nil {
+		t.Fatal(err)
+	}
+	imp.release(ctx)
+
+	var refs, claims int
+	if err := db.SQL.QueryRow(`SELECT refcount, (SELECT COUNT(*) FROM content_claims) FROM contents WHERE cid = ?`, c).Scan(&refs, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if refs != 2 || claims != 0 {
+		t.Fatalf("refcount %d, claims %d", refs, claims)
+	}
+}
+
+func TestUploadBacksOffFromRunningRelease(t *testing.T) {
+	svc, h, db := newReleaseService(t)
+	ctx := context.Background()
+	car, c := testCAR(t, []byte("being released"))
+	f1 := put(t, svc, "u1", car)
+	if _, err := db.SQL.Exec(`DELETE FROM files WHERE id = ?`, f1); err != nil {
+		t.Fatal(err)
+	}
+	// освобождение уже поставило заявку и удаляет car
+	h.afterPut = func(c string) {
+		if _, err := db.SQL.Exec(`INSERT INTO content_claims (cid, releasing_at) VALUES (?, ?)`, c, time.Now().UnixNano()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := svc.PutCAR(ctx, "u2", "d1", "f", "application/octet-stream", int64(len(car)), bytes.NewReader(car), nil); !errors.Is(err, ErrContentReleasing) {
+		t.Fatalf("upload during release: %v", err)
+	}
+	var n int
+	if err := db.SQL.QueryRow(`SELECT COUNT(*) FROM files WHERE cid = ?`, c).Scan(&n); err != nil || n != 0 {
+		t.Fatalf("file created over released content: %d, %v", n, err)
+	}
+}
+
+func TestUploadDetectsReleaseFinishedBeforeClaim(t *testing.T) {
+	svc, h, db := newReleaseService(t)
+	ctx := context.Background()
+	car, c := testCAR(t, []byte("released in between"))
+	f1 := put(t, svc, "u1", car)
+	if _, err := db.SQL.Exec(`DELETE FROM files WHERE id = ?`, f1); err != nil {
+		t.Fatal(err)
+	}
+	// освобождение целиком проходит между записью car и отметкой
+	h.afterPut = func(c string) {
+		if err := svc.ReleaseUnreferenced(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := svc.PutCAR(ctx, "u2", "d1", "f", "application/octet-stream", int64(len(car)), bytes.NewReader(car), nil); !errors.Is(err, ErrContentReleasing) {
+		t.Fatalf("upload after its car was released: %v", err)
+	}
+	if blobStored(t, h, c) {
+		t.Fatal("released content back without a file")
+	}
+
+	h.afterPut = nil
+	put(t, svc, "u2", car)
+	if !blobStored(t, h, c) {
+		t.Fatal("retried upload not stored")
+	}
+}
+
+func TestRejectedUploadKeepsReferencedContent(t *testing.T) {
+	svc, h, _ := newReleaseService(t)
+	ctx := context.Background()
+	car, c := testCAR(t, []byte("shared, then uploaded with a wrong hash"))
+	put(t, svc, "u1", car)
+
+	wrong := make([]byte, 32)
+	if _, _, err := svc.PutCAR(ctx, "u2", "d1", "f", "application/octet-stream", int64(len(car)), bytes.NewReader(car), wrong); !errors.Is(err, ErrBlake3Mismatch) {
+		t.Fatalf("upload with wrong hash: %v", err)
+	}
+	if !blobStored(t, h, c) {
+		t.Fatal("rejected upload deleted content referenced by another file")
+	}
+
+	other, oc := testCAR(t, []byte("only the rejected upload"))
+	if _, _, err := svc.PutCAR(ctx, "u2", "d1", "f", "application/octet-stream", int64(len(other)), bytes.NewReader(other), wrong); !errors.Is(err, ErrBlake3Mismatch) {
+		t.Fatalf("upload with wrong hash: %v", err)
+	}
+	if blobStored(t, h, oc) {
+		t.Fatal("rejected upload kept")
+	}
+}
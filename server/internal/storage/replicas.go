@@ -0,0 +1,676 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"dev.c0rex64.heroin/internal/ipfs"
+)
+
+var (
+	ErrQuorum            = errors.New("write quorum not reached")
+	ErrReplicatedBackend = errors.New("replication requires the kubo blob store")
+)
+
+// за один проход восстановления копируется не больше стольких cid, остальные ждут следующего
+const maxRepairsPerRun = 100
+
+// узел kubo из ipfs.endpoints. до первой проверки считается живым
+type Node struct {
+	Endpoint string
+	client   *ipfs.Client
+	streamer *CARStreamer
+	healthy  atomic.Bool
+}
+
+func (n *Node) Healthy() bool {
+	return n.healthy.Load()
+}
+
+// последняя проверка узла и состояние его предохранителя
+func (n *Node) Health() ipfs.Health {
+	return n.client.Health()
+}
+
+// итог прохода восстановления. Under - cid с живыми копиями меньше фактора репликации,
+// Unavailable - cid без единой живой копии
+type RepairReport struct {
+	RunAt       time.Time
+	Under       int
+	Unavailable int
+	Copied      int
+	Adopted     int
+	Lost        int
+	Err         string
+}
+
+// несколько узлов kubo с фактором репликации. запись идет сразу на replicas живых узлов
+// и считается успешной при quorum закрепленных копиях, недостающие копии дописывает
+// проход восстановления. размещение хранится в replicas по cid
+type Nodes struct {
+	db       *sql.DB
+	list     []*Node
+	replicas int
+	quorum   int
+	next     atomic.Uint32
+	running  sync.Mutex
+	onRepair func(RepairReport)
+	onHealth func(*Node)
+}
+
+// replicas больше числа узлов урезается до него, quorum 0 значит большинство от replicas
+func NewNodes(db *sql.DB, endpoints []string, replicas, quorum int, opts ipfs.Options) (*Nodes, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("no ipfs nodes configured")
+	}
+	n := &Nodes{db: db}
+	seen := map[string]bool{}
+	for _, e := range endpoints {
+		if seen[e] {
+			return nil, fmt.Errorf("duplicate ipfs node %s", e)
+		}
+		seen[e] = true
+		client := ipfs.NewWithOptions(e, opts)
+		node := &Node{Endpoint: e, client: client, streamer: NewCARStreamer(client, 0)}
+		node.healthy.Store(true)
+		n.list = append(n.list, node)
+	}
+	n.replicas = min(max(replicas, 1), len(n.list))
+	n.quorum = quorum
+	if n.quorum <= 0 {
+		n.quorum = n.replicas/2 + 1
+	}
+	n.quorum = min(n.quorum, n.replicas)
+	return n, nil
+}
+
+func (n *Nodes) Health() []ipfs.Health {
+	out := make([]ipfs.Health, len(n.list))
+	for i, node := range n.list {
+		out[i] = node.Health()
+	}
+	return out
+}
+
+// f вызывается после каждого прохода восстановления
+func (n *Nodes) OnRepair(f func(RepairReport)) {
+	n.onRepair = f
+}
+
+// f вызывается после каждой проверки здоровья узла
+func (n *Nodes) OnHealth(f func(*Node)) {
+	n.onHealth = f
+}
+
+// проверка здоровья и восстановление копий в фоне
+func (n *Nodes) Start(ctx context.Context, healthInterval, repairInterval time.Duration) {
+	n.checkHealth(ctx)
+	go func() {
+		health := time.NewTicker(healthInterval)
+		repair := time.NewTicker(repairInterval)
+		defer health.Stop()
+		defer repair.Stop()
+		for {
+			select {
+			case <-health.C:
+				n.checkHealth(ctx)
+			case <-repair.C:
+				if _, err := n.Repair(ctx); err != nil && ctx.Err() == nil {
+					slog.Warn("replicas: repair", "error", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (n *Nodes) checkHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, node := range n.list {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := node.client.Probe(ctx)
+			if ctx.Err() == nil {
+				n.setHealth(node, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// переходы узла вверх и вниз пишет в лог сам клиент
+func (n *Nodes) setHealth(node *Node, err error) {
+	node.healthy.Store(err == nil)
+	if n.onHealth != nil {
+		n.onHealth(node)
+	}
+}
+
+// до limit живых узлов, кроме except. начало списка сдвигается от вызова к вызову,
+// чтобы записи расходились по узлам равномерно
+func (n *Nodes) pick(limit int, except map[string]bool) []*Node {
+	start := int(n.next.Add(1))
+	var out []*Node
+	for i := range n.list {
+		node := n.list[(start+i)%len(n.list)]
+		if len(out) == limit {
+			break
+		}
+		if node.Healthy() && !except[node.Endpoint] {
+			out = append(out, node)
+		}
+	}
+	return out
+}
+
+// узлы для чтения cid: живые с копией, затем остальные живые. пустой список,
+// если живых нет, тогда читаем с первого узла в надежде, что он уже поднялся
+func (n *Nodes) readers(ctx context.Context, cid string) []*Node {
+	placed, err := n.placed(ctx, cid)
+	if err != nil {
+		slog.Warn("replicas: load placement", "cid", cid, "error", err)
+	}
+	var first, rest []*Node
+	for _, node := range n.list {
+		if !node.Healthy() {
+			continue
+		}
+		if placed[node.Endpoint] {
+			first = append(first, node)
+		} else {
+			rest = append(rest, node)
+		}
+	}
+	return append(first, rest...)
+}
+
+// f по очереди на узлах из readers, пока один не ответит. упавший или отставший
+// узел не валит чтение, пока копия есть на другом. ошибка - от первого узла,
+// у него копия должна была быть
+func (n *Nodes) read(ctx context.Context, cid string, f func(*Node) error) error {
+	nodes := n.readers(ctx, cid)
+	if len(nodes) == 0 {
+		nodes = n.list[:1]
+	}
+	var firstErr error
+	for _, node := range nodes {
+		err := f(node)
+		if err == nil {
+			return nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return firstErr
+}
+
+func (n *Nodes) placed(ctx context.Context, cid string) (map[string]bool, error) {
+	rows, err := n.db.QueryContext(ctx, `SELECT node FROM replicas WHERE cid = ?`, cid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]bool{}
+	for rows.Next() {
+		var node string
+		if err := rows.Scan(&node); err != nil {
+			return nil, err
+		}
+		out[node] = true
+	}
+	return out, rows.Err()
+}
+
+func (n *Nodes) place(ctx context.Context, cid string, node *Node) error {
+	_, err := n.db.ExecContext(ctx, `INSERT INTO replicas (cid, node, pinned_at) VALUES (?, ?, ?) ON CONFLICT(cid, node) DO NOTHING`,
+		cid, node.Endpoint, time.Now().Unix())
+	return err
+}
+
+// импорт одного потока сразу на replicas живых узлов. узел, чей импорт оборвался,
+// отключается от потока, остальные продолжают. ошибка источника (лимит, обрыв,
+// порча) возвращается как есть
+func (n *Nodes) importCAR(ctx context.Context, src io.Reader) (string, []*Node, error) {
+	targets := n.pick(n.replicas, nil)
+	if len(targets) < n.quorum {
+		return "", nil, fmt.Errorf("%w: %d healthy ipfs nodes, need %d", ErrQuorum, len(targets), n.quorum)
+	}
+	type result struct {
+		node *Node
+		cid  string
+		err  error
+	}
+	results := make(chan result, len(targets))
+	pipes := make([]*io.PipeWriter, len(targets))
+	for i, node := range targets {
+		pr, pw := io.Pipe()
+		pipes[i] = pw
+		go func() {
+			c, err := node.streamer.ImportCAR(ctx, pr, false)
+			pr.CloseWithError(errRequestDone)
+			results <- result{node: node, cid: c, err: err}
+		}()
+	}
+	fo := &fanout{pipes: pipes, alive: len(pipes)}
+	_, copyErr := io.Copy(fo, src)
+	for _, pw := range fo.pipes {
+		if pw != nil {
+			pw.CloseWithError(copyErr)
+		}
+	}
+	// все узлы отвалились сами, это не ошибка источника
+	if fo.alive == 0 {
+		copyErr = nil
+	}
+	var ok []*Node
+	var cid string
+	var firstErr error
+	for range targets {
+		r := <-results
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			if copyErr == nil && ctx.Err() == nil {
+				slog.Warn("replicas: import", "node", r.node.Endpoint, "error", r.err)
+			}
+			continue
+		}
+		cid = r.cid
+		ok = append(ok, r.node)
+	}
+	if copyErr != nil {
+		return "", nil, copyErr
+	}
+	if len(ok) == 0 {
+		return "", nil, firstErr
+	}
+	if len(ok) < n.quorum {
+		return "", nil, fmt.Errorf("%w: imported on %d of %d nodes: %v", ErrQuorum, len(ok), n.quorum, firstErr)
+	}
+	return cid, ok, nil
+}
+
+// загрузка с кворумом: импорт на replicas узлов, затем пины
+func (n *Nodes) Put(ctx context.Context, car io.Reader) (string, error) {
+	cid, placed, err := n.importCAR(ctx, car)
+	if err != nil {
+		return "", err
+	}
+	if err := n.pin(ctx, cid, placed); err != nil {
+		return "", err
+	}
+	return cid, nil
+}
+
+func (n *Nodes) Get(ctx context.Context, cid string) (io.ReadCloser, error) {
+	var rc io.ReadCloser
+	err := n.read(ctx, cid, func(node *Node) error {
+		var err error
+		rc, err = node.streamer.Export(ctx, cid)
+		return err
+	})
+	return rc, err
+}
+
+func (n *Nodes) Stat(ctx context.Context, cid string) (BlobInfo, error) {
+	var size int64
+	err := n.read(ctx, cid, func(node *Node) error {
+		var err error
+		size, _, err = node.client.DagStat(ctx, cid)
+		return err
+	})
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	return BlobInfo{CID: cid, Size: size}, nil
+}
+
+func (n *Nodes) BlockCIDs(ctx context.Context, cid string) ([]string, error) {
+	var refs []string
+	err := n.read(ctx, cid, func(node *Node) error {
+		var err error
+		refs, err = node.client.Refs(ctx, cid)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{cid}, refs...), nil
+}
+
+func (n *Nodes) Block(ctx context.Context, cid string) ([]byte, error) {
+	var b []byte
+	err := n.read(ctx, cid, func(node *Node) error {
+		var err error
+		b, err = node.client.BlockGet(ctx, cid)
+		return err
+	})
+	return b, err
+}
+
+// закрепить cid на узлах после импорта. без кворума уже поставленные пины снимаются
+func (n *Nodes) pin(ctx context.Context, cid string, nodes []*Node) error {
+	errs := make([]error, len(nodes))
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = node.client.PinAdd(ctx, cid)
+		}()
+	}
+	wg.Wait()
+	var pinned []*Node
+	var firstErr error
+	for i, err := range errs {
+		if err != nil {
+			slog.Warn("replicas: pin", "node", nodes[i].Endpoint, "cid", cid, "error", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		pinned = append(pinned, nodes[i])
+	}
+	if len(pinned) < n.quorum {
+		for _, node := range pinned {
+			if err := node.client.PinRm(ctx, cid); err != nil {
+				slog.Warn("replicas: unpin after failed quorum", "node", node.Endpoint, "cid", cid, "error", err)
+			}
+		}
+		return fmt.Errorf("%w: pinned on %d of %d nodes: %v", ErrQuorum, len(pinned), n.quorum, firstErr)
+	}
+	for _, node := range pinned {
+		if err := n.place(ctx, cid, node); err != nil {
+			return fmt.Errorf("record replica: %w", err)
+		}
+	}
+	return nil
+}
+
+// закрепить cid хотя бы на одном живом узле: сначала там, где он уже должен быть
+func (n *Nodes) Pin(ctx context.Context, cid string) error {
+	var lastErr error = errors.New("no healthy ipfs nodes")
+	for _, node := range n.readers(ctx, cid) {
+		if err := node.client.PinAdd(ctx, cid); err != nil {
+			lastErr = err
+			continue
+		}
+		return n.place(ctx, cid, node)
+	}
+	return lastErr
+}
+
+// открепить cid на всех узлах. недоступный узел оставляет свою запись в replicas и ошибку,
+// повтор подберет его позже
+func (n *Nodes) Delete(ctx context.Context, cid string) error {
+	var firstErr error
+	for _, node := range n.list {
+		var err error
+		if !node.Healthy() {
+			err = fmt.Errorf("node %s is down", node.Endpoint)
+		} else if err = node.client.PinRm(ctx, cid); err == nil {
+			_, err = n.db.ExecContext(ctx, `DELETE FROM replicas WHERE cid = ? AND node = ?`, cid, node.Endpoint)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// блоки удаляются на живых узлах. на упавшем узле они останутся до его собственного gc
+func (n *Nodes) DeleteBlocks(ctx context.Context, cids []string) (int, error) {
+	removed := 0
+	var firstErr error
+	for _, node := range n.list {
+		if !node.Healthy() {
+			continue
+		}
+		r, err := node.client.BlockRm(ctx, cids)
+		removed += r
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return removed, firstErr
+}
+
+// рекурсивные пины всех живых узлов вместе
+func (n *Nodes) List(ctx context.Context) ([]string, error) {
+	seen := map[string]bool{}
+	var out []string
+	live := 0
+	for _, node := range n.list {
+		if !node.Healthy() {
+			continue
+		}
+		pins, err := node.client.PinLs(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list pins on %s: %w", node.Endpoint, err)
+		}
+		live++
+		for _, c := range pins {
+			if !seen[c] {
+				seen[c] = true
+				out = append(out, c)
+			}
+		}
+	}
+	if live == 0 {
+		return nil, errors.New("no healthy ipfs nodes")
+	}
+	return out, nil
+}
+
+// проход восстановления: сверить размещение с пинами живых узлов, принять пины файлов
+// без записи о размещении, забыть потерянные копии и дописать недостающие.
+// копии на упавших узлах не считаются, вернувшийся узел просто дает лишнюю копию
+func (n *Nodes) Repair(ctx context.Context) (RepairReport, error) {
+	rep := RepairReport{RunAt: time.Now()}
+	if !n.running.TryLock() {
+		return rep, errors.New("replica repair already running")
+	}
+	defer n.running.Unlock()
+	err := n.repair(ctx, &rep)
+	if err != nil {
+		rep.Err = err.Error()
+	}
+	if n.onRepair != nil {
+		n.onRepair(rep)
+	}
+	return rep, err
+}
+
+func (n *Nodes) repair(ctx context.Context, rep *RepairReport) error {
+	refs := map[string]string{}
+	rows, err := n.db.QueryContext(ctx, `SELECT cid FROM contents WHERE refcount > 0`)
+	if err != nil {
+		return fmt.Errorf("list referenced cids: %w", err)
+	}
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			rows.Close()
+			return err
+		}
+		refs[normalizeCID(c)] = c
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	known := map[string]*Node{}
+	for _, node := range n.list {
+		known[node.Endpoint] = node
+	}
+	// размещение по нормализованному cid
+	placed := map[string]map[string]string{}
+	rows, err = n.db.QueryContext(ctx, `SELECT cid, node FROM replicas`)
+	if err != nil {
+		return fmt.Errorf("load replicas: %w", err)
+	}
+	type placement struct{ cid, node string }
+	var all []placement
+	for rows.Next() {
+		var p placement
+		if err := rows.Scan(&p.cid, &p.node); err != nil {
+			rows.Close()
+			return err
+		}
+		all = append(all, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, p := range all {
+		// узел убран из конфига: его копии больше не считаются
+		if known[p.node] == nil {
+			if _, err := n.db.ExecContext(ctx, `DELETE FROM replicas WHERE cid = ? AND node = ?`, p.cid, p.node); err != nil {
+				return err
+			}
+			continue
+		}
+		k := normalizeCID(p.cid)
+		if placed[k] == nil {
+			placed[k] = map[string]string{}
+		}
+		placed[k][p.node] = p.cid
+	}
+	// живые копии: пины на живых узлах
+	live := map[string]map[string]bool{}
+	for _, node := range n.list {
+		if !node.Healthy() {
+			continue
+		}
+		pins, err := node.client.PinLs(ctx)
+		if err != nil {
+			n.setHealth(node, err)
+			continue
+		}
+		pinned := map[string]bool{}
+		for _, c := range pins {
+			k := normalizeCID(c)
+			pinned[k] = true
+			if orig, ok := refs[k]; ok {
+				if live[k] == nil {
+					live[k] = map[string]bool{}
+				}
+				live[k][node.Endpoint] = true
+				if _, ok := placed[k][node.Endpoint]; !ok {
+					if err := n.place(ctx, orig, node); err != nil {
+						return err
+					}
+					rep.Adopted++
+				}
+			}
+		}
+		for k, nodes := range placed {
+			if c, ok := nodes[node.Endpoint]; ok && !pinned[k] {
+				if _, err := n.db.ExecContext(ctx, `DELETE FROM replicas WHERE cid = ? AND node = ?`, c, node.Endpoint); err != nil {
+					return err
+				}
+				slog.Warn("replicas: copy lost", "node", node.Endpoint, "cid", c)
+				rep.Lost++
+			}
+		}
+	}
+	copies := 0
+	for k, c := range refs {
+		have := len(live[k])
+		if have >= n.replicas {
+			continue
+		}
+		rep.Under++
+		if have == 0 {
+			rep.Unavailable++
+			continue
+		}
+		if copies >= maxRepairsPerRun || ctx.Err() != nil {
+			continue
+		}
+		var src *Node
+		for e := range live[k] {
+			src = known[e]
+			break
+		}
+		for _, dst := range n.pick(n.replicas-have, live[k]) {
+			if err := n.copyCAR(ctx, c, src, dst); err != nil {
+				slog.Warn("replicas: copy", "cid", c, "from", src.Endpoint, "to", dst.Endpoint, "error", err)
+				continue
+			}
+			rep.Copied++
+		}
+		copies++
+	}
+	return ctx.Err()
+}
+
+// скопировать dag между узлами через сервер: экспорт с одного, импорт с пином на другой.
+// узлам не нужно видеть друг друга в сети
+func (n *Nodes) copyCAR(ctx context.Context, c string, src, dst *Node) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	chunks, errs := src.streamer.StreamCAR(ctx, c)
+	pr, pw := io.Pipe()
+	go func() {
+		var werr error
+		for ch := range chunks {
+			if werr == nil {
+				_, werr = pw.Write(ch)
+			}
+		}
+		pw.CloseWithError(<-errs)
+	}()
+	got, err := dst.streamer.ImportCAR(ctx, pr, true)
+	pr.CloseWithError(errRequestDone)
+	if err != nil {
+		return err
+	}
+	if normalizeCID(got) != normalizeCID(c) {
+		return fmt.Errorf("copied root %s, want %s", got, c)
+	}
+	return n.place(ctx, c, dst)
+}
+
+// раздача потока в несколько pipe. ошибка записи означает, что импорт на этом узле
+// уже завершился неудачей, узел выбывает, поток идет дальше, пока жив хоть один
+type fanout struct {
+	pipes []*io.PipeWriter
+	alive int
+	err   error
+}
+
+func (f *fanout) Write(p []byte) (int, error) {
+	for i, pw := range f.pipes {
+		if pw == nil {
+			continue
+		}
+		if _, err := pw.Write(p); err != nil {
+			f.pipes[i] = nil
+			f.alive--
+			if f.err == nil {
+				f.err = err
+			}
+		}
+	}
+	if f.alive == 0 {
+		return 0, f.err
+	}
+	return len(p), nil
+}
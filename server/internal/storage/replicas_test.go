@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"dev.c0rex64.heroin/internal/ipfs"
+	"dev.c0rex64.heroin/internal/store"
+)
+
+func newTestNodes(t *testing.T, count, replicas, quorum int) (*Nodes, []*fakeKubo, *store.DB) {
+	t.Helper()
+	db := newTestDB(t)
+	var kubos []*fakeKubo
+	var endpoints []string
+	for i := 0; i < count; i++ {
+		k, client := newFakeKubo(t)
+		kubos = append(kubos, k)
+		endpoints = append(endpoints, client.Endpoint())
+	}
+	n, err := NewNodes(db.SQL, endpoints, replicas, quorum, ipfs.Options{Retries: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n, kubos, db
+}
+
+// узлы, на которых cid закреплен, и узлы с записью о размещении
+func placement(t *testing.T, n *Nodes, kubos []*fakeKubo, c string) (pinned, placed int) {
+	t.Helper()
+	for _, k := range kubos {
+		if k.pinned(c) {
+			pinned++
+		}
+	}
+	p, err := n.placed(context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pinned, len(p)
+}
+
+func TestNodesPutReachesQuorum(t *testing.T) {
+	n, kubos, _ := newTestNodes(t, 3, 2, 0)
+	car, c := testCAR(t, []byte("replicated twice"))
+	got, err := n.Put(context.Background(), bytes.NewReader(car))
+	if err != nil || got != c {
+		t.Fatalf("put: %s %v", got, err)
+	}
+	if pinned, placed := placement(t, n, kubos, c); pinned != 2 || placed != 2 {
+		t.Fatalf("pinned on %d nodes, %d placements", pinned, placed)
+	}
+
+	// одна неудачная копия из двух при кворуме 1 запись не валит
+	n1, kubos1, _ := newTestNodes(t, 2, 2, 1)
+	kubos1[0].failImport = true
+	if _, err := n1.Put(context.Background(), bytes.NewReader(car)); err != nil {
+		t.Fatalf("put with one failed import: %v", err)
+	}
+	if pinned, placed := placement(t, n1, kubos1, c); pinned != 1 || placed != 1 || !kubos1[1].pinned(c) {
+		t.Fatalf("pinned on %d nodes, %d placements", pinned, placed)
+	}
+}
+
+func TestNodesPutRollsBackWithoutQuorum(t *testing.T) {
+	ctx := context.Background()
+	car, c := testCAR(t, []byte("quorum missed"))
+
+	// пин не встал на одном узле из трех при кворуме 3: остальные пины снимаются
+	n, kubos, _ := newTestNodes(t, 3, 3, 3)
+	kubos[1].failAdd[c] = true
+	if _, err := n.Put(ctx, bytes.NewReader(car)); !errors.Is(err, ErrQuorum) {
+		t.Fatalf("put without pin quorum: %v", err)
+	}
+	if pinned, placed := placement(t, n, kubos, c); pinned != 0 || placed != 0 {
+		t.Fatalf("pins left after rollback: %d pinned, %d placements", pinned, placed)
+	}
+
+	// импорт прошел на одном узле из двух при кворуме 2: до пинов не доходит
+	n, kubos, _ = newTestNodes(t, 2, 2, 2)
+	kubos[0].failImport = true
+	if _, err := n.Put(ctx, bytes.NewReader(car)); !errors.Is(err, ErrQuorum) {
+		t.Fatalf("put without import quorum: %v", err)
+	}
+	if pinned, placed := placement(t, n, kubos, c); pinned != 0 || placed != 0 {
+		t.Fatalf("pinned without import quorum: %d pinned, %d placements", pinned, placed)
+	}
+
+	// живых узлов меньше кворума: запись не начинается
+	n, kubos, _ = newTestNodes(t, 2, 2, 2)
+	n.list[0].healthy.Store(false)
+	if _, err := n.Put(ctx, bytes.NewReader(car)); !errors.Is(err, ErrQuorum) {
+		t.Fatalf("put with one healthy node: %v", err)
+	}
+	if len(kubos[1].cars) != 0 {
+		t.Fatal("car imported without enough healthy nodes")
+	}
+}
+
+func TestNodesReadFallsBackToOtherReplica(t *testing.T) {
+	ctx := context.Background()
+	n, kubos, _ := newTestNodes(t, 3, 2, 1)
+	data := []byte("read from the second copy")
+	car, c := testCAR(t, data)
+	if _, err := n.Put(ctx, bytes.NewReader(car)); err != nil {
+		t.Fatal(err)
+	}
+	// первый узел с копией отвечает ошибками, но по проверке здоровья еще живой
+	readers := n.readers(ctx, c)
+	for i, k := range kubos {
+		if n.list[i] == readers[0] {
+			k.broken = true
+		}
+	}
+
+	rc, err := n.Get(ctx, c)
+	if got := readAll(t, rc, err); !bytes.Equal(got, car) {
+		t.Fatalf("get: %d bytes", len(got))
+	}
+	if info, err := n.Stat(ctx, c); err != nil || info.Size != int64(len(car)) {
+		t.Fatalf("stat: %+v %v", info, err)
+	}
+	if cids, err := n.BlockCIDs(ctx, c); err != nil || len(cids) != 1 || cids[0] != c {
+		t.Fatalf("block cids: %v %v", cids, err)
+	}
+	if b, err := n.Block(ctx, c); err != nil || !bytes.Equal(b, data) {
+		t.Fatalf("block: %q %v", b, err)
+	}
+
+	// копии нет нигде: ошибка первого узла
+	for _, k := range kubos {
+		k.broken = false
+		delete(k.cars, c)
+	}
+	if _, err := n.Stat(ctx, c); err == nil {
+		t.Fatal("stat of a lost cid succeeded")
+	}
+}
+
+func TestNodesRepairRestoresReplicas(t *testing.T) {
+	ctx := context.Background()
+	n, kubos, db := newTestNodes(t, 3, 2, 0)
+	car, c := testCAR(t, []byte("repaired"))
+	if _, err := n.Put(ctx, bytes.NewReader(car)); err != nil {
+		t.Fatal(err)
+	}
+	reference(t, db, c)
+
+	// один узел потерял копию
+	var lost *fakeKubo
+	for _, k := range kubos {
+		if k.pinned(c) {
+			lost = k
+			break
+		}
+	}
+	delete(lost.pins, c)
+	delete(lost.cars, c)
+
+	rep, err := n.Repair(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Lost != 1 || rep.Under != 1 || rep.Copied != 1 || rep.Unavailable != 0 {
+		t.Fatalf("report %+v", rep)
+	}
+	if pinned, placed := placement(t, n, kubos, c); pinned != 2 || placed != 2 {
+		t.Fatalf("after repair: %d pinned, %d placements", pinned, placed)
+	}
+
+	// пин файла без записи о размещении принимается, лишние копии не пишутся
+	for _, k := range kubos {
+		k.pins[c] = true
+	}
+	rep, err = n.Repair(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Adopted != 1 || rep.Under != 0 || rep.Copied != 0 {
+		t.Fatalf("report %+v", rep)
+	}
+	if _, placed := placement(t, n, kubos, c); placed != 3 {
+		t.Fatalf("%d placements after adoption", placed)
+	}
+
+	// копий не осталось: cid недоступен, копировать не из чего
+	for _, k := range kubos {
+		delete(k.pins, c)
+		delete(k.cars, c)
+	}
+	rep, err = n.Repair(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Lost != 3 || rep.Unavailable != 1 || rep.Copied != 0 {
+		t.Fatalf("report %+v", rep)
+	}
+}
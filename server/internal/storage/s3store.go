@@ -0,0 +1,296 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// пустое тело, sha256 для подписи запросов без тела
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+type S3Options struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	Prefix    string
+	// бакет в пути, а не в имени хоста: так работают minio и большинство s3-совместимых
+	PathStyle bool
+	// куда принимать car перед отправкой, пусто - системный временный каталог
+	SpoolDir string
+}
+
+// car в s3-совместимом бакете под ключом prefix+cid+".car". put требует длину заранее,
+// поэтому car сначала принимается на диск с проверкой блоков, потом уходит одним запросом
+type S3Store struct {
+	opts     S3Options
+	endpoint *url.URL
+	http     *http.Client
+}
+
+func NewS3Store(opts S3Options) (*S3Store, error) {
+	u, err := url.Parse(opts.Endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("s3 endpoint %q: invalid url", opts.Endpoint)
+	}
+	if opts.Bucket == "" {
+		return nil, errors.New("s3 bucket required")
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	if opts.SpoolDir != "" {
+		if err := os.MkdirAll(opts.SpoolDir, 0o700); err != nil {
+			return nil, fmt.Errorf("create spool dir: %w", err)
+		}
+	}
+	return &S3Store{opts: opts, endpoint: u, http: &http.Client{}}, nil
+}
+
+func (s *S3Store) key(cid string) (string, error) {
+	k, err := blobKey(cid)
+	if err != nil {
+		return "", err
+	}
+	return s.opts.Prefix + k + ".car", nil
+}
+
+func (s *S3Store) Put(ctx context.Context, car io.Reader) (string, error) {
+	sp, err := spoolCAR(s.opts.SpoolDir, car)
+	if err != nil {
+		return "", err
+	}
+	defer sp.Close()
+	key, err := s.key(sp.root)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.do(ctx, http.MethodPut, key, nil, sp.file, sp.size, hex.EncodeToString(sp.sha256))
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return sp.root, nil
+}
+
+func (s *S3Store) Get(ctx context.Context, cid string) (io.ReadCloser, error) {
+	key, err := s.key(cid)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil, 0, emptySHA256)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Store) Stat(ctx context.Context, cid string) (BlobInfo, error) {
+	key, err := s.key(cid)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	resp, err := s.do(ctx, http.MethodHead, key, nil, nil, 0, emptySHA256)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	resp.Body.Close()
+	return BlobInfo{CID: cid, Size: resp.ContentLength}, nil
+}
+
+// удаление отсутствующего ключа в s3 не ошибка
+func (s *S3Store) Delete(ctx context.Context, cid string) error {
+	key, err := s.key(cid)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, 0, emptySHA256)
+	if errors.Is(err, ErrBlobNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3Store) List(ctx context.Context) ([]string, error) {
+	var out []string
+	token := ""
+	for {
+		q := url.Values{"list-type": {"2"}, "prefix": {s.opts.Prefix}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		resp, err := s.do(ctx, http.MethodGet, "", q, nil, 0, emptySHA256)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("parse s3 listing: %w", err)
+		}
+		for _, c := range page.Contents {
+			name, ok := strings.CutSuffix(strings.TrimPrefix(c.Key, s.opts.Prefix), ".car")
+			if ok && !strings.Contains(name, "/") {
+				out = append(out, name)
+			}
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return out, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// подписанный запрос к объекту key (пустой key - к бакету). 404 превращается в ErrBlobNotFound,
+// прочие ответы не 2xx - в ошибку с телом ответа
+func (s *S3Store) do(ctx context.Context, method, key string, query url.Values, body io.Reader, size int64, payloadHash string) (*http.Response, error) {
+	u := *s.endpoint
+	objPath := "/" + key
+	if s.opts.PathStyle {
+		objPath = "/" + s.opts.Bucket + objPath
+	} else {
+		u.Host = s.opts.Bucket + "." + u.Host
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + objPath
+	u.RawPath = ""
+	u.RawQuery = ""
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.Opaque = ""
+	req.URL.RawPath = awsEscapePath(u.Path)
+	req.URL.RawQuery = awsQuery(query)
+	if body != nil {
+		req.ContentLength = size
+	}
+	signV4(req, s.opts.AccessKey, s.opts.SecretKey, s.opts.Region, "s3", payloadHash, time.Now())
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound && key != "" {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s", ErrBlobNotFound, key)
+	}
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		return nil, fmt.Errorf("s3 %s %s: %s: %s", method, key, resp.Status, strings.TrimSpace(string(b)))
+	}
+	return resp, nil
+}
+
+// подпись aws sigv4 в заголовке Authorization. подписываются host, range и все x-amz-*
+func signV4(req *http.Request, accessKey, secretKey, region, service, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	day := amzDate[:8]
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	headers := map[string]string{"host": req.URL.Host}
+	for name, vals := range req.Header {
+		lname := strings.ToLower(name)
+		if strings.HasPrefix(lname, "x-amz-") || lname == "range" {
+			headers[lname] = strings.TrimSpace(strings.Join(vals, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonHeaders strings.Builder
+	for _, name := range names {
+		canonHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signed := strings.Join(names, ";")
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonHeaders.String(),
+		signed,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + region + "/" + service + "/aws4_request"
+	sum := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+	key := hmacSHA256([]byte("AWS4"+secretKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	sig := hex.EncodeToString(hmacSHA256(key, toSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKey+"/"+scope+", SignedHeaders="+signed+", Signature="+sig)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
+
+// экранирование sigv4: все, кроме unreserved из rfc 3986
+func awsEscape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && keepSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func awsEscapePath(p string) string {
+	return awsEscape(p, true)
+}
+
+// канонический query: ключи по порядку, пустые значения с "="
+func awsQuery(q url.Values) string {
+	if len(q) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vals := append([]string(nil), q[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, awsEscape(k, false)+"="+awsEscape(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
@@ -4,19 +4,36 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"time"
 
 	"dev.c0rex64.heroin/internal/ipfs"
 	"lukechampine.com/blake3"
 )
 
+var (
+	ErrBlake3Mismatch   = errors.New("blake3 mismatch")
+	ErrContentReleasing = errors.New("content is being released, retry the upload")
+)
+
 type Service struct {
-	ipfs     *ipfs.Client
-	pin      bool
-	replicas int
-	db       *sql.DB
+	ipfs      *ipfs.Client
+	pin       bool
+	replicas  int
+	db        *sql.DB
+	streamer  *CARStreamer
+	maxUpload int64
+	outboards *Outboards
+	quotas    *Quotas
+	nodes     *Nodes
+	blobs     BlobStore
+	carPolicy CARPolicy
+	blockIdx  *BlockIndex
+	retention VersionRetention
+	cache     *BlobCache
 }
 
 func New(ipfsClient *ipfs.Client, pin bool, replicas int) *Service {
@@ -27,51 +44,255 @@ func NewWithDB(ipfsClient *ipfs.Client, pin bool, replicas int, db *sql.DB) *Ser
 	return &Service{ipfs: ipfsClient, pin: pin, replicas: replicas, db: db}
 }
 
-func (s *Service) PutCAR(ctx context.Context, name, mime string, size int64, carChunks [][]byte, totalBlake3 []byte) (string, string, error) {
-	buf := bytes.Join(carChunks, nil)
-	if len(totalBlake3) > 0 {
-		calc := blake3Sum(buf)
-		if !bytes.Equal(calc, totalBlake3) {
-			return "", "", fmt.Errorf("blake3 mismatch")
-		}
-	}
-	cid, err := s.ipfs.AddCAR(ctx, buf)
+// потоковая загрузка в ipfs, maxUpload <= 0 снимает лимит
+func (s *Service) SetStreamer(st *CARStreamer, maxUpload int64) {
+	s.streamer = st
+	s.maxUpload = maxUpload
+	s.blobs = NewKuboStore(s.ipfs, st, s.pin)
+}
+
+// хранить car не в kubo, вызывается после SetStreamer
+func (s *Service) SetBlobStore(b BlobStore) {
+	s.blobs = b
+}
+
+// какие блоки принимаются в загружаемых car, по умолчанию любые проверенные
+func (s *Service) SetCARPolicy(p CARPolicy) {
+	s.carPolicy = p
+}
+
+// индекс блоков загрузок для дедупликации и gc блоков
+func (s *Service) SetBlockIndex(b *BlockIndex) {
+	s.blockIdx = b
+}
+
+// outboard строится в фоне после каждой загрузки
+func (s *Service) SetOutboards(o *Outboards) {
+	s.outboards = o
+}
+
+// квоты проверяются до импорта и ограничивают поток, без них загрузки не лимитируются по объему
+func (s *Service) SetQuotas(q *Quotas) {
+	s.quotas = q
+}
+
+func (s *Service) Quotas() *Quotas {
+	return s.quotas
+}
+
+// несколько узлов ipfs: запись с кворумом, чтение с живого узла, где есть копия.
+// без них все идет через единственный endpoint. репликация только поверх kubo:
+// local или s3 хранилище она бы молча подменила
+func (s *Service) SetNodes(n *Nodes) error {
+	if _, ok := s.blobs.(*KuboStore); s.blobs != nil && !ok { return ErrReplicatedBackend }
+	s.nodes = n
+	s.blobs = n
+	return nil
+}
+
+// горячий кэш car на диске перед хранилищем, без него каждое чтение идет в хранилище
+func (s *Service) SetBlobCache(c *BlobCache) {
+	s.cache = c
+}
+
+// car для отдачи: из кэша, если он есть
+func (s *Service) getCAR(ctx context.Context, cid string) (io.ReadCloser, error) {
+	if s.cache == nil { return s.blobs.Get(ctx, cid) }
+	return s.cache.Get(ctx, cid, func(ctx context.Context) (io.ReadCloser, error) { return s.blobs.Get(ctx, cid) })
+}
+
+func (s *Service) Nodes() *Nodes {
+	return s.nodes
+}
+
+// клиент единственного узла kubo, nil при репликации и при car не в kubo
+func (s *Service) IPFS() *ipfs.Client {
+	if k, ok := s.blobs.(*KuboStore); ok { return k.client }
+	return nil
+}
+
+// здоровье узлов kubo, через которые идет хранение. пусто, если car лежат не в kubo
+func (s *Service) IPFSHealth() []ipfs.Health {
+	if s.nodes != nil { return s.nodes.Health() }
+	if ic := s.IPFS(); ic != nil { return []ipfs.Health{ic.Health()} }
+	return nil
+}
+
+// car читается из потока один раз: лимит размера и blake3 считаются на лету по пути
+// в хранилище. отвергнутая после приема загрузка удаляется из хранилища, если на ее
+// cid не ссылаются другие файлы
+// deviceID - автор первой версии файла
+func (s *Service) PutCAR(ctx context.Context, userID, deviceID, name, mime string, size int64, car io.Reader, totalBlake3 []byte) (string, string, error) {
+	return s.putCAR(ctx, userID, deviceID, "", name, mime, size, car, totalBlake3)
+}
+
+// uploadID - фиксируемая возобновляемая загрузка, ее резерв в квоте не учитывается.
+// size объявлен клиентом: он проверяется по квоте до импорта, а в files пишется
+// число реально принятых байт
+func (s *Service) putCAR(ctx context.Context, userID, deviceID, uploadID, name, mime string, size int64, car io.Reader, totalBlake3 []byte) (string, string, error) {
+	imp, err := s.importCAR(ctx, userID, uploadID, size, car, totalBlake3)
 	if err != nil { return "", "", err }
-	if s.pin {
-		if err := s.ipfs.PinAdd(ctx, cid); err != nil { return "", "", err }
+	defer imp.release(ctx)
+	if s.db == nil { return imp.cid, imp.cid, nil }
+	fileID := generateFileID()
+	now := time.Now().Unix()
+	if err := s.insertFile(ctx, fileID, userID, deviceID, uploadID, name, mime, imp, now); err != nil {
+		s.abandon(ctx, imp)
+		return "", "", err
 	}
-	if s.db != nil {
-		fileID := generateFileID()
-		_, err := s.db.ExecContext(ctx, `INSERT INTO files (id, user_id, cid, name, mime, size_bytes, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
-			fileID, "system", cid, name, mime, size, time.Now().Unix())
-		if err != nil { return "", "", fmt.Errorf("store file metadata: %w", err) }
-		return fileID, cid, nil
+	s.recordCAR(ctx, imp, now)
+	return fileID, imp.cid, nil
+}
+
+// первую версию создает триггер, здесь дописываются ее автор и хеш. фиксируемая
+// загрузка получает результат в той же транзакции
+func (s *Service) insertFile(ctx context.Context, fileID, userID, deviceID, uploadID, name, mime string, imp *importedCAR, now int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil { return err }
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, `INSERT INTO files (id, user_id, cid, name, mime, size_bytes, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		fileID, userID, imp.cid, name, mime, imp.received, now)
+	if err != nil { return fmt.Errorf("store file metadata: %w", err) }
+	_, err = tx.ExecContext(ctx, `UPDATE file_versions SET blake3 = ?, device_id = ? WHERE file_id = ? AND version = 1`, imp.blake3, deviceID, fileID)
+	if err != nil { return fmt.Errorf("store file metadata: %w", err) }
+	if uploadID != "" {
+		_, err = tx.ExecContext(ctx, `UPDATE upload_commits SET state = 'committed', file_id = ?, cid = ? WHERE upload_id = ?`, fileID, imp.cid, uploadID)
+		if err != nil { return fmt.Errorf("store file metadata: %w", err) }
 	}
-	return cid, cid, nil
+	if err := tx.Commit(); err != nil { return fmt.Errorf("store file metadata: %w", err) }
+	return nil
+}
+
+// car, принятый хранилищем, до записи метаданных
+type importedCAR struct {
+	cid      string
+	received int64
+	blake3   []byte
+	v        *carValidator
+	quota    *reservation
+	db       *sql.DB
+	claim    int64
 }
 
-func (s *Service) GetCAR(ctx context.Context, cid string) ([][]byte, error) {
-	r, err := s.ipfs.ExportCAR(ctx, cid)
+// снять резерв квоты и отметку загрузки, когда строки с содержимым записаны или запись
+// не удалась. повторный вызов ничего не делает
+func (imp *importedCAR) release(ctx context.Context) {
+	imp.quota.release(ctx)
+	if imp.claim == 0 { return }
+	if _, err := imp.db.ExecContext(ctx, `DELETE FROM content_claims WHERE cid = ? AND claimed_at = ? AND releasing_at = 0`, imp.cid, imp.claim); err != nil {
+		slog.Warn("storage: drop upload claim", "cid", imp.cid, "error", err)
+	}
+	imp.claim = 0
+}
+
+// отпустить принятый car, когда строки, которые бы на него ссылались, записать не вышло
+func (s *Service) abandon(ctx context.Context, imp *importedCAR) {
+	imp.release(ctx)
+	s.discard(ctx, imp.cid)
+}
+
+func (s *Service) importCAR(ctx context.Context, userID, uploadID string, size int64, car io.Reader, totalBlake3 []byte) (*importedCAR, error) {
+	if s.blobs == nil { return nil, errors.New("blob store not configured") }
+	if s.maxUpload > 0 && size > s.maxUpload { return nil, &LimitError{Limit: s.maxUpload} }
+	var res *reservation
+	if s.quotas != nil && userID != "" {
+		r, err := s.quotas.reserve(ctx, userID, uploadID, size)
+		if err != nil { return nil, err }
+		res = r
+	}
+	imp, err := s.importReserved(ctx, size, res.stream(car, size), totalBlake3)
+	if err != nil {
+		res.release(ctx)
+		return nil, err
+	}
+	imp.quota = res
+	return imp, nil
+}
+
+// импорт в хранилище. car уже ограничен остатком квоты, сверх него поток обрывается
+// *QuotaError, сверх maxUpload - *LimitError, обе доходят до вызывающего как есть
+func (s *Service) importReserved(ctx context.Context, size int64, car io.Reader, totalBlake3 []byte) (*importedCAR, error) {
+	h := blake3.New(32, nil)
+	cr := NewCARReader(io.NopCloser(car), s.maxUpload)
+	// car разбирается до хранилища: битый или запрещенный блок обрывает загрузку сразу
+	v := newCARValidator(io.TeeReader(cr, h), s.carPolicy)
+	cid, err := s.blobs.Put(ctx, v)
 	if err != nil { return nil, err }
-	defer r.Close()
-	const chunkSize = 64 * 1024
-	var chunks [][]byte
-	buf := make([]byte, chunkSize)
-	for {
-		n, readErr := io.ReadFull(r, buf)
-		if n > 0 {
-			copyBuf := make([]byte, n)
-			copy(copyBuf, buf[:n])
-			chunks = append(chunks, copyBuf)
-		}
-		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
-			break
-		}
-		if readErr != nil {
-			return nil, readErr
-		}
+	received := cr.BytesRead()
+	if size > 0 && received != size {
+		s.discard(ctx, cid)
+		return nil, ErrSizeMismatch
+	}
+	sum := h.Sum(nil)
+	if len(totalBlake3) > 0 && !bytes.Equal(sum, totalBlake3) {
+		s.discard(ctx, cid)
+		return nil, ErrBlake3Mismatch
 	}
-	return chunks, nil
+	if st := v.Stats(); !sameCID(st.Root, cid) {
+		s.discard(ctx, cid)
+		return nil, fmt.Errorf("%w: stored as %s, header root %s", ErrBadCARHeader, cid, st.Root)
+	}
+	imp := &importedCAR{cid: cid, received: received, blake3: sum, v: v, db: s.db}
+	if s.db != nil {
+		at, err := s.claim(ctx, cid, received)
+		if err != nil { return nil, err }
+		imp.claim = at
+	}
+	if s.outboards != nil { s.outboards.Schedule(cid) }
+	return imp, nil
+}
+
+// отметить принятый car до записи файла: строка contents без ссылок и отметка в
+// content_claims. освобождение, которое началось раньше, загрузка не перебивает и
+// отступает; закончившееся раньше могло удалить только что записанный car, поэтому
+// после отметки он проверяется и при нужде закрепляется заново
+func (s *Service) claim(ctx context.Context, cid string, size int64) (int64, error) {
+	now := time.Now()
+	at := now.UnixNano()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil { return 0, err }
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, `INSERT INTO contents (cid, size_bytes, refcount, created_at) VALUES (?, ?, 0, ?) ON CONFLICT(cid) DO NOTHING`, cid, size, now.Unix())
+	if err != nil { return 0, fmt.Errorf("claim content: %w", err) }
+	// брошенное освобождение старше contentClaimTTL загрузка забирает себе
+	res, err := tx.ExecContext(ctx, `INSERT INTO content_claims (cid, claimed_at) VALUES (?, ?)
+		ON CONFLICT(cid) DO UPDATE SET claimed_at = excluded.claimed_at, releasing_at = 0 WHERE releasing_at <= ?`, cid, at, now.Add(-contentClaimTTL).UnixNano())
+	if err != nil { return 0, fmt.Errorf("claim content: %w", err) }
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		if err == nil { err = ErrContentReleasing }
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil { return 0, fmt.Errorf("claim content: %w", err) }
+	if err := s.PinAdd(ctx, cid); err != nil {
+		if _, err := s.db.ExecContext(ctx, `DELETE FROM content_claims WHERE cid = ? AND claimed_at = ?`, cid, at); err != nil { slog.Warn("storage: drop upload claim", "cid", cid, "error", err) }
+		return 0, fmt.Errorf("%w: %v", ErrContentReleasing, err)
+	}
+	return at, nil
+}
+
+// разбор car и его блоки в базу. строка contents к этому моменту уже есть
+func (s *Service) recordCAR(ctx context.Context, imp *importedCAR, now int64) {
+	st := imp.v.Stats()
+	_, err := s.db.ExecContext(ctx, `INSERT OR IGNORE INTO car_info (cid, car_version, blocks, max_block_bytes, created_at) VALUES (?, ?, ?, ?, ?)`,
+		imp.cid, st.Version, st.Blocks, st.MaxBlock, now)
+	if err != nil { slog.Warn("storage: store car info", "cid", imp.cid, "error", err) }
+	if s.blockIdx != nil {
+		if err := s.blockIdx.add(ctx, imp.cid, imp.v.blocks); err != nil { slog.Warn("storage: index blocks", "cid", imp.cid, "error", err) }
+	}
+}
+
+// отдать car потоком: чанки приходят по мере чтения из ipfs, отмена ctx обрывает запрос к kubo.
+// после закрытия канала чанков в канале ошибок лежит итог
+func (s *Service) StreamCAR(ctx context.Context, cid string) (<-chan []byte, <-chan error) {
+	if s.blobs == nil {
+		chunks := make(chan []byte)
+		errs := make(chan error, 1)
+		close(chunks)
+		errs <- errors.New("blob store not configured")
+		close(errs)
+		return chunks, errs
+	}
+	return streamReader(ctx, func() (io.ReadCloser, error) { return s.getCAR(ctx, cid) }, 1<<20)
 }
 
 func generateFileID() string {
@@ -84,9 +305,109 @@ func generateFileID() string {
 	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
 }
 
-func blake3Sum(b []byte) []byte {
-	s := blake3.Sum256(b)
-	out := make([]byte, 32)
-	copy(out, s[:])
-	return out
+// то же для диапазона байт, нужно для докачки после обрыва
+func (s *Service) StreamCARRange(ctx context.Context, cid string, offset, length int64) (<-chan []byte, <-chan error) {
+	return streamRange(ctx, s.StreamCAR, cid, offset, length)
+}
+
+// cid всех блоков dag, корень первым. клиент сравнивает со своими и дозапрашивает недостающие
+// хранилища без доступа к блокам отдают их разбором car
+func (s *Service) BlockCIDs(ctx context.Context, cid string) ([]string, error) {
+	if bs, ok := s.blobs.(blockSource); ok { return bs.BlockCIDs(ctx, cid) }
+	rc, err := s.getCAR(ctx, cid)
+	if err != nil { return nil, err }
+	defer rc.Close()
+	return carBlockCIDs(rc, cid)
+}
+
+// блок ищется в car корня: у хранилищ без доступа к блокам по отдельности нет индекса.
+// без корня блок сам считается корнем
+func (s *Service) Block(ctx context.Context, root, cid string) ([]byte, error) {
+	if bs, ok := s.blobs.(blockSource); ok { return bs.Block(ctx, cid) }
+	if root == "" { root = cid }
+	rc, err := s.getCAR(ctx, root)
+	if err != nil { return nil, err }
+	defer rc.Close()
+	return carBlock(rc, cid)
+}
+
+// outboard экспорта cid и его корень для доказательств в GetFile
+func (s *Service) Outboard(ctx context.Context, cid string) (OutboardReader, [32]byte, error) {
+	if s.outboards == nil { return nil, [32]byte{}, ErrOutboardNotReady }
+	return s.outboards.Open(ctx, cid)
+}
+
+// сверить то, что сейчас отдает хранилище, с сохраненным outboard. мимо кэша
+func (s *Service) VerifyStored(ctx context.Context, cid string) error {
+	if s.outboards == nil { return errors.New("outboards not configured") }
+	ob, root, err := s.outboards.Open(ctx, cid)
+	if err != nil { return err }
+	defer ob.Close()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	chunks, errs := streamReader(ctx, func() (io.ReadCloser, error) { return s.blobs.Get(ctx, cid) }, 1<<20)
+	if err := verifyCAR(ctx, chunks, io.NewSectionReader(ob, 0, 1<<62), root[:]); err != nil { return err }
+	return <-errs
+}
+
+// отпустить отвергнутую загрузку. удалить car сразу нельзя: на cid могут ссылаться файлы
+// или его только что записала другая загрузка. он записывается строкой contents без
+// ссылок и проходит обычное освобождение, которое такие cid пропускает, их подберет
+// сверка пинов
+func (s *Service) discard(ctx context.Context, cid string) {
+	if s.db == nil {
+		if err := s.blobs.Delete(ctx, cid); err != nil { slog.Warn("storage: discard rejected upload", "cid", cid, "error", err) }
+		if s.cache != nil { s.cache.Remove(cid) }
+		return
+	}
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO contents (cid, size_bytes, refcount, created_at) VALUES (?, 0, 0, ?) ON CONFLICT(cid) DO NOTHING`, cid, time.Now().Unix()); err != nil {
+		slog.Warn("storage: discard rejected upload", "cid", cid, "error", err)
+		return
+	}
+	if _, err := s.release(ctx, cid); err != nil { slog.Warn("storage: discard rejected upload", "cid", cid, "error", err) }
+}
+
+// вернуть потерянное содержимое, если хранилище это умеет, иначе только проверить,
+// что оно на месте. при репликации хватает одной копии, остальные дописывает восстановление
+func (s *Service) PinAdd(ctx context.Context, cid string) error {
+	if p, ok := s.blobs.(pinner); ok { return p.Pin(ctx, cid) }
+	_, err := s.blobs.Stat(ctx, cid)
+	return err
+}
+
+// отпустить cid в хранилище, при репликации - на всех узлах. из кэша он уходит сразу
+func (s *Service) PinRm(ctx context.Context, cid string) error {
+	if s.cache != nil { s.cache.Remove(cid) }
+	return s.blobs.Delete(ctx, cid)
+}
+
+// для экспорта аккаунта. мимо кэша: экспорт читает все файлы подряд по разу и
+// вытеснил бы горячие записи
+func (s *Service) ExportCAR(ctx context.Context, cid string) (io.ReadCloser, error) {
+	return s.blobs.Get(ctx, cid)
+}
+
+// для импорта аккаунта: car проходит те же проверки, что и загрузка, квоту на весь
+// архив заранее занимает ReserveQuota. finish вызывают один раз: с keep после записи
+// строк, которые ссылаются на cid, он дописывает разбор car и индекс блоков; без keep
+// car неудавшегося импорта отпускается, если на него никто не ссылается
+func (s *Service) ImportCAR(ctx context.Context, car io.Reader, size int64) (string, func(ctx context.Context, keep bool), error) {
+	imp, err := s.importCAR(ctx, "", "", size, car, nil)
+	if err != nil { return "", nil, err }
+	return imp.cid, func(ctx context.Context, keep bool) {
+		if !keep {
+			s.abandon(ctx, imp)
+			return
+		}
+		imp.release(ctx)
+		if s.db != nil { s.recordCAR(ctx, imp, time.Now().Unix()) }
+	}, nil
+}
+
+// занять квоту userID под импорт аккаунта, release снимает резерв. без квот не делает ничего
+func (s *Service) ReserveQuota(ctx context.Context, userID string, size int64) (func(), error) {
+	if s.quotas == nil || size <= 0 { return func() {}, nil }
+	r, err := s.quotas.reserve(ctx, userID, "", size)
+	if err != nil { return nil, err }
+	return func() { r.release(ctx) }, nil
 }
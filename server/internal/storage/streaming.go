@@ -1,31 +1,33 @@
 package storage
 
 import (
+    "bufio"
+    "bytes"
     "context"
+    "encoding/json"
     "errors"
     "fmt"
     "io"
-    "net/http"
-    "sync"
+    "mime/multipart"
+    
+    "dev.c0rex64.heroin/internal/ipfs"
+    "dev.c0rex64.heroin/pkg/verify"
 )
 
-// car стример для больших файлов
+// car стример для больших файлов. запросы идут через клиент kubo с его таймаутами,
+// повторами и предохранителем
 type CARStreamer struct {
-    ipfsEndpoint string
-    httpClient   *http.Client
-    chunkSize    int
+    client    *ipfs.Client
+    chunkSize int
 }
 
-func NewCARStreamer(endpoint string, chunkSize int) *CARStreamer {
+func NewCARStreamer(client *ipfs.Client, chunkSize int) *CARStreamer {
     if chunkSize <= 0 {
         chunkSize = 1024 * 1024 // 1mb по умолчанию
     }
     
     return &CARStreamer{
-        ipfsEndpoint: endpoint,
-        httpClient: &http.Client{
-            Timeout: 0, // без таймаута для стриминга
-        },
+        client:    client,
         chunkSize: chunkSize,
     }
 }
@@ -40,24 +42,12 @@ func (s *CARStreamer) StreamCAR(ctx context.Context, cid string) (<-chan []byte,
         defer close(errChan)
         
         // запрос к ipfs
-        url := fmt.Sprintf("%s/api/v0/dag/export?arg=%s", s.ipfsEndpoint, cid)
-        req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
+        body, err := s.client.ExportCAR(ctx, cid)
         if err != nil {
             errChan <- err
             return
         }
-        
-        resp, err := s.httpClient.Do(req)
-        if err != nil {
-            errChan <- err
-            return
-        }
-        defer resp.Body.Close()
-        
-        if resp.StatusCode != http.StatusOK {
-            errChan <- fmt.Errorf("ipfs error: %s", resp.Status)
-            return
-        }
+        defer body.Close()
         
         // читаем чанками
         buffer := make([]byte, s.chunkSize)
@@ -69,7 +59,7 @@ func (s *CARStreamer) StreamCAR(ctx context.Context, cid string) (<-chan []byte,
             default:
             }
             
-            n, err := resp.Body.Read(buffer)
+            n, err := body.Read(buffer)
             if n > 0 {
                 chunk := make([]byte, n)
                 copy(chunk, buffer[:n])
@@ -95,98 +85,228 @@ func (s *CARStreamer) StreamCAR(ctx context.Context, cid string) (<-chan []byte,
     return chunkChan, errChan
 }
 
-// загрузить car стримом
-func (s *CARStreamer) UploadCAR(ctx context.Context, chunks <-chan []byte) (string, error) {
-    // создаем pipe для стриминга
-    pr, pw := io.Pipe()
-    
-    var wg sync.WaitGroup
-    var uploadErr error
-    var cid string
+// экспорт car одним reader, Close обрывает запрос к kubo
+func (s *CARStreamer) Export(ctx context.Context, cid string) (io.ReadCloser, error) {
+    return s.client.ExportCAR(ctx, cid)
+}
+
+var ErrRangeNotSatisfiable = errors.New("range not satisfiable")
+
+// отдать диапазон [offset, offset+length) car потоком, length 0 - до конца.
+// dag/export не умеет смещения, поэтому начало пропускается на нашей стороне,
+// а после конца диапазона запрос к kubo обрывается
+func (s *CARStreamer) StreamCARRange(ctx context.Context, cid string, offset, length int64) (<-chan []byte, <-chan error) {
+    return streamRange(ctx, s.StreamCAR, cid, offset, length)
+}
+
+// диапазон поверх потока всего car: начало пропускается, после конца диапазона
+// поток обрывается отменой ctx источника
+func streamRange(ctx context.Context, stream func(context.Context, string) (<-chan []byte, <-chan error), cid string, offset, length int64) (<-chan []byte, <-chan error) {
+    chunkChan := make(chan []byte, 2)
+    errChan := make(chan error, 1)
+    if offset < 0 || length < 0 {
+        close(chunkChan)
+        errChan <- ErrRangeNotSatisfiable
+        close(errChan)
+        return chunkChan, errChan
+    }
     
-    // горутина для записи чанков в pipe
-    wg.Add(1)
     go func() {
-        defer wg.Done()
-        defer pw.Close()
+        defer close(chunkChan)
+        defer close(errChan)
         
-        for chunk := range chunks {
+        upCtx, cancel := context.WithCancel(ctx)
+        defer cancel()
+        upChunks, upErrs := stream(upCtx, cid)
+        
+        var pos int64
+        remaining := length
+        for chunk := range upChunks {
+            start := pos
+            pos += int64(len(chunk))
+            if pos <= offset {
+                continue
+            }
+            if start < offset {
+                chunk = chunk[offset-start:]
+            }
+            if length > 0 {
+                if int64(len(chunk)) > remaining {
+                    chunk = chunk[:remaining]
+                }
+                remaining -= int64(len(chunk))
+            }
             select {
+            case chunkChan <- chunk:
             case <-ctx.Done():
-                pw.CloseWithError(ctx.Err())
+                errChan <- ctx.Err()
                 return
-            default:
             }
-            
-            if _, err := pw.Write(chunk); err != nil {
-                pw.CloseWithError(err)
+            if length > 0 && remaining == 0 {
+                // диапазон отдан, остаток экспорта не нужен
+                cancel()
+                for range upChunks {
+                }
                 return
             }
         }
+        if err := <-upErrs; err != nil {
+            errChan <- err
+            return
+        }
+        // пустой car не бывает, так что смещение за концом всегда ошибка
+        if pos <= offset {
+            errChan <- ErrRangeNotSatisfiable
+        }
     }()
     
-    // горутина для загрузки в ipfs
-    wg.Add(1)
+    return chunkChan, errChan
+}
+
+var errRequestDone = errors.New("dag import request finished")
+
+// загрузить car из канала чанков, канал должен быть закрыт в конце
+func (s *CARStreamer) UploadCAR(ctx context.Context, chunks <-chan []byte) (string, error) {
+    // создаем pipe для стриминга
+    pr, pw := io.Pipe()
+    
+    // горутина для записи чанков в pipe
     go func() {
-        defer wg.Done()
-        
-        url := fmt.Sprintf("%s/api/v0/dag/import", s.ipfsEndpoint)
-        req, err := http.NewRequestWithContext(ctx, "POST", url, pr)
-        if err != nil {
-            uploadErr = err
-            return
+        for chunk := range chunks {
+            if _, err := pw.Write(chunk); err != nil {
+                // дочитываем канал, чтобы не заблокировать отправителя
+                for range chunks {
+                }
+                return
+            }
         }
-        
-        req.Header.Set("Content-Type", "application/vnd.ipld.car")
-        
-        resp, err := s.httpClient.Do(req)
-        if err != nil {
-            uploadErr = err
-            return
+        pw.Close()
+    }()
+    
+    cid, err := s.ImportCAR(ctx, pr, false)
+    pr.CloseWithError(errRequestDone)
+    return cid, err
+}
+
+// импортировать car из r потоковым multipart запросом в dag/import, без буфера в памяти.
+// корень берется из заголовка car, так что работает и без пина корней
+func (s *CARStreamer) ImportCAR(ctx context.Context, r io.Reader, pinRoots bool) (string, error) {
+    br := bufio.NewReaderSize(r, s.chunkSize)
+    roots, header, err := readCARHeader(br)
+    if err != nil {
+        return "", err
+    }
+    body := io.MultiReader(bytes.NewReader(header), br)
+    
+    pr, pw := io.Pipe()
+    mw := multipart.NewWriter(pw)
+    
+    // горутина пишет multipart тело в pipe по мере чтения car,
+    // ошибка чтения источника (лимит, обрыв стрима) важнее ошибки транспорта
+    srcErr := make(chan error, 1)
+    go func() {
+        fw, err := mw.CreateFormFile("file", "data.car")
+        if err == nil {
+            _, err = io.CopyBuffer(fw, body, make([]byte, s.chunkSize))
         }
-        defer resp.Body.Close()
-        
-        if resp.StatusCode != http.StatusOK {
-            uploadErr = fmt.Errorf("ipfs error: %s", resp.Status)
-            return
+        if err == nil {
+            err = mw.Close()
         }
-        
-        // парсим cid из ответа
-        // тут должен быть json парсинг
-        // пока заглушка
-        cid = "bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi"
+        srcErr <- err
+        pw.CloseWithError(err)
     }()
     
-    wg.Wait()
-    
-    if uploadErr != nil {
-        return "", uploadErr
+    resp, err := s.client.DagImport(ctx, pr, mw.FormDataContentType(), pinRoots)
+    if err != nil {
+        pr.CloseWithError(errRequestDone)
+        // транспорт падает уже после того, как писатель отдал свою ошибку
+        select {
+        case copyErr := <-srcErr:
+            if copyErr != nil && !errors.Is(copyErr, errRequestDone) {
+                return "", copyErr
+            }
+        default:
+        }
+        return "", err
     }
+    defer resp.Close()
+    // останавливаем писателя, если kubo ответил раньше конца тела
+    defer pr.CloseWithError(errRequestDone)
     
-    if cid == "" {
-        return "", errors.New("no cid returned")
+    // ответ ndjson, при пине корней в нем есть Root, ошибки импорта приходят как Message
+    dec := json.NewDecoder(resp)
+    for {
+        var out struct {
+            Root *struct {
+                Cid struct {
+                    Path string `json:"/"`
+                } `json:"Cid"`
+                PinErrorMsg string `json:"PinErrorMsg"`
+            } `json:"Root"`
+            Message string `json:"Message"`
+            Type    string `json:"Type"`
+        }
+        if err := dec.Decode(&out); err == io.EOF {
+            break
+        } else if err != nil {
+            return "", fmt.Errorf("parse dag import response: %w", err)
+        }
+        if out.Type == "error" {
+            return "", fmt.Errorf("ipfs error: %s", out.Message)
+        }
+        if out.Root != nil && out.Root.PinErrorMsg != "" {
+            return "", fmt.Errorf("pin root %s: %s", out.Root.Cid.Path, out.Root.PinErrorMsg)
+        }
     }
     
-    return cid, nil
+    return roots[0], nil
 }
 
-// верифицировать car стримом
-func (s *CARStreamer) VerifyCAR(ctx context.Context, chunks <-chan []byte, expectedHash []byte) error {
-    // тут должна быть blake3 проверка по чанкам
-    // пока заглушка
-    
-    for range chunks {
-        select {
-        case <-ctx.Done():
-            return ctx.Err()
-        default:
-        }
+// верифицировать car стримом по bao outboard, порча находится с точностью до группы в 1 кб.
+// при ошибке вызывающий должен отменить ctx источника чанков
+func (s *CARStreamer) VerifyCAR(ctx context.Context, chunks <-chan []byte, outboard io.Reader, expectedHash []byte) error {
+    return verifyCAR(ctx, chunks, outboard, expectedHash)
+}
+
+func verifyCAR(ctx context.Context, chunks <-chan []byte, outboard io.Reader, expectedHash []byte) error {
+    if len(expectedHash) != 32 {
+        return errors.New("blake3 must be 32 bytes")
     }
+    pr, pw := io.Pipe()
+    go func() {
+        var werr error
+        for chunk := range chunks {
+            if werr == nil {
+                _, werr = pw.Write(chunk)
+            }
+        }
+        pw.Close()
+    }()
+    defer pr.Close()
+    stop := context.AfterFunc(ctx, func() { pr.CloseWithError(ctx.Err()) })
+    defer stop()
     
+    if err := verify.Stream(nil, pr, outboard, [32]byte(expectedHash)); err != nil {
+        return err
+    }
+    // outboard задает длину, хвост сверх нее тоже порча
+    var b [1]byte
+    if n, _ := pr.Read(b[:]); n > 0 {
+        return errors.New("car longer than outboard")
+    }
     return nil
 }
 
-// car reader для чтения с лимитами
+// превышен лимит размера загрузки
+type LimitError struct {
+    Limit int64
+}
+
+func (e *LimitError) Error() string {
+    return fmt.Sprintf("size limit exceeded: %d bytes", e.Limit)
+}
+
+// car reader для чтения с лимитами, maxBytes <= 0 значит без лимита
 type CARReader struct {
     source    io.ReadCloser
     bytesRead int64
@@ -201,8 +321,24 @@ func NewCARReader(source io.ReadCloser, maxBytes int64) *CARReader {
 }
 
 func (r *CARReader) Read(p []byte) (n int, err error) {
+    if r.maxBytes <= 0 {
+        n, err = r.source.Read(p)
+        r.bytesRead += int64(n)
+        return n, err
+    }
+    
     if r.bytesRead >= r.maxBytes {
-        return 0, errors.New("size limit exceeded")
+        // ровно на лимите: ошибка только если источник не закончился
+        var probe [1]byte
+        for {
+            n, err := r.source.Read(probe[:])
+            if n > 0 {
+                return 0, &LimitError{Limit: r.maxBytes}
+            }
+            if err != nil {
+                return 0, err
+            }
+        }
     }
     
     remaining := r.maxBytes - r.bytesRead
@@ -216,6 +352,10 @@ func (r *CARReader) Read(p []byte) (n int, err error) {
     return n, err
 }
 
+func (r *CARReader) BytesRead() int64 {
+    return r.bytesRead
+}
+
 func (r *CARReader) Close() error {
     return r.source.Close()
 }
@@ -0,0 +1,428 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"dev.c0rex64.heroin/pkg/verify"
+	"github.com/google/uuid"
+	"lukechampine.com/blake3"
+)
+
+// один чанк с запасом помещается в лимит сообщения grpc (4 мб)
+const MaxUploadChunk = 2 << 20
+
+var (
+	ErrUploadNotFound   = errors.New("upload not found")
+	ErrChunkOutOfRange  = errors.New("chunk outside declared size")
+	ErrChunkTooLarge    = errors.New("chunk too large")
+	ErrUploadIncomplete = errors.New("upload incomplete")
+	ErrBadUploadSize    = errors.New("invalid upload size")
+	ErrUploadCommitting = errors.New("upload is being committed")
+	ErrUploadCommitted  = errors.New("upload already committed")
+)
+
+// состояния загрузки, см. 022_upload_commit.sql
+const (
+	uploadOpen       = "open"
+	uploadCommitting = "committing"
+	uploadCommitted  = "committed"
+)
+
+// принятый диапазон байт [Start, End)
+type ByteRange struct {
+	Start int64
+	End   int64
+}
+
+type Upload struct {
+	ID        string
+	UserID    string
+	Name      string
+	Mime      string
+	Size      int64
+	Blake3    []byte
+	ExpiresAt time.Time
+	Ranges    []ByteRange
+	State     string
+	// результат фиксации, только в состоянии committed
+	FileID string
+	CID    string
+}
+
+// возобновляемые загрузки: чанки пишутся по смещению в файл staging каталога,
+// принятые диапазоны хранятся в sqlite, в ipfs уходит только проверенный целиком car
+type Uploads struct {
+	svc *Service
+	db  *sql.DB
+	dir string
+	ttl time.Duration
+}
+
+func NewUploads(svc *Service, db *sql.DB, dir string, ttl time.Duration) (*Uploads, error) {
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create staging dir: %w", err)
+	}
+	// фиксацию, захваченную до рестарта, уже никто не доведет: ее вел упавший процесс.
+	// без этого загрузка висела бы в committing до истечения. файл, который фиксация
+	// успела записать, остается: committed пишется в одной транзакции с ним
+	if _, err := db.ExecContext(context.Background(), `DELETE FROM upload_commits WHERE state = 'committing'`); err != nil {
+		return nil, fmt.Errorf("reopen interrupted commits: %w", err)
+	}
+	return &Uploads{svc: svc, db: db, dir: dir, ttl: ttl}, nil
+}
+
+func (u *Uploads) path(id string) string {
+	return filepath.Join(u.dir, id+".part")
+}
+
+func (u *Uploads) Begin(ctx context.Context, userID, name, mime string, size int64, totalBlake3 []byte) (*Upload, error) {
+	if size <= 0 {
+		return nil, ErrBadUploadSize
+	}
+	if u.svc.maxUpload > 0 && size > u.svc.maxUpload {
+		return nil, &LimitError{Limit: u.svc.maxUpload}
+	}
+	if len(totalBlake3) != 32 {
+		return nil, errors.New("blake3 must be 32 bytes")
+	}
+	id := uuid.NewString()
+	// файл сразу нужного размера, на большинстве фс он разреженный
+	f, err := os.OpenFile(u.path(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("create staging file: %w", err)
+	}
+	err = f.Truncate(size)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(u.path(id))
+		return nil, fmt.Errorf("allocate staging file: %w", err)
+	}
+	now := time.Now()
+	expires := now.Add(u.ttl)
+	if err := u.insert(ctx, id, userID, name, mime, size, totalBlake3, now, expires); err != nil {
+		os.Remove(u.path(id))
+		return nil, err
+	}
+	return &Upload{ID: id, UserID: userID, Name: name, Mime: mime, Size: size, Blake3: totalBlake3, ExpiresAt: expires}, nil
+}
+
+// объявленный размер резервируется в квоте до фиксации или истечения загрузки:
+// строка загрузки и есть резерв, квота проверяется в той же транзакции
+func (u *Uploads) insert(ctx context.Context, id, userID, name, mime string, size int64, totalBlake3 []byte, now, expires time.Time) error {
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, `INSERT INTO uploads (id, user_id, name, mime, size_bytes, blake3, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, userID, name, mime, size, totalBlake3, now.Unix(), expires.Unix())
+	if err != nil {
+		return fmt.Errorf("begin upload: %w", err)
+	}
+	if u.svc.quotas != nil {
+		if _, _, err := u.svc.quotas.admit(ctx, tx, userID, "", size); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("begin upload: %w", err)
+	}
+	return nil
+}
+
+// записать чанк по смещению. повтор того же чанка безопасен: данные перезаписываются,
+// диапазоны сливаются. каждый принятый чанк продлевает срок жизни загрузки.
+// с bao доказательством чанк проверяется по blake3 загрузки до записи
+func (u *Uploads) WriteChunk(ctx context.Context, userID, id string, offset int64, data, proof []byte) (int64, error) {
+	if len(data) > MaxUploadChunk {
+		return 0, ErrChunkTooLarge
+	}
+	up, err := u.load(ctx, userID, id, false)
+	if err != nil {
+		return 0, err
+	}
+	if up.State != uploadOpen {
+		return 0, ErrUploadCommitted
+	}
+	end := offset + int64(len(data))
+	if offset < 0 || end > up.Size {
+		return 0, ErrChunkOutOfRange
+	}
+	if len(proof) > 0 {
+		if err := verifyChunk(proof, data, offset, up.Size, up.Blake3); err != nil {
+			return 0, err
+		}
+	}
+	if len(data) > 0 {
+		f, err := os.OpenFile(u.path(id), os.O_WRONLY, 0)
+		if err != nil {
+			return 0, fmt.Errorf("open staging file: %w", err)
+		}
+		_, err = f.WriteAt(data, offset)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return 0, fmt.Errorf("write staging file: %w", err)
+		}
+		if err := u.addRange(ctx, id, offset, end); err != nil {
+			return 0, err
+		}
+	}
+	return u.received(ctx, id)
+}
+
+func verifyChunk(proof, data []byte, offset, size int64, sum []byte) error {
+	if n, err := verify.ProofSize(proof); err != nil || n != size {
+		return verify.ErrBadProof
+	}
+	return verify.Verify(proof, data, offset, [32]byte(sum))
+}
+
+func (u *Uploads) addRange(ctx context.Context, id string, start, end int64) error {
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	// все пересекающиеся и соседние диапазоны поглощаются новым
+	var lo, hi sql.NullInt64
+	err = tx.QueryRowContext(ctx, `SELECT MIN(range_start), MAX(range_end) FROM upload_ranges WHERE upload_id = ? AND range_start <= ? AND range_end >= ?`,
+		id, end, start).Scan(&lo, &hi)
+	if err != nil {
+		return fmt.Errorf("merge ranges: %w", err)
+	}
+	if lo.Valid && lo.Int64 < start {
+		start = lo.Int64
+	}
+	if hi.Valid && hi.Int64 > end {
+		end = hi.Int64
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM upload_ranges WHERE upload_id = ? AND range_start <= ? AND range_end >= ?`, id, end, start); err != nil {
+		return fmt.Errorf("merge ranges: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO upload_ranges (upload_id, range_start, range_end) VALUES (?, ?, ?)`, id, start, end); err != nil {
+		return fmt.Errorf("merge ranges: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE uploads SET expires_at = ? WHERE id = ?`, time.Now().Add(u.ttl).Unix(), id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (u *Uploads) received(ctx context.Context, id string) (int64, error) {
+	var n int64
+	err := u.db.QueryRowContext(ctx, `SELECT COALESCE(SUM(range_end - range_start), 0) FROM upload_ranges WHERE upload_id = ?`, id).Scan(&n)
+	return n, err
+}
+
+func (u *Uploads) Query(ctx context.Context, userID, id string) (*Upload, error) {
+	return u.load(ctx, userID, id, true)
+}
+
+func (u *Uploads) load(ctx context.Context, userID, id string, withRanges bool) (*Upload, error) {
+	var q querier = u.db
+	if withRanges {
+		// строка и куски из одного снимка: иначе между запросами Commit другого
+		// вызова успеет удалить куски, и загрузка покажется открытой и неполной
+		tx, err := u.db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("load upload: %w", err)
+		}
+		defer tx.Rollback()
+		q = tx
+	}
+	var up Upload
+	var name, mime, fileID, cid sql.NullString
+	var expires int64
+	err := q.QueryRowContext(ctx, `SELECT u.id, u.user_id, u.name, u.mime, u.size_bytes, u.blake3, u.expires_at,
+		COALESCE(c.state, 'open'), c.file_id, c.cid FROM uploads u LEFT JOIN upload_commits c ON c.upload_id = u.id WHERE u.id = ? AND u.user_id = ?`, id, userID).
+		Scan(&up.ID, &up.UserID, &name, &mime, &up.Size, &up.Blake3, &expires, &up.State, &fileID, &cid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUploadNotFound
+		}
+		return nil, fmt.Errorf("load upload: %w", err)
+	}
+	up.Name, up.Mime = name.String, mime.String
+	up.FileID, up.CID = fileID.String, cid.String
+	up.ExpiresAt = time.Unix(expires, 0)
+	if time.Now().After(up.ExpiresAt) {
+		return nil, ErrUploadNotFound
+	}
+	if !withRanges {
+		return &up, nil
+	}
+	rows, err := q.QueryContext(ctx, `SELECT range_start, range_end FROM upload_ranges WHERE upload_id = ? ORDER BY range_start`, id)
+	if err != nil {
+		return nil, fmt.Errorf("load ranges: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r ByteRange
+		if err := rows.Scan(&r.Start, &r.End); err != nil {
+			return nil, err
+		}
+		up.Ranges = append(up.Ranges, r)
+	}
+	return &up, rows.Err()
+}
+
+// проверить полноту и blake3 staging файла, затем импортировать его в ipfs.
+// при несовпадении хеша загрузка остается, клиент может перезалить битые куски.
+// фиксацию захватывает один вызов, параллельный получает ErrUploadCommitting, повторный
+// после успеха - тот же файл
+func (u *Uploads) Commit(ctx context.Context, userID, id string) (string, string, error) {
+	up, err := u.load(ctx, userID, id, true)
+	if err != nil {
+		return "", "", err
+	}
+	switch up.State {
+	case uploadCommitted:
+		return up.FileID, up.CID, nil
+	case uploadCommitting:
+		return "", "", ErrUploadCommitting
+	}
+	if len(up.Ranges) != 1 || up.Ranges[0].Start != 0 || up.Ranges[0].End != up.Size {
+		return "", "", ErrUploadIncomplete
+	}
+	claimed, err := u.claim(ctx, userID, id)
+	if err != nil {
+		return "", "", err
+	}
+	if !claimed {
+		// опередил другой вызов или janitor
+		up, err := u.load(ctx, userID, id, false)
+		if err != nil {
+			return "", "", err
+		}
+		if up.State == uploadCommitted {
+			return up.FileID, up.CID, nil
+		}
+		return "", "", ErrUploadCommitting
+	}
+	fileID, cid, err := u.commit(ctx, up)
+	if err != nil {
+		// вернуть загрузку клиенту, даже если его запрос уже отменен
+		if _, rerr := u.db.ExecContext(context.WithoutCancel(ctx), `DELETE FROM upload_commits WHERE upload_id = ? AND state = 'committing'`, id); rerr != nil {
+			slog.Warn("uploads: reopen upload", "upload_id", id, "error", rerr)
+		}
+		return "", "", err
+	}
+	// строка с результатом остается до истечения, staging больше не нужен
+	if err := os.Remove(u.path(id)); err != nil && !os.IsNotExist(err) {
+		slog.Warn("uploads: remove staging file", "upload_id", id, "error", err)
+	}
+	if _, err := u.db.ExecContext(ctx, `DELETE FROM upload_ranges WHERE upload_id = ?`, id); err != nil {
+		slog.Warn("uploads: delete ranges", "upload_id", id, "error", err)
+	}
+	return fileID, cid, nil
+}
+
+// захватить фиксацию. вместе с захватом продлевается срок, чтобы janitor не удалил
+// staging из-под импорта. false - загрузку опередил другой вызов или она истекла
+func (u *Uploads) claim(ctx context.Context, userID, id string) (bool, error) {
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	now := time.Now()
+	res, err := tx.ExecContext(ctx, `INSERT INTO upload_commits (upload_id, state) SELECT id, 'committing' FROM uploads
+		WHERE id = ? AND user_id = ? AND expires_at >= ? ON CONFLICT(upload_id) DO NOTHING`, id, userID, now.Unix())
+	if err != nil {
+		return false, fmt.Errorf("commit upload: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE uploads SET expires_at = ? WHERE id = ?`, now.Add(u.ttl).Unix(), id); err != nil {
+		return false, fmt.Errorf("commit upload: %w", err)
+	}
+	return true, tx.Commit()
+}
+
+// состояние committed пишет putCAR в одной транзакции с файлом
+func (u *Uploads) commit(ctx context.Context, up *Upload) (string, string, error) {
+	f, err := os.Open(u.path(up.ID))
+	if err != nil {
+		return "", "", fmt.Errorf("open staging file: %w", err)
+	}
+	defer f.Close()
+	h := blake3.New(32, nil)
+	if _, err := io.Copy(h, f); err != nil {
+		return "", "", fmt.Errorf("hash staging file: %w", err)
+	}
+	if !bytes.Equal(h.Sum(nil), up.Blake3) {
+		return "", "", ErrBlake3Mismatch
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", "", err
+	}
+	return u.svc.putCAR(ctx, up.UserID, "", up.ID, up.Name, up.Mime, up.Size, f, up.Blake3)
+}
+
+// удалить просроченную загрузку. условие повторяется в DELETE: загрузку, которую
+// успели захватить на фиксацию, не трогаем
+func (u *Uploads) remove(ctx context.Context, id string) {
+	res, err := u.db.ExecContext(ctx, `DELETE FROM uploads WHERE id = ? AND expires_at < ?`, id, time.Now().Unix())
+	if err != nil {
+		slog.Warn("uploads: delete upload", "upload_id", id, "error", err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return
+	}
+	if err := os.Remove(u.path(id)); err != nil && !os.IsNotExist(err) {
+		slog.Warn("uploads: remove staging file", "upload_id", id, "error", err)
+	}
+}
+
+// периодически удалять просроченные загрузки вместе с файлами
+func (u *Uploads) StartJanitor(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			u.expire(ctx)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (u *Uploads) expire(ctx context.Context) {
+	rows, err := u.db.QueryContext(ctx, `SELECT id FROM uploads WHERE expires_at < ?`, time.Now().Unix())
+	if err != nil {
+		slog.Error("uploads: list expired", "error", err)
+		return
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if rows.Scan(&id) == nil {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+	for _, id := range ids {
+		u.remove(ctx, id)
+	}
+}
@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"testing"
+
+	"dev.c0rex64.heroin/internal/store"
+	"github.com/ipfs/go-cid"
+	"lukechampine.com/blake3"
+)
+
+func newTestDB(t *testing.T) *store.DB {
+	t.Helper()
+	db, err := store.Open(context.Background(), "file:"+t.TempDir()+"/storage.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.SQL.Exec(`INSERT INTO users (id, username, server_salt, password_hash, second_factor_secret, created_at) VALUES ('u1', 'u1', x'00', x'00', x'00', 0)`); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// car v1 из одного raw блока с этим блоком в корне
+func testCAR(t *testing.T, data []byte) ([]byte, string) {
+	t.Helper()
+	c, err := cid.Prefix{Version: 1, Codec: cid.Raw, MhType: 0x12, MhLength: -1}.Sum(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	link := append([]byte{0x00}, c.Bytes()...)
+	// dag-cbor {"roots": [cid], "version": 1}
+	hdr := []byte{0xa2, 0x65, 'r', 'o', 'o', 't', 's', 0x81, 0xd8, 0x2a, 0x58, byte(len(link))}
+	hdr = append(hdr, link...)
+	hdr = append(hdr, 0x67, 'v', 'e', 'r', 's', 'i', 'o', 'n', 0x01)
+	car := binary.AppendUvarint(nil, uint64(len(hdr)))
+	car = append(car, hdr...)
+	car = binary.AppendUvarint(car, uint64(len(c.Bytes())+len(data)))
+	car = append(car, c.Bytes()...)
+	car = append(car, data...)
+	return car, c.String()
+}
+
+func newTestUploads(t *testing.T) (*Uploads, *store.DB) {
+	t.Helper()
+	db := newTestDB(t)
+	blobs, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := NewWithDB(nil, false, 0, db.SQL)
+	svc.SetBlobStore(blobs)
+	u, err := NewUploads(svc, db.SQL, t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u, db
+}
+
+func beginFull(t *testing.T, u *Uploads, car []byte) *Upload {
+	t.Helper()
+	sum := blake3.Sum256(car)
+	up, err := u.Begin(context.Background(), "u1", "a.bin", "application/octet-stream", int64(len(car)), sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.WriteChunk(context.Background(), "u1", up.ID, 0, car, nil); err != nil {
+		t.Fatal(err)
+	}
+	return up
+}
+
+func countFiles(t *testing.T, db *store.DB) int {
+	t.Helper()
+	var n int
+	if err := db.SQL.QueryRow(`SELECT COUNT(*) FROM files`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestUploadCommitIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	u, db := newTestUploads(t)
+	car, root := testCAR(t, []byte("hello uploads"))
+	up := beginFull(t, u, car)
+
+	fileID, c, err := u.Commit(ctx, "u1", up.ID)
+	if err != nil || c != root {
+		t.Fatalf("commit: %s %v, want cid %s", c, err, root)
+	}
+	// ответ на первый вызов потерялся, клиент повторяет
+	again, c2, err := u.Commit(ctx, "u1", up.ID)
+	if err != nil || again != fileID || c2 != c {
+		t.Fatalf("repeat commit: %s %s %v, want %s %s", again, c2, err, fileID, c)
+	}
+	if n := countFiles(t, db); n != 1 {
+		t.Fatalf("%d files after repeated commit", n)
+	}
+	if _, err := u.WriteChunk(ctx, "u1", up.ID, 0, car[:1], nil); !errors.Is(err, ErrUploadCommitted) {
+		t.Fatalf("write after commit: %v", err)
+	}
+	if usage, err := (&Quotas{db: db.SQL}).Usage(ctx, "u1"); err != nil || usage.ReservedBytes != 0 {
+		t.Fatalf("committed upload still reserved: %+v %v", usage, err)
+	}
+}
+
+func TestUploadConcurrentCommit(t *testing.T) {
+	u, db := newTestUploads(t)
+	car, _ := testCAR(t, make([]byte, 64<<10))
+	up := beginFull(t, u, car)
+
+	var wg sync.WaitGroup
+	ids := make([]string, 8)
+	errs := make([]error, 8)
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ids[i], _, errs[i] = u.Commit(context.Background(), "u1", up.ID)
+		}(i)
+	}
+	wg.Wait()
+	var fileID string
+	for i, err := range errs {
+		switch {
+		case err == nil && fileID == "":
+			fileID = ids[i]
+		case err == nil && ids[i] != fileID:
+			t.Fatalf("commits returned different files %s and %s", fileID, ids[i])
+		case err != nil && !errors.Is(err, ErrUploadCommitting):
+			t.Fatalf("commit: %v", err)
+		}
+	}
+	if fileID == "" {
+		t.Fatal("no commit succeeded")
+	}
+	if n := countFiles(t, db); n != 1 {
+		t.Fatalf("%d files after concurrent commits", n)
+	}
+}
+
+func TestUploadFailedCommitReopens(t *testing.T) {
+	ctx := context.Background()
+	u, db := newTestUploads(t)
+	car, _ := testCAR(t, []byte("damaged in staging"))
+	up := beginFull(t, u, car)
+
+	bad := append([]byte(nil), car...)
+	bad[len(bad)-1] ^= 0xff
+	if _, err := u.WriteChunk(ctx, "u1", up.ID, int64(len(bad)-1), bad[len(bad)-1:], nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := u.Commit(ctx, "u1", up.ID); !errors.Is(err, ErrBlake3Mismatch) {
+		t.Fatalf("commit of damaged upload: %v", err)
+	}
+	// битый кусок перезаливается, и фиксация проходит
+	if _, err := u.WriteChunk(ctx, "u1", up.ID, int64(len(car)-1), car[len(car)-1:], nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := u.Commit(ctx, "u1", up.ID); err != nil {
+		t.Fatalf("commit after repair: %v", err)
+	}
+	if n := countFiles(t, db); n != 1 {
+		t.Fatalf("%d files", n)
+	}
+}
+
+func TestUploadJanitorSkipsClaimedUpload(t *testing.T) {
+	ctx := context.Background()
+	u, db := newTestUploads(t)
+	car, _ := testCAR(t, []byte("claimed"))
+	up := beginFull(t, u, car)
+
+	if ok, err := u.claim(ctx, "u1", up.ID); err != nil || !ok {
+		t.Fatalf("claim: %v %v", ok, err)
+	}
+	if ok, err := u.claim(ctx, "u1", up.ID); err != nil || ok {
+		t.Fatalf("second claim: %v %v", ok, err)
+	}
+	u.expire(ctx)
+	if _, _, err := u.Commit(ctx, "u1", up.ID); !errors.Is(err, ErrUploadCommitting) {
+		t.Fatalf("commit while claimed: %v", err)
+	}
+	// истекшую загрузку захватить нельзя, janitor ее удаляет
+	other := beginFull(t, u, car)
+	if _, err := db.SQL.Exec(`UPDATE uploads SET expires_at = 1 WHERE id = ?`, other.ID); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := u.claim(ctx, "u1", other.ID); err != nil || ok {
+		t.Fatalf("claim of expired upload: %v %v", ok, err)
+	}
+	u.expire(ctx)
+	var n int
+	db.SQL.QueryRow(`SELECT COUNT(*) FROM uploads`).Scan(&n)
+	if n != 1 {
+		t.Fatalf("%d uploads after janitor, want the claimed one", n)
+	}
+}
+
+func TestUploadCommitInterruptedByRestartReopens(t *testing.T) {
+	ctx := context.Background()
+	u, db := newTestUploads(t)
+	car, root := testCAR(t, []byte("process died mid-commit"))
+	up := beginFull(t, u, car)
+	done := beginFull(t, u, car)
+	if _, _, err := u.Commit(ctx, "u1", done.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// захват остался от процесса, который упал посреди импорта
+	if ok, err := u.claim(ctx, "u1", up.ID); err != nil || !ok {
+		t.Fatalf("claim: %v %v", ok, err)
+	}
+	if _, _, err := u.Commit(ctx, "u1", up.ID); !errors.Is(err, ErrUploadCommitting) {
+		t.Fatalf("commit while claimed: %v", err)
+	}
+
+	restarted, err := NewUploads(u.svc, db.SQL, u.dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, c, err := restarted.Commit(ctx, "u1", up.ID); err != nil || c != root {
+		t.Fatalf("commit after restart: %s %v", c, err)
+	}
+	// завершенная фиксация рестарт переживает
+	if fileID, _, err := restarted.Commit(ctx, "u1", done.ID); err != nil || fileID == "" {
+		t.Fatalf("committed upload after restart: %s %v", fileID, err)
+	}
+	if n := countFiles(t, db); n != 2 {
+		t.Fatalf("%d files", n)
+	}
+}
@@ -0,0 +1,273 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+)
+
+var ErrVersionNotFound = errors.New("file version not found")
+
+// версия файла. текущая - последняя, ее cid лежит в files
+type Version struct {
+	FileID    string
+	Version   int64
+	CID       string
+	Size      int64
+	Blake3    []byte
+	DeviceID  string
+	CreatedAt time.Time
+	Current   bool
+}
+
+// сколько старых версий держать. версия остается, если она среди Keep последних
+// или моложе KeepFor; нулевое правило не действует, оба нулевые - история не чистится.
+// текущая версия не удаляется никогда
+type VersionRetention struct {
+	Keep    int
+	KeepFor time.Duration
+}
+
+func (r VersionRetention) enabled() bool {
+	return r.Keep > 0 || r.KeepFor > 0
+}
+
+func (s *Service) SetVersionRetention(r VersionRetention) {
+	s.retention = r
+}
+
+// загрузить новую версию файла. имя остается прежним, mime меняется, если задан.
+// версии, вышедшие за правила хранения, удаляются сразу
+func (s *Service) PutVersion(ctx context.Context, userID, deviceID, fileID, mime string, size int64, car io.Reader, totalBlake3 []byte) (*File, *Version, error) {
+	if s.db == nil {
+		return nil, nil, errors.New("storage database not configured")
+	}
+	if err := s.ownsFile(ctx, userID, fileID); err != nil {
+		return nil, nil, err
+	}
+	imp, err := s.importCAR(ctx, userID, "", size, car, totalBlake3)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer imp.release(ctx)
+	v := &Version{FileID: fileID, CID: imp.cid, Size: imp.received, Blake3: imp.blake3, DeviceID: deviceID, CreatedAt: time.Unix(time.Now().Unix(), 0), Current: true}
+	if err := s.addVersion(ctx, userID, v, mime); err != nil {
+		s.abandon(ctx, imp)
+		return nil, nil, err
+	}
+	s.recordCAR(ctx, imp, v.CreatedAt.Unix())
+	s.pruneFile(ctx, fileID)
+	f, err := s.StatFile(ctx, userID, fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, v, nil
+}
+
+// сделать v текущей версией: новая строка истории и cid файла. номер назначается здесь.
+// прежнее содержимое файла остается за своей версией, счетчики ведут триггеры
+func (s *Service) addVersion(ctx context.Context, userID string, v *Version, mime string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	r, err := tx.ExecContext(ctx, `UPDATE files SET cid = ?, size_bytes = ?, mime = COALESCE(NULLIF(?, ''), mime) WHERE id = ? AND user_id = ?`,
+		v.CID, v.Size, mime, v.FileID, userID)
+	if err != nil {
+		return fmt.Errorf("store version: %w", err)
+	}
+	if n, _ := r.RowsAffected(); n == 0 {
+		return ErrFileNotFound
+	}
+	err = tx.QueryRowContext(ctx, `INSERT INTO file_versions (file_id, version, cid, size_bytes, blake3, device_id, created_at)
+		SELECT ?, COALESCE(MAX(version), 0) + 1, ?, ?, ?, ?, ? FROM file_versions WHERE file_id = ? RETURNING version`,
+		v.FileID, v.CID, v.Size, v.Blake3, v.DeviceID, v.CreatedAt.Unix(), v.FileID).Scan(&v.Version)
+	if err != nil {
+		return fmt.Errorf("store version: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("store version: %w", err)
+	}
+	return nil
+}
+
+// история файла, новые версии сначала
+func (s *Service) ListVersions(ctx context.Context, userID, fileID string) ([]Version, error) {
+	if s.db == nil {
+		return nil, errors.New("storage database not configured")
+	}
+	if err := s.ownsFile(ctx, userID, fileID); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT file_id, version, cid, size_bytes, blake3, device_id, created_at FROM file_versions WHERE file_id = ? ORDER BY version DESC`, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("list versions: %w", err)
+	}
+	defer rows.Close()
+	var out []Version
+	for rows.Next() {
+		var v Version
+		var created int64
+		if err := rows.Scan(&v.FileID, &v.Version, &v.CID, &v.Size, &v.Blake3, &v.DeviceID, &created); err != nil {
+			return nil, err
+		}
+		v.CreatedAt = time.Unix(created, 0)
+		v.Current = len(out) == 0
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+// вернуть содержимое старой версии. история не переписывается: восстановленное
+// содержимое становится новой версией, автор - deviceID. восстановление текущей
+// версии ничего не меняет
+func (s *Service) RestoreVersion(ctx context.Context, userID, deviceID, fileID string, version int64) (*File, *Version, error) {
+	if s.db == nil {
+		return nil, nil, errors.New("storage database not configured")
+	}
+	versions, err := s.ListVersions(ctx, userID, fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var old *Version
+	for i := range versions {
+		if versions[i].Version == version {
+			old = &versions[i]
+		}
+	}
+	if old == nil {
+		return nil, nil, ErrVersionNotFound
+	}
+	if !old.Current {
+		v := &Version{FileID: fileID, CID: old.CID, Size: old.Size, Blake3: old.Blake3, DeviceID: deviceID, CreatedAt: time.Unix(time.Now().Unix(), 0), Current: true}
+		if err := s.addVersion(ctx, userID, v, ""); err != nil {
+			return nil, nil, err
+		}
+		old = v
+		s.pruneFile(ctx, fileID)
+	}
+	f, err := s.StatFile(ctx, userID, fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, old, nil
+}
+
+// проход хранения версий по всем файлам с историей, возвращает число удаленных версий
+func (s *Service) PruneVersions(ctx context.Context) (int, error) {
+	if s.db == nil || !s.retention.enabled() {
+		return 0, nil
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT file_id FROM file_versions GROUP BY file_id HAVING COUNT(*) > 1`)
+	if err != nil {
+		return 0, fmt.Errorf("prune versions: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return total, ctx.Err()
+		}
+		n, err := s.pruneVersions(ctx, id)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func (s *Service) pruneFile(ctx context.Context, fileID string) {
+	if _, err := s.pruneVersions(ctx, fileID); err != nil {
+		slog.Warn("storage: prune versions", "file", fileID, "error", err)
+	}
+}
+
+// удалить версии файла, вышедшие за правила хранения. их содержимое открепляется,
+// если на него не осталось ссылок, блоки подбирает gc блоков
+func (s *Service) pruneVersions(ctx context.Context, fileID string) (int, error) {
+	r := s.retention
+	if !r.enabled() {
+		return 0, nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	rows, err := tx.QueryContext(ctx, `SELECT version, cid, created_at FROM file_versions WHERE file_id = ? ORDER BY version DESC`, fileID)
+	if err != nil {
+		return 0, fmt.Errorf("prune versions: %w", err)
+	}
+	cutoff := time.Now().Add(-r.KeepFor).Unix()
+	var drop []int64
+	var cids []string
+	for i := 0; rows.Next(); i++ {
+		var version, created int64
+		var cid string
+		if err := rows.Scan(&version, &cid, &created); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if i == 0 || (r.Keep > 0 && i < r.Keep) || (r.KeepFor > 0 && created > cutoff) {
+			continue
+		}
+		drop = append(drop, version)
+		cids = append(cids, cid)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(drop) == 0 {
+		return 0, nil
+	}
+	for _, v := range drop {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM file_versions WHERE file_id = ? AND version = ?`, fileID, v); err != nil {
+			return 0, fmt.Errorf("prune versions: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("prune versions: %w", err)
+	}
+	if err := s.ReleaseUnreferenced(ctx, cids...); err != nil {
+		slog.Warn("storage: release unreferenced content", "error", err)
+	}
+	return len(drop), nil
+}
+
+// раз в interval, первый проход сразу
+func (s *Service) StartVersionPruning(ctx context.Context, interval time.Duration) {
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			if n, err := s.PruneVersions(ctx); err != nil && ctx.Err() == nil {
+				slog.Warn("storage: prune versions", "error", err)
+			} else if n > 0 {
+				slog.Info("storage: pruned versions", "versions", n)
+			}
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
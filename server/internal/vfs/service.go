@@ -0,0 +1,410 @@
+package vfs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Kind string
+
+const (
+	KindDir  Kind = "dir"
+	KindFile Kind = "file"
+)
+
+// name_hash - ключевой хеш имени от клиента, одинаковые имена в одном каталоге дают одинаковый хеш
+const (
+	nameHashSize    = 32
+	maxNameBlobSize = 1024
+	defaultPage     = 200
+	maxPage         = 1000
+)
+
+var (
+	ErrNodeNotFound    = errors.New("node not found")
+	ErrNameConflict    = errors.New("name already exists in folder")
+	ErrVersionConflict = errors.New("node was modified concurrently")
+	ErrNotDir          = errors.New("parent is not a folder")
+	ErrCycle           = errors.New("cannot move a folder into itself")
+	ErrParentTrashed   = errors.New("parent folder is in trash")
+	ErrBadName         = errors.New("invalid encrypted name")
+	ErrFileNotFound    = errors.New("file not found")
+
+	// каталог или его предок в корзине: для чтения он не существует
+	errInTrash = fmt.Errorf("folder is in trash: %w", ErrNodeNotFound)
+)
+
+// узел дерева. ParentID пустой у узлов в корне, FileID только у файлов
+type Node struct {
+	ID        string
+	UserID    string
+	ParentID  string
+	Kind      Kind
+	FileID    string
+	NameBlob  []byte
+	NameHash  []byte
+	Version   int64
+	TrashedAt time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// дерево каталогов пользователя поверх files. изменения идут транзакциями и проверяют
+// конфликты имен и версию узла, так что два клиента не перетрут друг друга молча
+type Service struct {
+	db *sql.DB
+}
+
+func NewService(db *sql.DB) *Service {
+	return &Service{db: db}
+}
+
+func (s *Service) MkDir(ctx context.Context, userID, parentID string, nameBlob, nameHash []byte) (*Node, error) {
+	return s.create(ctx, userID, parentID, KindDir, "", nameBlob, nameHash)
+}
+
+// поместить загруженный файл пользователя в каталог
+func (s *Service) LinkFile(ctx context.Context, userID, fileID, parentID string, nameBlob, nameHash []byte) (*Node, error) {
+	return s.create(ctx, userID, parentID, KindFile, fileID, nameBlob, nameHash)
+}
+
+func (s *Service) create(ctx context.Context, userID, parentID string, kind Kind, fileID string, nameBlob, nameHash []byte) (*Node, error) {
+	if err := checkName(nameBlob, nameHash); err != nil {
+		return nil, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if err := liveDir(ctx, tx, userID, parentID); err != nil {
+		return nil, err
+	}
+	if kind == KindFile {
+		var n int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM files WHERE id = ? AND user_id = ?`, fileID, userID).Scan(&n); err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return nil, ErrFileNotFound
+		}
+	}
+	if err := checkConflict(ctx, tx, userID, parentID, nameHash, ""); err != nil {
+		return nil, err
+	}
+	id := uuid.NewString()
+	now := time.Now().Unix()
+	_, err = tx.ExecContext(ctx, `INSERT INTO vfs_nodes (id, user_id, parent_id, kind, file_id, name_blob, name_hash, version, created_at, updated_at) VALUES (?, ?, NULLIF(?, ''), ?, NULLIF(?, ''), ?, ?, 1, ?, ?)`,
+		id, userID, parentID, kind, fileID, nameBlob, nameHash, now, now)
+	if err != nil {
+		return nil, mapConflict(fmt.Errorf("create node: %w", err))
+	}
+	n, err := loadNode(ctx, tx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return n, tx.Commit()
+}
+
+// перенести и/или переименовать узел. пустое имя оставляет текущее,
+// expectedVersion 0 отключает проверку версии
+func (s *Service) Move(ctx context.Context, userID, nodeID, newParentID string, nameBlob, nameHash []byte, expectedVersion int64) (*Node, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	n, err := loadNode(ctx, tx, userID, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if !n.TrashedAt.IsZero() {
+		return nil, ErrNodeNotFound
+	}
+	if expectedVersion != 0 && n.Version != expectedVersion {
+		return nil, ErrVersionConflict
+	}
+	if len(nameHash) == 0 && len(nameBlob) == 0 {
+		nameBlob, nameHash = n.NameBlob, n.NameHash
+	} else if err := checkName(nameBlob, nameHash); err != nil {
+		return nil, err
+	}
+	// в каталог из корзины или под ней узел пропал бы из живого дерева
+	if err := liveDir(ctx, tx, userID, newParentID); err != nil {
+		if errors.Is(err, errInTrash) {
+			return nil, ErrParentTrashed
+		}
+		return nil, err
+	}
+	if n.Kind == KindDir && newParentID != "" {
+		inside, err := isAncestor(ctx, tx, nodeID, newParentID)
+		if err != nil {
+			return nil, err
+		}
+		if inside {
+			return nil, ErrCycle
+		}
+	}
+	if err := checkConflict(ctx, tx, userID, newParentID, nameHash, nodeID); err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE vfs_nodes SET parent_id = NULLIF(?, ''), name_blob = ?, name_hash = ?, version = version + 1, updated_at = ? WHERE id = ?`,
+		newParentID, nameBlob, nameHash, time.Now().Unix(), nodeID)
+	if err != nil {
+		return nil, mapConflict(fmt.Errorf("move node: %w", err))
+	}
+	if n, err = loadNode(ctx, tx, userID, nodeID); err != nil {
+		return nil, err
+	}
+	return n, tx.Commit()
+}
+
+// живые узлы каталога, parentID пустой для корня
+func (s *Service) List(ctx context.Context, userID, parentID, pageToken string, limit int) ([]Node, string, error) {
+	if err := liveDir(ctx, s.db, userID, parentID); err != nil {
+		return nil, "", err
+	}
+	return s.page(ctx, `user_id = ? AND COALESCE(parent_id, '') = ? AND trashed_at IS NULL`, []any{userID, parentID}, pageToken, limit)
+}
+
+// узлы, помещенные в корзину. их потомки в корзине не показываются и вернутся вместе с ними
+func (s *Service) ListTrash(ctx context.Context, userID, pageToken string, limit int) ([]Node, string, error) {
+	return s.page(ctx, `user_id = ? AND trashed_at IS NOT NULL`, []any{userID}, pageToken, limit)
+}
+
+func (s *Service) page(ctx context.Context, where string, args []any, pageToken string, limit int) ([]Node, string, error) {
+	if limit <= 0 {
+		limit = defaultPage
+	}
+	if limit > maxPage {
+		limit = maxPage
+	}
+	q := `SELECT ` + nodeColumns + ` FROM vfs_nodes WHERE ` + where
+	if pageToken != "" {
+		q += ` AND id > ?`
+		args = append(args, pageToken)
+	}
+	q += ` ORDER BY id LIMIT ?`
+	args = append(args, limit+1)
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("list nodes: %w", err)
+	}
+	defer rows.Close()
+	var nodes []Node
+	for rows.Next() {
+		n, err := scanNode(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		nodes = append(nodes, *n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	next := ""
+	if len(nodes) > limit {
+		nodes = nodes[:limit]
+		next = nodes[limit-1].ID
+	}
+	return nodes, next, nil
+}
+
+// найти узел по пути из хешей имен от корня
+func (s *Service) Resolve(ctx context.Context, userID string, nameHashes [][]byte) (*Node, error) {
+	if len(nameHashes) == 0 {
+		return nil, ErrNodeNotFound
+	}
+	parentID := ""
+	var n *Node
+	for i, h := range nameHashes {
+		row := s.db.QueryRowContext(ctx, `SELECT `+nodeColumns+` FROM vfs_nodes WHERE user_id = ? AND COALESCE(parent_id, '') = ? AND name_hash = ? AND trashed_at IS NULL`,
+			userID, parentID, h)
+		var err error
+		if n, err = scanNode(row); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, ErrNodeNotFound
+			}
+			return nil, err
+		}
+		if i < len(nameHashes)-1 && n.Kind != KindDir {
+			return nil, ErrNotDir
+		}
+		parentID = n.ID
+	}
+	return n, nil
+}
+
+func (s *Service) Trash(ctx context.Context, userID, nodeID string, expectedVersion int64) (*Node, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	n, err := loadNode(ctx, tx, userID, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if !n.TrashedAt.IsZero() {
+		return n, nil
+	}
+	if expectedVersion != 0 && n.Version != expectedVersion {
+		return nil, ErrVersionConflict
+	}
+	now := time.Now().Unix()
+	if _, err := tx.ExecContext(ctx, `UPDATE vfs_nodes SET trashed_at = ?, version = version + 1, updated_at = ? WHERE id = ?`, now, now, nodeID); err != nil {
+		return nil, fmt.Errorf("trash node: %w", err)
+	}
+	if n, err = loadNode(ctx, tx, userID, nodeID); err != nil {
+		return nil, err
+	}
+	return n, tx.Commit()
+}
+
+// вернуть узел из корзины на прежнее место. если имя там уже занято,
+// клиент передает новое имя
+func (s *Service) Restore(ctx context.Context, userID, nodeID string, nameBlob, nameHash []byte) (*Node, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	n, err := loadNode(ctx, tx, userID, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if n.TrashedAt.IsZero() {
+		return n, nil
+	}
+	if len(nameHash) == 0 && len(nameBlob) == 0 {
+		nameBlob, nameHash = n.NameBlob, n.NameHash
+	} else if err := checkName(nameBlob, nameHash); err != nil {
+		return nil, err
+	}
+	if err := liveDir(ctx, tx, userID, n.ParentID); err != nil {
+		if errors.Is(err, ErrNodeNotFound) {
+			return nil, ErrParentTrashed
+		}
+		return nil, err
+	}
+	if err := checkConflict(ctx, tx, userID, n.ParentID, nameHash, nodeID); err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE vfs_nodes SET trashed_at = NULL, name_blob = ?, name_hash = ?, version = version + 1, updated_at = ? WHERE id = ?`,
+		nameBlob, nameHash, time.Now().Unix(), nodeID)
+	if err != nil {
+		return nil, mapConflict(fmt.Errorf("restore node: %w", err))
+	}
+	if n, err = loadNode(ctx, tx, userID, nodeID); err != nil {
+		return nil, err
+	}
+	return n, tx.Commit()
+}
+
+// запросы, которые выполняются и в транзакции, и без нее
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+const nodeColumns = `id, user_id, COALESCE(parent_id, ''), kind, COALESCE(file_id, ''), name_blob, name_hash, version, COALESCE(trashed_at, 0), created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanNode(r rowScanner) (*Node, error) {
+	var n Node
+	var trashed, created, updated int64
+	if err := r.Scan(&n.ID, &n.UserID, &n.ParentID, &n.Kind, &n.FileID, &n.NameBlob, &n.NameHash, &n.Version, &trashed, &created, &updated); err != nil {
+		return nil, err
+	}
+	if trashed > 0 {
+		n.TrashedAt = time.Unix(trashed, 0)
+	}
+	n.CreatedAt = time.Unix(created, 0)
+	n.UpdatedAt = time.Unix(updated, 0)
+	return &n, nil
+}
+
+func loadNode(ctx context.Context, q querier, userID, nodeID string) (*Node, error) {
+	n, err := scanNode(q.QueryRowContext(ctx, `SELECT `+nodeColumns+` FROM vfs_nodes WHERE id = ? AND user_id = ?`, nodeID, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNodeNotFound
+	}
+	return n, err
+}
+
+// каталог существует, принадлежит пользователю и ни он, ни его предки не в корзине
+func liveDir(ctx context.Context, q querier, userID, dirID string) error {
+	if dirID == "" {
+		return nil
+	}
+	n, err := loadNode(ctx, q, userID, dirID)
+	if err != nil {
+		return err
+	}
+	if n.Kind != KindDir {
+		return ErrNotDir
+	}
+	var trashed int
+	err = q.QueryRowContext(ctx, `WITH RECURSIVE up(id, parent_id, trashed_at) AS (
+			SELECT id, parent_id, trashed_at FROM vfs_nodes WHERE id = ?
+			UNION ALL
+			SELECT n.id, n.parent_id, n.trashed_at FROM vfs_nodes n JOIN up ON n.id = up.parent_id
+		) SELECT COUNT(*) FROM up WHERE trashed_at IS NOT NULL`, dirID).Scan(&trashed)
+	if err != nil {
+		return fmt.Errorf("check folder: %w", err)
+	}
+	if trashed > 0 {
+		return errInTrash
+	}
+	return nil
+}
+
+// лежит ли nodeID на пути от dirID к корню, включая сам dirID
+func isAncestor(ctx context.Context, q querier, nodeID, dirID string) (bool, error) {
+	var n int
+	err := q.QueryRowContext(ctx, `WITH RECURSIVE up(id, parent_id) AS (
+			SELECT id, parent_id FROM vfs_nodes WHERE id = ?
+			UNION ALL
+			SELECT n.id, n.parent_id FROM vfs_nodes n JOIN up ON n.id = up.parent_id
+		) SELECT COUNT(*) FROM up WHERE id = ?`, dirID, nodeID).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("check ancestry: %w", err)
+	}
+	return n > 0, nil
+}
+
+func checkConflict(ctx context.Context, q querier, userID, parentID string, nameHash []byte, exceptID string) error {
+	var n int
+	err := q.QueryRowContext(ctx, `SELECT COUNT(*) FROM vfs_nodes WHERE user_id = ? AND COALESCE(parent_id, '') = ? AND name_hash = ? AND trashed_at IS NULL AND id != ?`,
+		userID, parentID, nameHash, exceptID).Scan(&n)
+	if err != nil {
+		return fmt.Errorf("check name: %w", err)
+	}
+	if n > 0 {
+		return ErrNameConflict
+	}
+	return nil
+}
+
+// уникальный индекс ловит гонку, которую не поймала проверка выше
+func mapConflict(err error) error {
+	if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return ErrNameConflict
+	}
+	return err
+}
+
+func checkName(blob, hash []byte) error {
+	if len(hash) != nameHashSize || len(blob) == 0 || len(blob) > maxNameBlobSize {
+		return ErrBadName
+	}
+	return nil
+}
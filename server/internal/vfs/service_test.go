@@ -0,0 +1,64 @@
+package vfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"dev.c0rex64.heroin/internal/store"
+)
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	db, err := store.Open(context.Background(), "file:"+t.TempDir()+"/vfs.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.SQL.Exec(`INSERT INTO users (id, username, server_salt, password_hash, second_factor_secret, created_at) VALUES ('u1', 'u1', x'00', x'00', x'00', 0)`); err != nil {
+		t.Fatal(err)
+	}
+	return NewService(db.SQL)
+}
+
+func name(b byte) ([]byte, []byte) {
+	return []byte{b}, bytes.Repeat([]byte{b}, nameHashSize)
+}
+
+func mkdir(t *testing.T, s *Service, parentID string, b byte) *Node {
+	t.Helper()
+	blob, hash := name(b)
+	n, err := s.MkDir(context.Background(), "u1", parentID, blob, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestMoveRejectsTrashedDestination(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	trashed := mkdir(t, s, "", 1)
+	nested := mkdir(t, s, trashed.ID, 2)
+	node := mkdir(t, s, "", 3)
+	if _, err := s.Trash(ctx, "u1", trashed.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dest := range []string{trashed.ID, nested.ID} {
+		if _, err := s.Move(ctx, "u1", node.ID, dest, nil, nil, 0); !errors.Is(err, ErrParentTrashed) {
+			t.Fatalf("move into %s: %v", dest, err)
+		}
+	}
+	live, _, err := s.List(ctx, "u1", "", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(live) != 1 || live[0].ID != node.ID {
+		t.Fatalf("root after rejected moves: %+v", live)
+	}
+	if _, _, err := s.List(ctx, "u1", nested.ID, "", 0); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("list under trash: %v", err)
+	}
+}
@@ -47,7 +47,6 @@ CREATE TABLE IF NOT EXISTS files (
   FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
 );
 CREATE INDEX IF NOT EXISTS idx_files_user ON files(user_id);
-CREATE UNIQUE INDEX IF NOT EXISTS idx_files_cid ON files(cid);
 
 -- messages (offline envelopes)
 CREATE TABLE IF NOT EXISTS messages (
@@ -0,0 +1,11 @@
+-- ссылки управляющих конвертов (edit, delete, react, unreact) на исходные сообщения
+CREATE TABLE IF NOT EXISTS message_refs (
+  conversation_id TEXT NOT NULL,
+  message_id TEXT NOT NULL,
+  target_message_id TEXT NOT NULL,
+  kind INTEGER NOT NULL,
+  sender_id TEXT NOT NULL,
+  created_at INTEGER NOT NULL,
+  PRIMARY KEY(conversation_id, message_id)
+);
+CREATE INDEX IF NOT EXISTS idx_message_refs_target ON message_refs(conversation_id, target_message_id);
@@ -0,0 +1,7 @@
+-- настройки приватности присутствия, сами события присутствия не сохраняются
+CREATE TABLE IF NOT EXISTS presence_settings (
+  user_id TEXT PRIMARY KEY,
+  last_seen_visibility INTEGER NOT NULL DEFAULT 0,
+  updated_at INTEGER NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,23 @@
+-- push токены устройств для офлайн уведомлений
+CREATE TABLE IF NOT EXISTS push_tokens (
+  user_id TEXT NOT NULL,
+  device_id TEXT NOT NULL,
+  platform TEXT NOT NULL,
+  token TEXT NOT NULL,
+  updated_at INTEGER NOT NULL,
+  PRIMARY KEY(user_id, device_id),
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- уведомления, которые не удалось доставить после всех попыток
+CREATE TABLE IF NOT EXISTS notification_dead_letters (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  device_id TEXT NOT NULL,
+  conversation_id TEXT NOT NULL,
+  message_id TEXT NOT NULL,
+  attempts INTEGER NOT NULL,
+  last_error TEXT,
+  created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_notification_dead_letters_user ON notification_dead_letters(user_id, created_at);
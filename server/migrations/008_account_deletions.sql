@@ -0,0 +1,12 @@
+-- фоновые задания удаления аккаунта, step - индекс следующего невыполненного шага
+CREATE TABLE IF NOT EXISTS account_deletions (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL UNIQUE,
+  state TEXT NOT NULL CHECK(state IN ('pending', 'running', 'done', 'failed')),
+  step INTEGER NOT NULL DEFAULT 0,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  last_error TEXT,
+  created_at INTEGER NOT NULL,
+  updated_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_account_deletions_state ON account_deletions(state);
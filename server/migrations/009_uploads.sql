@@ -0,0 +1,21 @@
+-- незавершенные возобновляемые загрузки, данные лежат в staging каталоге
+CREATE TABLE IF NOT EXISTS uploads (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  name TEXT,
+  mime TEXT,
+  size_bytes INTEGER NOT NULL,
+  blake3 BLOB NOT NULL,
+  created_at INTEGER NOT NULL,
+  expires_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_uploads_expires ON uploads(expires_at);
+
+-- принятые диапазоны [start, end), соседние и пересекающиеся сливаются
+CREATE TABLE IF NOT EXISTS upload_ranges (
+  upload_id TEXT NOT NULL,
+  range_start INTEGER NOT NULL,
+  range_end INTEGER NOT NULL,
+  PRIMARY KEY(upload_id, range_start),
+  FOREIGN KEY(upload_id) REFERENCES uploads(id) ON DELETE CASCADE
+);
@@ -0,0 +1,7 @@
+-- bao outboard над экспортом kubo, сам outboard лежит файлом <cid>.obao в каталоге outboard_dir
+CREATE TABLE IF NOT EXISTS outboards (
+  cid TEXT PRIMARY KEY,
+  blake3 BLOB NOT NULL,
+  size_bytes INTEGER NOT NULL,
+  created_at INTEGER NOT NULL
+);
@@ -0,0 +1,30 @@
+-- один cid может принадлежать нескольким файлам разных пользователей,
+-- уникальный индекс из первой версии схемы больше не нужен
+DROP INDEX IF EXISTS idx_files_cid;
+CREATE INDEX IF NOT EXISTS idx_files_by_cid ON files(cid);
+CREATE INDEX IF NOT EXISTS idx_files_user_created ON files(user_id, created_at);
+
+-- содержимое в ipfs и число ссылающихся файлов, при нуле cid открепляется
+CREATE TABLE IF NOT EXISTS contents (
+  cid TEXT PRIMARY KEY,
+  size_bytes INTEGER NOT NULL,
+  refcount INTEGER NOT NULL,
+  created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_contents_unreferenced ON contents(refcount) WHERE refcount <= 0;
+
+-- файлы, загруженные до появления contents
+INSERT OR IGNORE INTO contents (cid, size_bytes, refcount, created_at)
+  SELECT cid, MAX(COALESCE(size_bytes, 0)), COUNT(*), MIN(created_at) FROM files GROUP BY cid;
+
+-- счетчик ведут триггеры, так что его держат в порядке и импорт, и каскадное удаление пользователя
+CREATE TRIGGER IF NOT EXISTS files_contents_insert AFTER INSERT ON files
+BEGIN
+  INSERT INTO contents (cid, size_bytes, refcount, created_at) VALUES (NEW.cid, COALESCE(NEW.size_bytes, 0), 1, NEW.created_at)
+    ON CONFLICT(cid) DO UPDATE SET refcount = refcount + 1;
+END;
+
+CREATE TRIGGER IF NOT EXISTS files_contents_delete AFTER DELETE ON files
+BEGIN
+  UPDATE contents SET refcount = refcount - 1 WHERE cid = OLD.cid;
+END;
@@ -0,0 +1,22 @@
+-- виртуальная файловая система поверх files. имена шифруются на клиенте: сервер хранит
+-- непрозрачный name_blob и name_hash (ключевой хеш имени от клиента) для проверки конфликтов
+CREATE TABLE IF NOT EXISTS vfs_nodes (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  parent_id TEXT,
+  kind TEXT NOT NULL CHECK (kind IN ('dir', 'file')),
+  file_id TEXT,
+  name_blob BLOB NOT NULL,
+  name_hash BLOB NOT NULL,
+  version INTEGER NOT NULL DEFAULT 1,
+  trashed_at INTEGER,
+  created_at INTEGER NOT NULL,
+  updated_at INTEGER NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY(parent_id) REFERENCES vfs_nodes(id) ON DELETE CASCADE,
+  FOREIGN KEY(file_id) REFERENCES files(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_vfs_parent ON vfs_nodes(user_id, parent_id);
+CREATE INDEX IF NOT EXISTS idx_vfs_trashed ON vfs_nodes(user_id, trashed_at) WHERE trashed_at IS NOT NULL;
+-- в одном каталоге не бывает двух живых узлов с одним именем, корень это parent_id NULL
+CREATE UNIQUE INDEX IF NOT EXISTS idx_vfs_name ON vfs_nodes(user_id, COALESCE(parent_id, ''), name_hash) WHERE trashed_at IS NULL;
@@ -0,0 +1,39 @@
+-- общий доступ к файлам и каталогам. ключ доступа wrapped_key зашифрован клиентом для получателя
+CREATE TABLE IF NOT EXISTS share_grants (
+  id TEXT PRIMARY KEY,
+  owner_id TEXT NOT NULL,
+  file_id TEXT,
+  node_id TEXT,
+  grantee_kind TEXT NOT NULL CHECK (grantee_kind IN ('user', 'group')),
+  grantee_id TEXT NOT NULL,
+  wrapped_key BLOB NOT NULL,
+  created_at INTEGER NOT NULL,
+  CHECK ((file_id IS NULL) <> (node_id IS NULL)),
+  FOREIGN KEY(owner_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY(file_id) REFERENCES files(id) ON DELETE CASCADE,
+  FOREIGN KEY(node_id) REFERENCES vfs_nodes(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_share_grants_target ON share_grants(COALESCE(file_id, ''), COALESCE(node_id, ''), grantee_kind, grantee_id);
+CREATE INDEX IF NOT EXISTS idx_share_grants_grantee ON share_grants(grantee_kind, grantee_id);
+CREATE INDEX IF NOT EXISTS idx_share_grants_owner ON share_grants(owner_id);
+
+-- публичные ссылки. хранится только sha256 токена, пароль - argon2id с солью
+CREATE TABLE IF NOT EXISTS share_links (
+  id TEXT PRIMARY KEY,
+  token_hash BLOB NOT NULL UNIQUE,
+  owner_id TEXT NOT NULL,
+  file_id TEXT,
+  node_id TEXT,
+  password_salt BLOB,
+  password_hash BLOB,
+  expires_at INTEGER,
+  max_downloads INTEGER,
+  downloads INTEGER NOT NULL DEFAULT 0,
+  created_at INTEGER NOT NULL,
+  revoked_at INTEGER,
+  CHECK ((file_id IS NULL) <> (node_id IS NULL)),
+  FOREIGN KEY(owner_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY(file_id) REFERENCES files(id) ON DELETE CASCADE,
+  FOREIGN KEY(node_id) REFERENCES vfs_nodes(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_share_links_owner ON share_links(owner_id);
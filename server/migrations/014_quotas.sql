@@ -0,0 +1,10 @@
+-- назначенные тарифы квот. сами тарифы и их лимиты задаются в конфиге (ipfs.quota_plans),
+-- у пользователя без своего тарифа действует наибольший тариф его групп, затем тариф по умолчанию
+CREATE TABLE IF NOT EXISTS quota_assignments (
+  subject_kind TEXT NOT NULL CHECK (subject_kind IN ('user', 'group')),
+  subject_id TEXT NOT NULL,
+  plan TEXT NOT NULL,
+  updated_at INTEGER NOT NULL,
+  PRIMARY KEY(subject_kind, subject_id)
+);
+CREATE INDEX IF NOT EXISTS idx_uploads_user ON uploads(user_id);
@@ -0,0 +1,10 @@
+-- расхождения пинов kubo с files, которые видит сверка. missing - файл есть, пина нет,
+-- orphan - пин без файлов, открепляется по истечении грейс периода от first_seen
+CREATE TABLE IF NOT EXISTS pin_drift (
+  cid TEXT PRIMARY KEY,
+  kind TEXT NOT NULL CHECK (kind IN ('missing', 'orphan')),
+  first_seen INTEGER NOT NULL,
+  last_seen INTEGER NOT NULL,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  last_error TEXT
+);
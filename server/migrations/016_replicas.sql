@@ -0,0 +1,8 @@
+-- на каких узлах ipfs закреплен cid. node - адрес api узла из ipfs.endpoints
+CREATE TABLE IF NOT EXISTS replicas (
+  cid TEXT NOT NULL,
+  node TEXT NOT NULL,
+  pinned_at INTEGER NOT NULL,
+  PRIMARY KEY(cid, node)
+);
+CREATE INDEX IF NOT EXISTS idx_replicas_node ON replicas(node);
@@ -0,0 +1,9 @@
+-- что показал разбор car при загрузке: версия, число блоков и самый крупный блок.
+-- для содержимого, загруженного раньше, строки нет
+CREATE TABLE IF NOT EXISTS car_info (
+  cid TEXT PRIMARY KEY REFERENCES contents(cid) ON DELETE CASCADE,
+  car_version INTEGER NOT NULL,
+  blocks INTEGER NOT NULL,
+  max_block_bytes INTEGER NOT NULL,
+  created_at INTEGER NOT NULL
+);
@@ -0,0 +1,29 @@
+-- блоки загруженных car. cid блока хранится как raw cidv1 от его multihash: kubo
+-- держит блоки по multihash, и один блок под cidv0 и cidv1 - одни и те же байты
+CREATE TABLE IF NOT EXISTS blocks (
+  cid TEXT PRIMARY KEY,
+  size_bytes INTEGER NOT NULL,
+  refcount INTEGER NOT NULL,
+  created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_blocks_unreferenced ON blocks(refcount) WHERE refcount <= 0;
+
+-- какие блоки входят в содержимое. строки уходят вместе с contents, счетчик блоков
+-- ведут триггеры, как у contents
+CREATE TABLE IF NOT EXISTS content_blocks (
+  content_cid TEXT NOT NULL REFERENCES contents(cid) ON DELETE CASCADE,
+  block_cid TEXT NOT NULL,
+  size_bytes INTEGER NOT NULL,
+  PRIMARY KEY(content_cid, block_cid)
+);
+
+CREATE TRIGGER IF NOT EXISTS content_blocks_insert AFTER INSERT ON content_blocks
+BEGIN
+  INSERT INTO blocks (cid, size_bytes, refcount, created_at) VALUES (NEW.block_cid, NEW.size_bytes, 1, CAST(strftime('%s', 'now') AS INTEGER))
+    ON CONFLICT(cid) DO UPDATE SET refcount = refcount + 1;
+END;
+
+CREATE TRIGGER IF NOT EXISTS content_blocks_delete AFTER DELETE ON content_blocks
+BEGIN
+  UPDATE blocks SET refcount = refcount - 1 WHERE cid = OLD.block_cid;
+END;
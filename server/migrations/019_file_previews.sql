@@ -0,0 +1,27 @@
+-- миниатюры, превью и постеры видео, которые клиент строит сам и загружает отдельным car
+-- со своим ключом, завернутым так же, как ключ файла. у файла по одной записи каждого вида
+CREATE TABLE IF NOT EXISTS file_previews (
+  file_id TEXT NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL,
+  cid TEXT NOT NULL,
+  mime TEXT NOT NULL,
+  size_bytes INTEGER NOT NULL,
+  width INTEGER NOT NULL,
+  height INTEGER NOT NULL,
+  wrapped_key BLOB NOT NULL,
+  created_at INTEGER NOT NULL,
+  PRIMARY KEY(file_id, kind)
+);
+CREATE INDEX IF NOT EXISTS idx_file_previews_cid ON file_previews(cid);
+
+-- превью держат свое содержимое так же, как файлы
+CREATE TRIGGER IF NOT EXISTS file_previews_contents_insert AFTER INSERT ON file_previews
+BEGIN
+  INSERT INTO contents (cid, size_bytes, refcount, created_at) VALUES (NEW.cid, NEW.size_bytes, 1, NEW.created_at)
+    ON CONFLICT(cid) DO UPDATE SET refcount = refcount + 1;
+END;
+
+CREATE TRIGGER IF NOT EXISTS file_previews_contents_delete AFTER DELETE ON file_previews
+BEGIN
+  UPDATE contents SET refcount = refcount - 1 WHERE cid = OLD.cid;
+END;
@@ -0,0 +1,45 @@
+-- история версий файла. files.cid - текущая версия, она же последняя строка здесь.
+-- blake3 - хеш принятого car, у версий, перенесенных из files, его нет
+CREATE TABLE IF NOT EXISTS file_versions (
+  file_id TEXT NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+  version INTEGER NOT NULL,
+  cid TEXT NOT NULL,
+  size_bytes INTEGER NOT NULL,
+  blake3 BLOB,
+  device_id TEXT NOT NULL,
+  created_at INTEGER NOT NULL,
+  PRIMARY KEY(file_id, version)
+);
+CREATE INDEX IF NOT EXISTS idx_file_versions_cid ON file_versions(cid);
+
+-- версии держат свое содержимое, пока их не уберет хранение версий
+CREATE TRIGGER IF NOT EXISTS file_versions_contents_insert AFTER INSERT ON file_versions
+BEGIN
+  INSERT INTO contents (cid, size_bytes, refcount, created_at) VALUES (NEW.cid, NEW.size_bytes, 1, NEW.created_at)
+    ON CONFLICT(cid) DO UPDATE SET refcount = refcount + 1;
+END;
+
+CREATE TRIGGER IF NOT EXISTS file_versions_contents_delete AFTER DELETE ON file_versions
+BEGIN
+  UPDATE contents SET refcount = refcount - 1 WHERE cid = OLD.cid;
+END;
+
+-- у каждого файла есть первая версия, автора и хеш дописывает загрузка
+CREATE TRIGGER IF NOT EXISTS files_first_version AFTER INSERT ON files
+BEGIN
+  INSERT INTO file_versions (file_id, version, cid, size_bytes, blake3, device_id, created_at)
+    VALUES (NEW.id, 1, NEW.cid, COALESCE(NEW.size_bytes, 0), NULL, '', NEW.created_at);
+END;
+
+-- новая версия меняет cid файла на месте
+CREATE TRIGGER IF NOT EXISTS files_contents_update AFTER UPDATE OF cid ON files WHEN OLD.cid <> NEW.cid
+BEGIN
+  INSERT INTO contents (cid, size_bytes, refcount, created_at) VALUES (NEW.cid, COALESCE(NEW.size_bytes, 0), 1, NEW.created_at)
+    ON CONFLICT(cid) DO UPDATE SET refcount = refcount + 1;
+  UPDATE contents SET refcount = refcount - 1 WHERE cid = OLD.cid;
+END;
+
+-- файлы без истории получают ее первую версию
+INSERT INTO file_versions (file_id, version, cid, size_bytes, blake3, device_id, created_at)
+  SELECT id, 1, cid, COALESCE(size_bytes, 0), NULL, '', created_at FROM files
+  WHERE NOT EXISTS (SELECT 1 FROM file_versions v WHERE v.file_id = files.id);
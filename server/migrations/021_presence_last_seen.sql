@@ -0,0 +1,6 @@
+-- последний раз в сети, переживает рестарт сервера. пишется при уходе в away и offline
+CREATE TABLE IF NOT EXISTS presence_last_seen (
+  user_id TEXT PRIMARY KEY,
+  last_seen_at INTEGER NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,10 @@
+-- фиксация загрузки: строки нет - загрузка открыта, committing - идет импорт,
+-- committed - файл создан. результат живет вместе с загрузкой до ее истечения,
+-- повторный CommitUpload возвращает его же
+CREATE TABLE IF NOT EXISTS upload_commits (
+  upload_id TEXT PRIMARY KEY,
+  state TEXT NOT NULL,
+  file_id TEXT,
+  cid TEXT,
+  FOREIGN KEY(upload_id) REFERENCES uploads(id) ON DELETE CASCADE
+);
@@ -0,0 +1,9 @@
+-- байты импортов, которые еще идут. резерв пишется в одной транзакции с проверкой
+-- квоты и снимается после записи файла, версии или превью либо при ошибке
+CREATE TABLE IF NOT EXISTS quota_reservations (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  size_bytes INTEGER NOT NULL,
+  created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_quota_reservations_user ON quota_reservations(user_id);
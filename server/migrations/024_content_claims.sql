@@ -0,0 +1,10 @@
+-- отметки загрузок и освобождений содержимого. загрузка после записи car в хранилище
+-- ставит claimed_at, освобождение ставит releasing_at одной командой с проверкой refcount
+-- и только потом удаляет car, так что cid, который только что приняла загрузка, не
+-- удаляется из-под нее. строка уходит вместе с освобожденным содержимым. время в
+-- наносекундах: отметка заодно опознает того, кто ее поставил
+CREATE TABLE IF NOT EXISTS content_claims (
+  cid TEXT PRIMARY KEY,
+  claimed_at INTEGER NOT NULL DEFAULT 0,
+  releasing_at INTEGER NOT NULL DEFAULT 0
+);
@@ -0,0 +1,267 @@
+// Package filecrypt реализует контейнер зашифрованного файла версии 1, см. docs/filecrypt.md.
+// Пакет публичный: тот же формат пишет и читает Android клиент, сервер видит только результат.
+//
+// Контейнер - заголовок и чанки XChaCha20-Poly1305 по схеме STREAM: nonce чанка собирается
+// из случайного префикса, номера чанка и флага последнего, поэтому любой чанк
+// расшифровывается отдельно, а обрезка, перестановка и дописывание чанков обнаруживаются.
+// Ключ файла случайный, в заголовке он лежит обернутым для владельца через X25519.
+// Из контейнера клиент собирает CAR для PutFile.
+package filecrypt
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	Version = 1
+
+	KeySize        = chacha20poly1305.KeySize
+	PublicKeySize  = curve25519.PointSize
+	PrivateKeySize = curve25519.ScalarSize
+	// эфемерный публичный ключ и зашифрованный ключ файла с тегом
+	WrappedKeySize = PublicKeySize + KeySize + chacha20poly1305.Overhead
+
+	NoncePrefixSize = chacha20poly1305.NonceSizeX - 5
+	HeaderSize      = 8 + WrappedKeySize + NoncePrefixSize
+	// тег poly1305 на каждый чанк
+	Overhead = chacha20poly1305.Overhead
+
+	// размер чанка открытого текста - степень двойки от 1 КБ до 16 МБ
+	MinChunkSize     = 1 << 10
+	MaxChunkSize     = 1 << 24
+	DefaultChunkSize = 64 << 10
+)
+
+// номер чанка в nonce занимает 4 байта
+const maxChunks = 1 << 32
+
+var magic = [4]byte{'H', 'R', 'F', 'C'}
+
+// контекст hkdf обертки ключа, меняется вместе с версией формата
+const wrapInfo = "heroin filecrypt v1 key wrap"
+
+var (
+	ErrFormat    = errors.New("filecrypt: not a filecrypt container")
+	ErrVersion   = errors.New("filecrypt: unsupported container version")
+	ErrChunkSize = errors.New("filecrypt: chunk size must be a power of two between 1 KiB and 16 MiB")
+	ErrKey       = errors.New("filecrypt: key unwrap failed")
+	ErrAuth      = errors.New("filecrypt: chunk authentication failed")
+	ErrTruncated = errors.New("filecrypt: container truncated")
+	ErrTrailing  = errors.New("filecrypt: data after final chunk")
+	ErrTooLarge  = errors.New("filecrypt: file exceeds 2^32 chunks")
+)
+
+// чанк не прошел проверку: неверный ключ или поврежденные данные.
+// errors.Is(err, ErrAuth) для любого номера
+type ChunkError struct {
+	Index int64
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("filecrypt: chunk %d failed authentication", e.Index)
+}
+
+func (e *ChunkError) Unwrap() error { return ErrAuth }
+
+// заголовок контейнера. целиком входит в aad каждого чанка
+type Header struct {
+	ChunkSize   int
+	WrappedKey  [WrappedKeySize]byte
+	NoncePrefix [NoncePrefixSize]byte
+}
+
+func (h *Header) Bytes() []byte {
+	b := make([]byte, HeaderSize)
+	copy(b, magic[:])
+	b[4] = Version
+	b[5] = byte(log2(h.ChunkSize))
+	copy(b[8:], h.WrappedKey[:])
+	copy(b[8+WrappedKeySize:], h.NoncePrefix[:])
+	return b
+}
+
+func ParseHeader(b []byte) (*Header, error) {
+	if len(b) < HeaderSize || !bytes.Equal(b[:4], magic[:]) {
+		return nil, ErrFormat
+	}
+	if b[4] != Version {
+		return nil, fmt.Errorf("%w %d", ErrVersion, b[4])
+	}
+	if b[6] != 0 || b[7] != 0 || b[5] >= 31 {
+		return nil, ErrFormat
+	}
+	h := &Header{ChunkSize: 1 << b[5]}
+	if err := checkChunkSize(h.ChunkSize); err != nil {
+		return nil, err
+	}
+	copy(h.WrappedKey[:], b[8:])
+	copy(h.NoncePrefix[:], b[8+WrappedKeySize:])
+	return h, nil
+}
+
+// ключ файла для владельца
+func (h *Header) Key(priv []byte) ([]byte, error) {
+	return UnwrapKey(h.WrappedKey[:], priv)
+}
+
+// nonce чанка: префикс, номер big endian, 1 у последнего чанка
+func (h *Header) nonce(i int64, last bool) []byte {
+	n := make([]byte, chacha20poly1305.NonceSizeX)
+	copy(n, h.NoncePrefix[:])
+	binary.BigEndian.PutUint32(n[NoncePrefixSize:], uint32(i))
+	if last {
+		n[len(n)-1] = 1
+	}
+	return n
+}
+
+// ключ файла владельца по первым HeaderSize байтам контейнера
+func OwnerKey(header, priv []byte) ([]byte, error) {
+	h, err := ParseHeader(header)
+	if err != nil {
+		return nil, err
+	}
+	return h.Key(priv)
+}
+
+func NewKey() ([]byte, error) {
+	k := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, k); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// пара X25519, совместима с crypto_kx_keypair libsodium
+func GenerateKeyPair() (pub, priv []byte, err error) {
+	priv = make([]byte, PrivateKeySize)
+	if _, err := io.ReadFull(rand.Reader, priv); err != nil {
+		return nil, nil, err
+	}
+	pub, err = curve25519.X25519(priv, curve25519.Basepoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pub, priv, nil
+}
+
+// обернуть ключ файла для получателя: эфемерный X25519, из общего секрета hkdf-sha256
+// выводит ключ и nonce обертки. тем же способом клиенты заворачивают wrapped_key
+// для общего доступа и превью
+func WrapKey(key, recipientPub []byte) ([]byte, error) {
+	return wrapKey(rand.Reader, key, recipientPub)
+}
+
+func wrapKey(rnd io.Reader, key, recipientPub []byte) ([]byte, error) {
+	if len(key) != KeySize || len(recipientPub) != PublicKeySize {
+		return nil, errors.New("filecrypt: bad key size")
+	}
+	eph := make([]byte, PrivateKeySize)
+	if _, err := io.ReadFull(rnd, eph); err != nil {
+		return nil, err
+	}
+	ephPub, err := curve25519.X25519(eph, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+	aead, nonce, err := wrapCipher(eph, recipientPub, ephPub, recipientPub)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(ephPub, nonce, key, nil), nil
+}
+
+func UnwrapKey(wrapped, priv []byte) ([]byte, error) {
+	if len(wrapped) != WrappedKeySize || len(priv) != PrivateKeySize {
+		return nil, ErrKey
+	}
+	pub, err := curve25519.X25519(priv, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+	ephPub := wrapped[:PublicKeySize]
+	aead, nonce, err := wrapCipher(priv, ephPub, ephPub, pub)
+	if err != nil {
+		return nil, ErrKey
+	}
+	key, err := aead.Open(nil, nonce, wrapped[PublicKeySize:], nil)
+	if err != nil {
+		return nil, ErrKey
+	}
+	return key, nil
+}
+
+// ikm - X25519(priv, peer), salt - эфемерный публичный ключ и ключ получателя
+func wrapCipher(priv, peer, ephPub, recipientPub []byte) (cipher.AEAD, []byte, error) {
+	shared, err := curve25519.X25519(priv, peer)
+	if err != nil {
+		// X25519 с точкой малого порядка дает нули
+		return nil, nil, err
+	}
+	salt := append(append([]byte{}, ephPub...), recipientPub...)
+	okm := make([]byte, KeySize+chacha20poly1305.NonceSizeX)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, salt, []byte(wrapInfo)), okm); err != nil {
+		return nil, nil, err
+	}
+	aead, err := chacha20poly1305.NewX(okm[:KeySize])
+	if err != nil {
+		return nil, nil, err
+	}
+	return aead, okm[KeySize:], nil
+}
+
+// размер контейнера для файла из size байт
+func CiphertextSize(size int64, chunkSize int) int64 {
+	c := int64(chunkSize)
+	n := max((size+c-1)/c, 1)
+	return HeaderSize + size + n*Overhead
+}
+
+// размер открытого текста по размеру контейнера. последний чанк непустой,
+// пустым он бывает только у пустого файла
+func PlaintextSize(size int64, chunkSize int) (int64, error) {
+	if err := checkChunkSize(chunkSize); err != nil {
+		return 0, err
+	}
+	body := size - HeaderSize
+	if body < Overhead {
+		return 0, ErrTruncated
+	}
+	sealed := int64(chunkSize) + Overhead
+	n := (body + sealed - 1) / sealed
+	if n > maxChunks {
+		return 0, ErrTooLarge
+	}
+	last := body - (n-1)*sealed - Overhead
+	if last < 0 || (last == 0 && n > 1) {
+		return 0, ErrTruncated
+	}
+	return body - n*Overhead, nil
+}
+
+func checkChunkSize(c int) error {
+	if c < MinChunkSize || c > MaxChunkSize || c&(c-1) != 0 {
+		return ErrChunkSize
+	}
+	return nil
+}
+
+func log2(c int) int {
+	n := 0
+	for c > 1 {
+		c >>= 1
+		n++
+	}
+	return n
+}
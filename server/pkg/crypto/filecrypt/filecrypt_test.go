@@ -0,0 +1,320 @@
+package filecrypt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+// последовательные байты from, from+1, ...
+func seq(from byte, n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = from + byte(i)
+	}
+	return b
+}
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func ownerPair(t *testing.T) (pub, priv []byte) {
+	t.Helper()
+	priv = seq(0x40, PrivateKeySize)
+	pub, err := curve25519.X25519(priv, curve25519.Basepoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pub, priv
+}
+
+// контейнер с детерминированными случайными байтами: эфемерный ключ, затем префикс
+func encrypt(t *testing.T, plain, key, ownerPub []byte, chunkSize int) []byte {
+	t.Helper()
+	rnd := bytes.NewReader(append(seq(0x80, PrivateKeySize), seq(0xa0, NoncePrefixSize)...))
+	var out bytes.Buffer
+	w, err := newWriter(rnd, &out, key, ownerPub, chunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(plain); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func decrypt(container, key []byte) ([]byte, error) {
+	r, err := NewReader(bytes.NewReader(container), key)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+const vectorWrapped = `493e82fc74464a59268817623d2053c5eb8e2cc4a988b4fee179ec6b010d531d
+	3c1749157564278032f0d099bc2c35d66d57a9ff31d8b8b70e13d332dda21914
+	e88bc14b9572fa0dae7020486062ca00`
+
+// векторы из docs/filecrypt.md, общие с Android клиентом
+func TestVectors(t *testing.T) {
+	pub, priv := ownerPair(t)
+	if got := hex.EncodeToString(pub); got != "79a631eede1bf9c98f12032cdeadd0e7a079398fc786b88cc846ec89af85a51a" {
+		t.Fatalf("owner public key %s", got)
+	}
+	key := seq(0x00, KeySize)
+	wrapped, err := wrapKey(bytes.NewReader(seq(0x80, PrivateKeySize)), key, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(wrapped, unhex(t, vectorWrapped)) {
+		t.Fatalf("wrapped_key %x", wrapped)
+	}
+	if k, err := UnwrapKey(wrapped, priv); err != nil || !bytes.Equal(k, key) {
+		t.Fatalf("unwrap: %x %v", k, err)
+	}
+
+	plain := make([]byte, 2500)
+	for i := range plain {
+		plain[i] = byte(i % 251)
+	}
+	c := encrypt(t, plain, key, pub, 1024)
+	if len(c) != 2655 || CiphertextSize(2500, 1024) != 2655 {
+		t.Fatalf("container %d bytes", len(c))
+	}
+	header := unhex(t, "48524643010a0000"+vectorWrapped+"a0a1a2a3a4a5a6a7a8a9aaabacadaeafb0b1b2")
+	if !bytes.Equal(c[:HeaderSize], header) {
+		t.Fatalf("header %x", c[:HeaderSize])
+	}
+	tags := []string{"5348120e62fe701bcfde1be96945e641", "033e8f55796156187e7bb70ea25617e4", "90c300639979d8c8c5559be301515d09"}
+	for i, end := range []int{HeaderSize + 1040, HeaderSize + 2080, len(c)} {
+		if got := hex.EncodeToString(c[end-Overhead : end]); got != tags[i] {
+			t.Fatalf("tag %d %s", i, got)
+		}
+	}
+	if sum := sha256.Sum256(c); hex.EncodeToString(sum[:]) != "1d41405bb2bec8d19f92e22559635c9e72581c59378754bded8741b10b2b6d31" {
+		t.Fatalf("sha256 %x", sum)
+	}
+
+	owner, err := OwnerKey(c[:HeaderSize], priv)
+	if err != nil || !bytes.Equal(owner, key) {
+		t.Fatalf("owner key: %x %v", owner, err)
+	}
+	if got, err := decrypt(c, owner); err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("decrypt vector: %v", err)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	pub, priv := ownerPair(t)
+	const cs = 1024
+	for _, size := range []int{0, 1, cs - 1, cs, cs + 1, 3 * cs, 3*cs + 7} {
+		plain := seq(7, size)
+		key := seq(byte(size), KeySize)
+		c := encrypt(t, plain, key, pub, cs)
+		if int64(len(c)) != CiphertextSize(int64(size), cs) {
+			t.Fatalf("size %d: container %d, CiphertextSize %d", size, len(c), CiphertextSize(int64(size), cs))
+		}
+		if p, err := PlaintextSize(int64(len(c)), cs); err != nil || p != int64(size) {
+			t.Fatalf("size %d: PlaintextSize %d %v", size, p, err)
+		}
+		if k, err := OwnerKey(c, priv); err != nil || !bytes.Equal(k, key) {
+			t.Fatalf("size %d: owner key %v", size, err)
+		}
+		got, err := decrypt(c, key)
+		if err != nil || !bytes.Equal(got, plain) {
+			t.Fatalf("size %d: stream decrypt %v", size, err)
+		}
+
+		ra, err := NewReaderAt(bytes.NewReader(c), int64(len(c)), key)
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if ra.Size() != int64(size) {
+			t.Fatalf("size %d: ReaderAt size %d", size, ra.Size())
+		}
+		// диапазоны поперек границ чанков
+		for _, r := range [][2]int{{0, size}, {cs - 3, 6}, {size / 2, size / 3}, {size - 1, 1}} {
+			off, n := r[0], r[1]
+			if off < 0 || n <= 0 || off+n > size {
+				continue
+			}
+			buf := make([]byte, n)
+			if k, err := ra.ReadAt(buf, int64(off)); k != n || (err != nil && err != io.EOF) || !bytes.Equal(buf, plain[off:off+n]) {
+				t.Fatalf("size %d: ReadAt(%d, %d) = %d %v", size, off, n, k, err)
+			}
+		}
+	}
+}
+
+func TestWrongKeys(t *testing.T) {
+	pub, priv := ownerPair(t)
+	key := seq(0, KeySize)
+	c := encrypt(t, seq(0, 3000), key, pub, 1024)
+
+	var ce *ChunkError
+	if _, err := decrypt(c, seq(1, KeySize)); !errors.As(err, &ce) || ce.Index != 0 || !errors.Is(err, ErrAuth) {
+		t.Fatalf("wrong file key: %v", err)
+	}
+	other := bytes.Clone(priv)
+	other[0] ^= 0xff
+	if _, err := OwnerKey(c, other); !errors.Is(err, ErrKey) {
+		t.Fatalf("wrong owner secret: %v", err)
+	}
+}
+
+func TestHeaderTampering(t *testing.T) {
+	pub, _ := ownerPair(t)
+	key := seq(0, KeySize)
+	c := encrypt(t, seq(0, 3000), key, pub, 1024)
+
+	// префикс nonce и обернутый ключ входят в aad: ломается первый же чанк
+	for _, pos := range []int{HeaderSize - 1, 8} {
+		bad := bytes.Clone(c)
+		bad[pos] ^= 1
+		if _, err := decrypt(bad, key); !errors.Is(err, ErrAuth) {
+			t.Fatalf("header byte %d flipped: %v", pos, err)
+		}
+	}
+	bad := bytes.Clone(c)
+	bad[4] = 2
+	if _, err := decrypt(bad, key); !errors.Is(err, ErrVersion) {
+		t.Fatalf("unknown version: %v", err)
+	}
+	bad = bytes.Clone(c)
+	bad[6] = 1
+	if _, err := decrypt(bad, key); !errors.Is(err, ErrFormat) {
+		t.Fatalf("reserved byte set: %v", err)
+	}
+	bad = bytes.Clone(c)
+	bad[5] = 9
+	if _, err := decrypt(bad, key); !errors.Is(err, ErrChunkSize) {
+		t.Fatalf("chunk size below minimum: %v", err)
+	}
+	if _, err := decrypt(c[:HeaderSize-1], key); !errors.Is(err, ErrFormat) {
+		t.Fatalf("short header: %v", err)
+	}
+}
+
+func TestTruncation(t *testing.T) {
+	pub, _ := ownerPair(t)
+	key := seq(0, KeySize)
+	const cs = 1024
+	c := encrypt(t, seq(0, 3*cs-100), key, pub, cs)
+	sealed := cs + Overhead
+
+	// по границе чанка: последним оказывается чанк с флагом 0
+	cut := c[:HeaderSize+2*sealed]
+	if _, err := decrypt(cut, key); !errors.Is(err, ErrTruncated) {
+		t.Fatalf("stream cut at chunk boundary: %v", err)
+	}
+	ra, err := NewReaderAt(bytes.NewReader(cut), int64(len(cut)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ra.Chunk(1); !errors.Is(err, ErrTruncated) {
+		t.Fatalf("random access cut at chunk boundary: %v", err)
+	}
+	// чанки до обрезки читаются: их проверка от хвоста не зависит
+	if _, err := ra.Chunk(0); err != nil {
+		t.Fatalf("chunk before cut: %v", err)
+	}
+
+	// посреди последнего чанка
+	if _, err := decrypt(c[:len(c)-10], key); !errors.Is(err, ErrAuth) {
+		t.Fatalf("stream cut inside last chunk: %v", err)
+	}
+	// от последнего чанка остался только кусок тега
+	if _, err := PlaintextSize(int64(HeaderSize+2*sealed+Overhead-1), cs); !errors.Is(err, ErrTruncated) {
+		t.Fatalf("size with partial tag: %v", err)
+	}
+	if _, err := PlaintextSize(HeaderSize+Overhead-1, cs); !errors.Is(err, ErrTruncated) {
+		t.Fatalf("size without chunks: %v", err)
+	}
+
+	// читатель не отдает непроверенный хвост и не сообщает io.EOF до последнего чанка
+	r, err := NewReader(bytes.NewReader(cut), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	if !errors.Is(err, ErrTruncated) || len(got) != cs {
+		t.Fatalf("read %d bytes before error %v", len(got), err)
+	}
+}
+
+func TestTrailingData(t *testing.T) {
+	pub, _ := ownerPair(t)
+	key := seq(0, KeySize)
+	const cs = 1024
+	c := encrypt(t, seq(0, 2*cs), key, pub, cs)
+	if _, err := decrypt(append(bytes.Clone(c), 0), key); !errors.Is(err, ErrTrailing) {
+		t.Fatalf("byte after final chunk: %v", err)
+	}
+	// лишний чанк целиком после последнего
+	extra := append(bytes.Clone(c), c[HeaderSize:HeaderSize+cs+Overhead]...)
+	if _, err := decrypt(extra, key); !errors.Is(err, ErrTrailing) {
+		t.Fatalf("chunk after final chunk: %v", err)
+	}
+}
+
+func TestReordering(t *testing.T) {
+	pub, _ := ownerPair(t)
+	key := seq(0, KeySize)
+	const cs = 1024
+	sealed := cs + Overhead
+	c := encrypt(t, seq(0, 3*cs+5), key, pub, cs)
+
+	swapped := bytes.Clone(c)
+	a, b := swapped[HeaderSize:HeaderSize+sealed], swapped[HeaderSize+sealed:HeaderSize+2*sealed]
+	tmp := bytes.Clone(a)
+	copy(a, b)
+	copy(b, tmp)
+	var ce *ChunkError
+	if _, err := decrypt(swapped, key); !errors.As(err, &ce) || ce.Index != 0 {
+		t.Fatalf("swapped chunks: %v", err)
+	}
+	ra, err := NewReaderAt(bytes.NewReader(swapped), int64(len(swapped)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ra.Chunk(1); !errors.As(err, &ce) || ce.Index != 1 {
+		t.Fatalf("swapped chunk by index: %v", err)
+	}
+	if _, err := ra.Chunk(2); err != nil {
+		t.Fatalf("untouched chunk: %v", err)
+	}
+
+	// чанк другого контейнера с тем же ключом не подходит: другой префикс nonce
+	rnd := bytes.NewReader(append(seq(0x80, PrivateKeySize), seq(0x10, NoncePrefixSize)...))
+	var other bytes.Buffer
+	w, err := newWriter(rnd, &other, key, pub, cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(seq(0, 3*cs+5)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	mixed := bytes.Clone(c)
+	copy(mixed[HeaderSize:HeaderSize+sealed], other.Bytes()[HeaderSize:HeaderSize+sealed])
+	if _, err := decrypt(mixed, key); !errors.As(err, &ce) || ce.Index != 0 {
+		t.Fatalf("chunk from another container: %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package filecrypt
+
+import (
+	"crypto/cipher"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// произвольный доступ: читаются и проверяются только чанки, покрывающие запрошенный
+// диапазон. годится поверх ReaderAt, который тянет диапазоны car по сети
+type ReaderAt struct {
+	r      io.ReaderAt
+	aead   cipher.AEAD
+	h      *Header
+	hdr    []byte
+	size   int64
+	chunks int64
+}
+
+// size - размер контейнера целиком
+func NewReaderAt(r io.ReaderAt, size int64, key []byte) (*ReaderAt, error) {
+	hdr := make([]byte, HeaderSize)
+	if _, err := r.ReadAt(hdr, 0); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, ErrFormat
+		}
+		return nil, err
+	}
+	h, err := ParseHeader(hdr)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := PlaintextSize(size, h.ChunkSize)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	c := int64(h.ChunkSize)
+	return &ReaderAt{r: r, aead: aead, h: h, hdr: hdr, size: plain, chunks: max((plain+c-1)/c, 1)}, nil
+}
+
+func (r *ReaderAt) Header() *Header { return r.h }
+
+// размер открытого текста
+func (r *ReaderAt) Size() int64 { return r.size }
+
+func (r *ReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("filecrypt: negative offset")
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	c := int64(r.h.ChunkSize)
+	n := 0
+	for n < len(p) && off < r.size {
+		i := off / c
+		chunk, err := r.Chunk(i)
+		if err != nil {
+			return n, err
+		}
+		k := copy(p[n:], chunk[off-i*c:])
+		n += k
+		off += int64(k)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// открытый текст чанка i
+func (r *ReaderAt) Chunk(i int64) ([]byte, error) {
+	if i < 0 || i >= r.chunks {
+		return nil, errors.New("filecrypt: chunk index out of range")
+	}
+	c := int64(r.h.ChunkSize)
+	start := HeaderSize + i*(c+Overhead)
+	length := c + Overhead
+	last := i == r.chunks-1
+	if last {
+		length = r.size - i*c + Overhead
+	}
+	sealed := make([]byte, length)
+	// ReaderAt может вернуть io.EOF вместе с последними байтами
+	if k, err := r.r.ReadAt(sealed, start); k < len(sealed) {
+		if err == nil || errors.Is(err, io.EOF) {
+			return nil, ErrTruncated
+		}
+		return nil, err
+	}
+	return openChunk(r.aead, r.h, r.hdr, nil, sealed, i, last)
+}
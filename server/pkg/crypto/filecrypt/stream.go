@@ -0,0 +1,223 @@
+package filecrypt
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// потоковое шифрование. заголовок пишется сразу, полный чанк уходит, когда за ним
+// пришел хотя бы байт: только Close знает, какой чанк последний
+type Writer struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	h      *Header
+	hdr    []byte
+	buf    []byte
+	out    []byte
+	n      int64
+	err    error
+	closed bool
+}
+
+// ключ файла оборачивается для ownerPub и кладется в заголовок.
+// chunkSize 0 - DefaultChunkSize
+func NewWriter(w io.Writer, key, ownerPub []byte, chunkSize int) (*Writer, error) {
+	return newWriter(rand.Reader, w, key, ownerPub, chunkSize)
+}
+
+func newWriter(rnd io.Reader, w io.Writer, key, ownerPub []byte, chunkSize int) (*Writer, error) {
+	if chunkSize == 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if err := checkChunkSize(chunkSize); err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	h := &Header{ChunkSize: chunkSize}
+	wrapped, err := wrapKey(rnd, key, ownerPub)
+	if err != nil {
+		return nil, err
+	}
+	copy(h.WrappedKey[:], wrapped)
+	if _, err := io.ReadFull(rnd, h.NoncePrefix[:]); err != nil {
+		return nil, err
+	}
+	hdr := h.Bytes()
+	if _, err := w.Write(hdr); err != nil {
+		return nil, err
+	}
+	return &Writer{
+		w:    w,
+		aead: aead,
+		h:    h,
+		hdr:  hdr,
+		buf:  make([]byte, 0, chunkSize),
+		out:  make([]byte, 0, chunkSize+Overhead),
+	}, nil
+}
+
+func (w *Writer) Header() *Header { return w.h }
+
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("filecrypt: write after close")
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	written := 0
+	for len(p) > 0 {
+		if len(w.buf) == w.h.ChunkSize {
+			if w.err = w.seal(false); w.err != nil {
+				return written, w.err
+			}
+		}
+		k := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+k]
+		p = p[k:]
+		written += k
+	}
+	return written, nil
+}
+
+// дописать последний чанк. нижний writer не закрывается
+func (w *Writer) Close() error {
+	if w.closed {
+		return w.err
+	}
+	w.closed = true
+	if w.err != nil {
+		return w.err
+	}
+	w.err = w.seal(true)
+	return w.err
+}
+
+func (w *Writer) seal(last bool) error {
+	if w.n >= maxChunks {
+		return ErrTooLarge
+	}
+	w.out = w.aead.Seal(w.out[:0], w.h.nonce(w.n, last), w.buf, w.hdr)
+	if _, err := w.w.Write(w.out); err != nil {
+		return err
+	}
+	w.n++
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// потоковая расшифровка. данные отдаются только после проверки чанка, io.EOF - только
+// после последнего чанка, так что обрезанный контейнер не сойдет за целый файл
+type Reader struct {
+	r    *bufio.Reader
+	aead cipher.AEAD
+	h    *Header
+	hdr  []byte
+	in   []byte
+	out  []byte
+	rest []byte
+	n    int64
+	done bool
+	err  error
+}
+
+func NewReader(r io.Reader, key []byte) (*Reader, error) {
+	hdr := make([]byte, HeaderSize)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+			return nil, ErrFormat
+		}
+		return nil, err
+	}
+	h, err := ParseHeader(hdr)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{
+		r:    bufio.NewReaderSize(r, h.ChunkSize+Overhead+1),
+		aead: aead,
+		h:    h,
+		hdr:  hdr,
+		in:   make([]byte, h.ChunkSize+Overhead),
+	}, nil
+}
+
+func (r *Reader) Header() *Header { return r.h }
+
+func (r *Reader) Read(p []byte) (int, error) {
+	for len(r.rest) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		r.err = r.next()
+	}
+	k := copy(p, r.rest)
+	r.rest = r.rest[k:]
+	return k, nil
+}
+
+func (r *Reader) next() error {
+	k, err := io.ReadFull(r.r, r.in)
+	last := false
+	switch {
+	case err == nil:
+		// полный чанк последний, если за ним ничего нет
+		if _, err := r.r.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	case errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF):
+		last = true
+	default:
+		return err
+	}
+	if k < Overhead {
+		return ErrTruncated
+	}
+	out, err := openChunk(r.aead, r.h, r.hdr, r.out[:0], r.in[:k], r.n, last)
+	if err != nil {
+		return err
+	}
+	r.out, r.rest = out, out
+	r.n++
+	r.done = last
+	return nil
+}
+
+// проверить и расшифровать чанк i. при ошибке пробуется противоположный флаг
+// последнего, чтобы отличить обрезку и дописанные данные от порчи
+func openChunk(aead cipher.AEAD, h *Header, hdr, dst, sealed []byte, i int64, last bool) ([]byte, error) {
+	if i >= maxChunks {
+		return nil, ErrTooLarge
+	}
+	out, err := aead.Open(dst, h.nonce(i, last), sealed, hdr)
+	if err == nil {
+		if last && len(out) == 0 && i > 0 {
+			return nil, ErrFormat
+		}
+		return out, nil
+	}
+	if _, err := aead.Open(dst, h.nonce(i, !last), sealed, hdr); err == nil {
+		if last {
+			return nil, ErrTruncated
+		}
+		return nil, ErrTrailing
+	}
+	return nil, &ChunkError{Index: i}
+}
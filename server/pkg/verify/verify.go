@@ -0,0 +1,198 @@
+// Package verify реализует проверку car по дереву BLAKE3 (bao) группами по 1 КБ.
+// Пакет публичный: им пользуются и сервер, и Go клиенты.
+//
+// Сервер хранит outboard - внутренние узлы дерева без данных. К каждому отданному
+// куску прикладывается доказательство: 8 байт размера и родительские узлы на пути
+// от корня к группам куска в прямом порядке обхода. Клиент проверяет кусок по
+// известному ему корню (BLAKE3 всего car) и узнает, с какой группы начались повреждения.
+package verify
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+
+	"lukechampine.com/blake3/bao"
+)
+
+// группа из 2^Group чанков blake3, 0 - стандартный bao с группами по 1 КБ
+const Group = 0
+
+const GroupSize = 1024 << Group
+
+var (
+	ErrBadProof     = errors.New("malformed bao proof")
+	ErrMisaligned   = errors.New("slice must start and end on a 1 KiB group boundary")
+	ErrSliceOutside = errors.New("slice outside of data")
+)
+
+// первая группа, не прошедшая проверку. Offset - смещение от начала всех данных
+type CorruptionError struct {
+	Offset int64
+}
+
+func (e *CorruptionError) Error() string {
+	return fmt.Sprintf("bao verification failed at byte %d", e.Offset)
+}
+
+// размер outboard для данных длины size
+func OutboardSize(size int64) int64 {
+	return int64(bao.EncodedSize(int(size), Group, true))
+}
+
+// построить outboard для ровно size байт из data. dst пишется не последовательно
+func Encode(dst io.WriterAt, data io.Reader, size int64) ([32]byte, error) {
+	return bao.Encode(dst, data, size, Group, true)
+}
+
+// число родительских узлов в поддереве над n байтами
+func parents(n uint64) uint64 {
+	if n <= GroupSize {
+		return 0
+	}
+	return (n+GroupSize-1)/GroupSize - 1
+}
+
+// длина левого поддерева, как в bao: наибольшая степень двойки меньше n
+func split(n uint64) uint64 {
+	return uint64(1) << (bits.Len64(n-1) - 1)
+}
+
+func checkSlice(size, offset, length uint64) error {
+	if offset+length > size || offset+length < offset {
+		return ErrSliceOutside
+	}
+	if offset%GroupSize != 0 {
+		return ErrMisaligned
+	}
+	if end := offset + length; end != size && end%GroupSize != 0 {
+		return ErrMisaligned
+	}
+	return nil
+}
+
+func overlaps(pos, n, offset, length uint64) bool {
+	return pos < offset+length && offset < pos+n
+}
+
+// записать в dst доказательство для [offset, offset+length) из outboard.
+// читаются только узлы на нужных путях, так что цена не зависит от размера файла
+func Proof(dst io.Writer, outboard io.ReaderAt, offset, length int64) error {
+	if offset < 0 || length < 0 {
+		return ErrSliceOutside
+	}
+	var hdr [8]byte
+	if _, err := outboard.ReadAt(hdr[:], 0); err != nil {
+		return fmt.Errorf("read outboard: %w", err)
+	}
+	size := binary.LittleEndian.Uint64(hdr[:])
+	off, n := uint64(offset), uint64(length)
+	if err := checkSlice(size, off, n); err != nil {
+		return err
+	}
+	if _, err := dst.Write(hdr[:]); err != nil {
+		return err
+	}
+	var node [64]byte
+	var rec func(pos, sub, at uint64) error
+	rec = func(pos, sub, at uint64) error {
+		if sub <= GroupSize || !overlaps(pos, sub, off, n) {
+			return nil
+		}
+		if _, err := outboard.ReadAt(node[:], int64(at)); err != nil {
+			return fmt.Errorf("read outboard: %w", err)
+		}
+		if _, err := dst.Write(node[:]); err != nil {
+			return err
+		}
+		mid := split(sub)
+		if err := rec(pos, mid, at+64); err != nil {
+			return err
+		}
+		return rec(pos+mid, sub-mid, at+64+64*parents(mid))
+	}
+	return rec(0, size, 8)
+}
+
+// размер всех данных из заголовка доказательства
+func ProofSize(proof []byte) (int64, error) {
+	if len(proof) < 8 {
+		return 0, ErrBadProof
+	}
+	return int64(binary.LittleEndian.Uint64(proof[:8])), nil
+}
+
+// проверить data по доказательству proof и корню root. data начинается с offset
+// и должна покрывать целые группы, кроме последней группы файла
+func Verify(proof, data []byte, offset int64, root [32]byte) error {
+	if len(proof) < 8 || offset < 0 {
+		return ErrBadProof
+	}
+	size := binary.LittleEndian.Uint64(proof[:8])
+	off, n := uint64(offset), uint64(len(data))
+	if err := checkSlice(size, off, n); err != nil {
+		return err
+	}
+	// собираем обычную bao slice кодировку: узлы и группы вперемешку в прямом порядке
+	enc := make([]byte, 0, len(proof)+len(data))
+	enc = append(enc, proof[:8]...)
+	nodes := proof[8:]
+	var rec func(pos, sub uint64) bool
+	rec = func(pos, sub uint64) bool {
+		if !overlaps(pos, sub, off, n) {
+			return true
+		}
+		if sub <= GroupSize {
+			enc = append(enc, data[pos-off:pos-off+sub]...)
+			return true
+		}
+		if len(nodes) < 64 {
+			return false
+		}
+		enc = append(enc, nodes[:64]...)
+		nodes = nodes[64:]
+		mid := split(sub)
+		return rec(pos, mid) && rec(pos+mid, sub-mid)
+	}
+	if !rec(0, size) || len(nodes) != 0 {
+		return ErrBadProof
+	}
+	var cw countWriter
+	ok, err := bao.DecodeSlice(&cw, bytes.NewReader(enc), Group, off, n, root)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadProof, err)
+	}
+	if !ok {
+		return &CorruptionError{Offset: offset + cw.n}
+	}
+	return nil
+}
+
+// проверить весь поток data по outboard, данные проходят в dst только проверенными группами
+func Stream(dst io.Writer, data, outboard io.Reader, root [32]byte) error {
+	cw := countWriter{w: dst}
+	ok, err := bao.Decode(&cw, data, outboard, Group, root)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &CorruptionError{Offset: cw.n}
+	}
+	return nil
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	if c.w == nil {
+		return len(p), nil
+	}
+	return c.w.Write(p)
+}
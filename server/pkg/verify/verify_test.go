@@ -0,0 +1,223 @@
+package verify
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// входные данные тестовых векторов blake3: байты i % 251
+func input(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i % 251)
+	}
+	return b
+}
+
+// outboard в памяти, Encode пишет его не по порядку
+type memWriterAt []byte
+
+func (m memWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	return copy(m[off:], p), nil
+}
+
+func encode(t *testing.T, data []byte) ([]byte, [32]byte) {
+	t.Helper()
+	ob := make(memWriterAt, OutboardSize(int64(len(data))))
+	root, err := Encode(ob, bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ob, root
+}
+
+func proof(t *testing.T, ob []byte, offset, length int64) []byte {
+	t.Helper()
+	var p bytes.Buffer
+	if err := Proof(&p, bytes.NewReader(ob), offset, length); err != nil {
+		t.Fatalf("proof [%d, +%d): %v", offset, length, err)
+	}
+	return p.Bytes()
+}
+
+// корень outboard - обычный хеш blake3, значения из официальных векторов
+func TestEncodeKnownAnswers(t *testing.T) {
+	cases := []struct {
+		n        int
+		hash     string
+		outboard int64
+	}{
+		{0, "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262", 8},
+		{1, "2d3adedff11b61f14c886e35afa036736dcd87a74d27b5c1510225d0f592e213", 8},
+		{1024, "42214739f095a406f3fc83deb889744ac00df831c10daa55189b5d121c855af7", 8},
+		{1025, "d00278ae47eb27b34faecf67b4fe263f82d5412916c1ffd97c8cb7fb814b8444", 8 + 64},
+		{2048, "e776b6028c7cd22a4d0ba182a8bf62205d2ef576467e838ed6f2529b85fba24a", 8 + 64},
+		{102400, "bc3e3d41a1146b069abffad3c0d44860cf664390afce4d9661f7902e7943e085", 8 + 64*99},
+	}
+	for _, tc := range cases {
+		ob, root := encode(t, input(tc.n))
+		if got := hex.EncodeToString(root[:]); got != tc.hash {
+			t.Errorf("%d bytes: root %s, want %s", tc.n, got, tc.hash)
+		}
+		if int64(len(ob)) != tc.outboard {
+			t.Errorf("%d bytes: outboard %d bytes, want %d", tc.n, len(ob), tc.outboard)
+		}
+		if size, err := ProofSize(ob); err != nil || size != int64(tc.n) {
+			t.Errorf("%d bytes: size in header %d %v", tc.n, size, err)
+		}
+	}
+}
+
+func TestVerifySliceBoundaries(t *testing.T) {
+	// пять целых групп и неполная шестая
+	data := input(5*GroupSize + 300)
+	ob, root := encode(t, data)
+	size := int64(len(data))
+	cases := []struct {
+		name           string
+		offset, length int64
+	}{
+		{"first group", 0, GroupSize},
+		{"middle groups", GroupSize, 3 * GroupSize},
+		{"last full group", 4 * GroupSize, GroupSize},
+		{"partial final group", 5 * GroupSize, 300},
+		{"tail with partial group", 3 * GroupSize, size - 3*GroupSize},
+		{"whole file", 0, size},
+		{"empty slice", GroupSize, 0},
+	}
+	for _, tc := range cases {
+		p := proof(t, ob, tc.offset, tc.length)
+		if err := Verify(p, data[tc.offset:tc.offset+tc.length], tc.offset, root); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+	}
+
+	// в файле из одной группы доказательство - только размер
+	small := input(700)
+	sob, sroot := encode(t, small)
+	p := proof(t, sob, 0, 700)
+	if len(p) != 8 {
+		t.Fatalf("proof for one group: %d bytes", len(p))
+	}
+	if err := Verify(p, small, 0, sroot); err != nil {
+		t.Fatal(err)
+	}
+	// в файле из двух групп путь к любой из них - весь outboard
+	pair := input(2 * GroupSize)
+	pob, _ := encode(t, pair)
+	if p := proof(t, pob, GroupSize, GroupSize); !bytes.Equal(p, pob) {
+		t.Fatalf("proof for two groups: %x", p)
+	}
+}
+
+func TestVerifyRejectsBadSlices(t *testing.T) {
+	data := input(4*GroupSize + 10)
+	ob, root := encode(t, data)
+	size := int64(len(data))
+	cases := []struct {
+		name           string
+		offset, length int64
+		want           error
+	}{
+		{"unaligned start", 100, GroupSize, ErrMisaligned},
+		{"unaligned end", 0, GroupSize + 1, ErrMisaligned},
+		{"past the end", 4 * GroupSize, 11, ErrSliceOutside},
+		{"starts past the end", size + GroupSize, 0, ErrSliceOutside},
+		{"negative offset", -GroupSize, GroupSize, ErrSliceOutside},
+	}
+	full := proof(t, ob, 0, size)
+	for _, tc := range cases {
+		var p bytes.Buffer
+		if err := Proof(&p, bytes.NewReader(ob), tc.offset, tc.length); !errors.Is(err, tc.want) {
+			t.Errorf("proof %s: %v, want %v", tc.name, err, tc.want)
+		}
+		if tc.offset < 0 {
+			continue
+		}
+		chunk := make([]byte, tc.length)
+		if err := Verify(full, chunk, tc.offset, root); !errors.Is(err, tc.want) && !errors.Is(err, ErrBadProof) {
+			t.Errorf("verify %s: %v, want %v", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	data := input(6 * GroupSize)
+	ob, root := encode(t, data)
+	offset, length := int64(GroupSize), int64(4*GroupSize)
+	p := proof(t, ob, offset, length)
+	chunk := data[offset : offset+length]
+
+	// испорченный байт в третьей группе среди проверяемых: первые две проходят
+	bad := bytes.Clone(chunk)
+	bad[2*GroupSize+7] ^= 1
+	var ce *CorruptionError
+	if err := Verify(p, bad, offset, root); !errors.As(err, &ce) || ce.Offset != offset+2*GroupSize {
+		t.Fatalf("tampered chunk: %v", err)
+	}
+
+	badNode := bytes.Clone(p)
+	badNode[8] ^= 1
+	if err := Verify(badNode, chunk, offset, root); err == nil {
+		t.Fatal("tampered proof node accepted")
+	}
+	var otherRoot [32]byte
+	if err := Verify(p, chunk, offset, otherRoot); !errors.As(err, &ce) {
+		t.Fatalf("wrong root: %v", err)
+	}
+	if err := Verify(p[:len(p)-1], chunk, offset, root); !errors.Is(err, ErrBadProof) {
+		t.Fatalf("truncated proof: %v", err)
+	}
+	if err := Verify(append(bytes.Clone(p), 0), chunk, offset, root); !errors.Is(err, ErrBadProof) {
+		t.Fatalf("proof with extra bytes: %v", err)
+	}
+	if err := Verify(p[:4], chunk, offset, root); !errors.Is(err, ErrBadProof) {
+		t.Fatalf("proof without size: %v", err)
+	}
+	// доказательство для группы из другого поддерева: у групп 0 и 1 путь общий
+	if err := Verify(proof(t, ob, 0, GroupSize), data[4*GroupSize:5*GroupSize], 4*GroupSize, root); err == nil {
+		t.Fatal("proof for another slice accepted")
+	}
+}
+
+func TestStream(t *testing.T) {
+	data := input(5*GroupSize + 300)
+	ob, root := encode(t, data)
+
+	var out bytes.Buffer
+	if err := Stream(&out, bytes.NewReader(data), bytes.NewReader(ob), root); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Fatal("stream output differs from input")
+	}
+
+	// до испорченной группы данные доходят, дальше нет
+	bad := bytes.Clone(data)
+	bad[3*GroupSize+1] ^= 1
+	out.Reset()
+	var ce *CorruptionError
+	if err := Stream(&out, bytes.NewReader(bad), bytes.NewReader(ob), root); !errors.As(err, &ce) || ce.Offset != 3*GroupSize {
+		t.Fatalf("tampered stream: %v", err)
+	}
+	if out.Len() != 3*GroupSize {
+		t.Fatalf("%d bytes passed before the corrupt group", out.Len())
+	}
+
+	// обрезанный outboard и обрезанные данные не проходят целиком
+	for name, tc := range map[string]struct{ data, ob []byte }{
+		"truncated outboard":   {data, ob[:len(ob)-64]},
+		"outboard header only": {data, ob[:8]},
+		"truncated data":       {data[:len(data)-1], ob},
+	} {
+		out.Reset()
+		if err := Stream(&out, bytes.NewReader(tc.data), bytes.NewReader(tc.ob), root); err == nil {
+			t.Errorf("%s accepted", name)
+		}
+		if out.Len() == len(data) {
+			t.Errorf("%s: all data passed", name)
+		}
+	}
+}